@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
 )
@@ -26,7 +25,7 @@ func (a *Client) AntMerchantShopModify(ctx context.Context, bm pay.BodyMap) (ali
 		return nil, err
 	}
 	aliRsp = new(AntMerchantShopModifyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -49,7 +48,7 @@ func (a *Client) AntMerchantShopCreate(ctx context.Context, bm pay.BodyMap) (ali
 		return nil, err
 	}
 	aliRsp = new(AntMerchantShopCreateRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -72,7 +71,7 @@ func (a *Client) AntMerchantShopConsult(ctx context.Context, bm pay.BodyMap) (al
 		return nil, err
 	}
 	aliRsp = new(AntMerchantShopConsultRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -95,7 +94,7 @@ func (a *Client) AntMerchantOrderQuery(ctx context.Context, bm pay.BodyMap) (ali
 		return nil, err
 	}
 	aliRsp = new(AntMerchantOrderQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -114,7 +113,7 @@ func (a *Client) AntMerchantShopQuery(ctx context.Context, bm pay.BodyMap) (aliR
 		return nil, err
 	}
 	aliRsp = new(AntMerchantShopQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -133,7 +132,7 @@ func (a *Client) AntMerchantShopClose(ctx context.Context, bm pay.BodyMap) (aliR
 		return nil, err
 	}
 	aliRsp = new(AntMerchantShopCloseRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
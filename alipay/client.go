@@ -12,18 +12,26 @@
 package alipay
 
 import (
+	"bytes"
 	"context"
 	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/rwscode/payutil/pkg/idempotency"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/retry"
 	"github.com/rwscode/payutil/pkg/util"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xlog"
 	"github.com/rwscode/payutil/pkg/xpem"
 	"github.com/rwscode/payutil/pkg/xrsa"
+	"github.com/tjfoc/gmsm/sm2"
 )
 
 type Client struct {
@@ -40,15 +48,26 @@ type Client struct {
 	bodySize           int // http response body size(MB), default is 10MB
 	privateKey         *rsa.PrivateKey
 	aliPayPublicKey    *rsa.PublicKey // 支付宝证书公钥内容 alipayCertPublicKey_RSA2.crt
+	sm2PrivateKey      *sm2.PrivateKey
+	aliPaySM2PublicKey *sm2.PublicKey // 国密支付宝公钥，SM2 签名方式使用
+	encryptKey         string         // 接口内容加密密钥，为空时不加密
+	encryptType        string         // 接口内容加密方式，AES 或 SM4
 	autoSign           bool
 	DebugSwitch        pay.DebugSwitch
 	location           *time.Location
+	hc                 xhttp.Doer         // 自定义 Doer，为空时使用 xhttp 共享连接池
+	retry              *retry.Policy      // 重试策略，为空时不重试
+	limiter            *xhttp.Limiter     // 按接口族限流、熔断，为空时不限制
+	baseURL            string             // 自定义网关地址，为空时按 IsProd 选择
+	mw                 observe.Chain      // 中间件链
+	logger             xlog.Logger        // 结构化日志，为空时使用全局 xlog
+	idempotency        *idempotency.Guard // 转账的幂等保护，为空时不启用
 }
 
 // 初始化支付宝客户端
 // 注意：如果使用支付宝公钥证书验签，请设置 支付宝根证书SN（client.SetAlipayRootCertSN()）、应用公钥证书SN（client.SetAppCertSN()）
 // appid：应用ID
-// privateKey：应用私钥，支持PKCS1和PKCS8
+// privateKey：应用私钥，支持PKCS1和PKCS8，传入国密 SM2 私钥时签名类型为 SM2
 // isProd：是否是正式环境
 func NewClient(appid, privateKey string, isProd bool) (client *Client, err error) {
 	if appid == util.NULL || privateKey == util.NULL {
@@ -57,7 +76,18 @@ func NewClient(appid, privateKey string, isProd bool) (client *Client, err error
 	key := xrsa.FormatAlipayPrivateKey(privateKey)
 	priKey, err := xpem.DecodePrivateKey([]byte(key))
 	if err != nil {
-		return nil, err
+		sm2Key, sm2Err := xpem.DecodeSM2PrivateKey([]byte(key))
+		if sm2Err != nil {
+			return nil, err
+		}
+		return &Client{
+			AppId:         appid,
+			Charset:       UTF8,
+			SignType:      SM2,
+			IsProd:        isProd,
+			sm2PrivateKey: sm2Key,
+			DebugSwitch:   pay.DebugOff,
+		}, nil
 	}
 	client = &Client{
 		AppId:       appid,
@@ -72,18 +102,170 @@ func NewClient(appid, privateKey string, isProd bool) (client *Client, err error
 
 // 开启请求完自动验签功能（默认不开启，推荐开启，只支持证书模式）
 // 注意：只支持证书模式
-// alipayPublicKeyContent：支付宝公钥证书文件内容[]byte
+// alipayPublicKeyContent：支付宝公钥证书文件内容[]byte，支持国密 SM2 证书；也可传入支付宝公钥（PEM 或不含头尾的 base64）
+// 设置的公钥同时用于 ParseAndVerifyNotify() 异步通知验签
 func (a *Client) AutoVerifySign(alipayPublicKeyContent []byte) {
+	if !bytes.Contains(alipayPublicKeyContent, []byte("-----BEGIN")) {
+		alipayPublicKeyContent = []byte(xrsa.FormatAlipayPublicKey(string(bytes.TrimSpace(alipayPublicKeyContent))))
+	}
 	pubKey, err := xpem.DecodePublicKey(alipayPublicKeyContent)
+	if err != nil || pubKey == nil {
+		if sm2Key, sm2Err := xpem.DecodeSM2PublicKey(alipayPublicKeyContent); sm2Err == nil {
+			a.aliPayPublicKey, a.aliPaySM2PublicKey = nil, sm2Key
+			a.autoSign = true
+			return
+		}
+	}
 	if err != nil {
 		xlog.Errorf("AutoVerifySign(%s),err:%+v", alipayPublicKeyContent, err)
 	}
 	if pubKey != nil {
-		a.aliPayPublicKey = pubKey
+		a.aliPayPublicKey, a.aliPaySM2PublicKey = pubKey, nil
 		a.autoSign = true
 	}
 }
 
+// SetBaseURL 设置网关地址，如 https://openapi.alipay.com/gateway.do，设置后忽略 IsProd
+// 用于出口代理、本地模拟服务（paytest）等，传空字符串恢复默认
+func (a *Client) SetBaseURL(url string) {
+	a.baseURL = url
+}
+
+// gatewayURL 返回网关地址，utf8 为 true 时附加 charset=utf-8
+func (a *Client) gatewayURL(utf8 bool) string {
+	url := a.baseURL
+	if url == util.NULL {
+		url = sandboxBaseUrl
+		if a.IsProd {
+			url = baseUrl
+		}
+	}
+	if !utf8 {
+		return url
+	}
+	if strings.Contains(url, "?") {
+		return url + "&charset=utf-8"
+	}
+	return url + "?charset=utf-8"
+}
+
+// SetHTTPClient 设置自定义 *http.Client，默认使用 xhttp 共享连接池
+func (a *Client) SetHTTPClient(hc *http.Client) {
+	if hc == nil {
+		a.hc = nil
+		return
+	}
+	a.hc = hc
+}
+
+// SetDoer 设置自定义 xhttp.Doer，用于链路追踪、代理、mock 等，传 nil 恢复默认
+func (a *Client) SetDoer(d xhttp.Doer) {
+	a.hc = d
+}
+
+// SetTLSOptions 设置服务端证书校验配置，如自定义根证书、公钥固定，会替换 SetHTTPClient() 设置的客户端
+func (a *Client) SetTLSOptions(o *xhttp.TLSOptions) {
+	a.hc = xhttp.NewHttpClient(&xhttp.TransportConfig{TLSConfig: xhttp.NewTLSConfig(o)})
+}
+
+// SetRetryPolicy 设置重试策略，默认不重试
+// 查询、下载账单等接口按策略重试网络错误、5xx 及 ACQ.SYSTEM_ERROR
+// 其余接口（下单、退款等）需通过 retry.WithIdempotent(ctx) 显式开启
+func (a *Client) SetRetryPolicy(p *retry.Policy) {
+	a.retry = p
+}
+
+// SetLimiter 设置按接口族的限流、熔断，每次 http 请求（含重试）经过 l，接口标识同 observe.Call.Method
+//
+//	limiter := xhttp.NewLimiter(xhttp.BreakerConfig{Failures: 5},
+//		xhttp.Family{Name: "bill", Prefixes: []string{"alipay.data.dataservice.bill"}, Rate: 1},
+//		xhttp.Family{Name: "marketing", Prefixes: []string{"alipay.marketing."}, Rate: 20},
+//		xhttp.Family{Name: "transaction", Rate: 100},
+//	)
+//	client.SetLimiter(limiter)
+func (a *Client) SetLimiter(l *xhttp.Limiter) {
+	a.limiter = l
+}
+
+// retryPolicy method 为查询、下载类接口时可重试，如 alipay.trade.query、alipay.data.dataservice.bill.downloadurl.query
+func (a *Client) retryPolicy(ctx context.Context, method string) *retry.Policy {
+	if strings.HasSuffix(method, ".query") || strings.Contains(method, "download") || retry.IsIdempotent(ctx) {
+		return a.retry.Idempotent()
+	}
+	return nil
+}
+
+// 支付宝返回 ACQ.SYSTEM_ERROR 时可重试
+func isSystemError(bs []byte) bool {
+	return bytes.Contains(bs, []byte(`"ACQ.SYSTEM_ERROR"`))
+}
+
+// Use 添加中间件，用于指标统计、链路追踪等，见 observe 包，请在初始化时调用
+func (a *Client) Use(mw ...observe.Middleware) {
+	a.mw = append(a.mw, mw...)
+}
+
+// SetLogger 设置当前客户端的结构化日志，日志携带 app_id 字段，并记录每次接口调用（见 observe.Logging）
+//
+//	调试日志（DebugSwitch 开启时）也输出到该 logger，可使用 xlog.NewSlogLogger() 接入 slog、zap、logrus
+func (a *Client) SetLogger(l xlog.Logger) {
+	a.logger = xlog.With(l, "app_id", a.AppId)
+}
+
+func (a *Client) debugf(format string, args ...interface{}) {
+	xlog.Logf(xlog.With(a.logger, "provider", pay.ProviderAlipay), xlog.DebugLevel, format, args...)
+}
+
+// outTradeNo 请求中的商户订单号，用于日志
+func outTradeNo(bm pay.BodyMap) string {
+	if no := bm.GetString("out_trade_no"); no != util.NULL {
+		return no
+	}
+	var bz struct {
+		OutTradeNo string `json:"out_trade_no"`
+	}
+	if s, ok := bm.GetInterface("biz_content").(string); ok && json.Unmarshal([]byte(s), &bz) == nil {
+		return bz.OutTradeNo
+	}
+	return util.NULL
+}
+
+// do 经过中间件链，按 policy 发送请求
+func (a *Client) do(ctx context.Context, method, outTradeNo string, policy *retry.Policy, send func(ctx context.Context) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	call := &observe.Call{Provider: pay.ProviderAlipay, Method: method, OutTradeNo: outTradeNo}
+	return a.mw.WithLogging(a.logger).DoHTTP(ctx, call, func(ctx context.Context) (*http.Response, []byte, error) {
+		return policy.DoHTTP(ctx, a.limiter.Wrap(call.Method, send), isSystemError)
+	}, resultCode)
+}
+
+// resultCode 支付宝业务结果码，code 不为 10000 时返回 sub_code，无 sub_code 时返回 code
+func resultCode(_ int, bs []byte) string {
+	if bytes.Contains(bs, []byte(`"code":"10000"`)) {
+		return util.NULL
+	}
+	var rsp map[string]json.RawMessage
+	if json.Unmarshal(bs, &rsp) != nil {
+		return util.NULL
+	}
+	for k, v := range rsp {
+		if !strings.HasSuffix(k, "_response") {
+			continue
+		}
+		var r struct {
+			Code    string `json:"code"`
+			SubCode string `json:"sub_code"`
+		}
+		if json.Unmarshal(v, &r) != nil || r.Code == util.NULL || r.Code == "10000" {
+			continue
+		}
+		if r.SubCode != util.NULL {
+			return r.SubCode
+		}
+		return r.Code
+	}
+	return util.NULL
+}
+
 // SetBodySize 设置http response body size(MB)
 func (a *Client) SetBodySize(sizeMB int) {
 	if sizeMB > 0 {
@@ -99,7 +281,7 @@ func (a *Client) PostAliPayAPISelf(ctx context.Context, bm pay.BodyMap, method s
 	if bs, err = a.doAliPay(ctx, bm, method); err != nil {
 		return err
 	}
-	if err = json.Unmarshal(bs, aliRsp); err != nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil {
 		return err
 	}
 	return nil
@@ -134,15 +316,18 @@ func (a *Client) RequestParam(bm pay.BodyMap, method string) (string, error) {
 
 	// check sign
 	if bm.GetString("sign") == "" {
-		sign, err = GetRsaSign(bm, bm.GetString("sign_type"), a.privateKey)
+		if err = a.encryptBizContent(bm); err != nil {
+			return "", err
+		}
+		sign, err = a.getSign(bm)
 		if err != nil {
-			return "", fmt.Errorf("GetRsaSign Error: %w", err)
+			return "", fmt.Errorf("GetSign Error: %w", err)
 		}
 		bm.Set("sign", sign)
 	}
 
 	if a.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Alipay_Request: %s", bm.JsonBody())
+		a.debugf("Alipay_Request: %s", redact.String(bm.JsonBody()))
 	}
 	return bm.EncodeURLParams(), nil
 }
@@ -166,7 +351,7 @@ func (a *Client) PostAliPayAPISelfV2(ctx context.Context, bm pay.BodyMap, method
 	if bs, err = a.doAliPaySelf(ctx, bm, method); err != nil {
 		return err
 	}
-	if err = json.Unmarshal(bs, aliRsp); err != nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil {
 		return err
 	}
 	return nil
@@ -182,31 +367,30 @@ func (a *Client) doAliPaySelf(ctx context.Context, bm pay.BodyMap, method string
 	a.checkPublicParam(bm)
 	// check sign
 	if bm.GetString("sign") == "" {
-		sign, err = GetRsaSign(bm, bm.GetString("sign_type"), a.privateKey)
+		if err = a.encryptBizContent(bm); err != nil {
+			return nil, err
+		}
+		sign, err = a.getSign(bm)
 		if err != nil {
-			return nil, fmt.Errorf("GetRsaSign Error: %w", err)
+			return nil, fmt.Errorf("GetSign Error: %w", err)
 		}
 		bm.Set("sign", sign)
 	}
 	if a.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Alipay_Request: %s", bm.JsonBody())
+		a.debugf("Alipay_Request: %s", redact.String(bm.JsonBody()))
 	}
 
-	httpClient := xhttp.NewClient()
+	httpClient := xhttp.NewClient().SetDoer(a.hc)
 	if a.bodySize > 0 {
 		httpClient.SetBodySize(a.bodySize)
 	}
-	if a.IsProd {
-		url = baseUrlUtf8
-	} else {
-		url = sandboxBaseUrlUtf8
-	}
-	res, bs, err := httpClient.Type(xhttp.TypeForm).Post(url).SendString(bm.EncodeURLParams()).EndBytes(ctx)
+	url = a.gatewayURL(true)
+	res, bs, err := a.do(ctx, method, outTradeNo(bm), a.retryPolicy(ctx, method), httpClient.Type(xhttp.TypeForm).Post(url).SendString(bm.EncodeURLParams()).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if a.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Alipay_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		a.debugf("Alipay_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -231,30 +415,27 @@ func (a *Client) doAliPay(ctx context.Context, bm pay.BodyMap, method string, au
 	}
 	// 处理公共参数
 	param, err := a.pubParamsHandle(bm, method, bizContent, authToken...)
+	if err != nil {
+		return nil, err
+	}
 
 	switch method {
 	case "alipay.trade.app.pay", "alipay.fund.auth.order.app.freeze":
 		return []byte(param), nil
 	case "alipay.trade.wap.pay", "alipay.trade.page.pay", "alipay.user.certify.open.certify":
-		if !a.IsProd {
-			return []byte(sandboxBaseUrl + "?" + param), nil
-		}
-		return []byte(baseUrl + "?" + param), nil
+		return []byte(a.gatewayURL(false) + "?" + param), nil
 	default:
-		httpClient := xhttp.NewClient()
+		httpClient := xhttp.NewClient().SetDoer(a.hc)
 		if a.bodySize > 0 {
 			httpClient.SetBodySize(a.bodySize)
 		}
-		url = baseUrlUtf8
-		if !a.IsProd {
-			url = sandboxBaseUrlUtf8
-		}
-		res, bs, err := httpClient.Type(xhttp.TypeForm).Post(url).SendString(param).EndBytes(ctx)
+		url = a.gatewayURL(true)
+		res, bs, err := a.do(ctx, method, outTradeNo(bm), a.retryPolicy(ctx, method), httpClient.Type(xhttp.TypeForm).Post(url).SendString(param).EndBytes)
 		if err != nil {
 			return nil, err
 		}
 		if a.DebugSwitch == pay.DebugOn {
-			xlog.Debugf("Alipay_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+			a.debugf("Alipay_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 		}
 		if res.StatusCode != 200 {
 			return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -315,14 +496,17 @@ func (a *Client) pubParamsHandle(bm pay.BodyMap, method, bizContent string, auth
 	if bizContent != util.NULL {
 		pubBody.Set("biz_content", bizContent)
 	}
+	if err = a.encryptBizContent(pubBody); err != nil {
+		return "", err
+	}
 	// sign
-	sign, err := GetRsaSign(pubBody, pubBody.GetString("sign_type"), a.privateKey)
+	sign, err := a.getSign(pubBody)
 	if err != nil {
-		return "", fmt.Errorf("GetRsaSign Error: %w", err)
+		return "", fmt.Errorf("GetSign Error: %w", err)
 	}
 	pubBody.Set("sign", sign)
 	if a.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Alipay_Request: %s", pubBody.JsonBody())
+		a.debugf("Alipay_Request: %s", redact.String(pubBody.JsonBody()))
 	}
 	param = pubBody.EncodeURLParams()
 	return
@@ -410,27 +594,33 @@ func (a *Client) FileRequest(ctx context.Context, bm pay.BodyMap, file *util.Fil
 	if bodyStr != util.NULL {
 		pubBody.Set("biz_content", bodyStr)
 	}
-	sign, err := GetRsaSign(pubBody, pubBody.GetString("sign_type"), a.privateKey)
+	if err = a.encryptBizContent(pubBody); err != nil {
+		return nil, err
+	}
+	sign, err := a.getSign(pubBody)
 	if err != nil {
-		return nil, fmt.Errorf("GetRsaSign Error: %w", err)
+		return nil, fmt.Errorf("GetSign Error: %w", err)
 	}
 	// pubBody.Set("file_content", file.Content)
 	pubBody.Set("sign", sign)
 	if a.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Alipay_Request: %s", pubBody.JsonBody())
+		a.debugf("Alipay_Request: %s", redact.String(pubBody.JsonBody()))
 	}
 	param := pubBody.EncodeURLParams()
 	url := baseUrlUtf8 + "&" + param
+	if a.baseURL != util.NULL {
+		url = a.gatewayURL(true) + "&" + param
+	}
 	bm.Reset()
 	bm.SetFormFile("file_content", file)
-	httpClient := xhttp.NewClient()
-	res, bs, err := httpClient.Type(xhttp.TypeMultipartFormData).Post(url).
-		SendMultipartBodyMap(bm).EndBytes(ctx)
+	httpClient := xhttp.NewClient().SetDoer(a.hc)
+	res, bs, err := a.do(ctx, method, util.NULL, nil, httpClient.Type(xhttp.TypeMultipartFormData).Post(url).
+		SendMultipartBodyMap(bm).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if a.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Alipay_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		a.debugf("Alipay_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
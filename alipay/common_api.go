@@ -13,10 +13,6 @@ package alipay
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rsa"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,7 +21,6 @@ import (
 	"reflect"
 	"time"
 
-	xaes "github.com/rwscode/payutil/pkg/aes"
 	"github.com/rwscode/payutil/pkg/util"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xpem"
@@ -43,7 +38,7 @@ func FormatURLParam(body pay.BodyMap) (urlParam string) {
 
 // DecryptOpenDataToStruct 解密支付宝开放数据到 结构体
 // encryptedData:包括敏感数据在内的完整用户信息的加密数据
-// secretKey:AES密钥，支付宝管理平台配置
+// secretKey:AES密钥，支付宝管理平台配置，国密 SM4 密钥请使用 DecryptContent
 // beanPtr:需要解析到的结构体指针
 // 文档：https://opendocs.alipay.com/mini/introduce/aes
 // 文档：https://opendocs.alipay.com/open/common/104567
@@ -58,25 +53,9 @@ func DecryptOpenDataToStruct(encryptedData, secretKey string, beanPtr interface{
 	if beanValue.Elem().Kind() != reflect.Struct {
 		return errors.New("传入interface{}必须是结构体")
 	}
-	var (
-		block      cipher.Block
-		blockMode  cipher.BlockMode
-		originData []byte
-	)
-	aesKey, _ := base64.StdEncoding.DecodeString(secretKey)
-	ivKey := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
-	secretData, _ := base64.StdEncoding.DecodeString(encryptedData)
-	if block, err = aes.NewCipher(aesKey); err != nil {
-		return fmt.Errorf("aes.NewCipher：%w", err)
-	}
-	if len(secretData)%len(aesKey) != 0 {
-		return errors.New("encryptedData is error")
-	}
-	blockMode = cipher.NewCBCDecrypter(block, ivKey)
-	originData = make([]byte, len(secretData))
-	blockMode.CryptBlocks(originData, secretData)
-	if len(originData) > 0 {
-		originData = xaes.PKCS5UnPadding(originData)
+	originData, err := DecryptContent(encryptedData, secretKey, AES)
+	if err != nil {
+		return err
 	}
 	if err = json.Unmarshal(originData, beanPtr); err != nil {
 		return fmt.Errorf("json.Unmarshal(%s)：%w", string(originData), err)
@@ -86,32 +65,13 @@ func DecryptOpenDataToStruct(encryptedData, secretKey string, beanPtr interface{
 
 // DecryptOpenDataToBodyMap 解密支付宝开放数据到 BodyMap
 // encryptedData:包括敏感数据在内的完整用户信息的加密数据
-// secretKey:AES密钥，支付宝管理平台配置
+// secretKey:AES密钥，支付宝管理平台配置，国密 SM4 密钥请使用 DecryptContent
 // 文档：https://opendocs.alipay.com/mini/introduce/aes
 // 文档：https://opendocs.alipay.com/open/common/104567
 func DecryptOpenDataToBodyMap(encryptedData, secretKey string) (bm pay.BodyMap, err error) {
-	if encryptedData == util.NULL || secretKey == util.NULL {
-		return nil, errors.New("encryptedData or secretKey is null")
-	}
-	var (
-		aesKey, originData []byte
-		ivKey              = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
-		block              cipher.Block
-		blockMode          cipher.BlockMode
-	)
-	aesKey, _ = base64.StdEncoding.DecodeString(secretKey)
-	secretData, _ := base64.StdEncoding.DecodeString(encryptedData)
-	if block, err = aes.NewCipher(aesKey); err != nil {
-		return nil, fmt.Errorf("aes.NewCipher：%w", err)
-	}
-	if len(secretData)%len(aesKey) != 0 {
-		return nil, errors.New("encryptedData is error")
-	}
-	blockMode = cipher.NewCBCDecrypter(block, ivKey)
-	originData = make([]byte, len(secretData))
-	blockMode.CryptBlocks(originData, secretData)
-	if len(originData) > 0 {
-		originData = xaes.PKCS5UnPadding(originData)
+	originData, err := DecryptContent(encryptedData, secretKey, AES)
+	if err != nil {
+		return nil, err
 	}
 	bm = make(pay.BodyMap)
 	if err = json.Unmarshal(originData, &bm); err != nil {
@@ -146,7 +106,9 @@ func SystemOauthToken(ctx context.Context, appId string, privateKey, grantType,
 		bm.Set("grant_type", "authorization_code")
 		bm.Set("code", codeOrToken)
 	}
-	if bs, err = systemOauthToken(ctx, appId, priKey, bm, "alipay.system.oauth.token", true, signType); err != nil {
+	if bs, err = systemOauthToken(ctx, appId, func(bm pay.BodyMap) (string, error) {
+		return GetRsaSign(bm, bm.GetString("sign_type"), priKey)
+	}, bm, "alipay.system.oauth.token", true, signType); err != nil {
 		return
 	}
 	rsp = new(SystemOauthTokenResponse)
@@ -160,7 +122,7 @@ func SystemOauthToken(ctx context.Context, appId string, privateKey, grantType,
 }
 
 // systemOauthToken 向支付宝发送请求
-func systemOauthToken(ctx context.Context, appId string, privateKey *rsa.PrivateKey, bm pay.BodyMap, method string, isProd bool, signType string) (bs []byte, err error) {
+func systemOauthToken(ctx context.Context, appId string, signer func(bm pay.BodyMap) (string, error), bm pay.BodyMap, method string, isProd bool, signType string) (bs []byte, err error) {
 	bm.Set("app_id", appId)
 	bm.Set("method", method)
 	bm.Set("format", "JSON")
@@ -176,7 +138,7 @@ func systemOauthToken(ctx context.Context, appId string, privateKey *rsa.Private
 		sign    string
 		baseUrl = baseUrlUtf8
 	)
-	if sign, err = GetRsaSign(bm, bm.GetString("sign_type"), privateKey); err != nil {
+	if sign, err = signer(bm); err != nil {
 		return nil, err
 	}
 	bm.Set("sign", sign)
@@ -13,9 +13,9 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/redact"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xlog"
 )
@@ -32,7 +32,7 @@ func (a *Client) TradeCustomsDeclare(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(TradeCustomsDeclareRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -85,16 +85,16 @@ func (a *Client) doAliPayCustoms(ctx context.Context, bm pay.BodyMap, service st
 
 	bm.Set("sign_type", RSA).Set("sign", sign)
 	if a.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Alipay_Request: %s", bm.JsonBody())
+		a.debugf("Alipay_Request: %s", redact.String(bm.JsonBody()))
 	}
 	// request
-	httpClient := xhttp.NewClient()
-	res, bs, err := httpClient.Type(xhttp.TypeForm).Post("https://mapi.alipay.com/gateway.do").SendString(bm.EncodeURLParams()).EndBytes(ctx)
+	httpClient := xhttp.NewClient().SetDoer(a.hc)
+	res, bs, err := a.do(ctx, service, bm.GetString("out_trade_no"), nil, httpClient.Type(xhttp.TypeForm).Post("https://mapi.alipay.com/gateway.do").SendString(bm.EncodeURLParams()).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if a.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Alipay_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		a.debugf("Alipay_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
 )
@@ -28,7 +27,7 @@ func (a *Client) DataBillBalanceQuery(ctx context.Context, bm pay.BodyMap) (aliR
 		return nil, err
 	}
 	aliRsp = new(DataBillBalanceQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -51,7 +50,7 @@ func (a *Client) DataBillDownloadUrlQuery(ctx context.Context, bm pay.BodyMap) (
 		return nil, err
 	}
 	aliRsp = new(DataBillDownloadUrlQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -0,0 +1,214 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	pay "github.com/rwscode/payutil"
+	xaes "github.com/rwscode/payutil/pkg/aes"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/util"
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+// 接口内容加密方式（encrypt_type）
+const (
+	AES = "AES"
+	SM4 = "SM4" // 国密 SM4
+)
+
+// EncryptContent 接口内容加密，CBC 模式、全 0 IV、PKCS5 填充，返回 base64 密文
+// content：明文
+// secretKey：base64 编码的内容加密密钥，支付宝开放平台配置
+// encryptType：加密方式，alipay.AES 或 alipay.SM4
+func EncryptContent(content []byte, secretKey, encryptType string) (encrypted string, err error) {
+	block, err := newContentCipher(secretKey, encryptType)
+	if err != nil {
+		return util.NULL, err
+	}
+	originData := xaes.PKCS5Padding(content, block.BlockSize())
+	secretData := make([]byte, len(originData))
+	cipher.NewCBCEncrypter(block, make([]byte, block.BlockSize())).CryptBlocks(secretData, originData)
+	return base64.StdEncoding.EncodeToString(secretData), nil
+}
+
+// DecryptContent 接口内容解密，同 EncryptContent
+// encryptedData：base64 密文
+// secretKey：base64 编码的内容加密密钥，支付宝开放平台配置
+// encryptType：加密方式，alipay.AES 或 alipay.SM4
+func DecryptContent(encryptedData, secretKey, encryptType string) (originData []byte, err error) {
+	if encryptedData == util.NULL || secretKey == util.NULL {
+		return nil, errors.New("encryptedData or secretKey is null")
+	}
+	block, err := newContentCipher(secretKey, encryptType)
+	if err != nil {
+		return nil, err
+	}
+	secretData, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil || len(secretData) == 0 || len(secretData)%block.BlockSize() != 0 {
+		return nil, errors.New("encryptedData is error")
+	}
+	originData = make([]byte, len(secretData))
+	cipher.NewCBCDecrypter(block, make([]byte, block.BlockSize())).CryptBlocks(originData, secretData)
+	padding := int(originData[len(originData)-1])
+	if padding == 0 || padding > block.BlockSize() || !bytes.Equal(originData[len(originData)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("encryptedData is error, invalid padding")
+	}
+	return originData[:len(originData)-padding], nil
+}
+
+func newContentCipher(secretKey, encryptType string) (block cipher.Block, err error) {
+	key, err := base64.StdEncoding.DecodeString(secretKey)
+	if err != nil {
+		return nil, fmt.Errorf("secretKey base64 decode error: %w", err)
+	}
+	switch encryptType {
+	case AES, util.NULL:
+		if block, err = aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("aes.NewCipher：%w", err)
+		}
+	case SM4:
+		if block, err = sm4.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("sm4.NewCipher：%w", err)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的内容加密方式：%s", encryptType)
+	}
+	return block, nil
+}
+
+// encryptBizContent 开启内容加密时加密 biz_content 并设置 encrypt_type
+func (a *Client) encryptBizContent(bm pay.BodyMap) error {
+	bizContent := bm.GetString("biz_content")
+	if a.encryptKey == util.NULL || bizContent == util.NULL || bm.GetString("encrypt_type") != util.NULL {
+		return nil
+	}
+	if a.DebugSwitch == pay.DebugOn {
+		a.debugf("Alipay_BizContent: %s", redact.String(bizContent))
+	}
+	encrypted, err := EncryptContent([]byte(bizContent), a.encryptKey, a.encryptType)
+	if err != nil {
+		return fmt.Errorf("[%w]: %v", pay.EncryptErr, err)
+	}
+	bm.Set("biz_content", encrypted).Set("encrypt_type", a.encryptType)
+	return nil
+}
+
+// encryptedContent 解析内容加密的同步应答 {"<method>_response":"密文","sign":"..."}
+//
+//	只检查顶层字段，返回顶层字段及 <method>_response 的字段名，其值为密文原文（含双引号）
+//	应答未加密（如网关错误，_response 为 JSON 对象）时 ok 为 false
+func encryptedContent(bs []byte) (rsp map[string]json.RawMessage, key string, ok bool) {
+	if json.Unmarshal(bs, &rsp) != nil {
+		return nil, util.NULL, false
+	}
+	for k, v := range rsp {
+		if strings.HasSuffix(k, "_response") && len(v) > 0 && v[0] == '"' {
+			if ok {
+				return nil, util.NULL, false
+			}
+			key, ok = k, true
+		}
+	}
+	return rsp, key, ok
+}
+
+// unmarshalResponse 解析同步应答到 ptr，内容加密的应答先解密 <method>_response 再解析
+//
+//	bs 保持原样，getSignData(bs) 仍按密文验签
+func (a *Client) unmarshalResponse(bs []byte, ptr interface{}) error {
+	if a.encryptKey != util.NULL {
+		if rsp, key, ok := encryptedContent(bs); ok {
+			var encrypted string
+			if err := json.Unmarshal(rsp[key], &encrypted); err != nil {
+				return fmt.Errorf("[%w]: %v", pay.UnmarshalErr, err)
+			}
+			originData, err := DecryptContent(encrypted, a.encryptKey, a.encryptType)
+			if err != nil {
+				return fmt.Errorf("[%w]: %v", pay.DecryptErr, err)
+			}
+			if !json.Valid(originData) {
+				return fmt.Errorf("[%w]: decrypted response is not json", pay.DecryptErr)
+			}
+			if a.DebugSwitch == pay.DebugOn {
+				a.debugf("Alipay_Response_Decrypted: %s", redact.String(string(originData)))
+			}
+			rsp[key] = originData
+			if bs, err = json.Marshal(rsp); err != nil {
+				return fmt.Errorf("[%w]: %v", pay.MarshalErr, err)
+			}
+		}
+	}
+	return json.Unmarshal(bs, ptr)
+}
+
+// DecryptOpenData 验签并解密小程序加密的开放数据，如 my.getPhoneNumber 返回的 response，需先 SetEncryptKey() 或 SetAESKey()，并 AutoVerifySign()
+//
+//	content 为前端获取的完整结果 {"response":"密文","sign":"...","sign_type":"RSA2",...}，先对 "\"" + response + "\"" 验签再解密
+//	缺少 sign 或未设置支付宝公钥时返回错误，不会接受未签名的数据；已自行验签的密文见 DecryptOpenDataUnsigned()
+//
+//	phone := new(alipay.UserPhone)
+//	err := client.DecryptOpenData(content, phone)
+//	文档：https://opendocs.alipay.com/mini/api/getphonenumber
+func (a *Client) DecryptOpenData(content string, beanPtr interface{}) (err error) {
+	if a.encryptKey == util.NULL {
+		return errors.New("encrypt key is null, please call SetEncryptKey()")
+	}
+	var payload struct {
+		Response string `json:"response"`
+		Sign     string `json:"sign"`
+		SignType string `json:"sign_type"`
+	}
+	if err = json.Unmarshal([]byte(strings.TrimSpace(content)), &payload); err != nil {
+		return fmt.Errorf("[%w]: %v", pay.UnmarshalErr, err)
+	}
+	if payload.Sign == util.NULL {
+		return fmt.Errorf("[%w]: open data sign is empty", pay.VerifySignatureErr)
+	}
+	signType := payload.SignType
+	if signType == util.NULL {
+		signType = RSA2
+	}
+	if err = a.verifyByPublicKey(`"`+payload.Response+`"`, payload.Sign, signType); err != nil {
+		return err
+	}
+	return a.decryptOpenData(payload.Response, beanPtr)
+}
+
+// DecryptOpenDataUnsigned 不验签，直接解密开放数据的 response 密文
+//
+//	注意：仅用于调用方已自行验签或来源可信的密文，前端直接提交的数据请使用 DecryptOpenData()
+func (a *Client) DecryptOpenDataUnsigned(encryptedData string, beanPtr interface{}) (err error) {
+	if a.encryptKey == util.NULL {
+		return errors.New("encrypt key is null, please call SetEncryptKey()")
+	}
+	return a.decryptOpenData(strings.TrimSpace(encryptedData), beanPtr)
+}
+
+func (a *Client) decryptOpenData(encryptedData string, beanPtr interface{}) error {
+	originData, err := DecryptContent(encryptedData, a.encryptKey, a.encryptType)
+	if err != nil {
+		return fmt.Errorf("[%w]: %v", pay.DecryptErr, err)
+	}
+	if err = json.Unmarshal(originData, beanPtr); err != nil {
+		return fmt.Errorf("[%w]: %v", pay.UnmarshalErr, err)
+	}
+	return nil
+}
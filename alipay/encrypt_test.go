@@ -0,0 +1,38 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"testing"
+)
+
+func TestEncryptContent(t *testing.T) {
+	key := "TDftre9FpItr46e9BVNJcw=="
+	content := `{"out_trade_no":"GZ201909081743431443","total_amount":"0.01"}`
+	for _, typ := range []string{AES, SM4} {
+		encrypted, err := EncryptContent([]byte(content), key, typ)
+		if err != nil {
+			t.Fatalf("EncryptContent(%s): %v", typ, err)
+		}
+		bs, err := DecryptContent(encrypted, key, typ)
+		if err != nil || string(bs) != content {
+			t.Fatalf("DecryptContent(%s) = %s, %v", typ, bs, err)
+		}
+	}
+	aesData, _ := EncryptContent([]byte(content), key, AES)
+	if bs, err := DecryptContent(aesData, key, SM4); err == nil && string(bs) == content {
+		t.Fatal("DecryptContent(SM4) decrypted AES content")
+	}
+	if _, err := EncryptContent([]byte(content), key, "DES"); err == nil {
+		t.Fatal("EncryptContent(DES) want error")
+	}
+}
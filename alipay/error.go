@@ -12,7 +12,11 @@
 package alipay
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+
+	pay "github.com/rwscode/payutil"
 )
 
 // BizErr 用于判断支付宝的业务逻辑是否有错误
@@ -27,7 +31,7 @@ type BizErr struct {
 func bizErrCheck(errRsp ErrorResponse) error {
 	if errRsp.Code != "10000" {
 		return &BizErr{
-			Code:    errRsp.SubCode,
+			Code:    errRsp.Code,
 			Msg:     errRsp.Msg,
 			SubCode: errRsp.SubCode,
 			SubMsg:  errRsp.SubMsg,
@@ -41,8 +45,82 @@ func (e *BizErr) Error() string {
 }
 
 func IsBizError(err error) (*BizErr, bool) {
-	if bizErr, ok := err.(*BizErr); ok {
+	var bizErr *BizErr
+	if errors.As(err, &bizErr) {
 		return bizErr, true
 	}
 	return nil, false
 }
+
+// APIError 转换为 *pay.APIError，并将 code、sub_code 映射为统一分类
+func (e *BizErr) APIError() *pay.APIError {
+	msg := e.SubMsg
+	if msg == "" {
+		msg = e.Msg
+	}
+	return pay.NewAPIError(pay.ProviderAlipay, 0, e.Code, e.SubCode, msg, errCategory(e.Code, e.SubCode))
+}
+
+// As 支持 errors.As(err, &apiErr)，apiErr 类型为 *pay.APIError
+func (e *BizErr) As(target interface{}) bool {
+	if t, ok := target.(**pay.APIError); ok {
+		*t = e.APIError()
+		return true
+	}
+	return false
+}
+
+// Is 支持 errors.Is(err, pay.OrderPaidErr) 等分类判断
+func (e *BizErr) Is(target error) bool {
+	return e.APIError().Is(target)
+}
+
+// 支付宝公共错误码：https://opendocs.alipay.com/common/02km9f
+// 业务错误码见各接口文档，如统一收单交易支付接口：https://opendocs.alipay.com/open/02ekfp
+var subCodeCategory = map[string]pay.ErrorCategory{
+	"ACQ.TRADE_HAS_SUCCESS":                 pay.CategoryOrderPaid,
+	"ACQ.TRADE_HAS_FINISHED":                pay.CategoryOrderPaid,
+	"ACQ.TRADE_HAS_CLOSE":                   pay.CategoryOrderClosed,
+	"ACQ.TRADE_STATUS_ERROR":                pay.CategoryOrderClosed,
+	"ACQ.TRADE_NOT_EXIST":                   pay.CategoryOrderNotExist,
+	"ORDER_NOT_EXIST":                       pay.CategoryOrderNotExist,
+	"ACQ.BUYER_BALANCE_NOT_ENOUGH":          pay.CategoryInsufficientFunds,
+	"ACQ.BUYER_BANKCARD_BALANCE_NOT_ENOUGH": pay.CategoryInsufficientFunds,
+	"ACQ.SELLER_BALANCE_NOT_ENOUGH":         pay.CategoryInsufficientFunds,
+	"PAYER_BALANCE_NOT_ENOUGH":              pay.CategoryInsufficientFunds,
+	"BALANCE_IS_NOT_ENOUGH":                 pay.CategoryInsufficientFunds,
+	"ACQ.SYSTEM_ERROR":                      pay.CategorySystemError,
+	"SYSTEM_ERROR":                          pay.CategorySystemError,
+	"ACQ.INVALID_PARAMETER":                 pay.CategoryInvalidParam,
+	"ACQ.ACCESS_FORBIDDEN":                  pay.CategoryAuthFailed,
+	"ACQ.REQUEST_LIMIT":                     pay.CategoryThrottled,
+}
+
+var codeCategory = map[string]pay.ErrorCategory{
+	"20000": pay.CategorySystemError,  // 服务不可用
+	"20001": pay.CategoryAuthFailed,   // 授权权限不足
+	"40001": pay.CategoryInvalidParam, // 缺少必选参数
+	"40002": pay.CategoryInvalidParam, // 非法的参数
+	"40006": pay.CategoryAuthFailed,   // 权限不足
+}
+
+func errCategory(code, subCode string) pay.ErrorCategory {
+	if c, ok := subCodeCategory[subCode]; ok {
+		return c
+	}
+	switch {
+	case strings.HasPrefix(subCode, "isv.invalid-signature"), strings.HasPrefix(subCode, "isv.invalid-app-id"),
+		strings.HasPrefix(subCode, "aop.invalid-auth-token"), strings.HasPrefix(subCode, "aop.invalid-app-auth-token"):
+		return pay.CategoryAuthFailed
+	case strings.HasPrefix(subCode, "isp."), strings.HasPrefix(subCode, "aop.ACQ.SYSTEM_ERROR"):
+		return pay.CategorySystemError
+	case strings.Contains(subCode, "FREQUENCY") || strings.Contains(subCode, "LIMIT_EXCEEDED"):
+		return pay.CategoryThrottled
+	case strings.HasPrefix(subCode, "isv.missing-") || strings.HasPrefix(subCode, "isv.invalid-"):
+		return pay.CategoryInvalidParam
+	}
+	if c, ok := codeCategory[code]; ok {
+		return c
+	}
+	return pay.CategoryUnknown
+}
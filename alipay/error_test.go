@@ -12,7 +12,10 @@
 package alipay
 
 import (
+	"errors"
 	"testing"
+
+	pay "github.com/rwscode/payutil"
 )
 
 func TestBizErr_BizErrCheck(t *testing.T) {
@@ -54,3 +57,23 @@ func TestBizErr_AsBizError(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestBizErr_APIError(t *testing.T) {
+	err := bizErrCheck(ErrorResponse{Code: "40004", Msg: "Business Failed", SubCode: "ACQ.TRADE_HAS_SUCCESS", SubMsg: "交易已被支付"})
+	var apiErr *pay.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatal("errors.As *pay.APIError failed")
+	}
+	if apiErr.Code != "40004" || apiErr.SubCode != "ACQ.TRADE_HAS_SUCCESS" || apiErr.Category != pay.CategoryOrderPaid {
+		t.Fatalf("unexpected APIError: %+v", apiErr)
+	}
+	if !errors.Is(err, pay.OrderPaidErr) {
+		t.Fatal("errors.Is pay.OrderPaidErr failed")
+	}
+	if errCategory("20000", "isp.unknow-error") != pay.CategorySystemError {
+		t.Fatal("isp.* should be system error")
+	}
+	if errCategory("40002", "isv.invalid-signature") != pay.CategoryAuthFailed {
+		t.Fatal("isv.invalid-signature should be auth failed")
+	}
+}
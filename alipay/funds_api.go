@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/util"
@@ -21,17 +20,25 @@ import (
 
 // alipay.fund.trans.uni.transfer(单笔转账接口)
 // 文档地址：https://opendocs.alipay.com/open/02byuo
+// 设置 client.SetIdempotency() 后按 out_biz_no 幂等，见 idempotency 包
 func (a *Client) FundTransUniTransfer(ctx context.Context, bm pay.BodyMap) (aliRsp *FundTransUniTransferResponse, err error) {
 	err = bm.CheckEmptyError("out_biz_no", "trans_amount", "product_code", "payee_info")
 	if err != nil {
 		return nil, err
 	}
+	if a.idempotency != nil {
+		return a.idempotentTransfer(ctx, bm)
+	}
+	return a.fundTransUniTransfer(ctx, bm)
+}
+
+func (a *Client) fundTransUniTransfer(ctx context.Context, bm pay.BodyMap) (aliRsp *FundTransUniTransferResponse, err error) {
 	var bs []byte
 	if bs, err = a.doAliPay(ctx, bm, "alipay.fund.trans.uni.transfer"); err != nil {
 		return nil, err
 	}
 	aliRsp = new(FundTransUniTransferResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -54,7 +61,7 @@ func (a *Client) FundAccountQuery(ctx context.Context, bm pay.BodyMap) (aliRsp *
 		return nil, err
 	}
 	aliRsp = new(FundAccountQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -73,7 +80,7 @@ func (a *Client) FundTransCommonQuery(ctx context.Context, bm pay.BodyMap) (aliR
 		return nil, err
 	}
 	aliRsp = new(FundTransCommonQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -100,7 +107,7 @@ func (a *Client) FundTransOrderQuery(ctx context.Context, bm pay.BodyMap) (aliRs
 	}
 
 	aliRsp = new(FundTransOrderQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -123,7 +130,7 @@ func (a *Client) FundTransRefund(ctx context.Context, bm pay.BodyMap) (aliRsp *F
 		return nil, err
 	}
 	aliRsp = new(FundTransRefundResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -146,7 +153,7 @@ func (a *Client) FundAuthOrderFreeze(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(FundAuthOrderFreezeResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -169,7 +176,7 @@ func (a *Client) FundAuthOrderVoucherCreate(ctx context.Context, bm pay.BodyMap)
 		return nil, err
 	}
 	aliRsp = new(FundAuthOrderVoucherCreateResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -207,7 +214,7 @@ func (a *Client) FundAuthOrderUnfreeze(ctx context.Context, bm pay.BodyMap) (ali
 		return nil, err
 	}
 	aliRsp = new(FundAuthOrderUnfreezeResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -226,7 +233,7 @@ func (a *Client) FundAuthOperationDetailQuery(ctx context.Context, bm pay.BodyMa
 		return nil, err
 	}
 	aliRsp = new(FundAuthOperationDetailQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -249,7 +256,7 @@ func (a *Client) FundAuthOperationCancel(ctx context.Context, bm pay.BodyMap) (a
 		return nil, err
 	}
 	aliRsp = new(FundAuthOperationCancelResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -272,7 +279,7 @@ func (a *Client) FundBatchCreate(ctx context.Context, bm pay.BodyMap) (aliRsp *F
 		return nil, err
 	}
 	aliRsp = new(FundBatchCreateResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -295,7 +302,7 @@ func (a *Client) FundBatchClose(ctx context.Context, bm pay.BodyMap) (aliRsp *Fu
 		return nil, err
 	}
 	aliRsp = new(FundBatchCloseResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -318,7 +325,7 @@ func (a *Client) FundBatchDetailQuery(ctx context.Context, bm pay.BodyMap) (aliR
 		return nil, err
 	}
 	aliRsp = new(FundBatchDetailQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -341,7 +348,7 @@ func (a *Client) FundTransAppPay(ctx context.Context, bm pay.BodyMap) (aliRsp *F
 		return nil, err
 	}
 	aliRsp = new(FundTransAppPayResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -364,7 +371,7 @@ func (a *Client) FundTransPayeeBindQuery(ctx context.Context, bm pay.BodyMap) (a
 		return nil, err
 	}
 	aliRsp = new(FundTransPayeeBindQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -387,7 +394,7 @@ func (a *Client) FundTransPagePay(ctx context.Context, bm pay.BodyMap) (aliRsp *
 		return nil, err
 	}
 	aliRsp = new(FundTransPagePayRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -0,0 +1,232 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+type gateway struct {
+	c *Client
+}
+
+// NewGateway 将支付宝 Client 适配为 pay.Gateway
+//
+//	SceneQRCode：alipay.trade.precreate
+//	SceneApp：alipay.trade.app.pay
+//	SceneJSAPI：alipay.trade.create，Order.OpenId 作为 buyer_id
+//	SceneWap：alipay.trade.wap.pay
+//	ScenePage：alipay.trade.page.pay
+//	Order.Currency、Refund.Currency 非 CNY 时金额按该币种精度格式化，并设置 trans_currency、refund_currency
+func NewGateway(client *Client) pay.Gateway {
+	return &gateway{c: client}
+}
+
+func (g *gateway) Provider() string {
+	return pay.ProviderAlipay
+}
+
+func (g *gateway) Charge(ctx context.Context, o *pay.Order) (*pay.ChargeResult, error) {
+	bm := make(pay.BodyMap)
+	bm.Set("out_trade_no", o.OutTradeNo).
+		Set("subject", o.Subject).
+		SetMoney("total_amount", pay.NewMoney(o.Amount, currencyOrCNY(o.Currency)))
+	// 外币标价，total_amount 为 trans_currency 币种的金额
+	if c := currencyOrCNY(o.Currency); c != "CNY" {
+		bm.Set("trans_currency", c)
+	}
+	if o.Body != util.NULL {
+		bm.Set("body", o.Body)
+	}
+	if o.NotifyUrl != util.NULL {
+		bm.Set("notify_url", o.NotifyUrl)
+	}
+	if o.ReturnUrl != util.NULL {
+		bm.Set("return_url", o.ReturnUrl)
+	}
+	rs := &pay.ChargeResult{OutTradeNo: o.OutTradeNo}
+	switch o.Scene {
+	case pay.SceneQRCode:
+		bm.Merge(o.Extra)
+		aliRsp, err := g.c.TradePrecreate(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		rs.CodeUrl, rs.Raw = aliRsp.Response.QrCode, aliRsp
+	case pay.SceneApp:
+		bm.Merge(o.Extra)
+		payParam, err := g.c.TradeAppPay(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		rs.PayParams, rs.Raw = payParam, payParam
+	case pay.SceneJSAPI:
+		bm.Set("buyer_id", o.OpenId).Merge(o.Extra)
+		aliRsp, err := g.c.TradeCreate(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		rs.TradeNo, rs.Raw = aliRsp.Response.TradeNo, aliRsp
+	case pay.SceneWap:
+		bm.Set("product_code", "QUICK_WAP_WAY").Merge(o.Extra)
+		payUrl, err := g.c.TradeWapPay(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		rs.PayUrl, rs.Raw = payUrl, payUrl
+	case pay.ScenePage:
+		bm.Set("product_code", "FAST_INSTANT_TRADE_PAY").Merge(o.Extra)
+		payUrl, err := g.c.TradePagePay(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		rs.PayUrl, rs.Raw = payUrl, payUrl
+	default:
+		return nil, fmt.Errorf("[%w]: alipay scene %q", pay.UnsupportedErr, o.Scene)
+	}
+	return rs, nil
+}
+
+func (g *gateway) Query(ctx context.Context, q *pay.OrderQuery) (*pay.OrderResult, error) {
+	aliRsp, err := g.c.TradeQuery(ctx, tradeNoBodyMap(q.OutTradeNo, q.TradeNo))
+	if err != nil {
+		return nil, err
+	}
+	r := aliRsp.Response
+	rs := &pay.OrderResult{
+		OutTradeNo: r.OutTradeNo,
+		TradeNo:    r.TradeNo,
+		Status:     tradeStatus(r.TradeStatus),
+		RawStatus:  r.TradeStatus,
+		Currency:   "CNY",
+		Raw:        aliRsp,
+	}
+	if r.TransCurrency != util.NULL {
+		rs.Currency = r.TransCurrency
+	}
+	if r.TotalAmount != util.NULL {
+		m, err := pay.ParseMoney(r.TotalAmount, rs.Currency)
+		if err != nil {
+			return nil, err
+		}
+		rs.Amount = m.Amount
+	}
+	return rs, nil
+}
+
+func (g *gateway) Close(ctx context.Context, q *pay.OrderQuery) error {
+	_, err := g.c.TradeClose(ctx, tradeNoBodyMap(q.OutTradeNo, q.TradeNo))
+	return err
+}
+
+func (g *gateway) Refund(ctx context.Context, r *pay.Refund) (*pay.RefundResult, error) {
+	bm := tradeNoBodyMap(r.OutTradeNo, r.TradeNo)
+	currency := currencyOrCNY(r.Currency)
+	bm.SetMoney("refund_amount", pay.NewMoney(r.Amount, currency)).
+		Set("out_request_no", r.OutRefundNo)
+	if currency != "CNY" {
+		bm.Set("refund_currency", currency)
+	}
+	if r.Reason != util.NULL {
+		bm.Set("refund_reason", r.Reason)
+	}
+	bm.Merge(r.Extra)
+	aliRsp, err := g.c.TradeRefund(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	rs := &pay.RefundResult{
+		OutTradeNo:  aliRsp.Response.OutTradeNo,
+		TradeNo:     aliRsp.Response.TradeNo,
+		OutRefundNo: r.OutRefundNo,
+		Status:      pay.RefundStatusProcessing,
+		RawStatus:   aliRsp.Response.FundChange,
+		Amount:      r.Amount,
+		Currency:    currency,
+		Raw:         aliRsp,
+	}
+	// fund_change=Y 表示本次退款资金已发生变化，即退款成功
+	if aliRsp.Response.FundChange == "Y" {
+		rs.Status = pay.RefundStatusSuccess
+	}
+	if aliRsp.Response.RefundCurrency != util.NULL {
+		rs.Currency = aliRsp.Response.RefundCurrency
+	}
+	return rs, nil
+}
+
+func (g *gateway) QueryRefund(ctx context.Context, q *pay.RefundQuery) (*pay.RefundResult, error) {
+	bm := tradeNoBodyMap(q.OutTradeNo, q.TradeNo)
+	bm.Set("out_request_no", q.OutRefundNo).
+		Set("query_options", []string{"gmt_refund_pay"})
+	aliRsp, err := g.c.TradeFastPayRefundQuery(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	r := aliRsp.Response
+	rs := &pay.RefundResult{
+		OutTradeNo:  r.OutTradeNo,
+		TradeNo:     r.TradeNo,
+		OutRefundNo: r.OutRequestNo,
+		Status:      pay.RefundStatusProcessing,
+		RawStatus:   r.RefundStatus,
+		Currency:    currencyOrCNY(q.Currency),
+		Raw:         aliRsp,
+	}
+	if r.RefundStatus == "REFUND_SUCCESS" {
+		rs.Status = pay.RefundStatusSuccess
+	}
+	if r.RefundAmount != util.NULL {
+		m, err := pay.ParseMoney(r.RefundAmount, rs.Currency)
+		if err != nil {
+			return nil, err
+		}
+		rs.Amount = m.Amount
+	}
+	return rs, nil
+}
+
+func currencyOrCNY(currency string) string {
+	if currency == util.NULL {
+		return "CNY"
+	}
+	return strings.ToUpper(currency)
+}
+
+func tradeNoBodyMap(outTradeNo, tradeNo string) pay.BodyMap {
+	bm := make(pay.BodyMap)
+	if outTradeNo != util.NULL {
+		bm.Set("out_trade_no", outTradeNo)
+	}
+	if tradeNo != util.NULL {
+		bm.Set("trade_no", tradeNo)
+	}
+	return bm
+}
+
+func tradeStatus(status string) pay.TradeStatus {
+	switch status {
+	case "WAIT_BUYER_PAY":
+		return pay.TradeStatusPending
+	case "TRADE_SUCCESS", "TRADE_FINISHED":
+		return pay.TradeStatusPaid
+	case "TRADE_CLOSED":
+		return pay.TradeStatusClosed
+	default:
+		return pay.TradeStatusUnknown
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"errors"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+func TestGateway_Charge(t *testing.T) {
+	var gw pay.Gateway = NewGateway(client)
+
+	// 手机网站支付只生成跳转链接，不发起请求
+	rs, err := gw.Charge(ctx, &pay.Order{
+		OutTradeNo: util.RandomString(32),
+		Subject:    "统一网关下单",
+		Amount:     1,
+		Scene:      pay.SceneWap,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	xlog.Debug("PayUrl:", rs.PayUrl)
+	if rs.PayUrl == "" {
+		t.Fatal("empty pay url")
+	}
+
+	_, err = gw.Charge(ctx, &pay.Order{OutTradeNo: util.RandomString(32), Scene: "UNKNOWN"})
+	if !errors.Is(err, pay.UnsupportedErr) {
+		t.Fatalf("want UnsupportedErr, got %v", err)
+	}
+}
+
+func TestGateway_TradeStatus(t *testing.T) {
+	cases := map[string]pay.TradeStatus{
+		"WAIT_BUYER_PAY": pay.TradeStatusPending,
+		"TRADE_SUCCESS":  pay.TradeStatusPaid,
+		"TRADE_FINISHED": pay.TradeStatusPaid,
+		"TRADE_CLOSED":   pay.TradeStatusClosed,
+		"":               pay.TradeStatusUnknown,
+	}
+	for raw, want := range cases {
+		if got := tradeStatus(raw); got != want {
+			t.Fatalf("tradeStatus(%q) = %s, want %s", raw, got, want)
+		}
+	}
+}
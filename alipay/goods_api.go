@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/util"
@@ -30,7 +29,7 @@ func (a *Client) MerchantItemFileUpload(ctx context.Context, file *util.File) (a
 		return nil, err
 	}
 	aliRsp = new(MerchantItemFileUploadRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil {
 		return nil, err
 	}
 	if aliRsp.Response != nil && aliRsp.Response.Code != "10000" {
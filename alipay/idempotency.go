@@ -0,0 +1,92 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"context"
+	"errors"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/idempotency"
+)
+
+// SetIdempotency 开启单笔转账（FundTransUniTransfer）的幂等保护，g 为 nil 时关闭
+//
+//	按 app_id + out_biz_no 记录结果，已成功的请求再次调用直接返回保存的结果（不含签名）
+//	请求超时等结果未知时，再次调用先通过 alipay.fund.trans.common.query 查询，已受理则直接返回，否则重新发起
+//	client.SetIdempotency(idempotency.New(idempotency.NewMemoryStore()))
+func (a *Client) SetIdempotency(g *idempotency.Guard) {
+	a.idempotency = g
+}
+
+// aliOutcome 将应答转换为 idempotency.Operation.Create 的结果：业务错误且不可重试时视为渠道已拒绝，其余错误结果未知
+func aliOutcome(err error) error {
+	if bizErr, ok := IsBizError(err); ok && !bizErr.APIError().Retryable {
+		return idempotency.Rejected(err)
+	}
+	return err
+}
+
+func (a *Client) idempotentTransfer(ctx context.Context, bm pay.BodyMap) (aliRsp *FundTransUniTransferResponse, err error) {
+	var (
+		outBizNo = bm.GetString("out_biz_no")
+		stored   = new(TransUniTransfer)
+		created  bool
+		rspErr   error
+	)
+	status, err := a.idempotency.Do(ctx, idempotency.Key(pay.ProviderAlipay, a.AppId, "transfer", outBizNo), stored, idempotency.Operation{
+		Create: func(ctx context.Context) (interface{}, error) {
+			created = true
+			aliRsp, rspErr = a.fundTransUniTransfer(ctx, bm)
+			if rspErr != nil {
+				return nil, aliOutcome(rspErr)
+			}
+			return aliRsp.Response, nil
+		},
+		Query: func(ctx context.Context) (interface{}, bool, error) {
+			qm := make(pay.BodyMap)
+			qm.Set("out_biz_no", outBizNo).
+				Set("product_code", bm.GetString("product_code"))
+			if bizScene := bm.GetString("biz_scene"); bizScene != pay.NULL {
+				qm.Set("biz_scene", bizScene)
+			}
+			q, err := a.FundTransCommonQuery(ctx, qm)
+			if errors.Is(err, pay.OrderNotExistErr) {
+				return nil, false, nil
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			aliRsp = &FundTransUniTransferResponse{
+				Response: &TransUniTransfer{
+					ErrorResponse:  q.Response.ErrorResponse,
+					OutBizNo:       q.Response.OutBizNo,
+					OrderId:        q.Response.OrderId,
+					PayFundOrderId: q.Response.PayFundOrderId,
+					Status:         q.Response.Status,
+					TransDate:      q.Response.PayDate,
+				},
+				AlipayCertSn: q.AlipayCertSn,
+				SignData:     q.SignData,
+				Sign:         q.Sign,
+			}
+			return aliRsp.Response, true, nil
+		},
+	})
+	switch {
+	case status == idempotency.Replayed:
+		return &FundTransUniTransferResponse{Response: stored}, nil
+	case created:
+		return aliRsp, rspErr
+	}
+	return aliRsp, err
+}
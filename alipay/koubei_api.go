@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
 )
@@ -30,7 +29,7 @@ func (a *Client) KoubeiTradeOrderAggregateConsult(ctx context.Context, bm pay.Bo
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeOrderAggregateConsultRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -53,7 +52,7 @@ func (a *Client) KoubeiTradeOrderPrecreate(ctx context.Context, bm pay.BodyMap)
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeOrderPrecreateRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -76,7 +75,7 @@ func (a *Client) KoubeiTradeItemorderBuy(ctx context.Context, bm pay.BodyMap) (a
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeItemorderBuyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -99,7 +98,7 @@ func (a *Client) KoubeiTradeOrderConsult(ctx context.Context, bm pay.BodyMap) (a
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeOrderConsultRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -122,7 +121,7 @@ func (a *Client) KoubeiTradeItemorderRefund(ctx context.Context, bm pay.BodyMap)
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeItemorderRefundRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -145,7 +144,7 @@ func (a *Client) KoubeiTradeItemorderQuery(ctx context.Context, bm pay.BodyMap)
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeItemorderQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -168,7 +167,7 @@ func (a *Client) KoubeiTradeTicketTicketcodeSend(ctx context.Context, bm pay.Bod
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeTicketTicketcodeSendRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -191,7 +190,7 @@ func (a *Client) KoubeiTradeTicketTicketcodeDelay(ctx context.Context, bm pay.Bo
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeTicketTicketcodeDelayRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -214,7 +213,7 @@ func (a *Client) KoubeiTradeTicketTicketcodeQuery(ctx context.Context, bm pay.Bo
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeTicketTicketcodeQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -237,7 +236,7 @@ func (a *Client) KoubeiTradeTicketTicketcodeCancel(ctx context.Context, bm pay.B
 		return nil, err
 	}
 	aliRsp = new(KoubeiTradeTicketTicketcodeCancelRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
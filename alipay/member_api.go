@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	pay "github.com/rwscode/payutil"
@@ -32,7 +31,7 @@ func (a *Client) UserInfoShare(ctx context.Context, authToken string) (aliRsp *U
 		return nil, err
 	}
 	aliRsp = new(UserInfoShareResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -55,7 +54,7 @@ func (a *Client) UserCertifyOpenInit(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(UserCertifyOpenInitResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -94,7 +93,7 @@ func (a *Client) UserCertifyOpenQuery(ctx context.Context, bm pay.BodyMap) (aliR
 		return nil, err
 	}
 	aliRsp = new(UserCertifyOpenQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -127,7 +126,7 @@ func (a *Client) UserAgreementPageUnSign(ctx context.Context, bm pay.BodyMap) (a
 		return nil, err
 	}
 	aliRsp = new(UserAgreementPageUnSignRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(*aliRsp.Response); err != nil {
@@ -146,7 +145,7 @@ func (a *Client) UserAgreementQuery(ctx context.Context, bm pay.BodyMap) (aliRsp
 		return nil, err
 	}
 	aliRsp = new(UserAgreementQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -169,7 +168,7 @@ func (a *Client) UserAgreementExecutionplanModify(ctx context.Context, bm pay.Bo
 		return nil, err
 	}
 	aliRsp = new(UserAgreementExecutionplanModifyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -192,7 +191,7 @@ func (a *Client) UserAgreementTransfer(ctx context.Context, bm pay.BodyMap) (ali
 		return nil, err
 	}
 	aliRsp = new(UserAgreementTransferRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -215,7 +214,7 @@ func (a *Client) UserTwostageCommonUse(ctx context.Context, bm pay.BodyMap) (ali
 		return nil, err
 	}
 	aliRsp = new(UserTwostageCommonUseRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -238,7 +237,7 @@ func (a *Client) UserAuthZhimaorgIdentityApply(ctx context.Context, bm pay.BodyM
 		return nil, err
 	}
 	aliRsp = new(UserAuthZhimaorgIdentityApplyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -261,7 +260,7 @@ func (a *Client) UserCharityRecordexistQuery(ctx context.Context, bm pay.BodyMap
 		return nil, err
 	}
 	aliRsp = new(UserCharityRecordexistQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -284,7 +283,7 @@ func (a *Client) UserAlipaypointSend(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(UserAlipaypointSendRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -307,7 +306,7 @@ func (a *Client) MemberDataIsvCreate(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(MemberDataIsvCreateRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -330,7 +329,7 @@ func (a *Client) UserFamilyArchiveQuery(ctx context.Context, bm pay.BodyMap) (al
 		return nil, err
 	}
 	aliRsp = new(UserFamilyArchiveQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -353,7 +352,7 @@ func (a *Client) UserFamilyArchiveInitialize(ctx context.Context, bm pay.BodyMap
 		return nil, err
 	}
 	aliRsp = new(UserFamilyArchiveInitializeRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -376,7 +375,7 @@ func (a *Client) UserCertdocCertverifyPreconsult(ctx context.Context, bm pay.Bod
 		return nil, err
 	}
 	aliRsp = new(UserCertdocCertverifyPreconsultRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -395,7 +394,7 @@ func (a *Client) UserCertdocCertverifyConsult(ctx context.Context, bm pay.BodyMa
 		return nil, err
 	}
 	aliRsp = new(UserCertdocCertverifyConsultRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -418,7 +417,7 @@ func (a *Client) UserFamilyShareZmgoInitialize(ctx context.Context, bm pay.BodyM
 		return nil, err
 	}
 	aliRsp = new(UserFamilyShareZmgoInitializeRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -441,7 +440,7 @@ func (a *Client) UserDtbankQrcodedataQuery(ctx context.Context, bm pay.BodyMap)
 		return nil, err
 	}
 	aliRsp = new(UserDtbankQrcodedataQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -464,7 +463,7 @@ func (a *Client) UserAlipaypointBudgetlibQuery(ctx context.Context, bm pay.BodyM
 		return nil, err
 	}
 	aliRsp = new(UserAlipaypointBudgetlibQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
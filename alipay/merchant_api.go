@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
 )
@@ -30,7 +29,7 @@ func (a *Client) TradeRelationBind(ctx context.Context, bm pay.BodyMap) (aliRsp
 		return nil, err
 	}
 	aliRsp = new(TradeRelationBindResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -53,7 +52,7 @@ func (a *Client) TradeRelationUnbind(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(TradeRelationUnbindResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -76,7 +75,7 @@ func (a *Client) TradeRelationBatchQuery(ctx context.Context, bm pay.BodyMap) (a
 		return nil, err
 	}
 	aliRsp = new(TradeRelationBatchQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -99,7 +98,7 @@ func (a *Client) TradeOrderSettle(ctx context.Context, bm pay.BodyMap) (aliRsp *
 		return nil, err
 	}
 	aliRsp = new(TradeOrderSettleResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -122,7 +121,7 @@ func (a *Client) TradeOrderSettleQuery(ctx context.Context, bm pay.BodyMap) (ali
 		return nil, err
 	}
 	aliRsp = new(TradeOrderSettleQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
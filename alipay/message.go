@@ -0,0 +1,247 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// 消息应答
+const (
+	MessageAckSuccess = "success"
+	MessageAckFail    = "fail"
+
+	MessageAll = "*" // On() 匹配未单独注册回调的消息
+)
+
+// ErrNoMessageHandler 消息没有匹配的回调，应答 fail，支付宝按其策略重推
+var ErrNoMessageHandler = errors.New("alipay: no handler for message")
+
+// Message 验签后的支付宝消息服务消息
+type Message struct {
+	MsgMethod    string
+	NotifyId     string // 消息ID，重复推送时不变，用于去重
+	AppId        string
+	UtcTimestamp string
+	Version      string
+	BizContent   string      // 消息内容原文
+	Data         interface{} // 按 MessageReceiver.Register() 注册的结构体指针解析的 BizContent，未注册时为 pay.BodyMap
+	BodyMap      pay.BodyMap // 全部参数，含 sign、sign_type
+}
+
+// MessageHandler 消息回调，返回 error 时应答 fail，支付宝按其策略重推
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// ZftAuditMessage 直付通二级商户进件审核结果
+type ZftAuditMessage struct {
+	OrderId      string `json:"order_id,omitempty"`
+	ExternalId   string `json:"external_id,omitempty"`
+	MerchantName string `json:"merchant_name,omitempty"`
+	Smid         string `json:"smid,omitempty"`
+	CardAliasNo  string `json:"card_alias_no,omitempty"`
+	Memo         string `json:"memo,omitempty"`
+	Reason       string `json:"reason,omitempty"` // 驳回原因
+}
+
+// 默认注册的消息结构
+var defaultMessageTypes = map[string]interface{}{
+	"alipay.fund.trans.order.changed":           TransferNotify{},
+	"ant.merchant.expand.indirect.zft.passed":   ZftAuditMessage{},
+	"ant.merchant.expand.indirect.zft.rejected": ZftAuditMessage{},
+}
+
+// MessageReceiver 支付宝消息服务接收器，验签后按 msg_method 解析消息内容并分发回调
+//
+//	实现 http.Handler，用于接收 msg_method 的 POST 推送，处理完成后应答 success 或 fail
+//	长连接通道收到的消息，将参数转换为 url.Values 后调用 Receive()，按返回的 error 使用 MessageAck() 应答
+//	使用 client.AutoVerifySign() 设置的支付宝公钥或公钥证书验签，默认仅接收 app_id 为 client.AppId 的消息，见 SetAppIds()
+//
+//	r := alipay.NewMessageReceiver(client)
+//	r.Register("alipay.user.agreement.page.sign", alipay.AgreementNotify{})
+//	r.On("alipay.fund.trans.order.changed", func(ctx context.Context, msg *alipay.Message) error {
+//		transfer := msg.Data.(*alipay.TransferNotify)
+//		return nil
+//	})
+//	http.Handle("/alipay/message", r)
+type MessageReceiver struct {
+	c        *Client
+	mu       sync.RWMutex
+	types    map[string]reflect.Type // key: msg_method
+	handlers map[string]MessageHandler
+	appIds   []string
+}
+
+// NewMessageReceiver 初始化消息接收器，默认注册转账单据状态变更、直付通进件审核结果消息
+func NewMessageReceiver(c *Client) *MessageReceiver {
+	r := &MessageReceiver{
+		c:        c,
+		types:    make(map[string]reflect.Type),
+		handlers: make(map[string]MessageHandler),
+	}
+	for method, v := range defaultMessageTypes {
+		r.Register(method, v)
+	}
+	return r
+}
+
+// Register 注册 msgMethod 的消息结构，v 为结构体或结构体指针，Message.Data 为对应的结构体指针
+func (r *MessageReceiver) Register(msgMethod string, v interface{}) *MessageReceiver {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	r.mu.Lock()
+	r.types[msgMethod] = t
+	r.mu.Unlock()
+	return r
+}
+
+// On 注册 msgMethod 的回调，msgMethod 为 MessageAll 时处理未单独注册回调的消息
+//
+//	没有匹配回调的消息返回 ErrNoMessageHandler 并应答 fail，需要确认所有消息时注册 MessageAll
+func (r *MessageReceiver) On(msgMethod string, h MessageHandler) *MessageReceiver {
+	r.mu.Lock()
+	r.handlers[msgMethod] = h
+	r.mu.Unlock()
+	return r
+}
+
+// SetAppIds 设置接收的 app_id，替换默认的 client.AppId，如同一应用公钥证书下的多个应用共用接收器
+func (r *MessageReceiver) SetAppIds(appIds ...string) *MessageReceiver {
+	r.mu.Lock()
+	r.appIds = append([]string(nil), appIds...)
+	r.mu.Unlock()
+	return r
+}
+
+// checkAppId 校验验签后的 app_id，防止同一支付宝公钥下其它应用的消息被重放到本应用
+func (r *MessageReceiver) checkAppId(appId string) error {
+	r.mu.RLock()
+	appIds := r.appIds
+	r.mu.RUnlock()
+	if appIds == nil {
+		if r.c.AppId == util.NULL {
+			return nil
+		}
+		appIds = []string{r.c.AppId}
+	}
+	for _, id := range appIds {
+		if appId == id {
+			return nil
+		}
+	}
+	return fmt.Errorf("[%w]: app_id %s", pay.NotifyMismatchErr, appId)
+}
+
+// Parse 验签并解析消息，参数重复出现时返回验签错误，app_id 不是本应用时返回 pay.NotifyMismatchErr
+func (r *MessageReceiver) Parse(values url.Values) (msg *Message, err error) {
+	bm, err := r.c.verifyFormValues(values)
+	if err != nil {
+		return nil, err
+	}
+	msg = &Message{
+		MsgMethod:    bm.GetString("msg_method"),
+		NotifyId:     bm.GetString("notify_id"),
+		AppId:        bm.GetString("app_id"),
+		UtcTimestamp: bm.GetString("utc_timestamp"),
+		Version:      bm.GetString("version"),
+		BizContent:   bm.GetString("biz_content"),
+		BodyMap:      bm,
+	}
+	if err = r.checkAppId(msg.AppId); err != nil {
+		return nil, err
+	}
+	if msg.MsgMethod == util.NULL {
+		return nil, fmt.Errorf("[%w]: msg_method", pay.MissParamErr)
+	}
+	r.mu.RLock()
+	t, ok := r.types[msg.MsgMethod]
+	r.mu.RUnlock()
+	if !ok {
+		data := make(pay.BodyMap)
+		if msg.BizContent != util.NULL {
+			if err = json.Unmarshal([]byte(msg.BizContent), &data); err != nil {
+				return nil, fmt.Errorf("[%w]: biz_content %s: %v", pay.UnmarshalErr, msg.BizContent, err)
+			}
+		}
+		msg.Data = data
+		return msg, nil
+	}
+	data := reflect.New(t).Interface()
+	if msg.BizContent != util.NULL {
+		if err = json.Unmarshal([]byte(msg.BizContent), data); err != nil {
+			return nil, fmt.Errorf("[%w]: biz_content %s: %v", pay.UnmarshalErr, msg.BizContent, err)
+		}
+	}
+	msg.Data = data
+	return msg, nil
+}
+
+// Receive 验签、解析并分发消息，用于长连接通道等非 http 推送的消息
+func (r *MessageReceiver) Receive(ctx context.Context, values url.Values) error {
+	msg, err := r.Parse(values)
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	h, ok := r.handlers[msg.MsgMethod]
+	if !ok {
+		h = r.handlers[MessageAll]
+	}
+	r.mu.RUnlock()
+	if h == nil {
+		return fmt.Errorf("%w: %s", ErrNoMessageHandler, msg.MsgMethod)
+	}
+	return h(ctx, msg)
+}
+
+// ServeHTTP 接收 msg_method 的 POST 推送
+func (r *MessageReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		AckMessage(w, err)
+		return
+	}
+	values := req.PostForm
+	if len(values) == 0 {
+		values = req.Form
+	}
+	err := r.Receive(req.Context(), values)
+	if err != nil && r.c.DebugSwitch == pay.DebugOn {
+		r.c.debugf("Alipay_Message: %s, err: %v", values.Get("msg_method"), err)
+	}
+	AckMessage(w, err)
+}
+
+// MessageAck 消息应答内容，err 为空时为 success，否则为 fail
+func MessageAck(err error) string {
+	if err != nil {
+		return MessageAckFail
+	}
+	return MessageAckSuccess
+}
+
+// AckMessage 向支付宝写回消息应答，err 为空时应答 success，否则应答 fail
+func AckMessage(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(MessageAck(err)))
+}
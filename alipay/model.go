@@ -23,6 +23,7 @@ const (
 	PKCS8            PKCSType = 2 // Java
 	RSA                       = "RSA"
 	RSA2                      = "RSA2"
+	SM2                       = "SM2" // 国密 SM3withSM2
 	UTF8                      = "utf-8"
 )
 
@@ -0,0 +1,333 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// NotifyEventType 异步通知事件类型
+type NotifyEventType string
+
+const (
+	NotifyTradeStatus     NotifyEventType = "TRADE_STATUS"     // 交易状态变更，notify_type=trade_status_sync
+	NotifyRefund          NotifyEventType = "REFUND"           // 退款，notify_type=trade_status_sync 且含 gmt_refund
+	NotifyFundAuthFreeze  NotifyEventType = "FUND_AUTH_FREEZE" // 资金授权冻结，notify_type=fund_auth_freeze
+	NotifyAgreementSign   NotifyEventType = "AGREEMENT_SIGN"   // 协议签约，notify_type=dut_user_sign
+	NotifyAgreementUnsign NotifyEventType = "AGREEMENT_UNSIGN" // 协议解约，notify_type=dut_user_unsign
+	NotifyTransferChanged NotifyEventType = "TRANSFER_CHANGED" // 转账单据状态变更，msg_method=alipay.fund.trans.order.changed
+	NotifyUnknown         NotifyEventType = "UNKNOWN"          // 未识别的通知，见 NotifyEvent.Values
+)
+
+// NotifyEvent 验签后的异步通知事件，按 Type 读取对应字段：
+//
+//	NotifyTradeStatus、NotifyRefund：Trade（退款时 Trade.OutBizNo 为退款请求号）
+//	NotifyFundAuthFreeze：FundAuth
+//	NotifyAgreementSign、NotifyAgreementUnsign：Agreement
+//	NotifyTransferChanged：Transfer
+type NotifyEvent struct {
+	Type       NotifyEventType
+	RawType    string     // notify_type，消息类通知为 msg_method
+	NotifyId   string     // 通知ID，用于去重
+	NotifyTime string     // 通知时间，消息类通知为 utc_timestamp
+	AppId      string     // 应用ID
+	Values     url.Values // 通知的全部原始参数，即参与验签的请求体参数
+	BodyMap    pay.BodyMap
+
+	Trade     *TradeNotify
+	FundAuth  *FundAuthNotify
+	Agreement *AgreementNotify
+	Transfer  *TransferNotify
+}
+
+// NotifyExpect 异步通知与商户订单的一致性校验，为空的字段不校验
+type NotifyExpect struct {
+	AppId       string // 应用ID，为空时使用 client.AppId
+	SellerId    string // 卖家支付宝用户号
+	TotalAmount string // 订单金额（元），按通知的 trans_currency（默认 CNY）精度比较
+}
+
+type TradeNotify struct {
+	NotifyType        string              `json:"notify_type,omitempty"`
+	AuthAppId         string              `json:"auth_app_id,omitempty"`
+	TradeNo           string              `json:"trade_no,omitempty"`
+	OutTradeNo        string              `json:"out_trade_no,omitempty"`
+	OutBizNo          string              `json:"out_biz_no,omitempty"`
+	BuyerId           string              `json:"buyer_id,omitempty"`
+	BuyerOpenId       string              `json:"buyer_open_id,omitempty"`
+	BuyerLogonId      string              `json:"buyer_logon_id,omitempty"`
+	SellerId          string              `json:"seller_id,omitempty"`
+	SellerEmail       string              `json:"seller_email,omitempty"`
+	TradeStatus       string              `json:"trade_status,omitempty"`
+	TotalAmount       string              `json:"total_amount,omitempty"`
+	ReceiptAmount     string              `json:"receipt_amount,omitempty"`
+	InvoiceAmount     string              `json:"invoice_amount,omitempty"`
+	BuyerPayAmount    string              `json:"buyer_pay_amount,omitempty"`
+	PointAmount       string              `json:"point_amount,omitempty"`
+	RefundFee         string              `json:"refund_fee,omitempty"`
+	Subject           string              `json:"subject,omitempty"`
+	Body              string              `json:"body,omitempty"`
+	GmtCreate         string              `json:"gmt_create,omitempty"`
+	GmtPayment        string              `json:"gmt_payment,omitempty"`
+	GmtRefund         string              `json:"gmt_refund,omitempty"`
+	GmtClose          string              `json:"gmt_close,omitempty"`
+	PassbackParams    string              `json:"passback_params,omitempty"`
+	FundBillList      []*FundBillListInfo `json:"-"`
+	VoucherDetailList []*VoucherDetail    `json:"-"`
+}
+
+type FundAuthNotify struct {
+	NotifyType          string `json:"notify_type,omitempty"`
+	AuthNo              string `json:"auth_no,omitempty"`
+	OutOrderNo          string `json:"out_order_no,omitempty"`
+	OperationId         string `json:"operation_id,omitempty"`
+	OutRequestNo        string `json:"out_request_no,omitempty"`
+	OperationType       string `json:"operation_type,omitempty"`
+	Amount              string `json:"amount,omitempty"`
+	Status              string `json:"status,omitempty"`
+	GmtCreate           string `json:"gmt_create,omitempty"`
+	GmtTrans            string `json:"gmt_trans,omitempty"`
+	PayerLogonId        string `json:"payer_logon_id,omitempty"`
+	PayerUserId         string `json:"payer_user_id,omitempty"`
+	PayeeLogonId        string `json:"payee_logon_id,omitempty"`
+	PayeeUserId         string `json:"payee_user_id,omitempty"`
+	TotalFreezeAmount   string `json:"total_freeze_amount,omitempty"`
+	TotalUnfreezeAmount string `json:"total_unfreeze_amount,omitempty"`
+	TotalPayAmount      string `json:"total_pay_amount,omitempty"`
+	RestAmount          string `json:"rest_amount,omitempty"`
+	CreditAmount        string `json:"credit_amount,omitempty"`
+	FundAmount          string `json:"fund_amount,omitempty"`
+	PreAuthType         string `json:"pre_auth_type,omitempty"`
+	TransCurrency       string `json:"trans_currency,omitempty"`
+}
+
+type AgreementNotify struct {
+	NotifyType          string `json:"notify_type,omitempty"`
+	AgreementNo         string `json:"agreement_no,omitempty"`
+	ExternalAgreementNo string `json:"external_agreement_no,omitempty"`
+	PersonalProductCode string `json:"personal_product_code,omitempty"`
+	SignScene           string `json:"sign_scene,omitempty"`
+	Status              string `json:"status,omitempty"`
+	SignTime            string `json:"sign_time,omitempty"`
+	ValidTime           string `json:"valid_time,omitempty"`
+	InvalidTime         string `json:"invalid_time,omitempty"`
+	UnsignTime          string `json:"unsign_time,omitempty"`
+	AlipayUserId        string `json:"alipay_user_id,omitempty"`
+	AlipayOpenId        string `json:"alipay_open_id,omitempty"`
+	AlipayLogonId       string `json:"alipay_logon_id,omitempty"`
+	ExternalLogonId     string `json:"external_logon_id,omitempty"`
+	PartnerId           string `json:"partner_id,omitempty"`
+}
+
+// TransferNotify 转账单据状态变更，解析自 biz_content
+type TransferNotify struct {
+	OutBizNo        string `json:"out_biz_no,omitempty"`
+	OrderId         string `json:"order_id,omitempty"`
+	PayFundOrderId  string `json:"pay_fund_order_id,omitempty"`
+	Status          string `json:"status,omitempty"`
+	TransAmount     string `json:"trans_amount,omitempty"`
+	BizScene        string `json:"biz_scene,omitempty"`
+	ProductCode     string `json:"product_code,omitempty"`
+	ActionType      string `json:"action_type,omitempty"`
+	OriginInterface string `json:"origin_interface,omitempty"`
+	PayDate         string `json:"pay_date,omitempty"`
+	ErrorCode       string `json:"error_code,omitempty"`
+	FailReason      string `json:"fail_reason,omitempty"`
+}
+
+// ParseAndVerifyNotify 解析并验签支付宝异步通知，返回按 notify_type、msg_method 识别的事件
+//
+//	使用 AutoVerifySign() 设置的支付宝公钥或公钥证书验签，只使用请求体参数验签，notify_url 中的查询参数不参与验签
+//	expect 不为空时校验 app_id、seller_id、total_amount 与商户订单一致，不一致返回 pay.NotifyMismatchErr，
+//	也可在查询订单后调用 event.Check()
+//
+//	e, err := client.ParseAndVerifyNotify(req, &alipay.NotifyExpect{SellerId: "2088102169227503", TotalAmount: "88.88"})
+//	文档：https://opendocs.alipay.com/open/203/105286
+func (a *Client) ParseAndVerifyNotify(req *http.Request, expect ...*NotifyExpect) (e *NotifyEvent, err error) {
+	if err = req.ParseForm(); err != nil {
+		return nil, err
+	}
+	values := req.PostForm
+	if len(values) == 0 {
+		values = req.Form
+	}
+	bm, err := a.verifyFormValues(values)
+	if err != nil {
+		return nil, err
+	}
+	if e, err = parseNotifyEvent(bm); err != nil {
+		return nil, err
+	}
+	e.Values = cloneValues(values)
+	if len(expect) > 0 && expect[0] != nil {
+		exp := *expect[0]
+		if exp.AppId == util.NULL {
+			exp.AppId = a.AppId
+		}
+		if err = e.Check(&exp); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// VerifyNotify 同 client.ParseAndVerifyNotify()，使用支付宝公钥或公钥证书内容验签，不校验订单信息，用于未初始化 Client 的场景，如 notify.AlipaySource
+//
+//	alipayPublicKeyContent：支付宝公钥（base64 或 PEM），或公钥证书 alipayCertPublicKey_RSA2.crt 的内容
+func VerifyNotify(req *http.Request, alipayPublicKeyContent []byte) (e *NotifyEvent, err error) {
+	a := new(Client)
+	a.AutoVerifySign(alipayPublicKeyContent)
+	if !a.autoSign {
+		return nil, errors.New("invalid alipay public key or cert")
+	}
+	return a.ParseAndVerifyNotify(req)
+}
+
+// verifyFormValues 使用支付宝公钥验签表单参数，参数重复出现时返回验签错误
+func (a *Client) verifyFormValues(values url.Values) (bm pay.BodyMap, err error) {
+	bm = make(pay.BodyMap, len(values))
+	for k, v := range values {
+		if len(v) != 1 {
+			return nil, fmt.Errorf("[%w]: duplicate parameter %s", pay.VerifySignatureErr, k)
+		}
+		bm.Set(k, v[0])
+	}
+	sign := bm.GetString("sign")
+	if sign == util.NULL {
+		return nil, fmt.Errorf("[%w]: sign is empty", pay.VerifySignatureErr)
+	}
+	signData := bm.DeepCopy()
+	signData.Remove("sign")
+	signData.Remove("sign_type")
+	if err = a.verifyByPublicKey(signData.EncodeAliPaySignParams(), sign, bm.GetString("sign_type")); err != nil {
+		return nil, err
+	}
+	return bm, nil
+}
+
+// Check 校验通知与商户订单一致，不一致返回 pay.NotifyMismatchErr
+func (e *NotifyEvent) Check(expect *NotifyExpect) error {
+	if expect == nil {
+		return nil
+	}
+	if expect.AppId != util.NULL && e.AppId != expect.AppId {
+		return fmt.Errorf("[%w]: app_id %s, want %s", pay.NotifyMismatchErr, e.AppId, expect.AppId)
+	}
+	if expect.SellerId != util.NULL && e.BodyMap.GetString("seller_id") != expect.SellerId {
+		return fmt.Errorf("[%w]: seller_id %s, want %s", pay.NotifyMismatchErr, e.BodyMap.GetString("seller_id"), expect.SellerId)
+	}
+	if expect.TotalAmount != util.NULL {
+		currency := e.BodyMap.GetString("trans_currency")
+		if currency == util.NULL {
+			currency = "CNY"
+		}
+		want, err := pay.ParseMoney(expect.TotalAmount, currency)
+		if err != nil {
+			return fmt.Errorf("expect total_amount %s: %w", expect.TotalAmount, err)
+		}
+		got, err := pay.ParseMoney(e.BodyMap.GetString("total_amount"), currency)
+		if err != nil || got != want {
+			return fmt.Errorf("[%w]: total_amount %s, want %s", pay.NotifyMismatchErr, e.BodyMap.GetString("total_amount"), expect.TotalAmount)
+		}
+	}
+	return nil
+}
+
+func parseNotifyEvent(bm pay.BodyMap) (e *NotifyEvent, err error) {
+	e = &NotifyEvent{
+		Type:       NotifyUnknown,
+		RawType:    bm.GetString("notify_type"),
+		NotifyId:   bm.GetString("notify_id"),
+		NotifyTime: bm.GetString("notify_time"),
+		AppId:      bm.GetString("app_id"),
+		BodyMap:    bm,
+	}
+	if method := bm.GetString("msg_method"); method != util.NULL {
+		e.RawType = method
+		e.NotifyTime = bm.GetString("utc_timestamp")
+		if method == "alipay.fund.trans.order.changed" {
+			e.Type = NotifyTransferChanged
+			e.Transfer = new(TransferNotify)
+			if err = json.Unmarshal([]byte(bm.GetString("biz_content")), e.Transfer); err != nil {
+				return nil, fmt.Errorf("[%w]: biz_content %s: %v", pay.UnmarshalErr, bm.GetString("biz_content"), err)
+			}
+		}
+		return e, nil
+	}
+	switch e.RawType {
+	case "trade_status_sync":
+		e.Type = NotifyTradeStatus
+		// 退款会触发 trade_status_sync 通知，携带 gmt_refund、out_biz_no（退款请求号）
+		if bm.GetString("gmt_refund") != util.NULL {
+			e.Type = NotifyRefund
+		}
+		e.Trade = new(TradeNotify)
+		if err = decodeNotify(bm, e.Trade); err != nil {
+			return nil, err
+		}
+		if list := bm.GetString("fund_bill_list"); list != util.NULL {
+			if err = json.Unmarshal([]byte(list), &e.Trade.FundBillList); err != nil {
+				return nil, fmt.Errorf("[%w]: fund_bill_list %s: %v", pay.UnmarshalErr, list, err)
+			}
+		}
+		if list := bm.GetString("voucher_detail_list"); list != util.NULL {
+			if err = json.Unmarshal([]byte(list), &e.Trade.VoucherDetailList); err != nil {
+				return nil, fmt.Errorf("[%w]: voucher_detail_list %s: %v", pay.UnmarshalErr, list, err)
+			}
+		}
+	case "fund_auth_freeze":
+		e.Type = NotifyFundAuthFreeze
+		e.FundAuth = new(FundAuthNotify)
+		err = decodeNotify(bm, e.FundAuth)
+	case "dut_user_sign", "dut_user_unsign":
+		e.Type = NotifyAgreementSign
+		if e.RawType == "dut_user_unsign" {
+			e.Type = NotifyAgreementUnsign
+		}
+		e.Agreement = new(AgreementNotify)
+		err = decodeNotify(bm, e.Agreement)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// decodeNotify 通知参数均为字符串，按 json tag 填充结构体
+func decodeNotify(bm pay.BodyMap, ptr interface{}) error {
+	bs, err := json.Marshal(bm)
+	if err != nil {
+		return fmt.Errorf("[%w]: %v", pay.MarshalErr, err)
+	}
+	if err = json.Unmarshal(bs, ptr); err != nil {
+		return fmt.Errorf("[%w]: %v", pay.UnmarshalErr, err)
+	}
+	return nil
+}
+
+func cloneValues(v url.Values) url.Values {
+	if v == nil {
+		return nil
+	}
+	c := make(url.Values, len(v))
+	for k, vs := range v {
+		c[k] = append([]string(nil), vs...)
+	}
+	return c
+}
@@ -24,7 +24,7 @@ import (
 // Format  string `json:"format"`   //仅支持 JSON
 // ReturnUrl  string `json:"return_url"`  //HTTP/HTTPS开头字符串
 // Charset string `json:"charset"`  //请求使用的编码格式，如utf-8,gbk,gb2312等，推荐使用 utf-8
-// SignType   string `json:"sign_type"`   //商户生成签名字符串所使用的签名算法类型，目前支持RSA2和RSA，推荐使用 RSA2
+// SignType   string `json:"sign_type"`   //商户生成签名字符串所使用的签名算法类型，目前支持RSA2、RSA和SM2，推荐使用 RSA2
 // Sign    string `json:"sign"`  //商户请求参数的签名串
 // Timestamp  string `json:"timestamp"`   //发送请求的时间，格式"yyyy-MM-dd HH:mm:ss"
 // Version string `json:"version"`  //调用的接口版本，固定为：1.0
@@ -150,7 +150,8 @@ func (a *Client) SetCharset(charset string) (client *Client) {
 	return a
 }
 
-// 设置签名算法类型，目前支持RSA2和RSA，默认推荐使用 RSA2
+// 设置签名算法类型，目前支持RSA2、RSA和SM2，默认推荐使用 RSA2
+// SM2 需使用国密 SM2 应用私钥初始化客户端
 func (a *Client) SetSignType(signType string) (client *Client) {
 	if signType != util.NULL {
 		a.SignType = signType
@@ -158,6 +159,18 @@ func (a *Client) SetSignType(signType string) (client *Client) {
 	return a
 }
 
+// SetAESKey 开启接口内容加密（encrypt_type=AES），aesKey 为开放平台配置的 AES 密钥，传空字符串关闭，同 SetEncryptKey(aesKey, alipay.AES)
+func (a *Client) SetAESKey(aesKey string) (client *Client) {
+	return a.SetEncryptKey(aesKey, AES)
+}
+
+// SetEncryptKey 开启接口内容加密，key 为开放平台配置的内容加密密钥，encryptType 为 alipay.AES 或 alipay.SM4，key 传空字符串关闭
+// 开启后请求的 biz_content 自动加密，加密的同步应答验签后自动解密，见 DecryptOpenData()
+func (a *Client) SetEncryptKey(key, encryptType string) (client *Client) {
+	a.encryptKey, a.encryptType = key, encryptType
+	return a
+}
+
 // 设置应用授权
 func (a *Client) SetAppAuthToken(appAuthToken string) (client *Client) {
 	a.AppAuthToken = appAuthToken
@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	pay "github.com/rwscode/payutil"
@@ -32,7 +31,7 @@ func (a *Client) TradePay(ctx context.Context, bm pay.BodyMap) (aliRsp *TradePay
 		return nil, err
 	}
 	aliRsp = new(TradePayResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -55,7 +54,7 @@ func (a *Client) TradePrecreate(ctx context.Context, bm pay.BodyMap) (aliRsp *Tr
 		return nil, err
 	}
 	aliRsp = new(TradePrecreateResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -129,7 +128,7 @@ func (a *Client) TradeCreate(ctx context.Context, bm pay.BodyMap) (aliRsp *Trade
 		return nil, err
 	}
 	aliRsp = new(TradeCreateResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -151,7 +150,7 @@ func (a *Client) TradeQuery(ctx context.Context, bm pay.BodyMap) (aliRsp *TradeQ
 		return nil, err
 	}
 	aliRsp = new(TradeQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -173,7 +172,7 @@ func (a *Client) TradeCancel(ctx context.Context, bm pay.BodyMap) (aliRsp *Trade
 		return nil, err
 	}
 	aliRsp = new(TradeCancelResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -195,7 +194,7 @@ func (a *Client) TradeClose(ctx context.Context, bm pay.BodyMap) (aliRsp *TradeC
 		return nil, err
 	}
 	aliRsp = new(TradeCloseResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -221,7 +220,7 @@ func (a *Client) TradeRefund(ctx context.Context, bm pay.BodyMap) (aliRsp *Trade
 		return nil, err
 	}
 	aliRsp = new(TradeRefundResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -247,7 +246,7 @@ func (a *Client) TradePageRefund(ctx context.Context, bm pay.BodyMap) (aliRsp *T
 		return nil, err
 	}
 	aliRsp = new(TradePageRefundResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -273,7 +272,7 @@ func (a *Client) TradeFastPayRefundQuery(ctx context.Context, bm pay.BodyMap) (a
 		return nil, err
 	}
 	aliRsp = new(TradeFastpayRefundQueryResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -296,7 +295,7 @@ func (a *Client) TradeOrderInfoSync(ctx context.Context, bm pay.BodyMap) (aliRsp
 		return nil, err
 	}
 	aliRsp = new(TradeOrderInfoSyncRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -315,7 +314,7 @@ func (a *Client) TradeAdvanceConsult(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(TradeAdvanceConsultRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -338,7 +337,7 @@ func (a *Client) PcreditHuabeiAuthSettleApply(ctx context.Context, bm pay.BodyMa
 		return nil, err
 	}
 	aliRsp = new(PcreditHuabeiAuthSettleApplyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -361,7 +360,7 @@ func (a *Client) CommerceTransportNfccardSend(ctx context.Context, bm pay.BodyMa
 		return nil, err
 	}
 	aliRsp = new(CommerceTransportNfccardSendRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -384,7 +383,7 @@ func (a *Client) DataDataserviceAdDataQuery(ctx context.Context, bm pay.BodyMap)
 		return nil, err
 	}
 	aliRsp = new(DataDataserviceAdDataQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -407,7 +406,7 @@ func (a *Client) CommerceAirCallcenterTradeApply(ctx context.Context, bm pay.Bod
 		return nil, err
 	}
 	aliRsp = new(CommerceAirCallcenterTradeApplyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -430,7 +429,7 @@ func (a *Client) PaymentTradeOrderCreate(ctx context.Context, bm pay.BodyMap) (a
 		return nil, err
 	}
 	aliRsp = new(PaymentTradeOrderCreateRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -453,7 +452,7 @@ func (a *Client) CommerceBenefitApply(ctx context.Context, bm pay.BodyMap) (aliR
 		return nil, err
 	}
 	aliRsp = new(CommerceBenefitApplyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -476,7 +475,7 @@ func (a *Client) CommerceBenefitVerify(ctx context.Context, bm pay.BodyMap) (ali
 		return nil, err
 	}
 	aliRsp = new(CommerceBenefitVerifyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -495,7 +494,7 @@ func (a *Client) TradeRepaybillQuery(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(TradeRepaybillQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
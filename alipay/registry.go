@@ -0,0 +1,65 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"context"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// NewClientFromCredential 根据凭证初始化支付宝客户端
+//
+//	MerchantId 为 app_id，PrivateKey 为应用私钥，PublicKey 为支付宝公钥（可空）
+//	公钥证书模式时 Certs 需包含 pay.CertAlipayApp、pay.CertAlipayRoot、pay.CertAlipayPublic，未设置 PublicKey 时使用支付宝公钥证书验签
+//	opts 在初始化后调用，用于设置中间件、日志、重试策略等
+func NewClientFromCredential(c *pay.Credential, opts ...func(c *Client)) (client *Client, err error) {
+	if client, err = NewClient(c.MerchantId, c.PrivateKey, c.IsProd); err != nil {
+		return nil, err
+	}
+	client.SetBaseURL(c.BaseURL)
+	publicKey := c.PublicKey
+	if appCert := c.Certs[pay.CertAlipayApp]; appCert != "" {
+		publicCert := c.Certs[pay.CertAlipayPublic]
+		if err = client.SetCertSnByContent([]byte(appCert), []byte(c.Certs[pay.CertAlipayRoot]), []byte(publicCert)); err != nil {
+			return nil, err
+		}
+		if publicKey == "" {
+			publicKey = publicCert
+		}
+	}
+	if publicKey != "" {
+		client.AutoVerifySign([]byte(publicKey))
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client, nil
+}
+
+// Register 向注册表注册支付宝客户端构建方法，opts 见 NewClientFromCredential()
+//
+//	alipay.Register(registry, func(c *alipay.Client) { c.Use(metrics.Middleware()) })
+func Register(r *pay.Registry, opts ...func(c *Client)) {
+	r.Register(pay.ProviderAlipay, func(_ context.Context, c *pay.Credential) (interface{}, error) {
+		return NewClientFromCredential(c, opts...)
+	})
+}
+
+// FromRegistry 从注册表获取 appid 对应的支付宝客户端，需先调用 alipay.Register()
+func FromRegistry(ctx context.Context, r *pay.Registry, appid string) (*Client, error) {
+	client, err := r.Client(ctx, pay.ProviderAlipay, appid)
+	if err != nil {
+		return nil, err
+	}
+	return client.(*Client), nil
+}
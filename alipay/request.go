@@ -0,0 +1,114 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"fmt"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// TradePayRequest alipay.trade.pay(统一收单交易支付接口) 请求参数
+//
+//	bm, err := req.BodyMap()
+//	aliRsp, err := client.TradePay(ctx, bm)
+type TradePayRequest struct {
+	OutTradeNo     string `json:"out_trade_no" validate:"required,max=64"`
+	Scene          string `json:"scene" validate:"required,oneof=bar_code security_code"`
+	AuthCode       string `json:"auth_code" validate:"required,max=64"`
+	Subject        string `json:"subject" validate:"required,max=256"`
+	TotalAmount    string `json:"total_amount" validate:"required,max=11"` // 单位元，精确到小数点后两位，范围 [0.01,100000000]
+	ProductCode    string `json:"product_code,omitempty" validate:"max=32"`
+	Body           string `json:"body,omitempty" validate:"max=128"`
+	StoreId        string `json:"store_id,omitempty" validate:"max=32"`
+	OperatorId     string `json:"operator_id,omitempty" validate:"max=28"`
+	TerminalId     string `json:"terminal_id,omitempty" validate:"max=32"`
+	TimeoutExpress string `json:"timeout_express,omitempty" validate:"max=6"`
+	NotifyUrl      string `json:"notify_url,omitempty" validate:"max=256"`
+}
+
+func (r *TradePayRequest) Validate() error {
+	if err := pay.ValidateStruct(r); err != nil {
+		return err
+	}
+	return checkAmount("total_amount", r.TotalAmount)
+}
+
+// BodyMap 校验后转为 TradePay 的请求参数
+func (r *TradePayRequest) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
+
+// TradePrecreateRequest alipay.trade.precreate(统一收单线下交易预创建) 请求参数
+type TradePrecreateRequest struct {
+	OutTradeNo           string `json:"out_trade_no" validate:"required,max=64"`
+	Subject              string `json:"subject" validate:"required,max=256"`
+	TotalAmount          string `json:"total_amount" validate:"required,max=11"` // 单位元，精确到小数点后两位，范围 [0.01,100000000]
+	ProductCode          string `json:"product_code,omitempty" validate:"max=32"`
+	Body                 string `json:"body,omitempty" validate:"max=128"`
+	StoreId              string `json:"store_id,omitempty" validate:"max=32"`
+	OperatorId           string `json:"operator_id,omitempty" validate:"max=28"`
+	TerminalId           string `json:"terminal_id,omitempty" validate:"max=32"`
+	TimeoutExpress       string `json:"timeout_express,omitempty" validate:"max=6"`
+	QrCodeTimeoutExpress string `json:"qr_code_timeout_express,omitempty" validate:"max=6"`
+	NotifyUrl            string `json:"notify_url,omitempty" validate:"max=256"`
+}
+
+func (r *TradePrecreateRequest) Validate() error {
+	if err := pay.ValidateStruct(r); err != nil {
+		return err
+	}
+	return checkAmount("total_amount", r.TotalAmount)
+}
+
+// BodyMap 校验后转为 TradePrecreate 的请求参数
+func (r *TradePrecreateRequest) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
+
+// TradeRefundRequest alipay.trade.refund(统一收单交易退款接口) 请求参数
+//
+//	OutTradeNo 与 TradeNo 不能同时为空
+type TradeRefundRequest struct {
+	OutTradeNo   string `json:"out_trade_no,omitempty" validate:"max=64"`
+	TradeNo      string `json:"trade_no,omitempty" validate:"max=64"`
+	RefundAmount string `json:"refund_amount" validate:"required,max=11"`
+	RefundReason string `json:"refund_reason,omitempty" validate:"max=256"`
+	OutRequestNo string `json:"out_request_no,omitempty" validate:"max=64"` // 部分退款时必传
+	OperatorId   string `json:"operator_id,omitempty" validate:"max=30"`
+	StoreId      string `json:"store_id,omitempty" validate:"max=32"`
+	TerminalId   string `json:"terminal_id,omitempty" validate:"max=32"`
+}
+
+func (r *TradeRefundRequest) Validate() error {
+	if r.OutTradeNo == "" && r.TradeNo == "" {
+		return fmt.Errorf("[%w], out_trade_no and trade_no are not allowed to be null at the same time", pay.MissParamErr)
+	}
+	if err := pay.ValidateStruct(r); err != nil {
+		return err
+	}
+	return checkAmount("refund_amount", r.RefundAmount)
+}
+
+// BodyMap 校验后转为 TradeRefund 的请求参数
+func (r *TradeRefundRequest) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
+
+// checkAmount 校验金额为大于 0 且不超过两位小数的人民币元
+func checkAmount(field, value string) error {
+	m, err := pay.ParseMoney(value, "CNY")
+	if err != nil || m.Amount <= 0 {
+		return fmt.Errorf("[%w]: %s must be a positive CNY amount, got %q", pay.InvalidParamErr, field, value)
+	}
+	return nil
+}
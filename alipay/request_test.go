@@ -0,0 +1,67 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"errors"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+)
+
+func TestTradePayRequest(t *testing.T) {
+	req := &TradePayRequest{
+		OutTradeNo:  "GZ201909081743431443",
+		Scene:       "bar_code",
+		AuthCode:    "286248566432274952",
+		Subject:     "条码支付",
+		TotalAmount: "0.01",
+	}
+	bm, err := req.BodyMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.GetString("total_amount") != "0.01" || bm.GetString("scene") != "bar_code" {
+		t.Fatalf("bm = %s", bm.JsonBody())
+	}
+	if _, ok := bm["store_id"]; ok {
+		t.Fatalf("empty store_id should be omitted: %s", bm.JsonBody())
+	}
+
+	req.Subject = ""
+	if err = req.Validate(); !errors.Is(err, pay.MissParamErr) {
+		t.Fatalf("missing subject: %v", err)
+	}
+	req.Subject = "条码支付"
+	req.Scene = "wave_code"
+	if err = req.Validate(); !errors.Is(err, pay.InvalidParamErr) {
+		t.Fatalf("invalid scene: %v", err)
+	}
+	req.Scene = "bar_code"
+	for _, amount := range []string{"0", "0.001", "-1", "abc"} {
+		req.TotalAmount = amount
+		if err = req.Validate(); !errors.Is(err, pay.InvalidParamErr) {
+			t.Fatalf("total_amount %q: %v", amount, err)
+		}
+	}
+}
+
+func TestTradeRefundRequest(t *testing.T) {
+	req := &TradeRefundRequest{RefundAmount: "5"}
+	if err := req.Validate(); !errors.Is(err, pay.MissParamErr) {
+		t.Fatalf("missing out_trade_no and trade_no: %v", err)
+	}
+	req.TradeNo = "2019090822001446081000123456"
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
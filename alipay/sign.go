@@ -31,10 +31,12 @@ import (
 	"strings"
 
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/redact"
 	"github.com/rwscode/payutil/pkg/util"
-	"github.com/rwscode/payutil/pkg/xlog"
 	"github.com/rwscode/payutil/pkg/xpem"
 	"github.com/rwscode/payutil/pkg/xrsa"
+	"github.com/tjfoc/gmsm/sm2"
+	gmx509 "github.com/tjfoc/gmsm/x509"
 )
 
 // 允许进行 sn 提取的证书签名算法
@@ -85,12 +87,20 @@ func GetCertSN(certPathOrData interface{}) (sn string, err error) {
 	}
 
 	if block, _ := pem.Decode(certData); block != nil {
+		var (
+			name, serialNumber string
+		)
 		cert, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
-			return util.NULL, err
+			// 国密 SM2 证书
+			gmCert, gmErr := gmx509.ParseCertificate(block.Bytes)
+			if gmErr != nil {
+				return util.NULL, err
+			}
+			name, serialNumber = gmCert.Issuer.String(), gmCert.SerialNumber.String()
+		} else {
+			name, serialNumber = cert.Issuer.String(), cert.SerialNumber.String()
 		}
-		name := cert.Issuer.String()
-		serialNumber := cert.SerialNumber.String()
 		h := md5.New()
 		h.Write([]byte(name))
 		h.Write([]byte(serialNumber))
@@ -153,7 +163,7 @@ func GetRootCertSN(rootCertPathOrData interface{}) (sn string, err error) {
 
 // 获取支付宝参数签名
 // bm：签名参数
-// signType：签名类型，alipay.RSA 或 alipay.RSA2
+// signType：签名类型，alipay.RSA 或 alipay.RSA2，国密 SM2 签名见 GetSM2Sign
 // privateKey：应用私钥，支持PKCS1和PKCS8
 func GetRsaSign(bm pay.BodyMap, signType string, privateKey *rsa.PrivateKey) (sign string, err error) {
 	var (
@@ -183,6 +193,32 @@ func GetRsaSign(bm pay.BodyMap, signType string, privateKey *rsa.PrivateKey) (si
 	return
 }
 
+// GetSM2Sign 获取支付宝参数国密签名（SM3withSM2）
+// bm：签名参数，sign_type 应为 alipay.SM2
+// privateKey：应用 SM2 私钥，见 xpem.DecodeSM2PrivateKey
+func GetSM2Sign(bm pay.BodyMap, privateKey *sm2.PrivateKey) (sign string, err error) {
+	if privateKey == nil {
+		return util.NULL, fmt.Errorf("[%w]: sm2 private key is nil", pay.SignatureErr)
+	}
+	signBytes, err := privateKey.Sign(rand.Reader, []byte(bm.EncodeAliPaySignParams()), nil)
+	if err != nil {
+		return util.NULL, fmt.Errorf("[%w]: %+v", pay.SignatureErr, err)
+	}
+	return base64.StdEncoding.EncodeToString(signBytes), nil
+}
+
+// getSign 按 sign_type 使用应用私钥签名
+func (a *Client) getSign(bm pay.BodyMap) (sign string, err error) {
+	signType := bm.GetString("sign_type")
+	if signType == SM2 {
+		return GetSM2Sign(bm, a.sm2PrivateKey)
+	}
+	if a.privateKey == nil {
+		return util.NULL, fmt.Errorf("[%w]: sign_type %s 缺少 RSA 应用私钥", pay.SignatureErr, signType)
+	}
+	return GetRsaSign(bm, signType, a.privateKey)
+}
+
 // =============================== 获取SignData ===============================
 
 // 需注意的是，公钥签名模式和公钥证书签名模式的不同之处
@@ -195,11 +231,17 @@ func (a *Client) getSignData(bs []byte, alipayCertSN string) (signData string, e
 	)
 	indexStart = indexStart + 11
 	bsLen := len(str)
+	if alipayCertSN != "" && alipayCertSN != a.AliPayPublicCertSN {
+		return pay.NULL, fmt.Errorf("[%w], 当前使用的支付宝公钥证书SN[%s]与网关响应报文中的SN[%s]不匹配", pay.CertNotMatchErr, a.AliPayPublicCertSN, alipayCertSN)
+	}
+	// 内容加密的应答，验签内容为密文（含双引号）
+	if a.encryptKey != util.NULL {
+		if rsp, key, ok := encryptedContent(bs); ok {
+			return string(rsp[key]), nil
+		}
+	}
 	if alipayCertSN != "" {
 		// 公钥证书模式
-		if alipayCertSN != a.AliPayPublicCertSN {
-			return pay.NULL, fmt.Errorf("[%w], 当前使用的支付宝公钥证书SN[%s]与网关响应报文中的SN[%s]不匹配", pay.CertNotMatchErr, a.AliPayPublicCertSN, alipayCertSN)
-		}
 		indexEnd = strings.Index(str, `,"alipay_cert_sn":`)
 		if indexEnd > indexStart && bsLen > indexStart {
 			signData = str[indexStart:indexEnd]
@@ -260,22 +302,37 @@ func VerifySyncSignWithCert(alipayPublicKeyCert interface{}, signData, sign stri
 }
 
 func (a *Client) autoVerifySignByCert(sign, signData string, signDataErr error) (err error) {
-	if a.autoSign && a.aliPayPublicKey != nil {
+	if a.autoSign && (a.aliPayPublicKey != nil || a.aliPaySM2PublicKey != nil) {
 		if a.DebugSwitch == pay.DebugOn {
-			xlog.Debugf("Alipay_SyncSignData: %s, Sign=[%s]", signData, sign)
+			a.debugf("Alipay_SyncSignData: %s, Sign=[%s]", redact.String(signData), sign)
 		}
 		// 只有证书验签时，才可能出现此error
 		if signDataErr != nil {
 			return signDataErr
 		}
 
-		signBytes, _ := base64.StdEncoding.DecodeString(sign)
-		hashs := crypto.SHA256
-		h := hashs.New()
-		h.Write([]byte(signData))
-		if err = rsa.VerifyPKCS1v15(a.aliPayPublicKey, hashs, h.Sum(nil), signBytes); err != nil {
-			return fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
-		}
+		return a.verifyByPublicKey(signData, sign, RSA2)
+	}
+	return nil
+}
+
+// verifyByPublicKey 使用 AutoVerifySign() 设置的支付宝公钥验签，RSA 公钥按 signType 选择摘要算法
+func (a *Client) verifyByPublicKey(signData, sign, signType string) (err error) {
+	if a.aliPaySM2PublicKey != nil {
+		return verifySM2Sign(signData, sign, a.aliPaySM2PublicKey)
+	}
+	if a.aliPayPublicKey == nil {
+		return errors.New("alipay public key is nil, please call AutoVerifySign()")
+	}
+	signBytes, _ := base64.StdEncoding.DecodeString(sign)
+	hashs := crypto.SHA256
+	if signType == RSA {
+		hashs = crypto.SHA1
+	}
+	h := hashs.New()
+	h.Write([]byte(signData))
+	if err = rsa.VerifyPKCS1v15(a.aliPayPublicKey, hashs, h.Sum(nil), signBytes); err != nil {
+		return fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
 	}
 	return nil
 }
@@ -385,38 +442,11 @@ func VerifySignWithCert(aliPayPublicKeyCert, notifyBean interface{}) (ok bool, e
 // =============================== 通用底层验签方法 ===============================
 
 func verifySign(signData, sign, signType, alipayPublicKey string) (err error) {
-	var (
-		h     hash.Hash
-		hashs crypto.Hash
-	)
-	publicKey, err := xpem.DecodePublicKey([]byte(alipayPublicKey))
-	if err != nil {
-		return err
-	}
-	signBytes, _ := base64.StdEncoding.DecodeString(sign)
-
-	switch signType {
-	case RSA:
-		hashs = crypto.SHA1
-	case RSA2:
-		hashs = crypto.SHA256
-	default:
-		hashs = crypto.SHA256
-	}
-	h = hashs.New()
-	h.Write([]byte(signData))
-	if err = rsa.VerifyPKCS1v15(publicKey, hashs, h.Sum(nil), signBytes); err != nil {
-		return fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
-	}
-	return nil
+	return verifySignByPEM(signData, sign, signType, []byte(alipayPublicKey))
 }
 
 func verifySignCert(signData, sign, signType string, alipayPublicKeyCert interface{}) (err error) {
-	var (
-		h     hash.Hash
-		hashs crypto.Hash
-		bytes []byte
-	)
+	var bytes []byte
 	if v, ok := alipayPublicKeyCert.(string); ok {
 		if bytes, err = ioutil.ReadFile(v); err != nil {
 			return fmt.Errorf("支付宝公钥文件读取失败: %w", err)
@@ -427,9 +457,25 @@ func verifySignCert(signData, sign, signType string, alipayPublicKeyCert interfa
 			return fmt.Errorf("支付宝公钥读取失败: %w", err)
 		}
 	}
-	publicKey, err := xpem.DecodePublicKey(bytes)
-	if err != nil {
-		return err
+	return verifySignByPEM(signData, sign, signType, bytes)
+}
+
+// verifySignByPEM 按公钥类型验签：RSA 公钥按 signType 选择摘要算法，SM2 公钥使用 SM3withSM2
+func verifySignByPEM(signData, sign, signType string, pemContent []byte) (err error) {
+	var (
+		h     hash.Hash
+		hashs crypto.Hash
+	)
+	publicKey, err := xpem.DecodePublicKey(pemContent)
+	if err != nil || publicKey == nil {
+		sm2Key, sm2Err := xpem.DecodeSM2PublicKey(pemContent)
+		if sm2Err != nil {
+			if err == nil {
+				err = sm2Err
+			}
+			return err
+		}
+		return verifySM2Sign(signData, sign, sm2Key)
 	}
 	signBytes, _ := base64.StdEncoding.DecodeString(sign)
 
@@ -448,3 +494,14 @@ func verifySignCert(signData, sign, signType string, alipayPublicKeyCert interfa
 	}
 	return nil
 }
+
+func verifySM2Sign(signData, sign string, publicKey *sm2.PublicKey) (err error) {
+	signBytes, err := base64.StdEncoding.DecodeString(sign)
+	if err != nil {
+		return fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+	}
+	if !publicKey.Verify([]byte(signData), signBytes) {
+		return fmt.Errorf("[%w]: sm2 verification failure", pay.VerifySignatureErr)
+	}
+	return nil
+}
@@ -12,12 +12,18 @@
 package alipay
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"io/ioutil"
 	"testing"
 
 	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/xlog"
 	"github.com/rwscode/payutil/pkg/xrsa"
+	"github.com/tjfoc/gmsm/sm2"
+	gmx509 "github.com/tjfoc/gmsm/x509"
 )
 
 func TestSyncVerifySign(t *testing.T) {
@@ -157,3 +163,73 @@ func TestGetCertSN(t *testing.T) {
 	// 687b59193f3f462dd5336e5abf83c5d8_02941eef3187dddf3d3b83462e1dfcf6
 	// 687b59193f3f462dd5336e5abf83c5d8_02941eef3187dddf3d3b83462e1dfcf6
 }
+
+func TestSM2SignAndVerify(t *testing.T) {
+	priKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priPem, _ := gmx509.WritePrivateKeyToPem(priKey, nil)
+	pubPem, _ := gmx509.WritePublicKeyToPem(&priKey.PublicKey)
+	// 支付宝开放平台格式：去掉 PEM 头尾的 base64 内容
+	stripPem := func(bs []byte) string {
+		block, _ := pem.Decode(bs)
+		return base64.StdEncoding.EncodeToString(block.Bytes)
+	}
+
+	client, err := NewClient("2016091200494382", stripPem(priPem), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.SignType != SM2 {
+		t.Fatalf("SignType = %s, want SM2", client.SignType)
+	}
+	bm := make(pay.BodyMap)
+	bm.Set("app_id", client.AppId).
+		Set("method", "alipay.trade.query").
+		Set("sign_type", SM2).
+		Set("biz_content", `{"out_trade_no":"GZ201909081743431443"}`)
+	sign, err := client.getSign(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sign == "" {
+		t.Fatal("getSign() returned empty sign")
+	}
+
+	// 异步通知：sign、sign_type 不参与签名
+	notify := make(pay.BodyMap)
+	notify.Set("notify_type", "trade_status_sync").
+		Set("out_trade_no", "GZ201909081743431443").
+		Set("trade_status", "TRADE_SUCCESS")
+	notifySign, err := GetSM2Sign(notify, priKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notify.Set("sign", notifySign).Set("sign_type", SM2)
+	if ok, err := VerifySign(stripPem(pubPem), notify); !ok || err != nil {
+		t.Fatalf("VerifySign() = %v, %v", ok, err)
+	}
+	// 同步应答
+	signData := `{"code":"10000","msg":"Success","out_trade_no":"GZ201909081743431443"}`
+	syncSign, _ := priKey.Sign(rand.Reader, []byte(signData), nil)
+	b64 := base64.StdEncoding.EncodeToString(syncSign)
+	if ok, err := VerifySyncSign(stripPem(pubPem), signData, b64); !ok || err != nil {
+		t.Fatalf("VerifySyncSign() = %v, %v", ok, err)
+	}
+	client.AutoVerifySign(pubPem)
+	if err = client.autoVerifySignByCert(b64, signData, nil); err != nil {
+		t.Fatalf("autoVerifySignByCert() = %v", err)
+	}
+	if err = client.autoVerifySignByCert(b64, signData+" ", nil); !errors.Is(err, pay.VerifySignatureErr) {
+		t.Fatalf("autoVerifySignByCert(tampered) = %v, want VerifySignatureErr", err)
+	}
+
+	// 切换为 RSA 签名类型时缺少 RSA 私钥
+	bm.Remove("sign")
+	bm.Set("sign_type", RSA2)
+	if _, err = client.getSign(bm); !errors.Is(err, pay.SignatureErr) {
+		t.Fatalf("getSign(RSA2) = %v, want SignatureErr", err)
+	}
+}
@@ -14,7 +14,6 @@ package alipay
 import (
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -38,7 +37,7 @@ func (a *Client) UserInfoAuth(ctx context.Context, bm pay.BodyMap) (aliRsp *User
 		return nil, errors.New(string(bs))
 	}
 	aliRsp = new(UserInfoAuthResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(*aliRsp.Response); err != nil {
@@ -68,11 +67,11 @@ func (a *Client) SystemOauthToken(ctx context.Context, bm pay.BodyMap) (aliRsp *
 	}
 
 	var bs []byte
-	if bs, err = systemOauthToken(ctx, a.AppId, a.privateKey, bm, "alipay.system.oauth.token", a.IsProd, a.SignType); err != nil {
+	if bs, err = systemOauthToken(ctx, a.AppId, a.getSign, bm, "alipay.system.oauth.token", a.IsProd, a.SignType); err != nil {
 		return nil, err
 	}
 	aliRsp = new(SystemOauthTokenResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if aliRsp.ErrorResponse != nil {
@@ -99,7 +98,7 @@ func (a *Client) OpenAuthTokenApp(ctx context.Context, bm pay.BodyMap) (aliRsp *
 		return nil, err
 	}
 	aliRsp = new(OpenAuthTokenAppResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -122,7 +121,7 @@ func (a *Client) PublicCertDownload(ctx context.Context, bm pay.BodyMap) (aliRsp
 		return nil, err
 	}
 	aliRsp = new(PublicCertDownloadRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
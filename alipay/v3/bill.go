@@ -0,0 +1,46 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// 查询对账单下载地址
+//
+//	Code = 0 is success
+//	bm 作为 URL 参数：bill_type（trade、signcustomer）、bill_date
+func (c *ClientV3) V3BillDownloadUrlQuery(ctx context.Context, bm pay.BodyMap) (aliRsp *BillDownloadUrlRsp, err error) {
+	if err = bm.CheckEmptyError("bill_type", "bill_date"); err != nil {
+		return nil, err
+	}
+	res, si, bs, err := c.doProd(ctx, MethodGet, v3BillDownloadUrl, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &BillDownloadUrlRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(BillDownloadUrl)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
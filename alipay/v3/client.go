@@ -0,0 +1,327 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	pay "github.com/rwscode/payutil"
+	alipayv2 "github.com/rwscode/payutil/alipay"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/retry"
+	"github.com/rwscode/payutil/pkg/util"
+	"github.com/rwscode/payutil/pkg/xhttp"
+	"github.com/rwscode/payutil/pkg/xlog"
+	"github.com/rwscode/payutil/pkg/xpem"
+	"github.com/rwscode/payutil/pkg/xrsa"
+)
+
+// ClientV3 支付宝开放平台 v3 协议（RESTful JSON，Authorization 请求头签名，应答头 alipay-signature 验签）
+type ClientV3 struct {
+	AppId              string
+	AppCertSN          string
+	AliPayPublicCertSN string
+	AliPayRootCertSN   string
+	AppAuthToken       string
+	IsProd             bool
+	DebugSwitch        pay.DebugSwitch
+	autoSign           bool
+	bodySize           int // http response body size(MB), default is 10MB
+	privateKey         *rsa.PrivateKey
+	aliPayPublicKey    *rsa.PublicKey // 支付宝公钥或支付宝公钥证书 alipayCertPublicKey_RSA2.crt 中的公钥
+	aliPayPublicPEM    []byte         // 支付宝公钥证书或 PEM 公钥内容，用于异步通知验签
+	hc                 xhttp.Doer     // 自定义 Doer，为空时使用 xhttp 共享连接池
+	retry              *retry.Policy  // 重试策略，为空时不重试
+	limiter            *xhttp.Limiter // 按接口族限流、熔断，为空时不限制
+	baseURL            string         // 自定义接口域名，为空时按 IsProd 选择
+	mw                 observe.Chain  // 中间件链
+	logger             xlog.Logger    // 结构化日志，为空时使用全局 xlog
+}
+
+// NewClientV3 初始化支付宝客户端 V3
+// 注意：公钥证书模式请通过 client.SetCertSnByContent() 或 client.SetCertSnByPath() 设置证书SN
+// appid：应用ID
+// privateKey：应用私钥，支持PKCS1和PKCS8
+// isProd：是否是正式环境
+func NewClientV3(appid, privateKey string, isProd bool) (client *ClientV3, err error) {
+	if appid == util.NULL || privateKey == util.NULL {
+		return nil, pay.MissAlipayInitParamErr
+	}
+	priKey, err := xpem.DecodePrivateKey([]byte(xrsa.FormatAlipayPrivateKey(privateKey)))
+	if err != nil {
+		return nil, err
+	}
+	client = &ClientV3{
+		AppId:       appid,
+		IsProd:      isProd,
+		privateKey:  priKey,
+		DebugSwitch: pay.DebugOff,
+	}
+	return client, nil
+}
+
+// AutoVerifySign 开启请求完自动验签功能（默认不开启，推荐开启）
+// alipayPublicKeyContent：支付宝公钥证书 alipayCertPublicKey_RSA2.crt 文件内容，或 PEM 格式的支付宝公钥
+func (c *ClientV3) AutoVerifySign(alipayPublicKeyContent []byte) (err error) {
+	pubKey, err := xpem.DecodePublicKey(alipayPublicKeyContent)
+	if err != nil {
+		return err
+	}
+	c.aliPayPublicKey = pubKey
+	c.aliPayPublicPEM = alipayPublicKeyContent
+	c.autoSign = true
+	return nil
+}
+
+// AutoVerifySignByPublicKey 公钥模式开启请求完自动验签功能
+// alipayPublicKey：支付宝开放平台获取的支付宝公钥（不含 PEM 头尾）
+func (c *ClientV3) AutoVerifySignByPublicKey(alipayPublicKey string) (err error) {
+	return c.AutoVerifySign([]byte(xrsa.FormatAlipayPublicKey(alipayPublicKey)))
+}
+
+// SetCertSnByContent 通过证书内容设置 app_cert_sn、alipay_root_cert_sn、alipay_cert_sn，与 alipay.Client 一致
+// appCertContent：应用公钥证书文件内容
+// aliPayRootCertContent：支付宝根证书文件内容
+// aliPayPublicCertContent：支付宝公钥证书文件内容
+func (c *ClientV3) SetCertSnByContent(appCertContent, aliPayRootCertContent, aliPayPublicCertContent []byte) (err error) {
+	return c.setCertSn(appCertContent, aliPayRootCertContent, aliPayPublicCertContent)
+}
+
+// SetCertSnByPath 通过证书路径设置 app_cert_sn、alipay_root_cert_sn、alipay_cert_sn，与 alipay.Client 一致
+// appCertPath：应用公钥证书路径
+// aliPayRootCertPath：支付宝根证书文件路径
+// aliPayPublicCertPath：支付宝公钥证书文件路径
+func (c *ClientV3) SetCertSnByPath(appCertPath, aliPayRootCertPath, aliPayPublicCertPath string) (err error) {
+	return c.setCertSn(appCertPath, aliPayRootCertPath, aliPayPublicCertPath)
+}
+
+func (c *ClientV3) setCertSn(appCert, rootCert, publicCert interface{}) (err error) {
+	appCertSn, err := alipayv2.GetCertSN(appCert)
+	if err != nil {
+		return fmt.Errorf("get app_cert_sn err: %w", err)
+	}
+	rootCertSn, err := alipayv2.GetRootCertSN(rootCert)
+	if err != nil {
+		return fmt.Errorf("get alipay_root_cert_sn err: %w", err)
+	}
+	publicCertSn, err := alipayv2.GetCertSN(publicCert)
+	if err != nil {
+		return fmt.Errorf("get alipay_cert_sn err: %w", err)
+	}
+	c.AppCertSN = appCertSn
+	c.AliPayRootCertSN = rootCertSn
+	c.AliPayPublicCertSN = publicCertSn
+	return nil
+}
+
+// SetAppAuthToken 设置第三方应用授权 app_auth_token，通过 alipay-app-auth-token 请求头传递并参与签名
+func (c *ClientV3) SetAppAuthToken(appAuthToken string) {
+	c.AppAuthToken = appAuthToken
+}
+
+// SetBaseURL 设置接口域名，如 https://openapi.alipay.com，末尾不带 /，设置后忽略 IsProd
+// 用于出口代理、本地模拟服务（paytest）等，传空字符串恢复默认
+func (c *ClientV3) SetBaseURL(url string) {
+	c.baseURL = strings.TrimSuffix(url, "/")
+}
+
+func (c *ClientV3) apiURL(path string) string {
+	switch {
+	case c.baseURL != util.NULL:
+		return c.baseURL + path
+	case c.IsProd:
+		return v3BaseUrl + path
+	}
+	return v3SandboxBaseUrl + path
+}
+
+// SetHTTPClient 设置自定义 *http.Client，默认使用 xhttp 共享连接池
+func (c *ClientV3) SetHTTPClient(hc *http.Client) {
+	if hc == nil {
+		c.hc = nil
+		return
+	}
+	c.hc = hc
+}
+
+// SetDoer 设置自定义 xhttp.Doer，用于链路追踪、代理、mock 等，传 nil 恢复默认
+func (c *ClientV3) SetDoer(d xhttp.Doer) {
+	c.hc = d
+}
+
+// SetTLSOptions 设置服务端证书校验配置，如自定义根证书、公钥固定，会替换 SetHTTPClient() 设置的客户端
+func (c *ClientV3) SetTLSOptions(o *xhttp.TLSOptions) {
+	c.hc = xhttp.NewHttpClient(&xhttp.TransportConfig{TLSConfig: xhttp.NewTLSConfig(o)})
+}
+
+// SetRetryPolicy 设置重试策略，默认不重试
+// GET 请求及查询接口（/query 结尾）按策略重试网络错误、5xx 及 SYSTEM_ERROR
+// 其余请求（下单、退款等）需通过 alipay.WithRequestId(ctx) 设置幂等键或 retry.WithIdempotent(ctx) 显式开启
+func (c *ClientV3) SetRetryPolicy(p *retry.Policy) {
+	c.retry = p
+}
+
+// SetLimiter 设置按接口族的限流、熔断，每次 http 请求（含重试）经过 l，接口标识同 observe.Call.Method
+//
+//	limiter := xhttp.NewLimiter(xhttp.BreakerConfig{Failures: 5},
+//		xhttp.Family{Name: "bill", Prefixes: []string{"GET /v3/alipay/data/dataservice/bill/"}, Rate: 1},
+//		xhttp.Family{Name: "transaction", Rate: 100},
+//	)
+//	client.SetLimiter(limiter)
+func (c *ClientV3) SetLimiter(l *xhttp.Limiter) {
+	c.limiter = l
+}
+
+func (c *ClientV3) retryPolicy(ctx context.Context, method, path string) *retry.Policy {
+	if method == MethodGet || strings.HasSuffix(path, "/query") || RequestId(ctx) != util.NULL || retry.IsIdempotent(ctx) {
+		return c.retry.Idempotent()
+	}
+	return nil
+}
+
+// 支付宝返回 SYSTEM_ERROR 时可重试
+func isSystemError(bs []byte) bool {
+	if !bytes.Contains(bs, []byte("SYSTEM_ERROR")) {
+		return false
+	}
+	var rsp struct {
+		Code string `json:"code"`
+	}
+	return json.Unmarshal(bs, &rsp) == nil && strings.HasSuffix(rsp.Code, "SYSTEM_ERROR")
+}
+
+// Use 添加中间件，用于指标统计、链路追踪等，见 observe 包，请在初始化时调用
+func (c *ClientV3) Use(mw ...observe.Middleware) {
+	c.mw = append(c.mw, mw...)
+}
+
+// SetLogger 设置当前客户端的结构化日志，日志携带 app_id 字段，并记录每次接口调用（见 observe.Logging）
+//
+//	调试日志（DebugSwitch 开启时）也输出到该 logger，可使用 xlog.NewSlogLogger() 接入 slog、zap、logrus
+func (c *ClientV3) SetLogger(l xlog.Logger) {
+	c.logger = xlog.With(l, "app_id", c.AppId)
+}
+
+func (c *ClientV3) debugf(format string, args ...interface{}) {
+	xlog.Logf(xlog.With(c.logger, "provider", pay.ProviderAlipay), xlog.DebugLevel, format, args...)
+}
+
+// SetBodySize 设置http response body size(MB)
+func (c *ClientV3) SetBodySize(sizeMB int) {
+	if sizeMB > 0 {
+		c.bodySize = sizeMB
+	}
+}
+
+// do 经过中间件链，按 policy 发送请求，bm 为请求体，用于日志记录商户订单号
+func (c *ClientV3) do(ctx context.Context, method, path string, bm pay.BodyMap, policy *retry.Policy, send func(ctx context.Context) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	call := &observe.Call{Provider: pay.ProviderAlipay, Method: observe.Endpoint(method, path), OutTradeNo: bm.GetString("out_trade_no")}
+	return c.mw.WithLogging(c.logger).DoHTTP(ctx, call, func(ctx context.Context) (*http.Response, []byte, error) {
+		res, bs, err := policy.DoHTTP(ctx, c.limiter.Wrap(call.Method, send), isSystemError)
+		if res != nil {
+			call.RequestID = res.Header.Get(HeaderTraceID)
+		}
+		return res, bs, err
+	}, resultCode)
+}
+
+// resultCode 支付宝V3 错误码，HTTP 状态码非 2xx 时返回应答中的 code
+func resultCode(status int, bs []byte) string {
+	if status >= http.StatusOK && status < http.StatusMultipleChoices {
+		return util.NULL
+	}
+	var rsp struct {
+		Code string `json:"code"`
+	}
+	_ = json.Unmarshal(bs, &rsp)
+	return rsp.Code
+}
+
+// doProd 发送 v3 请求，GET 请求时 bm 为 URL 参数，其余为 JSON 请求体
+func (c *ClientV3) doProd(ctx context.Context, method, path string, bm pay.BodyMap) (res *http.Response, si *SignInfo, bs []byte, err error) {
+	var body string
+	if method == MethodGet {
+		if len(bm) > 0 {
+			path += "?" + bm.EncodeURLParams()
+		}
+	} else if bm != nil {
+		body = bm.JsonBody()
+	}
+	authorization, err := c.authorization(method, path, body)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	requestId := RequestId(ctx)
+	if requestId == util.NULL {
+		requestId = util.RandomString(32)
+	}
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.hc)
+	if c.bodySize > 0 {
+		httpClient.SetBodySize(c.bodySize)
+	}
+	if c.DebugSwitch == pay.DebugOn {
+		c.debugf("Alipay_V3_Request: %s %s %s", method, redact.String(url), redact.String(body))
+		c.debugf("Alipay_V3_Authorization: %s", redact.Authorization(authorization))
+	}
+	// 重试时使用相同的 alipay-request-id，支付宝按幂等键返回首次结果
+	httpClient.Header.Add(HeaderRequestID, requestId)
+	if c.AppAuthToken != util.NULL {
+		httpClient.Header.Add(HeaderAppAuthToken, c.AppAuthToken)
+	}
+	if c.AliPayRootCertSN != util.NULL {
+		httpClient.Header.Add(HeaderRootCertSN, c.AliPayRootCertSN)
+	}
+	httpClient.Header.Add("Accept", "application/json")
+	var send func(ctx context.Context) (*http.Response, []byte, error)
+	if method == MethodGet {
+		send = httpClient.Type(xhttp.TypeJSON).Get(url).EndBytes
+	} else {
+		send = httpClient.Type(xhttp.TypeJSON).Post(url).SendString(body).EndBytes
+	}
+	attempt := 0
+	// 每次发送（含重试）使用新的 nonce、timestamp 重新签名，避免被支付宝按重放拒绝
+	signed := func(ctx context.Context) (*http.Response, []byte, error) {
+		if attempt++; attempt > 1 {
+			auth, err := c.authorization(method, path, body)
+			if err != nil {
+				return nil, nil, err
+			}
+			authorization = auth
+		}
+		httpClient.Header.Set(HeaderAuthorization, authorization)
+		return send(ctx)
+	}
+	res, bs, err = c.do(ctx, method, path, bm, c.retryPolicy(ctx, method, path), signed)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	si = &SignInfo{
+		HeaderTimestamp: res.Header.Get(HeaderTimestamp),
+		HeaderNonce:     res.Header.Get(HeaderNonce),
+		HeaderSignature: res.Header.Get(HeaderSignature),
+		HeaderSN:        res.Header.Get(HeaderSN),
+		SignBody:        string(bs),
+	}
+	if c.DebugSwitch == pay.DebugOn {
+		c.debugf("Alipay_V3_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("Alipay_V3_Headers: %#v", redact.Header(res.Header))
+	}
+	return res, si, bs, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+const (
+	Success = 0
+
+	MethodGet  = "GET"
+	MethodPost = "POST"
+
+	HeaderAuthorization = "Authorization"
+	HeaderAppAuthToken  = "alipay-app-auth-token"
+	HeaderRootCertSN    = "alipay-root-cert-sn"
+	HeaderRequestID     = "alipay-request-id" // 幂等键，相同值的请求支付宝只处理一次
+	HeaderTraceID       = "alipay-trace-id"
+
+	HeaderTimestamp = "alipay-timestamp"
+	HeaderNonce     = "alipay-nonce"
+	HeaderSignature = "alipay-signature"
+	HeaderSN        = "alipay-sn"
+
+	Authorization = "ALIPAY-SHA256withRSA"
+
+	v3BaseUrl        = "https://openapi.alipay.com"
+	v3SandboxBaseUrl = "https://openapi-sandbox.dl.alipaydev.com"
+
+	// 统一收单
+	v3TradePay         = "/v3/alipay/trade/pay"                  // 统一收单交易支付（付款码）
+	v3TradePrecreate   = "/v3/alipay/trade/precreate"            // 统一收单线下交易预创建（扫码）
+	v3TradeCreate      = "/v3/alipay/trade/create"               // 统一收单交易创建（小程序、JSAPI）
+	v3TradeQuery       = "/v3/alipay/trade/query"                // 统一收单交易查询
+	v3TradeClose       = "/v3/alipay/trade/close"                // 统一收单交易关闭
+	v3TradeCancel      = "/v3/alipay/trade/cancel"               // 统一收单交易撤销
+	v3TradeRefund      = "/v3/alipay/trade/refund"               // 统一收单交易退款
+	v3TradeRefundQuery = "/v3/alipay/trade/fastpay/refund/query" // 统一收单交易退款查询
+
+	// 账单
+	v3BillDownloadUrl = "/v3/alipay/data/dataservice/bill/downloadurl/query" // 查询对账单下载地址
+)
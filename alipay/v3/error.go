@@ -0,0 +1,70 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	alipayv2 "github.com/rwscode/payutil/alipay"
+)
+
+// ErrResponse 支付宝V3 错误应答，HTTP 状态码非 2xx 时返回
+type ErrResponse struct {
+	Code    string     `json:"code"`
+	Message string     `json:"message"`
+	Links   []*ErrLink `json:"links,omitempty"`
+}
+
+// ErrLink 错误诊断链接
+type ErrLink struct {
+	Link string `json:"link"`
+	Desc string `json:"desc"`
+}
+
+// NewAPIError 将接口返回的 Code（HTTP 状态码）及 Error（应答内容）转换为 *pay.APIError
+//
+//	aliRsp, err := client.V3TradeQuery(ctx, bm)
+//	if err == nil && aliRsp.Code != alipay.Success {
+//		err = alipay.NewAPIError(aliRsp.Code, aliRsp.Error)
+//	}
+func NewAPIError(statusCode int, body string) *pay.APIError {
+	var rsp ErrResponse
+	msg := body
+	if json.Unmarshal([]byte(body), &rsp) == nil && rsp.Message != "" {
+		msg = rsp.Message
+	}
+	return pay.NewAPIError(pay.ProviderAlipay, statusCode, rsp.Code, "", msg, errCategory(statusCode, rsp.Code))
+}
+
+// errCategory 错误码与原协议 sub_code 一致，按 alipay.BizErr 的规则分类，部分错误码不含 ACQ. 前缀
+func errCategory(statusCode int, code string) pay.ErrorCategory {
+	for _, subCode := range []string{code, "ACQ." + code} {
+		if c := (&alipayv2.BizErr{SubCode: subCode}).APIError().Category; c != pay.CategoryUnknown {
+			return c
+		}
+	}
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return pay.CategoryAuthFailed
+	case statusCode == http.StatusTooManyRequests:
+		return pay.CategoryThrottled
+	case statusCode == http.StatusBadRequest:
+		return pay.CategoryInvalidParam
+	case statusCode == http.StatusNotFound:
+		return pay.CategoryOrderNotExist
+	case statusCode >= http.StatusInternalServerError:
+		return pay.CategorySystemError
+	}
+	return pay.CategoryUnknown
+}
@@ -0,0 +1,30 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"context"
+)
+
+type requestIdKey struct{}
+
+// WithRequestId 设置本次请求的幂等键 alipay-request-id，支付宝对相同幂等键的请求只处理一次并返回首次结果，
+// 设置后 SetRetryPolicy() 的策略对该请求生效；未设置时每次调用随机生成，同一次调用的重试使用相同的值
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestId 返回 ctx 中通过 WithRequestId 设置的 alipay-request-id
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+// SignInfo 同步应答的验签信息
+type SignInfo struct {
+	HeaderTimestamp string `json:"alipay-timestamp"`
+	HeaderNonce     string `json:"alipay-nonce"`
+	HeaderSignature string `json:"alipay-signature"`
+	HeaderSN        string `json:"alipay-sn"`
+	SignBody        string `json:"sign_body"`
+}
+
+// ==================================分割==================================
+
+type TradePayRsp struct {
+	Code     int       `json:"-"`
+	SignInfo *SignInfo `json:"-"`
+	Response *TradePay `json:"response,omitempty"`
+	Error    string    `json:"-"`
+}
+
+type TradePrecreateRsp struct {
+	Code     int             `json:"-"`
+	SignInfo *SignInfo       `json:"-"`
+	Response *TradePrecreate `json:"response,omitempty"`
+	Error    string          `json:"-"`
+}
+
+type TradeCreateRsp struct {
+	Code     int          `json:"-"`
+	SignInfo *SignInfo    `json:"-"`
+	Response *TradeCreate `json:"response,omitempty"`
+	Error    string       `json:"-"`
+}
+
+type TradeQueryRsp struct {
+	Code     int         `json:"-"`
+	SignInfo *SignInfo   `json:"-"`
+	Response *TradeQuery `json:"response,omitempty"`
+	Error    string      `json:"-"`
+}
+
+type TradeCloseRsp struct {
+	Code     int         `json:"-"`
+	SignInfo *SignInfo   `json:"-"`
+	Response *TradeClose `json:"response,omitempty"`
+	Error    string      `json:"-"`
+}
+
+type TradeCancelRsp struct {
+	Code     int          `json:"-"`
+	SignInfo *SignInfo    `json:"-"`
+	Response *TradeCancel `json:"response,omitempty"`
+	Error    string       `json:"-"`
+}
+
+type TradeRefundRsp struct {
+	Code     int          `json:"-"`
+	SignInfo *SignInfo    `json:"-"`
+	Response *TradeRefund `json:"response,omitempty"`
+	Error    string       `json:"-"`
+}
+
+type TradeRefundQueryRsp struct {
+	Code     int               `json:"-"`
+	SignInfo *SignInfo         `json:"-"`
+	Response *TradeRefundQuery `json:"response,omitempty"`
+	Error    string            `json:"-"`
+}
+
+type BillDownloadUrlRsp struct {
+	Code     int              `json:"-"`
+	SignInfo *SignInfo        `json:"-"`
+	Response *BillDownloadUrl `json:"response,omitempty"`
+	Error    string           `json:"-"`
+}
+
+// ==================================分割==================================
+
+type TradePay struct {
+	TradeNo          string           `json:"trade_no,omitempty"`
+	OutTradeNo       string           `json:"out_trade_no,omitempty"`
+	BuyerLogonId     string           `json:"buyer_logon_id,omitempty"`
+	TotalAmount      string           `json:"total_amount,omitempty"`
+	ReceiptAmount    string           `json:"receipt_amount,omitempty"`
+	BuyerPayAmount   string           `json:"buyer_pay_amount,omitempty"`
+	PointAmount      string           `json:"point_amount,omitempty"`
+	InvoiceAmount    string           `json:"invoice_amount,omitempty"`
+	GmtPayment       string           `json:"gmt_payment,omitempty"`
+	FundBillList     []*TradeFundBill `json:"fund_bill_list,omitempty"`
+	StoreName        string           `json:"store_name,omitempty"`
+	BuyerUserId      string           `json:"buyer_user_id,omitempty"`
+	BuyerOpenId      string           `json:"buyer_open_id,omitempty"`
+	AsyncPaymentMode string           `json:"async_payment_mode,omitempty"`
+	DiscountAmount   string           `json:"discount_amount,omitempty"`
+	MdiscountAmount  string           `json:"mdiscount_amount,omitempty"`
+}
+
+type TradePrecreate struct {
+	OutTradeNo string `json:"out_trade_no,omitempty"`
+	QrCode     string `json:"qr_code,omitempty"`
+}
+
+type TradeCreate struct {
+	OutTradeNo string `json:"out_trade_no,omitempty"`
+	TradeNo    string `json:"trade_no,omitempty"`
+}
+
+type TradeQuery struct {
+	TradeNo         string           `json:"trade_no,omitempty"`
+	OutTradeNo      string           `json:"out_trade_no,omitempty"`
+	BuyerLogonId    string           `json:"buyer_logon_id,omitempty"`
+	TradeStatus     string           `json:"trade_status,omitempty"`
+	TotalAmount     string           `json:"total_amount,omitempty"`
+	BuyerPayAmount  string           `json:"buyer_pay_amount,omitempty"`
+	PointAmount     string           `json:"point_amount,omitempty"`
+	InvoiceAmount   string           `json:"invoice_amount,omitempty"`
+	ReceiptAmount   string           `json:"receipt_amount,omitempty"`
+	SendPayDate     string           `json:"send_pay_date,omitempty"`
+	StoreId         string           `json:"store_id,omitempty"`
+	TerminalId      string           `json:"terminal_id,omitempty"`
+	FundBillList    []*TradeFundBill `json:"fund_bill_list,omitempty"`
+	StoreName       string           `json:"store_name,omitempty"`
+	BuyerUserId     string           `json:"buyer_user_id,omitempty"`
+	BuyerOpenId     string           `json:"buyer_open_id,omitempty"`
+	Subject         string           `json:"subject,omitempty"`
+	Body            string           `json:"body,omitempty"`
+	DiscountAmount  string           `json:"discount_amount,omitempty"`
+	MdiscountAmount string           `json:"mdiscount_amount,omitempty"`
+	ExtInfos        string           `json:"ext_infos,omitempty"`
+}
+
+type TradeClose struct {
+	TradeNo    string `json:"trade_no,omitempty"`
+	OutTradeNo string `json:"out_trade_no,omitempty"`
+}
+
+type TradeCancel struct {
+	TradeNo            string `json:"trade_no,omitempty"`
+	OutTradeNo         string `json:"out_trade_no,omitempty"`
+	RetryFlag          string `json:"retry_flag,omitempty"`
+	Action             string `json:"action,omitempty"`
+	GmtRefundPay       string `json:"gmt_refund_pay,omitempty"`
+	RefundSettlementId string `json:"refund_settlement_id,omitempty"`
+}
+
+type TradeRefund struct {
+	TradeNo              string           `json:"trade_no,omitempty"`
+	OutTradeNo           string           `json:"out_trade_no,omitempty"`
+	BuyerLogonId         string           `json:"buyer_logon_id,omitempty"`
+	FundChange           string           `json:"fund_change,omitempty"`
+	RefundFee            string           `json:"refund_fee,omitempty"`
+	RefundDetailItemList []*TradeFundBill `json:"refund_detail_item_list,omitempty"`
+	StoreName            string           `json:"store_name,omitempty"`
+	BuyerUserId          string           `json:"buyer_user_id,omitempty"`
+	BuyerOpenId          string           `json:"buyer_open_id,omitempty"`
+	SendBackFee          string           `json:"send_back_fee,omitempty"`
+	GmtRefundPay         string           `json:"gmt_refund_pay,omitempty"`
+	RefundSettlementId   string           `json:"refund_settlement_id,omitempty"`
+}
+
+type TradeRefundQuery struct {
+	TradeNo              string           `json:"trade_no,omitempty"`
+	OutTradeNo           string           `json:"out_trade_no,omitempty"`
+	OutRequestNo         string           `json:"out_request_no,omitempty"`
+	TotalAmount          string           `json:"total_amount,omitempty"`
+	RefundAmount         string           `json:"refund_amount,omitempty"`
+	RefundStatus         string           `json:"refund_status,omitempty"`
+	GmtRefundPay         string           `json:"gmt_refund_pay,omitempty"`
+	RefundDetailItemList []*TradeFundBill `json:"refund_detail_item_list,omitempty"`
+	SendBackFee          string           `json:"send_back_fee,omitempty"`
+}
+
+type TradeFundBill struct {
+	FundChannel string `json:"fund_channel,omitempty"`
+	Amount      string `json:"amount,omitempty"`
+	RealAmount  string `json:"real_amount,omitempty"`
+	FundType    string `json:"fund_type,omitempty"`
+}
+
+type BillDownloadUrl struct {
+	BillDownloadUrl string `json:"bill_download_url,omitempty"`
+	BillFileCode    string `json:"bill_file_code,omitempty"`
+}
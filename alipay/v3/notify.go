@@ -0,0 +1,43 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"errors"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	alipayv2 "github.com/rwscode/payutil/alipay"
+)
+
+// V3ParseNotify 解析并验签支付宝异步通知
+//
+//	v3 协议的异步通知与原协议一致（表单 POST，sign、sign_type 参数签名），处理成功后需应答 success
+//	需先调用 client.AutoVerifySign() 或 client.AutoVerifySignByPublicKey() 设置支付宝公钥
+func (c *ClientV3) V3ParseNotify(req *http.Request) (bm pay.BodyMap, err error) {
+	if bm, err = alipayv2.ParseNotifyToBodyMap(req); err != nil {
+		return nil, err
+	}
+	if err = c.V3VerifyNotify(bm); err != nil {
+		return nil, err
+	}
+	return bm, nil
+}
+
+// V3VerifyNotify 使用客户端配置的支付宝公钥（证书）验签异步通知参数，bm 不会被修改
+func (c *ClientV3) V3VerifyNotify(bm pay.BodyMap) (err error) {
+	if c.aliPayPublicPEM == nil {
+		return errors.New("alipay public key is nil, call client.AutoVerifySign() first")
+	}
+	_, err = alipayv2.VerifySignWithCert(c.aliPayPublicPEM, bm.DeepCopy())
+	return err
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// 统一收单交易退款
+//
+//	Code = 0 is success
+//	同一笔退款请使用相同的 out_request_no，可通过 alipay.WithRequestId(ctx) 设置幂等键后按 SetRetryPolicy() 的策略重试
+func (c *ClientV3) V3TradeRefund(ctx context.Context, bm pay.BodyMap) (aliRsp *TradeRefundRsp, err error) {
+	if bm.GetString("out_trade_no") == util.NULL && bm.GetString("trade_no") == util.NULL {
+		return nil, errors.New("out_trade_no and trade_no are not allowed to be null at the same time")
+	}
+	if err = bm.CheckEmptyError("refund_amount"); err != nil {
+		return nil, err
+	}
+	res, si, bs, err := c.doProd(ctx, MethodPost, v3TradeRefund, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &TradeRefundRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(TradeRefund)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
+
+// 统一收单交易退款查询
+//
+//	Code = 0 is success
+//	退款不存在时仍返回成功，应答不含 refund_status
+func (c *ClientV3) V3TradeRefundQuery(ctx context.Context, bm pay.BodyMap) (aliRsp *TradeRefundQueryRsp, err error) {
+	if bm.GetString("out_trade_no") == util.NULL && bm.GetString("trade_no") == util.NULL {
+		return nil, errors.New("out_trade_no and trade_no are not allowed to be null at the same time")
+	}
+	if err = bm.CheckEmptyError("out_request_no"); err != nil {
+		return nil, err
+	}
+	res, si, bs, err := c.doProd(ctx, MethodPost, v3TradeRefundQuery, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &TradeRefundQueryRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(TradeRefundQuery)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
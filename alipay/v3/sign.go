@@ -0,0 +1,107 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// V3VerifySignByPK 支付宝V3 同步应答验签
+// 推荐直接开启自动同步验签功能 client.AutoVerifySign()
+// 验签内容：alipay-timestamp\nalipay-nonce\n应答内容\n
+// alipayPublicKey：支付宝公钥，或支付宝公钥证书中的公钥
+func V3VerifySignByPK(timestamp, nonce, signBody, sign string, alipayPublicKey *rsa.PublicKey) (err error) {
+	if alipayPublicKey == nil {
+		return errors.New("alipayPublicKey can't be nil")
+	}
+	str := timestamp + "\n" + nonce + "\n" + signBody + "\n"
+	signBytes, err := base64.StdEncoding.DecodeString(sign)
+	if err != nil {
+		return fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+	}
+	h := sha256.New()
+	h.Write([]byte(str))
+	if err = rsa.VerifyPKCS1v15(alipayPublicKey, crypto.SHA256, h.Sum(nil), signBytes); err != nil {
+		return fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+	}
+	return nil
+}
+
+// v3 鉴权请求Header
+//
+//	authString：app_id=,app_cert_sn=,nonce=,timestamp=（毫秒），非证书模式无 app_cert_sn
+//	签名内容：authString\n请求方法\n请求路径（含参数）\n请求体\n，设置了 app_auth_token 时末尾追加 app_auth_token\n
+func (c *ClientV3) authorization(method, uri, body string) (string, error) {
+	var (
+		nonce     = util.RandomString(32)
+		timestamp = strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	)
+	authString := "app_id=" + c.AppId
+	if c.AppCertSN != util.NULL {
+		authString += ",app_cert_sn=" + c.AppCertSN
+	}
+	authString += ",nonce=" + nonce + ",timestamp=" + timestamp
+	_str := authString + "\n" + method + "\n" + uri + "\n" + body + "\n"
+	if c.AppAuthToken != util.NULL {
+		_str += c.AppAuthToken + "\n"
+	}
+	if c.DebugSwitch == pay.DebugOn {
+		c.debugf("Alipay_V3_SignString:\n%s", redact.String(_str))
+	}
+	sign, err := c.rsaSign(_str)
+	if err != nil {
+		return "", err
+	}
+	return Authorization + " " + authString + ",sign=" + sign, nil
+}
+
+func (c *ClientV3) rsaSign(str string) (string, error) {
+	if c.privateKey == nil {
+		return "", errors.New("privateKey can't be nil")
+	}
+	h := sha256.New()
+	h.Write([]byte(str))
+	result, err := rsa.SignPKCS1v15(rand.Reader, c.privateKey, crypto.SHA256, h.Sum(nil))
+	if err != nil {
+		return util.NULL, fmt.Errorf("[%w]: %+v", pay.SignatureErr, err)
+	}
+	return base64.StdEncoding.EncodeToString(result), nil
+}
+
+// 自动同步请求验签
+func (c *ClientV3) verifySyncSign(si *SignInfo) (err error) {
+	if !c.autoSign {
+		return nil
+	}
+	if si == nil {
+		return errors.New("auto verify sign, but SignInfo is nil")
+	}
+	if si.HeaderSignature == util.NULL {
+		return fmt.Errorf("[%w]: response header %s is empty", pay.VerifySignatureErr, HeaderSignature)
+	}
+	if c.AliPayPublicCertSN != util.NULL && si.HeaderSN != util.NULL && si.HeaderSN != c.AliPayPublicCertSN {
+		return fmt.Errorf("[%w]: %s(%s) != alipay_cert_sn(%s), alipay public cert may be replaced", pay.VerifySignatureErr, HeaderSN, si.HeaderSN, c.AliPayPublicCertSN)
+	}
+	return V3VerifySignByPK(si.HeaderTimestamp, si.HeaderNonce, si.SignBody, si.HeaderSignature, c.aliPayPublicKey)
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+)
+
+func TestClientV3_Authorization(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	client, err := NewClientV3("2014060600164699", base64.StdEncoding.EncodeToString(der), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.AppCertSN = "ee8b3a6b5b9c0f1a"
+	client.AppAuthToken = "202301BB"
+	auth, err := client.authorization(MethodPost, v3TradeQuery, `{"out_trade_no":"T001"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(auth, Authorization+" app_id=2014060600164699,app_cert_sn=ee8b3a6b5b9c0f1a,nonce=") {
+		t.Fatalf("authorization = %s", auth)
+	}
+	i := strings.LastIndex(auth, ",sign=")
+	authString, sign := strings.TrimPrefix(auth[:i], Authorization+" "), auth[i+len(",sign="):]
+	content := authString + "\n" + MethodPost + "\n" + v3TradeQuery + "\n" + `{"out_trade_no":"T001"}` + "\n" + "202301BB\n"
+	h := sha256.Sum256([]byte(content))
+	signBytes, _ := base64.StdEncoding.DecodeString(sign)
+	if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, h[:], signBytes); err != nil {
+		t.Fatalf("verify authorization sign: %v", err)
+	}
+}
+
+func TestV3VerifySignByPK(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256([]byte("1689306000000\nabc\n{\"code\":\"10000\"}\n"))
+	bs, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	sign := base64.StdEncoding.EncodeToString(bs)
+	if err = V3VerifySignByPK("1689306000000", "abc", `{"code":"10000"}`, sign, &key.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if err = V3VerifySignByPK("1689306000000", "abc", `{"code":"40004"}`, sign, &key.PublicKey); !errors.Is(err, pay.VerifySignatureErr) {
+		t.Fatalf("verify tampered body err = %v", err)
+	}
+}
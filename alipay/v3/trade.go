@@ -0,0 +1,168 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alipay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// 统一收单交易支付（付款码支付）
+//
+//	Code = 0 is success
+//	返回 ACQ.TRADE_HAS_SUCCESS 等错误时 Code 为 HTTP 状态码，Error 为应答内容，可通过 alipay.NewAPIError() 转换
+func (c *ClientV3) V3TradePay(ctx context.Context, bm pay.BodyMap) (aliRsp *TradePayRsp, err error) {
+	if err = bm.CheckEmptyError("out_trade_no", "total_amount", "subject", "auth_code"); err != nil {
+		return nil, err
+	}
+	res, si, bs, err := c.doProd(ctx, MethodPost, v3TradePay, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &TradePayRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(TradePay)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
+
+// 统一收单线下交易预创建（扫码支付）
+//
+//	Code = 0 is success
+func (c *ClientV3) V3TradePrecreate(ctx context.Context, bm pay.BodyMap) (aliRsp *TradePrecreateRsp, err error) {
+	if err = bm.CheckEmptyError("out_trade_no", "total_amount", "subject"); err != nil {
+		return nil, err
+	}
+	res, si, bs, err := c.doProd(ctx, MethodPost, v3TradePrecreate, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &TradePrecreateRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(TradePrecreate)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
+
+// 统一收单交易创建（小程序、JSAPI 支付）
+//
+//	Code = 0 is success
+func (c *ClientV3) V3TradeCreate(ctx context.Context, bm pay.BodyMap) (aliRsp *TradeCreateRsp, err error) {
+	if err = bm.CheckEmptyError("out_trade_no", "total_amount", "subject"); err != nil {
+		return nil, err
+	}
+	res, si, bs, err := c.doProd(ctx, MethodPost, v3TradeCreate, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &TradeCreateRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(TradeCreate)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
+
+// 统一收单交易查询
+//
+//	Code = 0 is success
+func (c *ClientV3) V3TradeQuery(ctx context.Context, bm pay.BodyMap) (aliRsp *TradeQueryRsp, err error) {
+	if bm.GetString("out_trade_no") == util.NULL && bm.GetString("trade_no") == util.NULL {
+		return nil, errors.New("out_trade_no and trade_no are not allowed to be null at the same time")
+	}
+	res, si, bs, err := c.doProd(ctx, MethodPost, v3TradeQuery, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &TradeQueryRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(TradeQuery)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
+
+// 统一收单交易关闭
+//
+//	Code = 0 is success
+func (c *ClientV3) V3TradeClose(ctx context.Context, bm pay.BodyMap) (aliRsp *TradeCloseRsp, err error) {
+	if bm.GetString("out_trade_no") == util.NULL && bm.GetString("trade_no") == util.NULL {
+		return nil, errors.New("out_trade_no and trade_no are not allowed to be null at the same time")
+	}
+	res, si, bs, err := c.doProd(ctx, MethodPost, v3TradeClose, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &TradeCloseRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(TradeClose)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
+
+// 统一收单交易撤销
+//
+//	Code = 0 is success
+func (c *ClientV3) V3TradeCancel(ctx context.Context, bm pay.BodyMap) (aliRsp *TradeCancelRsp, err error) {
+	if bm.GetString("out_trade_no") == util.NULL && bm.GetString("trade_no") == util.NULL {
+		return nil, errors.New("out_trade_no and trade_no are not allowed to be null at the same time")
+	}
+	res, si, bs, err := c.doProd(ctx, MethodPost, v3TradeCancel, bm)
+	if err != nil {
+		return nil, err
+	}
+	aliRsp = &TradeCancelRsp{Code: Success, SignInfo: si}
+	if res.StatusCode != http.StatusOK {
+		aliRsp.Code = res.StatusCode
+		aliRsp.Error = string(bs)
+		return aliRsp, nil
+	}
+	aliRsp.Response = new(TradeCancel)
+	if err = json.Unmarshal(bs, aliRsp.Response); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
+	return aliRsp, c.verifySyncSign(si)
+}
@@ -13,7 +13,6 @@ package alipay
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/util"
@@ -35,7 +34,7 @@ func (a *Client) ZhimaCreditScoreGet(ctx context.Context, bm pay.BodyMap) (aliRs
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditScoreGetResponse)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -61,7 +60,7 @@ func (a *Client) ZhimaCreditEpSceneRatingInitialize(ctx context.Context, bm pay.
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditEpSceneRatingInitializeRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -84,7 +83,7 @@ func (a *Client) ZhimaCreditEpSceneFulfillmentSync(ctx context.Context, bm pay.B
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditEpSceneFulfillmentSyncRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -107,7 +106,7 @@ func (a *Client) ZhimaCreditEpSceneAgreementUse(ctx context.Context, bm pay.Body
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditEpSceneAgreementUseRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -130,7 +129,7 @@ func (a *Client) ZhimaCreditEpSceneAgreementCancel(ctx context.Context, bm pay.B
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditEpSceneAgreementCancelRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -153,7 +152,7 @@ func (a *Client) ZhimaCreditEpSceneFulfillmentlistSync(ctx context.Context, bm p
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditEpSceneFulfillmentlistSyncRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -176,7 +175,7 @@ func (a *Client) ZhimaCreditPeZmgoCumulationSync(ctx context.Context, bm pay.Bod
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoCumulationSyncRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -199,7 +198,7 @@ func (a *Client) ZhimaMerchantZmgoCumulateSync(ctx context.Context, bm pay.BodyM
 		return nil, err
 	}
 	aliRsp = new(ZhimaMerchantZmgoCumulateSyncRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -222,7 +221,7 @@ func (a *Client) ZhimaMerchantZmgoCumulateQuery(ctx context.Context, bm pay.Body
 		return nil, err
 	}
 	aliRsp = new(ZhimaMerchantZmgoCumulateQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -245,7 +244,7 @@ func (a *Client) ZhimaCreditPeZmgoBizoptClose(ctx context.Context, bm pay.BodyMa
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoBizoptCloseRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -268,7 +267,7 @@ func (a *Client) ZhimaCreditPeZmgoSettleRefund(ctx context.Context, bm pay.BodyM
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoSettleRefundRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -291,7 +290,7 @@ func (a *Client) ZhimaCreditPeZmgoPreorderCreate(ctx context.Context, bm pay.Bod
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoPreorderCreateRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -314,7 +313,7 @@ func (a *Client) ZhimaCreditPeZmgoAgreementUnsign(ctx context.Context, bm pay.Bo
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoAgreementUnsignRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -337,7 +336,7 @@ func (a *Client) ZhimaCreditPeZmgoAgreementQuery(ctx context.Context, bm pay.Bod
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoAgreementQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -360,7 +359,7 @@ func (a *Client) ZhimaCreditPeZmgoSettleUnfreeze(ctx context.Context, bm pay.Bod
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoSettleUnfreezeRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -383,7 +382,7 @@ func (a *Client) ZhimaCreditPeZmgoPaysignApply(ctx context.Context, bm pay.BodyM
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoPaysignApplyRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -406,7 +405,7 @@ func (a *Client) ZhimaCreditPeZmgoPaysignConfirm(ctx context.Context, bm pay.Bod
 		return nil, err
 	}
 	aliRsp = new(ZhimaCreditPeZmgoPaysignConfirmRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -425,7 +424,7 @@ func (a *Client) ZhimaCustomerJobworthAdapterQuery(ctx context.Context, bm pay.B
 		return nil, err
 	}
 	aliRsp = new(ZhimaCustomerJobworthAdapterQueryRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
@@ -444,7 +443,7 @@ func (a *Client) ZhimaCustomerJobworthSceneUse(ctx context.Context, bm pay.BodyM
 		return nil, err
 	}
 	aliRsp = new(ZhimaCustomerJobworthSceneUseRsp)
-	if err = json.Unmarshal(bs, aliRsp); err != nil || aliRsp.Response == nil {
+	if err = a.unmarshalResponse(bs, aliRsp); err != nil || aliRsp.Response == nil {
 		return nil, fmt.Errorf("[%w], bytes: %s", pay.UnmarshalErr, string(bs))
 	}
 	if err = bizErrCheck(aliRsp.Response.ErrorResponse); err != nil {
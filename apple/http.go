@@ -0,0 +1,136 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apple
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/xhttp"
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+// Client App Store Server API、verifyReceipt 请求客户端
+//
+//	Doer、域名、中间件、日志均为客户端级配置，不同商户（App）的客户端互不影响，请在初始化时设置
+//	包级函数 GetTransactionHistory()、GetAllSubscriptionStatuses()、VerifyReceipt() 使用默认配置的客户端
+type Client struct {
+	mu      sync.RWMutex
+	hc      xhttp.Doer    // 为空时使用 xhttp 共享连接池
+	baseURL string        // 自定义 App Store Server API 域名，为空时按 sandbox 参数选择
+	mw      observe.Chain // 中间件链
+	logger  xlog.Logger   // 结构化日志
+}
+
+// 包级函数使用的默认客户端，不可修改
+var defaultClient = NewClient()
+
+// NewClient 初始化 App Store 请求客户端
+func NewClient() *Client {
+	return &Client{}
+}
+
+// SetBaseURL 设置 App Store Server API 域名，末尾不带 /，设置后忽略 sandbox 参数
+// 用于出口代理、本地模拟服务（paytest）等，传空字符串恢复默认
+func (c *Client) SetBaseURL(url string) *Client {
+	c.mu.Lock()
+	c.baseURL = strings.TrimSuffix(url, "/")
+	c.mu.Unlock()
+	return c
+}
+
+func (c *Client) apiURL(path string, sandbox bool) string {
+	c.mu.RLock()
+	baseURL := c.baseURL
+	c.mu.RUnlock()
+	switch {
+	case baseURL != "":
+		return baseURL + path
+	case sandbox:
+		return sandBoxHostUrl + path
+	default:
+		return hostUrl + path
+	}
+}
+
+// SetHTTPClient 设置请求 App Store 使用的 *http.Client，传 nil 恢复默认
+func (c *Client) SetHTTPClient(hc *http.Client) *Client {
+	if hc == nil {
+		return c.SetDoer(nil)
+	}
+	return c.SetDoer(hc)
+}
+
+// SetDoer 设置请求 App Store 使用的 xhttp.Doer，用于链路追踪、代理、mock 等，传 nil 恢复默认
+func (c *Client) SetDoer(d xhttp.Doer) *Client {
+	c.mu.Lock()
+	c.hc = d
+	c.mu.Unlock()
+	return c
+}
+
+// SetTLSOptions 设置服务端证书校验配置，如自定义根证书、公钥固定
+func (c *Client) SetTLSOptions(o *xhttp.TLSOptions) *Client {
+	return c.SetDoer(xhttp.NewHttpClient(&xhttp.TransportConfig{TLSConfig: xhttp.NewTLSConfig(o)}))
+}
+
+// Use 添加中间件，用于指标统计、链路追踪等，见 observe 包
+func (c *Client) Use(m ...observe.Middleware) *Client {
+	c.mu.Lock()
+	c.mw = append(c.mw[:len(c.mw):len(c.mw)], m...)
+	c.mu.Unlock()
+	return c
+}
+
+// SetLogger 设置结构化日志，记录每次接口调用（见 observe.Logging）
+func (c *Client) SetLogger(l xlog.Logger) *Client {
+	c.mu.Lock()
+	c.logger = l
+	c.mu.Unlock()
+	return c
+}
+
+func (c *Client) httpClient() *xhttp.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return xhttp.NewClient().SetDoer(c.hc)
+}
+
+// do 经过中间件链发送请求
+func (c *Client) do(ctx context.Context, method, url string, send func(ctx context.Context) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	c.mu.RLock()
+	mw, logger := c.mw, c.logger
+	c.mu.RUnlock()
+	return mw.WithLogging(logger).DoHTTP(ctx, &observe.Call{Provider: pay.ProviderApple, Method: observe.Endpoint(method, url)}, send, resultCode)
+}
+
+// resultCode App Store 错误码：App Store Server API 非 200 时返回 errorCode，verifyReceipt 返回非 0 的 status
+func resultCode(status int, bs []byte) string {
+	var rsp struct {
+		ErrorCode int `json:"errorCode"`
+		Status    int `json:"status"`
+	}
+	_ = json.Unmarshal(bs, &rsp)
+	switch {
+	case status != http.StatusOK && rsp.ErrorCode != 0:
+		return strconv.Itoa(rsp.ErrorCode)
+	case status == http.StatusOK && rsp.Status != 0:
+		return strconv.Itoa(rsp.Status)
+	}
+	return ""
+}
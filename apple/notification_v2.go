@@ -42,8 +42,20 @@ at+qIxUCMG1mihDK1A3UT82NQz60imOlM27jbdoXt2QfyFMm+YhidDkLF1vLUagM
 -----END CERTIFICATE-----
 `
 
+// VerifyOptions JWS 证书链校验配置，为 nil 时使用 Apple Root CA - G3
+//
+//	仅作用于通过其调用的方法，用于本地模拟服务（paytest）等测试场景，生产环境请使用包级函数
+type VerifyOptions struct {
+	RootCAs *x509.CertPool // 信任的根证书，为空时使用 Apple Root CA - G3
+}
+
 // ExtractClaims 解析jws格式数据
 func ExtractClaims(signedPayload string, tran jwt.Claims) (interface{}, error) {
+	return (*VerifyOptions)(nil).ExtractClaims(signedPayload, tran)
+}
+
+// ExtractClaims 使用 o 的根证书校验证书链并解析jws格式数据
+func (o *VerifyOptions) ExtractClaims(signedPayload string, tran jwt.Claims) (interface{}, error) {
 	tokenStr := signedPayload
 	rootCertStr, err := extractHeaderByIndex(tokenStr, 2)
 	if err != nil {
@@ -53,7 +65,7 @@ func ExtractClaims(signedPayload string, tran jwt.Claims) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = verifyCert(rootCertStr, intermediaCertStr); err != nil {
+	if err = o.verifyCert(rootCertStr, intermediaCertStr); err != nil {
 		return nil, err
 	}
 	_, err = jwt.ParseWithClaims(tokenStr, tran, func(token *jwt.Token) (interface{}, error) {
@@ -67,11 +79,16 @@ func ExtractClaims(signedPayload string, tran jwt.Claims) (interface{}, error) {
 
 // DecodeSignedPayload 解析SignedPayload数据
 func DecodeSignedPayload(signedPayload string) (payload *NotificationV2Payload, err error) {
+	return (*VerifyOptions)(nil).DecodeSignedPayload(signedPayload)
+}
+
+// DecodeSignedPayload 使用 o 的根证书解析SignedPayload数据，返回值的 DecodeRenewalInfo()、DecodeTransactionInfo() 使用相同的根证书
+func (o *VerifyOptions) DecodeSignedPayload(signedPayload string) (payload *NotificationV2Payload, err error) {
 	if signedPayload == "" {
 		return nil, fmt.Errorf("signedPayload is empty")
 	}
-	payload = &NotificationV2Payload{}
-	_, err = ExtractClaims(signedPayload, payload)
+	payload = &NotificationV2Payload{opts: o}
+	_, err = o.ExtractClaims(signedPayload, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +118,8 @@ func extractHeaderByIndex(tokenStr string, index int) ([]byte, error) {
 		return nil, errors.New("invalid index")
 	}
 	tokenArr := strings.Split(tokenStr, ".")
-	headerByte, err := base64.RawStdEncoding.DecodeString(tokenArr[0])
+	// JWS header 为 base64url 编码
+	headerByte, err := jwt.DecodeSegment(tokenArr[0])
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +132,7 @@ func extractHeaderByIndex(tokenStr string, index int) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(header.X5c) < index {
+	if len(header.X5c) <= index {
 		return nil, fmt.Errorf("index[%d] > header.x5c slice len(%d)", index, len(header.X5c))
 	}
 	certByte, err := base64.StdEncoding.DecodeString(header.X5c[index])
@@ -124,11 +142,16 @@ func extractHeaderByIndex(tokenStr string, index int) ([]byte, error) {
 	return certByte, nil
 }
 
-func verifyCert(certByte, intermediaCertStr []byte) error {
-	roots := x509.NewCertPool()
-	ok := roots.AppendCertsFromPEM([]byte(rootPEM))
-	if !ok {
-		return errors.New("failed to parse root certificate")
+func (o *VerifyOptions) verifyCert(certByte, intermediaCertStr []byte) error {
+	var roots *x509.CertPool
+	if o != nil {
+		roots = o.RootCAs
+	}
+	if roots == nil {
+		roots = x509.NewCertPool()
+		if ok := roots.AppendCertsFromPEM([]byte(rootPEM)); !ok {
+			return errors.New("failed to parse root certificate")
+		}
 	}
 	interCert, err := x509.ParseCertificate(intermediaCertStr)
 	if err != nil {
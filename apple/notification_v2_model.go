@@ -14,6 +14,7 @@ package apple
 import (
 	"fmt"
 
+	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/jwt"
 )
 
@@ -65,6 +66,8 @@ type NotificationV2Payload struct {
 	NotificationUUID    string `json:"notificationUUID"`
 	NotificationVersion string `json:"notificationVersion"`
 	Data                *Data  `json:"data"`
+
+	opts *VerifyOptions // 解析时使用的证书链校验配置
 }
 
 func (d *NotificationV2Payload) DecodeRenewalInfo() (ri *RenewalInfo, err error) {
@@ -75,7 +78,7 @@ func (d *NotificationV2Payload) DecodeRenewalInfo() (ri *RenewalInfo, err error)
 		return nil, fmt.Errorf("data.signedRenewalInfo is empty")
 	}
 	ri = &RenewalInfo{}
-	_, err = ExtractClaims(d.Data.SignedRenewalInfo, ri)
+	_, err = d.opts.ExtractClaims(d.Data.SignedRenewalInfo, ri)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +93,7 @@ func (d *NotificationV2Payload) DecodeTransactionInfo() (ti *TransactionInfo, er
 		return nil, fmt.Errorf("data.signedTransactionInfo is empty")
 	}
 	ti = &TransactionInfo{}
-	_, err = ExtractClaims(d.Data.SignedTransactionInfo, ti)
+	_, err = d.opts.ExtractClaims(d.Data.SignedTransactionInfo, ti)
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +133,7 @@ type TransactionInfo struct {
 	jwt.StandardClaims
 	AppAccountToken             string `json:"appAccountToken"`
 	BundleId                    string `json:"bundleId"`
+	Currency                    string `json:"currency"` // ISO-4217 币种
 	Environment                 string `json:"environment"`
 	ExpiresDate                 int64  `json:"expiresDate"`
 	InAppOwnershipType          string `json:"inAppOwnershipType"` // FAMILY_SHARED  PURCHASED
@@ -138,14 +142,21 @@ type TransactionInfo struct {
 	OfferType                   int64  `json:"offerType"` // 1:An introductory offer. 2:A promotional offer. 3:An offer with a subscription offer code.
 	OriginalPurchaseDate        int64  `json:"originalPurchaseDate"`
 	OriginalTransactionId       string `json:"originalTransactionId"`
+	Price                       int64  `json:"price"` // 价格，千分之一主单位，见 Money()
 	ProductId                   string `json:"productId"`
 	PurchaseDate                int64  `json:"purchaseDate"`
 	Quantity                    int64  `json:"quantity"`
 	RevocationDate              int64  `json:"revocationDate"`
 	RevocationReason            int    `json:"revocationReason"`
 	SignedDate                  int64  `json:"signedDate"` // Auto-Renewable Subscription: An auto-renewable subscription.  Non-Consumable: A non-consumable in-app purchase.  Consumable: A consumable in-app purchase.  Non-Renewing Subscription: A non-renewing subcription.
+	Storefront                  string `json:"storefront"`
 	SubscriptionGroupIdentifier string `json:"subscriptionGroupIdentifier"`
 	TransactionId               string `json:"transactionId"`
 	Type                        string `json:"type"`
 	WebOrderLineItemId          string `json:"webOrderLineItemId"`
 }
+
+// Money 交易价格，price 为千分之一主单位，如 1990 CNY -> 1.99 元
+func (t *TransactionInfo) Money() (pay.Money, error) {
+	return pay.MoneyFromMilli(t.Price, t.Currency)
+}
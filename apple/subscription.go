@@ -17,23 +17,26 @@ import (
 	"fmt"
 	"net/http"
 
+	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/xhttp"
 )
 
 // GetAllSubscriptionStatuses
 // Doc: https://developer.apple.com/documentation/appstoreserverapi/get_all_subscription_statuses
 func GetAllSubscriptionStatuses(ctx context.Context, signConfig *SignConfig, originalTransactionId string, sandbox bool) (rsp *AllSubscriptionStatusesRsp, err error) {
-	uri := hostUrl + fmt.Sprintf(getAllSubscriptionStatuses, originalTransactionId)
-	if sandbox {
-		uri = sandBoxHostUrl + fmt.Sprintf(getAllSubscriptionStatuses, originalTransactionId)
-	}
+	return defaultClient.GetAllSubscriptionStatuses(ctx, signConfig, originalTransactionId, sandbox)
+}
+
+// GetAllSubscriptionStatuses 见包级函数 GetAllSubscriptionStatuses()
+func (c *Client) GetAllSubscriptionStatuses(ctx context.Context, signConfig *SignConfig, originalTransactionId string, sandbox bool) (rsp *AllSubscriptionStatusesRsp, err error) {
+	uri := c.apiURL(fmt.Sprintf(getAllSubscriptionStatuses, originalTransactionId), sandbox)
 	token, err := generatingToken(ctx, signConfig)
 	if err != nil {
 		return nil, err
 	}
-	cli := xhttp.NewClient()
+	cli := c.httpClient()
 	cli.Header.Set("Authorization", "Bearer "+token)
-	res, bs, err := cli.Type(xhttp.TypeJSON).Get(uri).EndBytes(ctx)
+	res, bs, err := c.do(ctx, http.MethodGet, uri, cli.Type(xhttp.TypeJSON).Get(uri).EndBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +29,11 @@ type SignConfig struct {
 	ApplePrivateKey string // 内购密钥.p8文件内容
 }
 
+// generatingToken 生成 App Store Server API 请求使用的 JWT，ctx 已结束时直接返回
 func generatingToken(ctx context.Context, signConfig *SignConfig) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	type CustomClaims struct {
 		jwt.Claims
 		Iss string `json:"iss"`
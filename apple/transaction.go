@@ -17,23 +17,26 @@ import (
 	"fmt"
 	"net/http"
 
+	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/xhttp"
 )
 
 // GetTransactionHistory
 // Doc: https://developer.apple.com/documentation/appstoreserverapi/get_transaction_history
 func GetTransactionHistory(ctx context.Context, signConfig *SignConfig, originalTransactionId string, bm pay.BodyMap, sandbox bool) (rsp *TransactionHistoryRsp, err error) {
-	uri := hostUrl + fmt.Sprintf(getTransactionHistory, originalTransactionId) + "?" + bm.EncodeURLParams()
-	if sandbox {
-		uri = sandBoxHostUrl + fmt.Sprintf(getTransactionHistory, originalTransactionId) + "?" + bm.EncodeURLParams()
-	}
+	return defaultClient.GetTransactionHistory(ctx, signConfig, originalTransactionId, bm, sandbox)
+}
+
+// GetTransactionHistory 见包级函数 GetTransactionHistory()
+func (c *Client) GetTransactionHistory(ctx context.Context, signConfig *SignConfig, originalTransactionId string, bm pay.BodyMap, sandbox bool) (rsp *TransactionHistoryRsp, err error) {
+	uri := c.apiURL(fmt.Sprintf(getTransactionHistory, originalTransactionId), sandbox) + "?" + bm.EncodeURLParams()
 	token, err := generatingToken(ctx, signConfig)
 	if err != nil {
 		return nil, err
 	}
-	cli := xhttp.NewClient()
+	cli := c.httpClient()
 	cli.Header.Set("Authorization", "Bearer "+token)
-	res, bs, err := cli.Type(xhttp.TypeJSON).Get(uri).EndBytes(ctx)
+	res, bs, err := c.do(ctx, http.MethodGet, uri, cli.Type(xhttp.TypeJSON).Get(uri).EndBytes)
 	if err != nil {
 		return nil, err
 	}
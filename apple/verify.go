@@ -13,7 +13,11 @@ package apple
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
 
+	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/xhttp"
 )
 
@@ -30,11 +34,19 @@ const (
 // pwd：苹果APP秘钥，https://help.apple.com/app-store-connect/#/devf341c0f01
 // 文档：https://developer.apple.com/documentation/appstorereceipts/verifyreceipt
 func VerifyReceipt(ctx context.Context, url, pwd, receipt string) (*VerifyResponse, error) {
+	return defaultClient.VerifyReceipt(ctx, url, pwd, receipt)
+}
+
+// VerifyReceipt 见包级函数 VerifyReceipt()，url 不受 SetBaseURL() 影响
+func (c *Client) VerifyReceipt(ctx context.Context, url, pwd, receipt string) (*VerifyResponse, error) {
 	req := &VerifyRequest{Receipt: receipt, Password: pwd}
 	vr := new(VerifyResponse)
-	_, err := xhttp.NewClient().Type(xhttp.TypeJSON).Post(url).SendStruct(req).EndStruct(ctx, vr)
+	_, bs, err := c.do(ctx, http.MethodPost, url, c.httpClient().Type(xhttp.TypeJSON).Post(url).SendStruct(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
+	if err = json.Unmarshal(bs, vr); err != nil {
+		return nil, fmt.Errorf("[%w]: %v, bytes: %s", pay.UnmarshalErr, err, string(bs))
+	}
 	return vr, nil
 }
@@ -12,13 +12,16 @@
 package pay
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"github.com/rwscode/payutil/pkg/util"
 	"io"
+	"math"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -53,12 +56,29 @@ func (bm BodyMap) SetFormFile(key string, file *util.File) BodyMap {
 	return bm
 }
 
+// SetMoney 以十进制字符串设置金额，如支付宝 total_amount "0.01"、Payssion amount，见 Money.Decimal()
+func (bm BodyMap) SetMoney(key string, m Money) BodyMap {
+	bm[key] = m.Decimal()
+	return bm
+}
+
+// SetMoneyMinor 以币种最小单位整数设置金额，如微信V2、QQ total_fee，微信V3 amount.total
+func (bm BodyMap) SetMoneyMinor(key string, m Money) BodyMap {
+	bm[key] = m.Amount
+	return bm
+}
+
+// GetMoney 按十进制字符串解析金额，见 ParseMoney()
+func (bm BodyMap) GetMoney(key, currency string) (Money, error) {
+	return ParseMoney(bm.GetString(key), currency)
+}
+
 // 获取参数，同 GetString()
 func (bm BodyMap) Get(key string) string {
 	return bm.GetString(key)
 }
 
-// 获取参数转换string
+// 获取参数转换string，key 按字面量查找，不解析点分隔路径，路径取值见 GetPathString()
 func (bm BodyMap) GetString(key string) string {
 	if bm == nil {
 		return NULL
@@ -67,11 +87,13 @@ func (bm BodyMap) GetString(key string) string {
 	if !ok {
 		return NULL
 	}
-	v, ok := value.(string)
-	if !ok {
-		return convertToString(value)
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
 	}
-	return v
+	return convertToString(value)
 }
 
 // 获取原始参数
@@ -82,6 +104,182 @@ func (bm BodyMap) GetInterface(key string) interface{} {
 	return bm[key]
 }
 
+// GetPath 按点分隔的路径获取参数，如 amount.total、receivers.0.account，数字段为数组下标
+//
+//	key 本身存在时（如 key 中含有 .）直接返回，中间层可为 BodyMap、map[string]interface{} 或 []interface{}
+func (bm BodyMap) GetPath(path string) (value interface{}, ok bool) {
+	if bm == nil {
+		return nil, false
+	}
+	if value, ok = bm[path]; ok {
+		return value, true
+	}
+	value = bm
+	for _, seg := range strings.Split(path, ".") {
+		switch cur := value.(type) {
+		case BodyMap:
+			value, ok = cur[seg]
+		case map[string]interface{}:
+			value, ok = cur[seg]
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			ok = err == nil && i >= 0 && i < len(cur)
+			if ok {
+				value = cur[i]
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// GetPathString 按点分隔的路径获取参数转换string（见 GetPath()），如 GetPathString("amount.currency")
+func (bm BodyMap) GetPathString(path string) string {
+	value, ok := bm.GetPath(path)
+	if !ok {
+		return NULL
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return convertToString(value)
+}
+
+// SetPath 按点分隔的路径设置参数，如 SetPath("amount.total", 100)，中间层不存在或不是对象时新建 BodyMap
+func (bm BodyMap) SetPath(path string, value interface{}) BodyMap {
+	segs := strings.Split(path, ".")
+	cur := bm
+	for _, seg := range segs[:len(segs)-1] {
+		switch next := cur[seg].(type) {
+		case BodyMap:
+			cur = next
+		case map[string]interface{}:
+			cur = next
+		default:
+			nb := make(BodyMap)
+			cur[seg] = nb
+			cur = nb
+		}
+	}
+	cur[segs[len(segs)-1]] = value
+	return bm
+}
+
+// GetInt64 获取整数参数，key 支持点分隔路径（见 GetPath()），兼容数字、json.Number 及数字字符串
+func (bm BodyMap) GetInt64(key string) (int64, error) {
+	value, ok := bm.GetPath(key)
+	if !ok || value == nil {
+		return 0, fmt.Errorf("[%w], %s", MissParamErr, key)
+	}
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), nil
+		}
+	case float32:
+		if f := float64(v); f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return int64(f), nil
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+			return int64(v), nil
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("[%w]: %s=%v is not an integer", InvalidParamErr, key, value)
+}
+
+// GetBool 获取布尔参数，key 支持点分隔路径，兼容 "true"、"false" 等字符串
+func (bm BodyMap) GetBool(key string) (bool, error) {
+	value, ok := bm.GetPath(key)
+	if !ok || value == nil {
+		return false, fmt.Errorf("[%w], %s", MissParamErr, key)
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("[%w]: %s=%v is not a bool", InvalidParamErr, key, value)
+}
+
+// GetBodyMap 获取嵌套对象参数，key 支持点分隔路径，不存在或不是对象时返回 nil
+//
+//	map[string]interface{}（如 JSON 解析结果）转为 BodyMap 返回，共享底层数据
+func (bm BodyMap) GetBodyMap(key string) BodyMap {
+	value, _ := bm.GetPath(key)
+	switch v := value.(type) {
+	case BodyMap:
+		return v
+	case map[string]interface{}:
+		return v
+	}
+	return nil
+}
+
+// GetSlice 获取数组参数，key 支持点分隔路径，不存在或不是数组时返回 nil
+func (bm BodyMap) GetSlice(key string) []interface{} {
+	value, _ := bm.GetPath(key)
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []BodyMap:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = e
+		}
+		return s
+	case []map[string]interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = e
+		}
+		return s
+	case []string:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = e
+		}
+		return s
+	}
+	return nil
+}
+
 // 删除参数
 func (bm BodyMap) Remove(key string) {
 	delete(bm, key)
@@ -94,6 +292,87 @@ func (bm BodyMap) Reset() {
 	}
 }
 
+// 将 src 中的参数合并到 bm，同名 key 以 src 为准
+func (bm BodyMap) Merge(src BodyMap) BodyMap {
+	for k, v := range src {
+		bm[k] = v
+	}
+	return bm
+}
+
+// DeepMerge 将 src 中的参数递归合并到 bm，双方均为对象的同名 key 逐层合并，其余以 src 为准，src 中的对象、数组复制后写入
+func (bm BodyMap) DeepMerge(src BodyMap) BodyMap {
+	for k, v := range src {
+		if sub := asBodyMap(v); sub != nil {
+			if dst := asBodyMap(bm[k]); dst != nil {
+				dst.DeepMerge(sub)
+				continue
+			}
+		}
+		bm[k] = deepCopy(v)
+	}
+	return bm
+}
+
+// DeepCopy 深拷贝，嵌套的 BodyMap、map[string]interface{}、[]interface{} 逐层复制，其余值直接引用
+func (bm BodyMap) DeepCopy() BodyMap {
+	if bm == nil {
+		return nil
+	}
+	cp := make(BodyMap, len(bm))
+	for k, v := range bm {
+		cp[k] = deepCopy(v)
+	}
+	return cp
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case BodyMap:
+		return t.DeepCopy()
+	case map[string]interface{}:
+		return map[string]interface{}(BodyMap(t).DeepCopy())
+	case []interface{}:
+		cp := make([]interface{}, len(t))
+		for i, e := range t {
+			cp[i] = deepCopy(e)
+		}
+		return cp
+	}
+	return v
+}
+
+func asBodyMap(v interface{}) BodyMap {
+	switch t := v.(type) {
+	case BodyMap:
+		return t
+	case map[string]interface{}:
+		return t
+	}
+	return nil
+}
+
+// UnmarshalJSON 解析 JSON 对象并合并到 bm，数字保留为 json.Number，避免大整数、金额精度丢失
+func (bm *BodyMap) UnmarshalJSON(data []byte) error {
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	if m == nil {
+		return nil
+	}
+	if *bm == nil {
+		*bm = make(BodyMap, len(m))
+	}
+	for k, v := range m {
+		(*bm)[k] = v
+	}
+	return nil
+}
+
+// JsonBody 序列化为 JSON，各层对象按 key 排序，相同参数的输出稳定，可用于签名
 func (bm BodyMap) JsonBody() (jb string) {
 	bs, err := json.Marshal(bm)
 	if err != nil {
@@ -120,7 +399,13 @@ func (bm BodyMap) MarshalXML(e *xml.Encoder, start xml.StartElement) (err error)
 	if err = e.EncodeToken(start); err != nil {
 		return
 	}
+	keys := make([]string, 0, len(bm))
 	for k := range bm {
+		keys = append(keys, k)
+	}
+	// 按 key 排序，输出稳定
+	sort.Strings(keys)
+	for _, k := range keys {
 		if v := bm.GetString(k); v != NULL {
 			e.Encode(xmlMapMarshal{XMLName: xml.Name{Local: k}, Value: v})
 		}
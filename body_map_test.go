@@ -14,6 +14,7 @@ package pay
 import (
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"github.com/rwscode/payutil/pkg/util"
 	"testing"
 
@@ -155,3 +156,93 @@ func TestSliceTest(t *testing.T) {
 
 	xlog.Debugf("%s", bm.JsonBody())
 }
+
+func TestBodyMapPath(t *testing.T) {
+	bm := make(BodyMap)
+	bm.SetPath("amount.total", 100).
+		SetPath("amount.currency", "CNY").
+		Set("settle_info", map[string]interface{}{"profit_sharing": "true"}).
+		Set("receivers", []interface{}{map[string]interface{}{"account": "190001001", "amount": json.Number("88")}})
+
+	if total, err := bm.GetInt64("amount.total"); err != nil || total != 100 {
+		t.Fatalf("GetInt64(amount.total) = %d, %v", total, err)
+	}
+	if amount, err := bm.GetInt64("receivers.0.amount"); err != nil || amount != 88 {
+		t.Fatalf("GetInt64(receivers.0.amount) = %d, %v", amount, err)
+	}
+	if b, err := bm.GetBool("settle_info.profit_sharing"); err != nil || !b {
+		t.Fatalf("GetBool = %v, %v", b, err)
+	}
+	if bm.GetBodyMap("amount").GetString("currency") != "CNY" || bm.GetBodyMap("settle_info") == nil || bm.GetBodyMap("amount.total") != nil {
+		t.Fatalf("GetBodyMap = %v", bm)
+	}
+	if s := bm.GetSlice("receivers"); len(s) != 1 || bm.GetSlice("amount") != nil {
+		t.Fatalf("GetSlice = %v", s)
+	}
+	if _, err := bm.GetInt64("amount.refund"); !errors.Is(err, MissParamErr) {
+		t.Fatalf("GetInt64 missing err = %v", err)
+	}
+	if _, err := bm.GetInt64("amount.currency"); !errors.Is(err, InvalidParamErr) {
+		t.Fatalf("GetInt64 invalid err = %v", err)
+	}
+	// key 本身含 . 时直接返回
+	bm.Set("a.b", "1")
+	if v, ok := bm.GetPath("a.b"); !ok || v != "1" {
+		t.Fatalf("GetPath(a.b) = %v, %v", v, ok)
+	}
+}
+
+func TestBodyMapDeepCopyMerge(t *testing.T) {
+	bm := make(BodyMap)
+	bm.SetBodyMap("amount", func(b BodyMap) {
+		b.Set("total", 100).Set("currency", "CNY")
+	}).Set("goods", []interface{}{"a"})
+
+	cp := bm.DeepCopy()
+	cp.SetPath("amount.total", 200)
+	cp.GetSlice("goods")[0] = "b"
+	if total, _ := bm.GetInt64("amount.total"); total != 100 || bm.GetSlice("goods")[0] != "a" {
+		t.Fatalf("DeepCopy shares data: %v", bm)
+	}
+
+	bm.DeepMerge(BodyMap{"amount": map[string]interface{}{"refund": 30}, "reason": "test"})
+	if bm.GetBodyMap("amount").GetString("currency") != "CNY" || bm.GetPathString("amount.refund") != "30" || bm.GetString("reason") != "test" {
+		t.Fatalf("DeepMerge = %v", bm)
+	}
+	if refund, _ := bm.GetInt64("amount.refund"); refund != 30 {
+		t.Fatalf("DeepMerge refund = %d", refund)
+	}
+	// GetString 按字面量 key 查找
+	if bm.GetString("amount.refund") != "" || bm.GetPathString("amount.missing") != "" {
+		t.Fatalf("GetString(amount.refund) = %s", bm.GetString("amount.refund"))
+	}
+}
+
+func TestBodyMapCanonical(t *testing.T) {
+	a := make(BodyMap)
+	a.Set("b", 1).Set("a", "x").SetBodyMap("c", func(b BodyMap) { b.Set("z", 1).Set("y", 2) })
+	b := make(BodyMap)
+	b.SetBodyMap("c", func(b BodyMap) { b.Set("y", 2).Set("z", 1) }).Set("a", "x").Set("b", 1)
+	if a.JsonBody() != `{"a":"x","b":1,"c":{"y":2,"z":1}}` || a.JsonBody() != b.JsonBody() {
+		t.Fatalf("JsonBody = %s, %s", a.JsonBody(), b.JsonBody())
+	}
+	xa, _ := xml.Marshal(BodyMap{"b": "2", "a": "1", "c": "3"})
+	if string(xa) != `<xml><a><![CDATA[1]]></a><b><![CDATA[2]]></b><c><![CDATA[3]]></c></xml>` {
+		t.Fatalf("MarshalXML = %s", xa)
+	}
+
+	// JSON 往返保留数字原文
+	var bm BodyMap
+	if err := json.Unmarshal([]byte(`{"total_amount":1.10,"trade_no":20230101123456789012,"amount":{"total":100}}`), &bm); err != nil {
+		t.Fatal(err)
+	}
+	if bm.GetString("total_amount") != "1.10" || bm.GetString("trade_no") != "20230101123456789012" {
+		t.Fatalf("Unmarshal = %v", bm)
+	}
+	if total, err := bm.GetInt64("amount.total"); err != nil || total != 100 {
+		t.Fatalf("GetInt64 = %d, %v", total, err)
+	}
+	if bm.JsonBody() != `{"amount":{"total":100},"total_amount":1.10,"trade_no":20230101123456789012}` {
+		t.Fatalf("JsonBody = %s", bm.JsonBody())
+	}
+}
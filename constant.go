@@ -18,7 +18,9 @@ const (
 	OK       = "OK"
 	DebugOff = 0
 	DebugOn  = 1
-	Version  = "1.5.86"
+	Version  = "1.5.87"
 )
 
+// DebugSwitch 调试日志开关，DebugOn 时输出请求、响应日志
+// 日志中的密钥、Authorization 凭证及 openid、手机号、姓名、证件号、银行卡号等敏感字段会脱敏，字段配置见 redact.SetFields()
 type DebugSwitch int8
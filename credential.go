@@ -0,0 +1,161 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Credential 商户凭证，由 CredentialProvider 提供，用于 Registry 构建渠道客户端
+type Credential struct {
+	Provider   string            `json:"provider"`              // 渠道标识，如 ProviderAlipay
+	MerchantId string            `json:"merchant_id"`           // 商户标识，支付宝为 app_id，微信为 mchid
+	AppId      string            `json:"app_id,omitempty"`      // 微信 appid 等，可空
+	IsProd     bool              `json:"is_prod"`               // 是否是正式环境
+	PrivateKey string            `json:"private_key,omitempty"` // 支付宝应用私钥、微信商户API私钥内容
+	PublicKey  string            `json:"public_key,omitempty"`  // 支付宝公钥、微信平台证书内容，可空
+	SerialNo   string            `json:"serial_no,omitempty"`   // 微信商户API证书序列号
+	ApiKey     string            `json:"api_key,omitempty"`     // 微信 APIv3Key、APIv2 key 等
+	BaseURL    string            `json:"base_url,omitempty"`    // 自定义接口域名，可空，见各渠道 client.SetBaseURL()
+	Certs      map[string]string `json:"certs,omitempty"`       // 其他证书内容，如支付宝 app_cert、alipay_root_cert、alipay_public_cert
+	Version    string            `json:"version,omitempty"`     // 凭证版本，为空时按凭证内容计算，版本变化时 Registry 重建客户端
+}
+
+// 支付宝公钥证书模式 Credential.Certs 的 key
+const (
+	CertAlipayApp    = "app_cert"           // 应用公钥证书
+	CertAlipayRoot   = "alipay_root_cert"   // 支付宝根证书
+	CertAlipayPublic = "alipay_public_cert" // 支付宝公钥证书
+)
+
+// version 凭证版本，Version 为空时返回凭证内容的 sha256
+func (c *Credential) version() string {
+	if c.Version != "" {
+		return c.Version
+	}
+	bs, _ := json.Marshal(c)
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+// CredentialProvider 商户凭证来源，如文件、环境变量、密钥管理服务（Vault、KMS 等）
+//
+//	商户不存在时返回 CredentialNotFoundErr
+type CredentialProvider interface {
+	Credential(ctx context.Context, provider, merchantId string) (*Credential, error)
+}
+
+// CredentialProviderFunc 函数形式的 CredentialProvider
+type CredentialProviderFunc func(ctx context.Context, provider, merchantId string) (*Credential, error)
+
+func (f CredentialProviderFunc) Credential(ctx context.Context, provider, merchantId string) (*Credential, error) {
+	return f(ctx, provider, merchantId)
+}
+
+// CredentialNotifier 可选接口，凭证来源支持主动通知凭证轮换时实现，NewRegistry() 会注册 fn，
+// 凭证轮换后调用 fn 即可重建对应客户端
+type CredentialNotifier interface {
+	Notify(fn func(provider, merchantId string))
+}
+
+// FileCredentialProvider 从目录读取凭证，文件路径为 Dir/{provider}/{merchantId}.json，内容为 Credential 的 JSON
+//
+//	文件更新后，通过 Registry.Refresh() 或 Registry.AutoRefresh() 重建客户端
+type FileCredentialProvider struct {
+	Dir string
+}
+
+func (p *FileCredentialProvider) Credential(_ context.Context, provider, merchantId string) (*Credential, error) {
+	if strings.ContainsAny(provider+merchantId, `/\`) || strings.Contains(provider+merchantId, "..") {
+		return nil, fmt.Errorf("[%w]: invalid provider(%s) or merchantId(%s)", InvalidParamErr, provider, merchantId)
+	}
+	path := filepath.Join(p.Dir, provider, merchantId+".json")
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("[%w]: %s", CredentialNotFoundErr, path)
+		}
+		return nil, err
+	}
+	c := new(Credential)
+	if err = json.Unmarshal(bs, c); err != nil {
+		return nil, fmt.Errorf("[%w]: %s: %v", UnmarshalErr, path, err)
+	}
+	c.Provider, c.MerchantId = provider, merchantId
+	return c, nil
+}
+
+// EnvCredentialProvider 从环境变量读取凭证，变量名为 {Prefix}_{PROVIDER}_{MERCHANTID}_{字段}，Prefix 默认为 PAY
+//
+//	字段：IS_PROD、APP_ID、PRIVATE_KEY、PUBLIC_KEY、SERIAL_NO、API_KEY、BASE_URL、VERSION，
+//	证书为 CERT_{KEY}，如 PAY_ALIPAY_2021000000000001_CERT_APP_CERT
+//	名称中非字母数字的字符替换为 _，如 PAY_WECHAT_1230000109_PRIVATE_KEY
+type EnvCredentialProvider struct {
+	Prefix string
+}
+
+func (p *EnvCredentialProvider) Credential(_ context.Context, provider, merchantId string) (*Credential, error) {
+	prefix := p.Prefix
+	if prefix == "" {
+		prefix = "PAY"
+	}
+	prefix = envName(prefix + "_" + provider + "_" + merchantId + "_")
+	get := func(field string) string {
+		return os.Getenv(prefix + field)
+	}
+	c := &Credential{
+		Provider:   provider,
+		MerchantId: merchantId,
+		AppId:      get("APP_ID"),
+		PrivateKey: get("PRIVATE_KEY"),
+		PublicKey:  get("PUBLIC_KEY"),
+		SerialNo:   get("SERIAL_NO"),
+		ApiKey:     get("API_KEY"),
+		BaseURL:    get("BASE_URL"),
+		Version:    get("VERSION"),
+	}
+	c.IsProd, _ = strconv.ParseBool(get("IS_PROD"))
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, prefix+"CERT_") {
+			if c.Certs == nil {
+				c.Certs = make(map[string]string)
+			}
+			c.Certs[strings.ToLower(strings.TrimPrefix(k, prefix+"CERT_"))] = v
+		}
+	}
+	if c.PrivateKey == "" && c.ApiKey == "" {
+		return nil, fmt.Errorf("[%w]: env %sPRIVATE_KEY or %sAPI_KEY not set", CredentialNotFoundErr, prefix, prefix)
+	}
+	return c, nil
+}
+
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -11,7 +11,11 @@
 
 package pay
 
-import "errors"
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
 
 var (
 	MissWechatInitParamErr = errors.New("missing wechat init parameter")
@@ -24,4 +28,123 @@ var (
 	VerifySignatureErr     = errors.New("verify signature error")
 	CertNotMatchErr        = errors.New("cert not match error")
 	GetSignDataErr         = errors.New("get signature data error")
+	UnsupportedErr         = errors.New("unsupported operation")
+	CredentialNotFoundErr  = errors.New("credential not found")
+	EncryptErr             = errors.New("encrypt error")
+	DecryptErr             = errors.New("decrypt error")
+	NotifyMismatchErr      = errors.New("notify mismatch")
+)
+
+// ErrorCategory 渠道错误码的统一分类
+type ErrorCategory string
+
+const (
+	CategoryUnknown           ErrorCategory = "UNKNOWN"            // 未识别的错误码
+	CategoryInsufficientFunds ErrorCategory = "INSUFFICIENT_FUNDS" // 余额不足
+	CategoryOrderPaid         ErrorCategory = "ORDER_PAID"         // 订单已支付
+	CategoryOrderClosed       ErrorCategory = "ORDER_CLOSED"       // 订单已关闭/已撤销
+	CategoryOrderNotExist     ErrorCategory = "ORDER_NOT_EXIST"    // 订单不存在
+	CategoryThrottled         ErrorCategory = "THROTTLED"          // 频率限制
+	CategoryAuthFailed        ErrorCategory = "AUTH_FAILED"        // 签名错误、鉴权失败、无权限
+	CategoryInvalidParam      ErrorCategory = "INVALID_PARAM"      // 参数错误
+	CategorySystemError       ErrorCategory = "SYSTEM_ERROR"       // 渠道系统错误/繁忙
+)
+
+// 分类对应的哨兵错误，可通过 errors.Is(err, pay.OrderPaidErr) 判断 *APIError 的分类
+var (
+	InsufficientFundsErr = errors.New("insufficient funds")
+	OrderPaidErr         = errors.New("order paid")
+	OrderClosedErr       = errors.New("order closed")
+	OrderNotExistErr     = errors.New("order not exist")
+	ThrottledErr         = errors.New("throttled")
+	AuthFailedErr        = errors.New("auth failed")
+	InvalidParamErr      = errors.New("invalid parameter")
+	SystemErr            = errors.New("system error")
 )
+
+var categoryErrs = map[ErrorCategory]error{
+	CategoryInsufficientFunds: InsufficientFundsErr,
+	CategoryOrderPaid:         OrderPaidErr,
+	CategoryOrderClosed:       OrderClosedErr,
+	CategoryOrderNotExist:     OrderNotExistErr,
+	CategoryThrottled:         ThrottledErr,
+	CategoryAuthFailed:        AuthFailedErr,
+	CategoryInvalidParam:      InvalidParamErr,
+	CategorySystemError:       SystemErr,
+}
+
+// APIError 渠道接口返回的业务错误
+//
+//	各渠道包将错误码映射为 Category，通过 errors.As(err, &apiErr) 获取，或 errors.Is(err, pay.OrderPaidErr) 判断分类
+type APIError struct {
+	Provider   string        // 渠道标识，如 ProviderAlipay
+	StatusCode int           // HTTP 状态码，未知时为 0
+	Code       string        // 渠道错误码，如支付宝 code、微信 code/err_code、PayPal name
+	SubCode    string        // 渠道子错误码，如支付宝 sub_code、PayPal details[0].issue
+	Message    string        // 错误描述
+	RequestId  string        // 渠道请求 ID，如 PayPal debug_id，用于联系渠道排查
+	Category   ErrorCategory // 统一分类
+	Retryable  bool          // 相同参数重试是否可能成功，如系统繁忙、频率限制
+}
+
+// NewAPIError 初始化 APIError，Retryable 由分类和 HTTP 状态码推断
+func NewAPIError(provider string, statusCode int, code, subCode, message string, category ErrorCategory) *APIError {
+	if category == "" {
+		category = CategoryUnknown
+	}
+	return &APIError{
+		Provider:   provider,
+		StatusCode: statusCode,
+		Code:       code,
+		SubCode:    subCode,
+		Message:    message,
+		Category:   category,
+		Retryable:  category == CategorySystemError || category == CategoryThrottled || statusCode >= 500,
+	}
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Provider)
+	b.WriteString(" api error")
+	if e.StatusCode != 0 {
+		b.WriteString(", http status: ")
+		b.WriteString(strconv.Itoa(e.StatusCode))
+	}
+	if e.Code != "" {
+		b.WriteString(", code: ")
+		b.WriteString(e.Code)
+	}
+	if e.SubCode != "" {
+		b.WriteString(", sub_code: ")
+		b.WriteString(e.SubCode)
+	}
+	if e.Message != "" {
+		b.WriteString(", message: ")
+		b.WriteString(e.Message)
+	}
+	if e.RequestId != "" {
+		b.WriteString(", request_id: ")
+		b.WriteString(e.RequestId)
+	}
+	return b.String()
+}
+
+// Is 支持 errors.Is(err, pay.OrderPaidErr) 等分类判断
+func (e *APIError) Is(target error) bool {
+	return target != nil && categoryErrs[e.Category] == target
+}
+
+// Temporary 返回 Retryable，retry.IsRetryableIdempotent 据此判断幂等调用是否重试
+func (e *APIError) Temporary() bool {
+	return e.Retryable
+}
+
+// AsAPIError 从 err 链中取出 *APIError
+func AsAPIError(err error) (*APIError, bool) {
+	var e *APIError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	err := fmt.Errorf("wrap: %w", NewAPIError(ProviderWechat, 403, "ORDERPAID", "", "该订单已支付", CategoryOrderPaid))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatal("errors.As *APIError failed")
+	}
+	if apiErr.Provider != ProviderWechat || apiErr.StatusCode != 403 || apiErr.Code != "ORDERPAID" {
+		t.Fatalf("unexpected APIError: %+v", apiErr)
+	}
+	if !errors.Is(err, OrderPaidErr) || errors.Is(err, OrderClosedErr) {
+		t.Fatal("errors.Is category mismatch")
+	}
+	if apiErr.Retryable || apiErr.Temporary() {
+		t.Fatal("ORDERPAID should not be retryable")
+	}
+	if e, ok := AsAPIError(err); !ok || e != apiErr {
+		t.Fatal("AsAPIError failed")
+	}
+}
+
+func TestAPIError_Retryable(t *testing.T) {
+	cases := []struct {
+		status   int
+		category ErrorCategory
+		want     bool
+	}{
+		{0, CategorySystemError, true},
+		{429, CategoryThrottled, true},
+		{502, "", true},
+		{400, CategoryInvalidParam, false},
+		{0, CategoryInsufficientFunds, false},
+	}
+	for _, c := range cases {
+		e := NewAPIError(ProviderAlipay, c.status, "code", "", "", c.category)
+		if e.Retryable != c.want {
+			t.Errorf("status %d category %s retryable = %v, want %v", c.status, c.category, e.Retryable, c.want)
+		}
+	}
+	if e := NewAPIError(ProviderAlipay, 0, "code", "", "", ""); e.Category != CategoryUnknown {
+		t.Errorf("empty category = %s, want %s", e.Category, CategoryUnknown)
+	}
+}
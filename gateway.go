@@ -0,0 +1,167 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import "context"
+
+// 支付渠道标识
+const (
+	ProviderAlipay   = "alipay"
+	ProviderWechat   = "wechat"    // 微信支付 v3
+	ProviderWechatV2 = "wechat_v2" // 微信支付 v2
+	ProviderQQ       = "qq"
+	ProviderPayPal   = "paypal"
+	ProviderPayssion = "payssion"
+	ProviderApple    = "apple"
+)
+
+// Gateway 统一支付网关，屏蔽各渠道 Client 的接口差异
+//
+//	各渠道适配器见：alipay.NewGateway、wechat.NewGateway（v2 及 wechat/v3）、qq.NewGateway、paypal.NewGateway、payssion.NewGateway
+//	渠道不支持的操作返回 UnsupportedErr
+//	需要买家批准后由商户确认扣款的渠道（PayPal）同时实现 Capturer，Charge 仅创建订单，买家批准后须调用 Capture 才会扣款入账
+type Gateway interface {
+	// Provider 渠道标识，如 ProviderAlipay
+	Provider() string
+	// Charge 下单
+	Charge(ctx context.Context, o *Order) (*ChargeResult, error)
+	// Query 查询订单
+	Query(ctx context.Context, q *OrderQuery) (*OrderResult, error)
+	// Close 关闭订单
+	Close(ctx context.Context, q *OrderQuery) error
+	// Refund 申请退款
+	Refund(ctx context.Context, r *Refund) (*RefundResult, error)
+	// QueryRefund 查询退款
+	QueryRefund(ctx context.Context, q *RefundQuery) (*RefundResult, error)
+}
+
+// Capturer 确认扣款，见 paypal.NewGateway
+//
+//	g.Charge() -> 跳转 ChargeResult.PayUrl 由买家批准 -> g.(pay.Capturer).Capture()
+type Capturer interface {
+	// Capture 确认扣款已批准的订单，返回扣款后的订单
+	Capture(ctx context.Context, q *OrderQuery) (*OrderResult, error)
+}
+
+// Scene 支付场景
+type Scene string
+
+const (
+	SceneQRCode Scene = "QRCODE" // 扫码支付：支付宝当面付预下单、微信 Native
+	SceneApp    Scene = "APP"    // APP 支付
+	SceneJSAPI  Scene = "JSAPI"  // 公众号/小程序支付，需 OpenId
+	SceneWap    Scene = "WAP"    // 手机网站/H5 支付
+	ScenePage   Scene = "PAGE"   // 电脑网站支付、PayPal/Payssion 跳转收银台
+)
+
+// TradeStatus 统一订单状态
+type TradeStatus string
+
+const (
+	TradeStatusPending  TradeStatus = "PENDING"  // 待支付/支付中
+	TradeStatusPaid     TradeStatus = "PAID"     // 支付成功
+	TradeStatusClosed   TradeStatus = "CLOSED"   // 已关闭/已撤销
+	TradeStatusRefunded TradeStatus = "REFUNDED" // 转入退款
+	TradeStatusFailed   TradeStatus = "FAILED"   // 支付失败
+	TradeStatusUnknown  TradeStatus = "UNKNOWN"  // 无法识别的渠道状态，见 OrderResult.RawStatus
+)
+
+// RefundStatus 统一退款状态
+type RefundStatus string
+
+const (
+	RefundStatusProcessing RefundStatus = "PROCESSING" // 退款处理中
+	RefundStatusSuccess    RefundStatus = "SUCCESS"    // 退款成功
+	RefundStatusClosed     RefundStatus = "CLOSED"     // 退款关闭
+	RefundStatusFailed     RefundStatus = "FAILED"     // 退款异常/失败
+	RefundStatusUnknown    RefundStatus = "UNKNOWN"    // 无法识别的渠道状态，见 RefundResult.RawStatus
+)
+
+// Order 统一下单请求
+type Order struct {
+	OutTradeNo string // 商户订单号
+	Subject    string // 订单标题
+	Body       string // 订单描述，为空时使用 Subject
+	Amount     int64  // 订单金额，币种最小单位（如：分）
+	Currency   string // ISO-4217 币种，为空时由适配器按渠道默认（CNY/USD）
+	Scene      Scene  // 支付场景
+	NotifyUrl  string // 异步通知地址
+	ReturnUrl  string // 同步跳转地址
+	OpenId     string // JSAPI 场景用户标识：微信 openid、支付宝 buyer_id
+	ClientIp   string // 用户端 IP
+	// Extra 渠道特有参数，最后合并到请求 BodyMap，可覆盖适配器生成的同名参数
+	Extra BodyMap
+}
+
+// ChargeResult 统一下单结果，按场景填充对应字段
+type ChargeResult struct {
+	OutTradeNo string      // 商户订单号
+	TradeNo    string      // 渠道订单号（如有）
+	CodeUrl    string      // 扫码支付二维码内容
+	PayUrl     string      // 跳转支付链接：H5/WAP/PAGE、PayPal approve 链接
+	PrepayId   string      // 微信预支付交易会话标识
+	PayParams  string      // APP/JSAPI 调起支付参数（支付宝 orderStr，微信为 JSON）
+	Raw        interface{} // 渠道原始响应
+}
+
+// OrderQuery 订单查询/关闭请求，OutTradeNo 与 TradeNo 二选一
+type OrderQuery struct {
+	OutTradeNo string // 商户订单号
+	TradeNo    string // 渠道订单号，PayPal 仅支持 order id
+}
+
+// OrderResult 统一订单查询结果
+type OrderResult struct {
+	OutTradeNo string
+	TradeNo    string
+	Status     TradeStatus
+	RawStatus  string      // 渠道原始状态
+	Amount     int64       // 订单金额，币种最小单位
+	Currency   string      // ISO-4217 币种
+	Raw        interface{} // 渠道原始响应
+}
+
+// Refund 统一退款请求
+type Refund struct {
+	OutTradeNo  string // 商户订单号，与 TradeNo 二选一
+	TradeNo     string // 渠道订单号，PayPal 为 order id
+	OutRefundNo string // 商户退款单号
+	Amount      int64  // 退款金额，币种最小单位
+	Total       int64  // 原订单金额，微信/QQ 必填
+	Currency    string // ISO-4217 币种
+	Reason      string // 退款原因
+	NotifyUrl   string // 退款结果通知地址
+	// Extra 渠道特有参数，最后合并到请求 BodyMap
+	Extra BodyMap
+}
+
+// RefundQuery 退款查询请求
+type RefundQuery struct {
+	OutTradeNo  string // 商户订单号
+	TradeNo     string // 渠道订单号
+	OutRefundNo string // 商户退款单号
+	RefundNo    string // 渠道退款单号，PayPal 必填 refund id
+	Currency    string // ISO-4217 币种，应答不含币种的渠道（如支付宝）按此解析退款金额
+}
+
+// RefundResult 统一退款结果
+type RefundResult struct {
+	OutTradeNo  string
+	TradeNo     string
+	OutRefundNo string
+	RefundNo    string
+	Status      RefundStatus
+	RawStatus   string      // 渠道原始状态
+	Amount      int64       // 退款金额，币种最小单位
+	Currency    string      // ISO-4217 币种
+	Raw         interface{} // 渠道原始响应
+}
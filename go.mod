@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/gin-gonic/gin v1.9.1
 	github.com/json-iterator/go v1.1.12
+	github.com/tjfoc/gmsm v1.4.1
 	golang.org/x/crypto v0.14.0
 )
 
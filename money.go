@@ -0,0 +1,159 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，Amount 为币种最小单位（如：分、美分、日元），Currency 为 ISO-4217 币种
+//
+//	各渠道金额格式：支付宝 total_amount、PayPal value、Payssion amount 为十进制字符串，见 Decimal()；
+//	微信、QQ 为最小单位整数，见 Amount；Apple price 为千分之一单位整数，见 Milli()
+//	全程使用整数运算，不经过浮点数
+type Money struct {
+	Amount   int64  // 金额，币种最小单位
+	Currency string // ISO-4217 币种，如 CNY、USD、JPY
+}
+
+// NewMoney 初始化金额，amount 为币种最小单位
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// CNY 初始化人民币金额，fen 单位为分
+func CNY(fen int64) Money {
+	return Money{Amount: fen, Currency: "CNY"}
+}
+
+// ISO-4217 中小数位数不为 2 的币种
+var currencyExponent = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyExponent 返回币种小数位数（最小单位与主单位的换算位数），如 CNY 为 2，JPY 为 0，KWD 为 3
+func CurrencyExponent(currency string) int {
+	if e, ok := currencyExponent[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// ParseMoney 解析十进制金额字符串，如 "0.01"、"100"、"-1.5"，小数位数不能超过币种精度
+func ParseMoney(value, currency string) (m Money, err error) {
+	m.Currency = strings.ToUpper(currency)
+	exp := CurrencyExponent(currency)
+	s := strings.TrimSpace(value)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" || len(fracPart) > exp {
+		return m, fmt.Errorf("[%w]: invalid %s amount %q", InvalidParamErr, m.Currency, value)
+	}
+	fracPart += strings.Repeat("0", exp-len(fracPart))
+	n, err := strconv.ParseUint(intPart+fracPart, 10, 63)
+	if err != nil {
+		return m, fmt.Errorf("[%w]: invalid %s amount %q", InvalidParamErr, m.Currency, value)
+	}
+	m.Amount = int64(n)
+	if neg {
+		m.Amount = -m.Amount
+	}
+	return m, nil
+}
+
+// MoneyFromMilli 千分之一主单位的金额转换为 Money，如 Apple price，不能整除最小单位时返回错误
+func MoneyFromMilli(milli int64, currency string) (Money, error) {
+	exp := CurrencyExponent(currency)
+	if exp >= 3 {
+		return NewMoney(milli*pow10(exp-3), currency), nil
+	}
+	d := pow10(3 - exp)
+	if milli%d != 0 {
+		return Money{}, fmt.Errorf("[%w]: %d milli %s is not a whole minor unit", InvalidParamErr, milli, currency)
+	}
+	return NewMoney(milli/d, currency), nil
+}
+
+// Exponent 币种小数位数，见 CurrencyExponent()
+func (m Money) Exponent() int {
+	return CurrencyExponent(m.Currency)
+}
+
+// Decimal 返回按币种精度格式化的十进制字符串，如 CNY 1 -> "0.01"，JPY 100 -> "100"，KWD 1500 -> "1.500"
+func (m Money) Decimal() string {
+	exp := m.Exponent()
+	sign, n := "", uint64(m.Amount)
+	if m.Amount < 0 {
+		sign, n = "-", uint64(-m.Amount)
+	}
+	if exp == 0 {
+		return sign + strconv.FormatUint(n, 10)
+	}
+	d := uint64(pow10(exp))
+	return fmt.Sprintf("%s%d.%0*d", sign, n/d, exp, n%d)
+}
+
+// Milli 返回千分之一主单位的金额，如 Apple price，CNY 1 分 -> 10
+func (m Money) Milli() int64 {
+	exp := m.Exponent()
+	if exp >= 3 {
+		return m.Amount / pow10(exp-3)
+	}
+	return m.Amount * pow10(3-exp)
+}
+
+// String 返回如 "0.01 CNY" 格式的金额
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// IsZero 金额是否为 0
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add 金额相加，币种不一致或溢出时返回错误
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return m, fmt.Errorf("[%w]: currency mismatch %s and %s", InvalidParamErr, m.Currency, o.Currency)
+	}
+	if (o.Amount > 0 && m.Amount > math.MaxInt64-o.Amount) || (o.Amount < 0 && m.Amount < math.MinInt64-o.Amount) {
+		return m, fmt.Errorf("[%w]: amount overflow", InvalidParamErr)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub 金额相减，币种不一致或溢出时返回错误
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return m, fmt.Errorf("[%w]: amount overflow", InvalidParamErr)
+	}
+	return m.Add(Money{Amount: -o.Amount, Currency: o.Currency})
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
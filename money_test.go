@@ -0,0 +1,80 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMoney(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		amount   int64
+		decimal  string
+		milli    int64
+	}{
+		{"0.01", "CNY", 1, "0.01", 10},
+		{"10", "usd", 1000, "10.00", 10000},
+		{"19.9", "USD", 1990, "19.90", 19900},
+		{"-1.5", "EUR", -150, "-1.50", -1500},
+		{"100", "JPY", 100, "100", 100000},
+		{"1.5", "KWD", 1500, "1.500", 1500},
+		{"92233720368547758.07", "CNY", 9223372036854775807, "92233720368547758.07", 0},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.value, tt.currency)
+		if err != nil || m.Amount != tt.amount {
+			t.Fatalf("ParseMoney(%s, %s) = %+v, %v", tt.value, tt.currency, m, err)
+		}
+		if m.Decimal() != tt.decimal {
+			t.Errorf("%+v Decimal() = %s, want %s", m, m.Decimal(), tt.decimal)
+		}
+		if tt.milli > 0 && m.Milli() != tt.milli {
+			t.Errorf("%+v Milli() = %d, want %d", m, m.Milli(), tt.milli)
+		}
+	}
+	for _, v := range [][2]string{{"0.001", "CNY"}, {"1.5", "JPY"}, {"", "CNY"}, {".5", "CNY"}, {"1e2", "USD"}, {"+1", "USD"}, {"92233720368547758.08", "CNY"}} {
+		if m, err := ParseMoney(v[0], v[1]); !errors.Is(err, InvalidParamErr) {
+			t.Errorf("ParseMoney(%s, %s) = %+v, %v, want InvalidParamErr", v[0], v[1], m, err)
+		}
+	}
+
+	if m, err := MoneyFromMilli(1990, "CNY"); err != nil || m != CNY(199) {
+		t.Fatalf("MoneyFromMilli() = %+v, %v", m, err)
+	}
+	if _, err := MoneyFromMilli(1995, "CNY"); err == nil {
+		t.Fatal("MoneyFromMilli(1995 CNY) should fail")
+	}
+	if s := NewMoney(1, "cny").String(); s != "0.01 CNY" {
+		t.Fatalf("String() = %s", s)
+	}
+	if sum, err := CNY(1).Add(CNY(99)); err != nil || sum != CNY(100) {
+		t.Fatalf("Add() = %+v, %v", sum, err)
+	}
+	if _, err := CNY(1).Sub(NewMoney(1, "USD")); !errors.Is(err, InvalidParamErr) {
+		t.Fatalf("Sub() currency mismatch err = %v", err)
+	}
+
+	bm := make(BodyMap)
+	bm.SetMoney("total_amount", CNY(1)).
+		SetBodyMap("amount", func(b BodyMap) {
+			b.SetMoneyMinor("total", CNY(1))
+		})
+	if bm.GetString("total_amount") != "0.01" || bm.GetInterface("amount").(BodyMap).GetInterface("total") != int64(1) {
+		t.Fatalf("bm = %s", bm.JsonBody())
+	}
+	if m, err := bm.GetMoney("total_amount", "CNY"); err != nil || m != CNY(1) {
+		t.Fatalf("GetMoney() = %+v, %v", m, err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/alipay"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// AlipaySource 支付宝异步通知
+//
+//	PublicKey 与 PublicKeyCert 二选一，分别对应公钥模式与公钥证书模式，验签及解析同 alipay.VerifyNotify()，参数重复出现时验签失败
+//	notify_type=trade_status_sync 为 EventPayment，含 gmt_refund 时为 EventRefund，
+//	其余（资金授权冻结、协议签约/解约、转账单据状态变更等）为 EventUnknown，按 RawType（notify_type 或 msg_method）区分
+//	Event.Data 类型为 *alipay.NotifyEvent
+//	应答：成功 success，失败 fail
+type AlipaySource struct {
+	PublicKey     string      // 支付宝公钥
+	PublicKeyCert interface{} // 支付宝公钥证书 alipayCertPublicKey_RSA2.crt 的路径或内容[]byte
+}
+
+func (s *AlipaySource) Provider() string {
+	return pay.ProviderAlipay
+}
+
+func (s *AlipaySource) Parse(req *http.Request) (*Event, error) {
+	var (
+		content []byte
+		err     error
+	)
+	switch {
+	case s.PublicKeyCert != nil:
+		switch cert := s.PublicKeyCert.(type) {
+		case string:
+			if content, err = os.ReadFile(cert); err != nil {
+				return nil, err
+			}
+		case []byte:
+			content = cert
+		default:
+			return nil, errors.New("alipay public key cert type assert error")
+		}
+	case s.PublicKey != util.NULL:
+		content = []byte(s.PublicKey)
+	default:
+		return nil, errors.New("alipay public key or cert is empty")
+	}
+	ne, err := alipay.VerifyNotify(req, content)
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{
+		Provider:   pay.ProviderAlipay,
+		Type:       EventUnknown,
+		RawType:    ne.RawType,
+		OutTradeNo: ne.BodyMap.GetString("out_trade_no"),
+		TradeNo:    ne.BodyMap.GetString("trade_no"),
+		Data:       ne,
+	}
+	switch ne.Type {
+	case alipay.NotifyTradeStatus:
+		e.Type = EventPayment
+	case alipay.NotifyRefund:
+		e.Type = EventRefund
+		e.OutRefundNo = ne.Trade.OutBizNo
+	}
+	return e, nil
+}
+
+func (s *AlipaySource) Ack(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err != nil {
+		_, _ = w.Write([]byte("fail"))
+		return
+	}
+	_, _ = w.Write([]byte("success"))
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/apple"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// AppleNotification App Store Server Notifications V2 解码结果
+type AppleNotification struct {
+	Payload     *apple.NotificationV2Payload
+	Transaction *apple.TransactionInfo // signedTransactionInfo 解码结果（如有）
+	Renewal     *apple.RenewalInfo     // signedRenewalInfo 解码结果（如有）
+}
+
+// AppleSource App Store Server Notifications V2
+//
+//	使用 x5c 证书链验签，Event.Data 类型为 *AppleNotification
+//	DID_RENEW 为 EventSubscriptionRenewal，REFUND 为 EventRefund，SUBSCRIBED、ONE_TIME_CHARGE 为 EventPayment，其余为 EventUnknown
+//	应答：成功 200，失败 500，App Store 会重试
+//	文档：https://developer.apple.com/documentation/appstoreservernotifications
+type AppleSource struct {
+	// VerifyOptions 证书链校验配置，为空时使用 Apple Root CA - G3，仅用于本地模拟服务（paytest）等测试场景
+	VerifyOptions *apple.VerifyOptions
+}
+
+func (s *AppleSource) Provider() string {
+	return pay.ProviderApple
+}
+
+func (s *AppleSource) Parse(req *http.Request) (*Event, error) {
+	bs, err := ioutil.ReadAll(io.LimitReader(req.Body, int64(3<<20)))
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll：%w", err)
+	}
+	notifyReq := new(apple.NotificationV2Req)
+	if err = json.Unmarshal(bs, notifyReq); err != nil {
+		return nil, fmt.Errorf("[%w]: apple notification: %v", pay.UnmarshalErr, err)
+	}
+	payload, err := s.VerifyOptions.DecodeSignedPayload(notifyReq.SignedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+	}
+	n := &AppleNotification{Payload: payload}
+	if payload.Data != nil && payload.Data.SignedTransactionInfo != util.NULL {
+		if n.Transaction, err = payload.DecodeTransactionInfo(); err != nil {
+			return nil, fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+		}
+	}
+	if payload.Data != nil && payload.Data.SignedRenewalInfo != util.NULL {
+		if n.Renewal, err = payload.DecodeRenewalInfo(); err != nil {
+			return nil, fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+		}
+	}
+	e := &Event{
+		Provider: pay.ProviderApple,
+		Type:     appleEventType(payload.NotificationType),
+		RawType:  payload.NotificationType,
+		Data:     n,
+	}
+	if n.Transaction != nil {
+		e.TradeNo = n.Transaction.TransactionId
+		e.OutTradeNo = n.Transaction.AppAccountToken
+	}
+	return e, nil
+}
+
+func (s *AppleSource) Ack(w http.ResponseWriter, err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func appleEventType(notificationType string) EventType {
+	switch notificationType {
+	case apple.NotificationTypeV2DidRenew:
+		return EventSubscriptionRenewal
+	case apple.NotificationTypeV2Refund:
+		return EventRefund
+	case apple.NotificationTypeV2Subscribed, "ONE_TIME_CHARGE":
+		return EventPayment
+	default:
+		return EventUnknown
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/payssion"
+)
+
+// PayssionSource Payssion 异步通知
+//
+//	使用 ApiKey、ApiSecret 校验 notify_sig，Event.Data 类型为 *payssion.NotifyData
+//	state 为 refunded、chargeback 时为 EventRefund，其余为 EventPayment
+//	应答：成功 200，失败 500，Payssion 会重试
+type PayssionSource struct {
+	ApiKey    string
+	ApiSecret string
+}
+
+func (s *PayssionSource) Provider() string {
+	return pay.ProviderPayssion
+}
+
+func (s *PayssionSource) Parse(req *http.Request) (*Event, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, fmt.Errorf("req.ParseForm：%w", err)
+	}
+	data := &payssion.NotifyData{
+		Appname:       req.Form.Get("app_name"),
+		PmID:          req.Form.Get("pm_id"),
+		TransactionID: req.Form.Get("transaction_id"),
+		OrderID:       req.Form.Get("order_id"),
+		Amount:        req.Form.Get("amount"),
+		Paid:          req.Form.Get("paid"),
+		Currency:      req.Form.Get("currency"),
+		Description:   req.Form.Get("description"),
+		State:         req.Form.Get("state"),
+		NotifySig:     req.Form.Get("notify_sig"),
+	}
+	if !data.Verify(s.ApiKey, s.ApiSecret) {
+		return nil, pay.VerifySignatureErr
+	}
+	e := &Event{
+		Provider:   pay.ProviderPayssion,
+		Type:       EventPayment,
+		RawType:    data.State,
+		OutTradeNo: data.OrderID,
+		TradeNo:    data.TransactionID,
+		Data:       data,
+	}
+	if data.State == "refunded" || data.State == "chargeback" {
+		e.Type = EventRefund
+	}
+	return e, nil
+}
+
+func (s *PayssionSource) Ack(w http.ResponseWriter, err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
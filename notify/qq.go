@@ -0,0 +1,71 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+	"github.com/rwscode/payutil/qq"
+)
+
+// QQSource QQ支付异步通知
+//
+//	使用 ApiKey 验签，Event.Data 类型为 pay.BodyMap
+//	应答：XML NotifyResponse，成功 return_code=SUCCESS，失败 return_code=FAIL
+type QQSource struct {
+	ApiKey string
+}
+
+func (s *QQSource) Provider() string {
+	return pay.ProviderQQ
+}
+
+func (s *QQSource) Parse(req *http.Request) (*Event, error) {
+	bm, err := qq.ParseNotifyToBodyMap(req)
+	if err != nil {
+		return nil, err
+	}
+	data := make(pay.BodyMap)
+	data.Merge(bm)
+	signType := bm.GetString("sign_type")
+	if signType == util.NULL {
+		signType = qq.SignType_MD5
+	}
+	ok, err := qq.VerifySign(s.ApiKey, signType, bm)
+	if err != nil {
+		return nil, fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+	}
+	if !ok {
+		return nil, pay.VerifySignatureErr
+	}
+	return &Event{
+		Provider:   pay.ProviderQQ,
+		Type:       EventPayment,
+		RawType:    data.GetString("trade_state"),
+		OutTradeNo: data.GetString("out_trade_no"),
+		TradeNo:    data.GetString("transaction_id"),
+		Data:       data,
+	}, nil
+}
+
+func (s *QQSource) Ack(w http.ResponseWriter, err error) {
+	rsp := &qq.NotifyResponse{ReturnCode: pay.SUCCESS, ReturnMsg: pay.OK}
+	if err != nil {
+		rsp.ReturnCode, rsp.ReturnMsg = pay.FAIL, pay.FAIL
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(rsp.ToXmlString()))
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notify 统一的异步通知路由
+//
+//	各渠道 Source 负责验签、解密并识别事件类型，Router 按事件类型分发到注册的回调，并按渠道要求写回应答。
+//
+//	r := notify.NewRouter()
+//	r.On(notify.EventPayment, func(ctx context.Context, e *notify.Event) error { ... })
+//	r.Handle("/notify/alipay", &notify.AlipaySource{PublicKey: aliPayPublicKey})
+//	r.Handle("/notify/wechat", &notify.WechatV3Source{Client: wxClientV3})
+//	http.ListenAndServe(":8080", r)
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+// ErrNoHandler 事件没有匹配的回调，向渠道应答失败，渠道会按其策略重试通知
+var ErrNoHandler = errors.New("notify: no handler for event")
+
+// EventType 统一事件类型
+type EventType string
+
+const (
+	EventAll                 EventType = "*"                    // 注册时使用，匹配所有事件
+	EventPayment             EventType = "payment"              // 支付成功/交易状态变更
+	EventRefund              EventType = "refund"               // 退款
+	EventProfitShare         EventType = "profit_share"         // 分账
+	EventScore               EventType = "score"                // 微信支付分
+	EventBusifavor           EventType = "busifavor"            // 微信商家券
+	EventSubscriptionRenewal EventType = "subscription_renewal" // 订阅续期
+	EventUnknown             EventType = "unknown"              // 无法识别的事件，见 Event.RawType
+)
+
+// Event 验签、解密后的通知事件
+type Event struct {
+	Provider    string    // 渠道标识，如 pay.ProviderAlipay
+	Type        EventType // 统一事件类型
+	RawType     string    // 渠道原始事件类型，如微信 v3 event_type、Apple notificationType
+	OutTradeNo  string    // 商户订单号（如有）
+	TradeNo     string    // 渠道订单号（如有）
+	OutRefundNo string    // 商户退款单号（如有）
+	RefundNo    string    // 渠道退款单号（如有）
+	// Data 渠道通知数据，具体类型见各 Source 的说明
+	Data interface{}
+}
+
+// HandlerFunc 事件回调，返回 error 时向渠道应答失败，渠道会按其策略重试通知
+type HandlerFunc func(ctx context.Context, e *Event) error
+
+// Source 渠道通知源
+type Source interface {
+	// Provider 渠道标识
+	Provider() string
+	// Parse 解析请求、验签并解密，返回事件
+	Parse(req *http.Request) (*Event, error)
+	// Ack 按渠道要求写回应答，err 为空时应答成功
+	Ack(w http.ResponseWriter, err error)
+}
+
+// Router 统一异步通知路由，实现 http.Handler
+type Router struct {
+	mux      *http.ServeMux
+	mu       sync.RWMutex
+	handlers map[EventType][]HandlerFunc
+	onError  func(req *http.Request, provider string, err error)
+}
+
+// NewRouter 初始化通知路由
+func NewRouter() *Router {
+	return &Router{
+		mux:      http.NewServeMux(),
+		handlers: make(map[EventType][]HandlerFunc),
+		onError: func(req *http.Request, provider string, err error) {
+			xlog.Errorf("notify %s %s error: %s", provider, req.URL.Path, redact.String(err.Error()))
+		},
+	}
+}
+
+// On 注册事件回调，同一事件可注册多个，按注册顺序执行，任一返回 error 即停止
+//
+//	没有匹配回调的事件返回 ErrNoHandler 并应答失败，需要确认所有事件时注册 EventAll
+func (r *Router) On(typ EventType, fn HandlerFunc) *Router {
+	r.mu.Lock()
+	r.handlers[typ] = append(r.handlers[typ], fn)
+	r.mu.Unlock()
+	return r
+}
+
+// OnError 设置验签失败、回调失败时的错误处理，默认脱敏后输出到 xlog
+func (r *Router) OnError(fn func(req *http.Request, provider string, err error)) *Router {
+	if fn != nil {
+		r.onError = fn
+	}
+	return r
+}
+
+// Handle 将渠道通知源挂载到 pattern，pattern 规则同 http.ServeMux
+func (r *Router) Handle(pattern string, s Source) *Router {
+	r.mux.Handle(pattern, r.Handler(s))
+	return r
+}
+
+// Handler 返回单个渠道通知源的 http.Handler，可挂载到任意路由框架
+func (r *Router) Handler(s Source) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		e, err := s.Parse(req)
+		if err == nil {
+			err = r.Dispatch(req.Context(), e)
+		}
+		if err != nil {
+			r.onError(req, s.Provider(), err)
+		}
+		s.Ack(w, err)
+	})
+}
+
+// Dispatch 将事件分发到注册的回调
+func (r *Router) Dispatch(ctx context.Context, e *Event) error {
+	r.mu.RLock()
+	fns := append(append([]HandlerFunc{}, r.handlers[e.Type]...), r.handlers[EventAll]...)
+	r.mu.RUnlock()
+	if len(fns) == 0 {
+		return fmt.Errorf("%w: %s %s", ErrNoHandler, e.Provider, e.RawType)
+	}
+	for _, fn := range fns {
+		if err := fn(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/wechat"
+)
+
+const testApiKey = "GFDS8j98rewnmgl45wHTt980jg543abc"
+
+func wechatNotifyXml(bm pay.BodyMap) string {
+	bm.Set("sign", wechat.GetReleaseSign(testApiKey, wechat.SignType_MD5, bm))
+	var b strings.Builder
+	b.WriteString("<xml>")
+	for k := range bm {
+		b.WriteString("<" + k + ">" + bm.GetString(k) + "</" + k + ">")
+	}
+	b.WriteString("</xml>")
+	return b.String()
+}
+
+func TestRouter_WechatV2(t *testing.T) {
+	var got *Event
+	r := NewRouter().
+		On(EventPayment, func(ctx context.Context, e *Event) error {
+			got = e
+			return nil
+		}).
+		OnError(func(req *http.Request, provider string, err error) {})
+	r.Handle("/notify/wechat", &WechatV2Source{ApiKey: testApiKey})
+
+	bm := make(pay.BodyMap)
+	bm.Set("return_code", pay.SUCCESS).
+		Set("result_code", pay.SUCCESS).
+		Set("appid", "wx2421b1c4370ec43b").
+		Set("mch_id", "10000100").
+		Set("nonce_str", "5d2b6c2a8db53831f7eda20af46e531c").
+		Set("out_trade_no", "1409811653").
+		Set("transaction_id", "1004400740201409030005092168").
+		Set("total_fee", "1")
+	body := wechatNotifyXml(bm)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notify/wechat", strings.NewReader(body)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<return_code><![CDATA[SUCCESS]]></return_code>") {
+		t.Fatalf("ack: %d %s", w.Code, w.Body.String())
+	}
+	if got == nil || got.Provider != pay.ProviderWechatV2 || got.OutTradeNo != "1409811653" || got.TradeNo != "1004400740201409030005092168" {
+		t.Fatalf("event: %+v", got)
+	}
+	if data, ok := got.Data.(pay.BodyMap); !ok || data.GetString("sign") == "" {
+		t.Fatalf("data: %+v", got.Data)
+	}
+
+	// 篡改金额，验签失败
+	w = httptest.NewRecorder()
+	tampered := strings.Replace(body, "<total_fee>1</total_fee>", "<total_fee>100</total_fee>", 1)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notify/wechat", strings.NewReader(tampered)))
+	if !strings.Contains(w.Body.String(), "<return_code><![CDATA[FAIL]]></return_code>") {
+		t.Fatalf("tampered ack: %s", w.Body.String())
+	}
+}
+
+func TestRouter_Payssion(t *testing.T) {
+	var (
+		types  []EventType
+		errCnt int
+	)
+	r := NewRouter().
+		On(EventAll, func(ctx context.Context, e *Event) error {
+			types = append(types, e.Type)
+			if e.OutTradeNo == "fail" {
+				return errors.New("handler failed")
+			}
+			return nil
+		}).
+		OnError(func(req *http.Request, provider string, err error) { errCnt++ })
+	h := r.Handler(&PayssionSource{ApiKey: "key", ApiSecret: "secret"})
+
+	post := func(orderId, state, sig string) int {
+		form := url.Values{}
+		form.Set("pm_id", "alipay_cn")
+		form.Set("amount", "1.00")
+		form.Set("currency", "USD")
+		form.Set("order_id", orderId)
+		form.Set("state", state)
+		if sig == "" {
+			sig = md5hex(strings.Join([]string{"key", "alipay_cn", "1.00", "USD", orderId, state, "secret"}, "|"))
+		}
+		form.Set("notify_sig", sig)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := post("o1", "completed", ""); code != http.StatusOK {
+		t.Fatalf("completed: %d", code)
+	}
+	if code := post("o2", "refunded", ""); code != http.StatusOK {
+		t.Fatalf("refunded: %d", code)
+	}
+	if code := post("o3", "completed", "bad"); code != http.StatusInternalServerError {
+		t.Fatalf("bad sig: %d", code)
+	}
+	if code := post("fail", "completed", ""); code != http.StatusInternalServerError {
+		t.Fatalf("handler error: %d", code)
+	}
+	if len(types) != 3 || types[0] != EventPayment || types[1] != EventRefund {
+		t.Fatalf("types: %v", types)
+	}
+	if errCnt != 2 {
+		t.Fatalf("errCnt: %d", errCnt)
+	}
+}
+
+func TestRouter_NoHandler(t *testing.T) {
+	var got error
+	r := NewRouter().
+		On(EventRefund, func(ctx context.Context, e *Event) error { return nil }).
+		OnError(func(req *http.Request, provider string, err error) { got = err })
+	form := url.Values{}
+	form.Set("pm_id", "alipay_cn")
+	form.Set("amount", "1.00")
+	form.Set("currency", "USD")
+	form.Set("order_id", "o1")
+	form.Set("state", "completed")
+	form.Set("notify_sig", md5hex(strings.Join([]string{"key", "alipay_cn", "1.00", "USD", "o1", "completed", "secret"}, "|")))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.Handler(&PayssionSource{ApiKey: "key", ApiSecret: "secret"}).ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || !errors.Is(got, ErrNoHandler) {
+		t.Fatalf("no handler: %d, %v", w.Code, got)
+	}
+}
+
+func TestV3EventType(t *testing.T) {
+	cases := map[string]EventType{
+		"TRANSACTION.SUCCESS":       EventPayment,
+		"REFUND.ABNORMAL":           EventRefund,
+		"PROFITSHARING.FINISHED":    EventProfitShare,
+		"PAYSCORE.USER_CONFIRM":     EventScore,
+		"COUPON.USE":                EventBusifavor,
+		"MCHTRANSFER.BILL.FINISHED": EventUnknown,
+	}
+	for in, want := range cases {
+		if got := v3EventType(in); got != want {
+			t.Errorf("v3EventType(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func md5hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+	"github.com/rwscode/payutil/wechat"
+	wechatv3 "github.com/rwscode/payutil/wechat/v3"
+)
+
+// WechatV3Source 微信支付 v3 异步通知
+//
+//	使用 Client 的微信平台证书验签（需开启 client.AutoVerifySign(ctx) 或 client.SetPlatformCert()），使用 Client.ApiV3Key 解密
+//	Event.Data 类型：
+//	EventPayment：*wechatv3.V3DecryptResult，服务商为 *V3DecryptPartnerResult，合单为 *V3DecryptCombineResult
+//	EventRefund：*wechatv3.V3DecryptRefundResult，服务商为 *V3DecryptPartnerRefundResult
+//	EventProfitShare：*wechatv3.V3DecryptProfitShareResult
+//	EventScore：*wechatv3.V3DecryptScoreResult
+//	EventBusifavor：*wechatv3.V3DecryptBusifavorResult
+//	EventUnknown：解密后的 pay.BodyMap
+//	应答：成功 200 {"code":"SUCCESS"}，失败 500 {"code":"FAIL","message":"失败"}，错误详情不返回给微信，见 Router.OnError()
+type WechatV3Source struct {
+	Client *wechatv3.ClientV3
+}
+
+func (s *WechatV3Source) Provider() string {
+	return pay.ProviderWechat
+}
+
+func (s *WechatV3Source) Parse(req *http.Request) (*Event, error) {
+	if s.Client == nil {
+		return nil, errors.New("wechat v3 client is nil")
+	}
+	notifyReq, err := wechatv3.V3ParseNotify(req)
+	if err != nil {
+		return nil, err
+	}
+	if notifyReq.SignInfo == nil {
+		return nil, errors.New("wechat v3 notify sign info is nil")
+	}
+	pkMap := s.Client.WxPublicKeyMap()
+	if pkMap[notifyReq.SignInfo.HeaderSerial] == nil {
+		return nil, fmt.Errorf("[%w]: unknown Wechatpay-Serial %s", pay.CertNotMatchErr, notifyReq.SignInfo.HeaderSerial)
+	}
+	if err = notifyReq.VerifySignByPKMap(pkMap); err != nil {
+		return nil, fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+	}
+	if notifyReq.Resource == nil {
+		return nil, errors.New("wechat v3 notify resource is nil")
+	}
+	bs, err := wechatv3.V3DecryptNotifyCipherTextToBytes(notifyReq.Resource.Ciphertext, notifyReq.Resource.Nonce, notifyReq.Resource.AssociatedData, string(s.Client.ApiV3Key))
+	if err != nil {
+		return nil, err
+	}
+	plain := make(pay.BodyMap)
+	if err = json.Unmarshal(bs, &plain); err != nil {
+		return nil, fmt.Errorf("[%w]: wechat v3 notify resource: %v", pay.UnmarshalErr, err)
+	}
+	e := &Event{
+		Provider:    pay.ProviderWechat,
+		Type:        v3EventType(notifyReq.EventType),
+		RawType:     notifyReq.EventType,
+		OutTradeNo:  plain.GetString("out_trade_no"),
+		TradeNo:     plain.GetString("transaction_id"),
+		OutRefundNo: plain.GetString("out_refund_no"),
+		RefundNo:    plain.GetString("refund_id"),
+	}
+	_, partner := plain["sp_mchid"]
+	var data interface{}
+	switch e.Type {
+	case EventPayment:
+		switch {
+		case plain.GetString("combine_out_trade_no") != util.NULL:
+			e.OutTradeNo = plain.GetString("combine_out_trade_no")
+			data = new(wechatv3.V3DecryptCombineResult)
+		case partner:
+			data = new(wechatv3.V3DecryptPartnerResult)
+		default:
+			data = new(wechatv3.V3DecryptResult)
+		}
+	case EventRefund:
+		if partner {
+			data = new(wechatv3.V3DecryptPartnerRefundResult)
+		} else {
+			data = new(wechatv3.V3DecryptRefundResult)
+		}
+	case EventProfitShare:
+		data = new(wechatv3.V3DecryptProfitShareResult)
+	case EventScore:
+		e.OutTradeNo = plain.GetString("out_order_no")
+		data = new(wechatv3.V3DecryptScoreResult)
+	case EventBusifavor:
+		data = new(wechatv3.V3DecryptBusifavorResult)
+	default:
+		e.Data = plain
+		return e, nil
+	}
+	if err = json.Unmarshal(bs, data); err != nil {
+		return nil, fmt.Errorf("[%w]: wechat v3 notify resource: %v", pay.UnmarshalErr, err)
+	}
+	e.Data = data
+	return e, nil
+}
+
+func (s *WechatV3Source) Ack(w http.ResponseWriter, err error) {
+	rsp := &wechatv3.V3NotifyRsp{Code: pay.SUCCESS, Message: "成功"}
+	status := http.StatusOK
+	if err != nil {
+		rsp.Code, rsp.Message, status = pay.FAIL, "失败", http.StatusInternalServerError
+	}
+	bs, _ := json.Marshal(rsp)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(bs)
+}
+
+// 微信支付 v3 event_type 前缀，如 TRANSACTION.SUCCESS、REFUND.ABNORMAL
+func v3EventType(eventType string) EventType {
+	prefix := eventType
+	if i := strings.IndexByte(eventType, '.'); i > 0 {
+		prefix = eventType[:i]
+	}
+	switch prefix {
+	case "TRANSACTION":
+		return EventPayment
+	case "REFUND":
+		return EventRefund
+	case "PROFITSHARING":
+		return EventProfitShare
+	case "PAYSCORE":
+		return EventScore
+	case "COUPON":
+		return EventBusifavor
+	default:
+		return EventUnknown
+	}
+}
+
+// WechatV2Source 微信支付 v2 异步通知
+//
+//	支付通知使用 ApiKey 验签，退款通知使用 ApiKey 解密 req_info
+//	Event.Data 类型：EventPayment 为 pay.BodyMap，EventRefund 为 *wechat.RefundNotify
+//	应答：XML NotifyResponse，成功 return_code=SUCCESS，失败 return_code=FAIL
+type WechatV2Source struct {
+	ApiKey string
+}
+
+func (s *WechatV2Source) Provider() string {
+	return pay.ProviderWechatV2
+}
+
+func (s *WechatV2Source) Parse(req *http.Request) (*Event, error) {
+	bm, err := wechat.ParseNotifyToBodyMap(req)
+	if err != nil {
+		return nil, err
+	}
+	if bm.GetString("return_code") != pay.SUCCESS {
+		return nil, fmt.Errorf("wechat notify return_code: %s, return_msg: %s", bm.GetString("return_code"), bm.GetString("return_msg"))
+	}
+	// 退款通知不签名，敏感信息加密在 req_info 中
+	if reqInfo := bm.GetString("req_info"); reqInfo != util.NULL {
+		refund, err := wechat.DecryptRefundNotifyReqInfo(reqInfo, s.ApiKey)
+		if err != nil {
+			return nil, err
+		}
+		return &Event{
+			Provider:    pay.ProviderWechatV2,
+			Type:        EventRefund,
+			RawType:     refund.RefundStatus,
+			OutTradeNo:  refund.OutTradeNo,
+			TradeNo:     refund.TransactionId,
+			OutRefundNo: refund.OutRefundNo,
+			RefundNo:    refund.RefundId,
+			Data:        refund,
+		}, nil
+	}
+	data := make(pay.BodyMap)
+	data.Merge(bm)
+	signType := bm.GetString("sign_type")
+	if signType == util.NULL {
+		signType = wechat.SignType_MD5
+	}
+	ok, err := wechat.VerifySign(s.ApiKey, signType, bm)
+	if err != nil {
+		return nil, fmt.Errorf("[%w]: %v", pay.VerifySignatureErr, err)
+	}
+	if !ok {
+		return nil, pay.VerifySignatureErr
+	}
+	return &Event{
+		Provider:   pay.ProviderWechatV2,
+		Type:       EventPayment,
+		RawType:    data.GetString("result_code"),
+		OutTradeNo: data.GetString("out_trade_no"),
+		TradeNo:    data.GetString("transaction_id"),
+		Data:       data,
+	}, nil
+}
+
+func (s *WechatV2Source) Ack(w http.ResponseWriter, err error) {
+	rsp := &wechat.NotifyResponse{ReturnCode: pay.SUCCESS, ReturnMsg: pay.OK}
+	if err != nil {
+		rsp.ReturnCode, rsp.ReturnMsg = pay.FAIL, pay.FAIL
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(rsp.ToXmlString()))
+}
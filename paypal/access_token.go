@@ -12,47 +12,42 @@
 package paypal
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/redact"
 	"github.com/rwscode/payutil/pkg/xhttp"
-	"github.com/rwscode/payutil/pkg/xlog"
 )
 
 // 获取AccessToken（Get an access token）
+// AccessToken 过期前需再次调用刷新，ctx 用于控制本次请求的超时、取消
 // 文档：https://developer.paypal.com/docs/api/reference/get-an-access-token
-func (c *Client) GetAccessToken() (token *AccessToken, err error) {
-	var (
-		baseUrl = baseUrlProd
-		url     string
-	)
-	if !c.IsProd {
-		baseUrl = baseUrlSandbox
-	}
-	url = baseUrl + getAccessToken
+func (c *Client) GetAccessToken(ctx context.Context) (token *AccessToken, err error) {
+	url := c.apiURL(getAccessToken)
 	// Authorization
 	authHeader := AuthorizationPrefixBasic + base64.StdEncoding.EncodeToString([]byte(c.Clientid+":"+c.Secret))
 	// Request
-	httpClient := xhttp.NewClient()
+	httpClient := xhttp.NewClient().SetDoer(c.hc)
 	httpClient.Header.Add(HeaderAuthorization, authHeader)
 	httpClient.Header.Add("Accept", "*/*")
 	// Body
 	bm := make(pay.BodyMap)
 	bm.Set("grant_type", "client_credentials")
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("PayPal_RequestBody: %s", bm.JsonBody())
-		xlog.Debugf("PayPal_Authorization: %s", authHeader)
+		c.debugf("PayPal_RequestBody: %s", bm.JsonBody())
+		c.debugf("PayPal_Authorization: %s", redact.Authorization(authHeader))
 	}
-	res, bs, err := httpClient.Type(xhttp.TypeForm).Post(url).SendBodyMap(bm).EndBytes(c.ctx)
+	res, bs, err := c.do(ctx, http.MethodPost, url, c.retryPolicy(ctx, true), httpClient.Type(xhttp.TypeForm).Post(url).SendBodyMap(bm).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("PayPal_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("PayPal_Headers: %#v", res.Header)
+		c.debugf("PayPal_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("PayPal_Headers: %#v", redact.Header(res.Header))
 	}
 	if res.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
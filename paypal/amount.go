@@ -0,0 +1,45 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paypal
+
+import (
+	"fmt"
+	"strings"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// PayPal 不支持小数的币种，金额须为整数主单位
+// 文档：https://developer.paypal.com/reference/currency-codes/
+var zeroDecimalCurrency = map[string]bool{"HUF": true, "JPY": true, "TWD": true}
+
+// NewAmount 将 pay.Money 转换为 PayPal 金额，如 USD 1050 -> {"currency_code":"USD","value":"10.50"}
+//
+//	HUF、TWD 在 ISO-4217 中有 2 位小数，但 PayPal 不支持小数，金额不是整数主单位时返回错误
+func NewAmount(m pay.Money) (*Amount, error) {
+	value := m.Decimal()
+	if zeroDecimalCurrency[m.Currency] {
+		i := strings.IndexByte(value, '.')
+		if i >= 0 && strings.Trim(value[i+1:], "0") != "" {
+			return nil, fmt.Errorf("[%w]: paypal %s amount must be whole units: %s", pay.InvalidParamErr, m.Currency, value)
+		}
+		if i >= 0 {
+			value = value[:i]
+		}
+	}
+	return &Amount{CurrencyCode: m.Currency, Value: value}, nil
+}
+
+// Money 将 PayPal 金额转换为 pay.Money
+func (a *Amount) Money() (pay.Money, error) {
+	return pay.ParseMoney(a.Value, a.CurrencyCode)
+}
@@ -15,8 +15,13 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/idempotency"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/retry"
 	"github.com/rwscode/payutil/pkg/util"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xlog"
@@ -31,12 +36,55 @@ type Client struct {
 	ExpiresIn   int
 	bodySize    int // http response body size(MB), default is 10MB
 	IsProd      bool
-	ctx         context.Context
 	DebugSwitch pay.DebugSwitch
+	hc          xhttp.Doer         // 自定义 Doer，为空时使用 xhttp 共享连接池
+	retry       *retry.Policy      // 重试策略，为空时不重试
+	limiter     *xhttp.Limiter     // 按接口族限流、熔断，为空时不限制
+	baseURL     string             // 自定义接口域名，为空时按 IsProd 选择
+	mw          observe.Chain      // 中间件链
+	logger      xlog.Logger        // 结构化日志，为空时使用全局 xlog
+	idempotency *idempotency.Guard // 退款的幂等保护，为空时不启用
 }
 
-// NewClient 初始化PayPal支付客户端
-func NewClient(clientid, secret string, isProd bool) (client *Client, err error) {
+// Option 初始化客户端的可选配置，在获取 AccessToken 之前生效
+type Option func(c *Client)
+
+// WithMiddleware 添加中间件，见 client.Use()
+func WithMiddleware(mw ...observe.Middleware) Option {
+	return func(c *Client) {
+		c.Use(mw...)
+	}
+}
+
+// WithLogger 设置结构化日志，见 client.SetLogger()
+func WithLogger(l xlog.Logger) Option {
+	return func(c *Client) {
+		c.SetLogger(l)
+	}
+}
+
+// WithBaseURL 设置接口域名，见 client.SetBaseURL()
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.SetBaseURL(url)
+	}
+}
+
+// WithHTTPClient 设置自定义 *http.Client，见 client.SetHTTPClient()
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.SetHTTPClient(hc)
+	}
+}
+
+// NewClient 初始化PayPal支付客户端，初始化时会请求获取 AccessToken
+// options：可选配置，如 paypal.WithBaseURL()
+func NewClient(clientid, secret string, isProd bool, options ...Option) (client *Client, err error) {
+	return NewClientWithContext(context.Background(), clientid, secret, isProd, options...)
+}
+
+// NewClientWithContext 同 NewClient()，ctx 用于控制初始化时获取 AccessToken 请求的超时、取消
+func NewClientWithContext(ctx context.Context, clientid, secret string, isProd bool, options ...Option) (client *Client, err error) {
 	if clientid == util.NULL || secret == util.NULL {
 		return nil, pay.MissPayPalInitParamErr
 	}
@@ -44,16 +92,131 @@ func NewClient(clientid, secret string, isProd bool) (client *Client, err error)
 		Clientid:    clientid,
 		Secret:      secret,
 		IsProd:      isProd,
-		ctx:         context.Background(),
 		DebugSwitch: pay.DebugOff,
 	}
-	_, err = client.GetAccessToken()
+	for _, o := range options {
+		o(client)
+	}
+	_, err = client.GetAccessToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return client, nil
 }
 
+// SetBaseURL 设置接口域名，如 https://api-m.paypal.com，末尾不带 /，设置后忽略 IsProd
+// 用于出口代理、本地模拟服务（paytest）等，传空字符串恢复默认
+func (c *Client) SetBaseURL(url string) {
+	c.baseURL = strings.TrimSuffix(url, "/")
+}
+
+func (c *Client) apiURL(path string) string {
+	switch {
+	case c.baseURL != util.NULL:
+		return c.baseURL + path
+	case c.IsProd:
+		return baseUrlProd + path
+	default:
+		return baseUrlSandbox + path
+	}
+}
+
+// SetHTTPClient 设置自定义 *http.Client，默认使用 xhttp 共享连接池
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	if hc == nil {
+		c.hc = nil
+		return
+	}
+	c.hc = hc
+}
+
+// SetDoer 设置自定义 xhttp.Doer，用于链路追踪、代理、mock 等，传 nil 恢复默认
+func (c *Client) SetDoer(d xhttp.Doer) {
+	c.hc = d
+}
+
+// SetTLSOptions 设置服务端证书校验配置，如自定义根证书、公钥固定，会替换 SetHTTPClient() 设置的客户端
+func (c *Client) SetTLSOptions(o *xhttp.TLSOptions) {
+	c.hc = xhttp.NewHttpClient(&xhttp.TransportConfig{TLSConfig: xhttp.NewTLSConfig(o)})
+}
+
+// SetRetryPolicy 设置重试策略，默认不重试
+// GET 请求（查询订单、退款等）及获取 AccessToken 按策略重试网络错误、5xx
+// 其余请求（创建订单、退款等）需通过 retry.WithIdempotent(ctx) 显式开启，或通过 paypal.WithRequestId(ctx) 设置 PayPal-Request-Id
+func (c *Client) SetRetryPolicy(p *retry.Policy) {
+	c.retry = p
+}
+
+// SetLimiter 设置按接口族的限流、熔断，每次 http 请求（含重试）经过 l，接口标识同 observe.Call.Method
+//
+//	limiter := xhttp.NewLimiter(xhttp.BreakerConfig{Failures: 5},
+//		xhttp.Family{Name: "payout", Prefixes: []string{"POST /v1/payments/payouts"}, Rate: 5},
+//		xhttp.Family{Name: "transaction", Rate: 100},
+//	)
+//	client.SetLimiter(limiter)
+func (c *Client) SetLimiter(l *xhttp.Limiter) {
+	c.limiter = l
+}
+
+func (c *Client) retryPolicy(ctx context.Context, idempotent bool) *retry.Policy {
+	if idempotent || retry.IsIdempotent(ctx) {
+		return c.retry.Idempotent()
+	}
+	return nil
+}
+
+// Use 添加中间件，用于指标统计、链路追踪等，见 observe 包，请在初始化时调用
+// 需统计获取 AccessToken 的请求时，请使用 paypal.WithMiddleware()
+func (c *Client) Use(mw ...observe.Middleware) {
+	c.mw = append(c.mw, mw...)
+}
+
+// SetLogger 设置当前客户端的结构化日志，日志携带 client_id 字段，并记录每次接口调用（见 observe.Logging）
+// 需记录获取 AccessToken 的请求时，请使用 paypal.WithLogger()
+//
+//	调试日志（DebugSwitch 开启时）也输出到该 logger，可使用 xlog.NewSlogLogger() 接入 slog、zap、logrus
+func (c *Client) SetLogger(l xlog.Logger) {
+	c.logger = xlog.With(l, "client_id", c.Clientid)
+}
+
+func (c *Client) debugf(format string, args ...interface{}) {
+	xlog.Logf(xlog.With(c.logger, "provider", pay.ProviderPayPal), xlog.DebugLevel, format, args...)
+}
+
+// do 经过中间件链，按 policy 发送请求
+func (c *Client) do(ctx context.Context, method, url string, policy *retry.Policy, send func(ctx context.Context) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	call := &observe.Call{Provider: pay.ProviderPayPal, Method: observe.Endpoint(method, url)}
+	return c.mw.WithLogging(c.logger).DoHTTP(ctx, call, func(ctx context.Context) (*http.Response, []byte, error) {
+		res, bs, err := policy.DoHTTP(ctx, c.limiter.Wrap(call.Method, send), nil)
+		if res != nil {
+			call.RequestID = res.Header.Get(HeaderDebugID)
+		}
+		return res, bs, err
+	}, resultCode)
+}
+
+// resultCode PayPal 错误码，HTTP 状态码非 2xx 时返回 details[0].issue，无 details 时返回 name 或 error
+func resultCode(status int, bs []byte) string {
+	if status >= http.StatusOK && status < http.StatusMultipleChoices {
+		return util.NULL
+	}
+	var rsp struct {
+		Name    string `json:"name"`
+		Error   string `json:"error"`
+		Details []struct {
+			Issue string `json:"issue"`
+		} `json:"details"`
+	}
+	_ = json.Unmarshal(bs, &rsp)
+	switch {
+	case len(rsp.Details) > 0 && rsp.Details[0].Issue != util.NULL:
+		return rsp.Details[0].Issue
+	case rsp.Name != util.NULL:
+		return rsp.Name
+	}
+	return rsp.Error
+}
+
 // SetBodySize 设置http response body size(MB)
 func (c *Client) SetBodySize(sizeMB int) {
 	if sizeMB > 0 {
@@ -62,83 +225,79 @@ func (c *Client) SetBodySize(sizeMB int) {
 }
 
 func (c *Client) doPayPalGet(ctx context.Context, uri string) (res *http.Response, bs []byte, err error) {
-	var url = baseUrlProd + uri
-	if !c.IsProd {
-		url = baseUrlSandbox + uri
-	}
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(uri)
+	httpClient := xhttp.NewClient().SetDoer(c.hc)
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	authHeader := AuthorizationPrefixBearer + c.AccessToken
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("PayPal_Url: %s", url)
-		xlog.Debugf("PayPal_Authorization: %s", authHeader)
+		c.debugf("PayPal_Url: %s", redact.String(url))
+		c.debugf("PayPal_Authorization: %s", redact.Authorization(authHeader))
 	}
 	httpClient.Header.Add(HeaderAuthorization, authHeader)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Get(url).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodGet, url, c.retryPolicy(ctx, true), httpClient.Type(xhttp.TypeJSON).Get(url).EndBytes)
 	if err != nil {
 		return nil, nil, err
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("PayPal_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("PayPal_Headers: %#v", res.Header)
+		c.debugf("PayPal_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("PayPal_Headers: %#v", redact.Header(res.Header))
 	}
 	return res, bs, nil
 }
 
 func (c *Client) doPayPalPost(ctx context.Context, bm pay.BodyMap, path string) (res *http.Response, bs []byte, err error) {
-	var url = baseUrlProd + path
-	if !c.IsProd {
-		url = baseUrlSandbox + path
-	}
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.hc)
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	authHeader := AuthorizationPrefixBearer + c.AccessToken
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("PayPal_RequestBody: %s", bm.JsonBody())
-		xlog.Debugf("PayPal_Authorization: %s", authHeader)
+		c.debugf("PayPal_RequestBody: %s", redact.String(bm.JsonBody()))
+		c.debugf("PayPal_Authorization: %s", redact.Authorization(authHeader))
 	}
 	httpClient.Header.Add(HeaderAuthorization, authHeader)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Post(url).SendBodyMap(bm).EndBytes(ctx)
+	// PayPal 按 PayPal-Request-Id 去重，设置后可安全重试
+	requestId := RequestId(ctx)
+	if requestId != pay.NULL {
+		httpClient.Header.Add(HeaderRequestId, requestId)
+	}
+	res, bs, err = c.do(ctx, http.MethodPost, url, c.retryPolicy(ctx, requestId != pay.NULL), httpClient.Type(xhttp.TypeJSON).Post(url).SendBodyMap(bm).EndBytes)
 	if err != nil {
 		return nil, nil, err
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("PayPal_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("PayPal_Headers: %#v", res.Header)
+		c.debugf("PayPal_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("PayPal_Headers: %#v", redact.Header(res.Header))
 	}
 	return res, bs, nil
 }
 
 func (c *Client) doPayPalPatch(ctx context.Context, patchs []*Patch, path string) (res *http.Response, bs []byte, err error) {
-	var url = baseUrlProd + path
-	if !c.IsProd {
-		url = baseUrlSandbox + path
-	}
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.hc)
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	authHeader := AuthorizationPrefixBearer + c.AccessToken
 	if c.DebugSwitch == pay.DebugOn {
 		jb, _ := json.Marshal(patchs)
-		xlog.Debugf("PayPal_RequestBody: %s", string(jb))
-		xlog.Debugf("PayPal_Authorization: %s", authHeader)
+		c.debugf("PayPal_RequestBody: %s", redact.String(string(jb)))
+		c.debugf("PayPal_Authorization: %s", redact.Authorization(authHeader))
 	}
 	httpClient.Header.Add(HeaderAuthorization, authHeader)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Patch(url).SendStruct(patchs).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodPatch, url, c.retryPolicy(ctx, false), httpClient.Type(xhttp.TypeJSON).Patch(url).SendStruct(patchs).EndBytes)
 	if err != nil {
 		return nil, nil, err
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("PayPal_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("PayPal_Headers: %#v", res.Header)
+		c.debugf("PayPal_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("PayPal_Headers: %#v", redact.Header(res.Header))
 	}
 	return res, bs, nil
 }
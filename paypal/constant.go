@@ -14,7 +14,9 @@ package paypal
 const (
 	Success = 0
 
-	HeaderAuthorization       = "Authorization" // 请求头Auth
+	HeaderAuthorization       = "Authorization"     // 请求头Auth
+	HeaderDebugID             = "Paypal-Debug-Id"   // 响应头，PayPal 请求 ID
+	HeaderRequestId           = "PayPal-Request-Id" // 请求头，幂等键，PayPal 对相同值的请求返回首次结果
 	AuthorizationPrefixBasic  = "Basic "
 	AuthorizationPrefixBearer = "Bearer "
 
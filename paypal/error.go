@@ -0,0 +1,86 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paypal
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// NewAPIError 将接口返回的 Code（HTTP 状态码）及 Error（应答内容）转换为 *pay.APIError
+//
+//	Code 为 ErrorResponse.Name，SubCode 为 Details[0].Issue，RequestId 为 DebugId
+//	文档：https://developer.paypal.com/api/rest/responses/#link-errors
+func NewAPIError(statusCode int, body string) *pay.APIError {
+	rsp := new(ErrorResponse)
+	msg := body
+	if json.Unmarshal([]byte(body), rsp) == nil && rsp.Message != "" {
+		msg = rsp.Message
+	}
+	var issue string
+	if len(rsp.Details) > 0 {
+		issue = rsp.Details[0].Issue
+		if rsp.Details[0].Description != "" {
+			msg = rsp.Details[0].Description
+		}
+	}
+	e := pay.NewAPIError(pay.ProviderPayPal, statusCode, rsp.Name, issue, msg, errCategory(statusCode, rsp.Name, issue))
+	e.RequestId = rsp.DebugId
+	return e
+}
+
+// 文档：https://developer.paypal.com/api/rest/reference/orders/v2/errors/
+var issueCategory = map[string]pay.ErrorCategory{
+	"ORDER_ALREADY_CAPTURED":   pay.CategoryOrderPaid,
+	"ORDER_ALREADY_COMPLETED":  pay.CategoryOrderPaid,
+	"ORDER_EXPIRED":            pay.CategoryOrderClosed,
+	"ORDER_IS_VOIDED":          pay.CategoryOrderClosed,
+	"CAPTURE_FULLY_REFUNDED":   pay.CategoryOrderClosed,
+	"INSUFFICIENT_FUNDS":       pay.CategoryInsufficientFunds,
+	"PERMISSION_DENIED":        pay.CategoryAuthFailed,
+	"PAYEE_ACCOUNT_RESTRICTED": pay.CategoryAuthFailed,
+}
+
+var nameCategory = map[string]pay.ErrorCategory{
+	"AUTHENTICATION_FAILURE": pay.CategoryAuthFailed,
+	"NOT_AUTHORIZED":         pay.CategoryAuthFailed,
+	"RESOURCE_NOT_FOUND":     pay.CategoryOrderNotExist,
+	"RATE_LIMIT_REACHED":     pay.CategoryThrottled,
+	"INVALID_REQUEST":        pay.CategoryInvalidParam,
+	"UNPROCESSABLE_ENTITY":   pay.CategoryInvalidParam,
+	"INTERNAL_SERVER_ERROR":  pay.CategorySystemError,
+	"SERVICE_UNAVAILABLE":    pay.CategorySystemError,
+}
+
+func errCategory(statusCode int, name, issue string) pay.ErrorCategory {
+	if c, ok := issueCategory[issue]; ok {
+		return c
+	}
+	if c, ok := nameCategory[name]; ok {
+		return c
+	}
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return pay.CategoryAuthFailed
+	case statusCode == http.StatusTooManyRequests:
+		return pay.CategoryThrottled
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity:
+		return pay.CategoryInvalidParam
+	case statusCode == http.StatusNotFound:
+		return pay.CategoryOrderNotExist
+	case statusCode >= http.StatusInternalServerError:
+		return pay.CategorySystemError
+	}
+	return pay.CategoryUnknown
+}
@@ -0,0 +1,273 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paypal
+
+import (
+	"context"
+	"fmt"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+type gateway struct {
+	c *Client
+}
+
+// NewGateway 将PayPal Client 适配为 pay.Gateway
+//
+//	Charge：创建 intent=CAPTURE 的订单，ChargeResult.PayUrl 为 approve 链接，TradeNo 为 PayPal order id
+//	Capture：实现 pay.Capturer，买家批准后确认扣款，未调用时订单不会入账
+//	Query/Refund：TradeNo 必须为 PayPal order id，Refund 退款该订单的第一笔 capture
+//	QueryRefund：RefundNo 必须为 PayPal refund id
+//	Close：PayPal 未提供关闭订单接口，返回 pay.UnsupportedErr
+func NewGateway(client *Client) pay.Gateway {
+	return &gateway{c: client}
+}
+
+var _ pay.Capturer = (*gateway)(nil)
+
+func (g *gateway) Provider() string {
+	return pay.ProviderPayPal
+}
+
+func (g *gateway) Charge(ctx context.Context, o *pay.Order) (*pay.ChargeResult, error) {
+	if o.Scene != pay.ScenePage && o.Scene != pay.SceneWap {
+		return nil, fmt.Errorf("[%w]: paypal scene %q", pay.UnsupportedErr, o.Scene)
+	}
+	amount, err := NewAmount(pay.NewMoney(o.Amount, currencyOrUSD(o.Currency)))
+	if err != nil {
+		return nil, err
+	}
+	description := o.Body
+	if description == util.NULL {
+		description = o.Subject
+	}
+	unit := &PurchaseUnit{
+		ReferenceId: o.OutTradeNo,
+		CustomId:    o.OutTradeNo,
+		Description: description,
+		Amount:      amount,
+	}
+	bm := make(pay.BodyMap)
+	bm.Set("intent", "CAPTURE").
+		Set("purchase_units", []*PurchaseUnit{unit})
+	if o.ReturnUrl != util.NULL {
+		bm.SetBodyMap("application_context", func(b pay.BodyMap) {
+			b.Set("return_url", o.ReturnUrl)
+		})
+	}
+	bm.Merge(o.Extra)
+	ppRsp, err := g.c.CreateOrder(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	if ppRsp.Code != Success {
+		return nil, gatewayErr("CreateOrder", ppRsp.Code, ppRsp.Error)
+	}
+	rs := &pay.ChargeResult{
+		OutTradeNo: o.OutTradeNo,
+		TradeNo:    ppRsp.Response.Id,
+		Raw:        ppRsp,
+	}
+	for _, link := range ppRsp.Response.Links {
+		if link.Rel == "approve" || link.Rel == "payer-action" {
+			rs.PayUrl = link.Href
+			break
+		}
+	}
+	return rs, nil
+}
+
+func (g *gateway) Query(ctx context.Context, q *pay.OrderQuery) (*pay.OrderResult, error) {
+	if q.TradeNo == util.NULL {
+		return nil, fmt.Errorf("[%w], %v", pay.MissParamErr, "order_id")
+	}
+	ppRsp, err := g.c.OrderDetail(ctx, q.TradeNo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ppRsp.Code != Success {
+		return nil, gatewayErr("OrderDetail", ppRsp.Code, ppRsp.Error)
+	}
+	return orderResult(ppRsp.Response, q.OutTradeNo, ppRsp)
+}
+
+func (g *gateway) Capture(ctx context.Context, q *pay.OrderQuery) (*pay.OrderResult, error) {
+	if q.TradeNo == util.NULL {
+		return nil, fmt.Errorf("[%w], %v", pay.MissParamErr, "order_id")
+	}
+	ppRsp, err := g.c.OrderCapture(ctx, q.TradeNo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ppRsp.Code != Success {
+		return nil, gatewayErr("OrderCapture", ppRsp.Code, ppRsp.Error)
+	}
+	return orderResult(ppRsp.Response, q.OutTradeNo, ppRsp)
+}
+
+func orderResult(r *OrderDetail, outTradeNo string, raw interface{}) (*pay.OrderResult, error) {
+	rs := &pay.OrderResult{
+		OutTradeNo: outTradeNo,
+		TradeNo:    r.Id,
+		Status:     orderStatus(r.Status),
+		RawStatus:  r.Status,
+		Raw:        raw,
+	}
+	if len(r.PurchaseUnits) > 0 && r.PurchaseUnits[0] != nil {
+		unit := r.PurchaseUnits[0]
+		if unit.CustomId != util.NULL {
+			rs.OutTradeNo = unit.CustomId
+		}
+		if unit.Amount != nil {
+			m, err := unit.Amount.Money()
+			if err != nil {
+				return nil, err
+			}
+			rs.Amount, rs.Currency = m.Amount, m.Currency
+		}
+	}
+	return rs, nil
+}
+
+func (g *gateway) Close(ctx context.Context, q *pay.OrderQuery) error {
+	return fmt.Errorf("[%w]: paypal close order", pay.UnsupportedErr)
+}
+
+func (g *gateway) Refund(ctx context.Context, r *pay.Refund) (*pay.RefundResult, error) {
+	if r.TradeNo == util.NULL {
+		return nil, fmt.Errorf("[%w], %v", pay.MissParamErr, "order_id")
+	}
+	detail, err := g.c.OrderDetail(ctx, r.TradeNo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if detail.Code != Success {
+		return nil, gatewayErr("OrderDetail", detail.Code, detail.Error)
+	}
+	var captureId string
+	for _, unit := range detail.Response.PurchaseUnits {
+		if unit != nil && unit.Payments != nil && len(unit.Payments.Captures) > 0 {
+			captureId = unit.Payments.Captures[0].Id
+			break
+		}
+	}
+	if captureId == util.NULL {
+		return nil, fmt.Errorf("paypal order %s has no capture to refund", r.TradeNo)
+	}
+	amount, err := NewAmount(pay.NewMoney(r.Amount, currencyOrUSD(r.Currency)))
+	if err != nil {
+		return nil, err
+	}
+	bm := make(pay.BodyMap)
+	bm.Set("amount", amount)
+	if r.OutRefundNo != util.NULL {
+		bm.Set("invoice_id", r.OutRefundNo)
+	}
+	if r.Reason != util.NULL {
+		bm.Set("note_to_payer", r.Reason)
+	}
+	bm.Merge(r.Extra)
+	ppRsp, err := g.c.PaymentCaptureRefund(ctx, captureId, bm)
+	if err != nil {
+		return nil, err
+	}
+	if ppRsp.Code != Success {
+		return nil, gatewayErr("PaymentCaptureRefund", ppRsp.Code, ppRsp.Error)
+	}
+	rs, err := refundResult(ppRsp.Response, ppRsp)
+	if err != nil {
+		return nil, err
+	}
+	rs.OutTradeNo, rs.TradeNo, rs.OutRefundNo = r.OutTradeNo, r.TradeNo, r.OutRefundNo
+	return rs, nil
+}
+
+func (g *gateway) QueryRefund(ctx context.Context, q *pay.RefundQuery) (*pay.RefundResult, error) {
+	if q.RefundNo == util.NULL {
+		return nil, fmt.Errorf("[%w], %v", pay.MissParamErr, "refund_id")
+	}
+	ppRsp, err := g.c.PaymentRefundDetail(ctx, q.RefundNo)
+	if err != nil {
+		return nil, err
+	}
+	if ppRsp.Code != Success {
+		return nil, gatewayErr("PaymentRefundDetail", ppRsp.Code, ppRsp.Error)
+	}
+	rs, err := refundResult(ppRsp.Response, ppRsp)
+	if err != nil {
+		return nil, err
+	}
+	rs.OutTradeNo, rs.TradeNo = q.OutTradeNo, q.TradeNo
+	if rs.OutRefundNo == util.NULL {
+		rs.OutRefundNo = q.OutRefundNo
+	}
+	return rs, nil
+}
+
+func refundResult(r *PaymentCaptureRefund, raw interface{}) (rs *pay.RefundResult, err error) {
+	rs = &pay.RefundResult{
+		OutRefundNo: r.InvoiceId,
+		RefundNo:    r.Id,
+		Status:      refundStatus(r.Status),
+		RawStatus:   r.Status,
+		Raw:         raw,
+	}
+	if r.Amount != nil {
+		m, err := r.Amount.Money()
+		if err != nil {
+			return nil, err
+		}
+		rs.Amount, rs.Currency = m.Amount, m.Currency
+	}
+	return rs, nil
+}
+
+func orderStatus(status string) pay.TradeStatus {
+	switch status {
+	case "CREATED", "SAVED", "APPROVED", "PAYER_ACTION_REQUIRED":
+		return pay.TradeStatusPending
+	case "COMPLETED":
+		return pay.TradeStatusPaid
+	case "VOIDED":
+		return pay.TradeStatusClosed
+	default:
+		return pay.TradeStatusUnknown
+	}
+}
+
+func refundStatus(status string) pay.RefundStatus {
+	switch status {
+	case "PENDING":
+		return pay.RefundStatusProcessing
+	case "COMPLETED":
+		return pay.RefundStatusSuccess
+	case "CANCELLED":
+		return pay.RefundStatusClosed
+	case "FAILED":
+		return pay.RefundStatusFailed
+	default:
+		return pay.RefundStatusUnknown
+	}
+}
+
+func currencyOrUSD(currency string) string {
+	if currency == util.NULL {
+		return "USD"
+	}
+	return currency
+}
+
+func gatewayErr(api string, code int, body string) error {
+	return fmt.Errorf("paypal %s failed: %w", api, NewAPIError(code, body))
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paypal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/idempotency"
+)
+
+// 渠道已返回 4xx，请求未生效
+var errRejected = errors.New("paypal: request rejected")
+
+type requestIdKey struct{}
+
+// WithRequestId 设置本次 POST 请求的 PayPal-Request-Id，PayPal 对相同 id 的请求只执行一次并返回首次结果，
+// 设置后 SetRetryPolicy() 的策略对该请求生效
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestId 返回 ctx 中通过 WithRequestId 设置的 PayPal-Request-Id
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// SetIdempotency 开启退款（PaymentCaptureRefund）的幂等保护，g 为 nil 时关闭
+//
+//	按 client_id + PayPal-Request-Id 记录结果，已成功的请求再次调用直接返回保存的结果
+//	请求超时等结果未知时，再次调用以相同的 PayPal-Request-Id 重新发起，由 PayPal 返回首次结果
+//	ctx 未设置 PayPal-Request-Id 且 bm 中没有 invoice_id 时，无法识别同一笔退款，不做幂等保护
+func (c *Client) SetIdempotency(g *idempotency.Guard) {
+	c.idempotency = g
+}
+
+// refundRequestId 退款的幂等键，优先使用 ctx 中的 PayPal-Request-Id，否则由 capture_id + invoice_id 生成
+func refundRequestId(ctx context.Context, captureId string, bm pay.BodyMap) string {
+	if id := RequestId(ctx); id != pay.NULL {
+		return id
+	}
+	invoiceId := bm.GetString("invoice_id")
+	if invoiceId == pay.NULL {
+		return pay.NULL
+	}
+	sum := sha256.Sum256([]byte(captureId + ":" + invoiceId))
+	return "refund-" + hex.EncodeToString(sum[:16])
+}
+
+func (c *Client) idempotentRefund(ctx context.Context, captureId string, bm pay.BodyMap) (ppRsp *PaymentCaptureRefundRsp, err error) {
+	var (
+		stored  = new(PaymentCaptureRefund)
+		created bool
+		rspErr  error
+	)
+	status, err := c.idempotency.Do(ctx, idempotency.Key(pay.ProviderPayPal, c.Clientid, "refund", RequestId(ctx)), stored, idempotency.Operation{
+		Create: func(ctx context.Context) (interface{}, error) {
+			created = true
+			if ppRsp, rspErr = c.paymentCaptureRefund(ctx, captureId, bm); rspErr != nil {
+				return nil, rspErr
+			}
+			switch {
+			case ppRsp.Code == Success:
+				return ppRsp.Response, nil
+			case ppRsp.Code < http.StatusInternalServerError:
+				return nil, idempotency.Rejected(errRejected)
+			}
+			return nil, fmt.Errorf("http status code: %d", ppRsp.Code)
+		},
+	})
+	switch {
+	case status == idempotency.Replayed:
+		return &PaymentCaptureRefundRsp{Code: Success, Response: stored}, nil
+	case created:
+		return ppRsp, rspErr
+	}
+	return ppRsp, err
+}
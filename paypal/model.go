@@ -303,8 +303,8 @@ type PurchaseUnit struct {
 }
 
 type Amount struct {
-	CurrencyCode string `json:"currency_code"`
-	Value        string `json:"value"`
+	CurrencyCode string `json:"currency_code" validate:"required,len=3"`
+	Value        string `json:"value" validate:"required,max=32"`
 }
 
 type Payee struct {
@@ -17,6 +17,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+
+	pay "github.com/rwscode/payutil"
 )
 
 // 支付授权详情（Show details for authorized payment）
@@ -148,10 +150,20 @@ func (c *Client) PaymentCaptureDetail(ctx context.Context, captureId string) (pp
 // 支付捕获退款（Refund captured payment）
 // Code = 0 is success
 // 文档：https://developer.paypal.com/docs/api/payments/v2/#captures_refund
+// 设置 client.SetIdempotency() 后按 PayPal-Request-Id（paypal.WithRequestId()，未设置时由 capture_id + invoice_id 生成）幂等，见 idempotency 包
 func (c *Client) PaymentCaptureRefund(ctx context.Context, captureId string, bm pay.BodyMap) (ppRsp *PaymentCaptureRefundRsp, err error) {
 	if captureId == pay.NULL {
 		return nil, errors.New("capture_id is empty")
 	}
+	if c.idempotency != nil {
+		if id := refundRequestId(ctx, captureId, bm); id != pay.NULL {
+			return c.idempotentRefund(WithRequestId(ctx, id), captureId, bm)
+		}
+	}
+	return c.paymentCaptureRefund(ctx, captureId, bm)
+}
+
+func (c *Client) paymentCaptureRefund(ctx context.Context, captureId string, bm pay.BodyMap) (ppRsp *PaymentCaptureRefundRsp, err error) {
 	url := fmt.Sprintf(paymentCaptureRefund, captureId)
 	res, bs, err := c.doPayPalPost(ctx, bm, url)
 	if err != nil {
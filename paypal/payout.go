@@ -21,6 +21,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+
+	pay "github.com/rwscode/payutil"
 )
 
 // 创建批量支出（Create batch payout）
@@ -0,0 +1,103 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paypal
+
+import (
+	"fmt"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// CreateOrderRequest 创建订单请求参数
+//
+//	bm, err := req.BodyMap()
+//	ppRsp, err := client.CreateOrder(ctx, bm)
+type CreateOrderRequest struct {
+	Intent             string                   `json:"intent" validate:"required,oneof=CAPTURE AUTHORIZE"`
+	PurchaseUnits      []*PurchaseUnitRequest   `json:"purchase_units" validate:"required,max=10"`
+	ApplicationContext *OrderApplicationContext `json:"application_context,omitempty"`
+}
+
+// PurchaseUnitRequest 创建订单的购买单元，多个购买单元时 reference_id 必传
+type PurchaseUnitRequest struct {
+	ReferenceId    string    `json:"reference_id,omitempty" validate:"max=256"`
+	Amount         *Amount   `json:"amount" validate:"required"`
+	Payee          *Payee    `json:"payee,omitempty"`
+	Description    string    `json:"description,omitempty" validate:"max=127"`
+	CustomId       string    `json:"custom_id,omitempty" validate:"max=127"`
+	InvoiceId      string    `json:"invoice_id,omitempty" validate:"max=127"`
+	SoftDescriptor string    `json:"soft_descriptor,omitempty" validate:"max=22"`
+	Items          []*Item   `json:"items,omitempty"`
+	Shipping       *Shipping `json:"shipping,omitempty"`
+}
+
+type OrderApplicationContext struct {
+	BrandName          string `json:"brand_name,omitempty" validate:"max=127"`
+	Locale             string `json:"locale,omitempty" validate:"max=10"`
+	LandingPage        string `json:"landing_page,omitempty" validate:"oneof=LOGIN BILLING NO_PREFERENCE"`
+	ShippingPreference string `json:"shipping_preference,omitempty" validate:"oneof=GET_FROM_FILE NO_SHIPPING SET_PROVIDED_ADDRESS"`
+	UserAction         string `json:"user_action,omitempty" validate:"oneof=CONTINUE PAY_NOW"`
+	ReturnUrl          string `json:"return_url,omitempty"`
+	CancelUrl          string `json:"cancel_url,omitempty"`
+}
+
+func (r *CreateOrderRequest) Validate() error {
+	if err := pay.ValidateStruct(r); err != nil {
+		return err
+	}
+	for i, pu := range r.PurchaseUnits {
+		if pu == nil {
+			return fmt.Errorf("[%w], purchase_units[%d]", pay.MissParamErr, i)
+		}
+		if len(r.PurchaseUnits) > 1 && pu.ReferenceId == pay.NULL {
+			return fmt.Errorf("[%w], purchase_units[%d].reference_id", pay.MissParamErr, i)
+		}
+		m, err := pu.Amount.Money()
+		if err != nil {
+			return err
+		}
+		if m.Amount <= 0 {
+			return fmt.Errorf("[%w]: purchase_units[%d].amount.value must be greater than 0", pay.InvalidParamErr, i)
+		}
+		// 校验金额精度，如 JPY 不支持小数
+		if _, err = NewAmount(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BodyMap 校验后转为 CreateOrder 的请求参数
+func (r *CreateOrderRequest) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
+
+// OrderCaptureRequest 订单捕获请求参数，OrderId 作为路径参数，不会放入 BodyMap
+//
+//	bm, err := req.BodyMap()
+//	ppRsp, err := client.OrderCapture(ctx, req.OrderId, bm)
+type OrderCaptureRequest struct {
+	OrderId       string         `json:"-"`
+	PaymentSource *PaymentSource `json:"payment_source,omitempty"`
+}
+
+func (r *OrderCaptureRequest) Validate() error {
+	if r.OrderId == pay.NULL {
+		return fmt.Errorf("[%w], order_id", pay.MissParamErr)
+	}
+	return pay.ValidateStruct(r)
+}
+
+// BodyMap 校验后转为 OrderCapture 的请求参数
+func (r *OrderCaptureRequest) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paypal
+
+import (
+	"errors"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+)
+
+func TestCreateOrderRequest(t *testing.T) {
+	req := &CreateOrderRequest{
+		Intent: "CAPTURE",
+		PurchaseUnits: []*PurchaseUnitRequest{
+			{ReferenceId: "ref-1", Amount: &Amount{CurrencyCode: "USD", Value: "8.00"}},
+		},
+		ApplicationContext: &OrderApplicationContext{BrandName: "gopay", UserAction: "PAY_NOW"},
+	}
+	bm, err := req.BodyMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.GetString("intent") != "CAPTURE" || bm.GetInterface("purchase_units") == nil {
+		t.Fatalf("bm = %s", bm.JsonBody())
+	}
+
+	req.PurchaseUnits = append(req.PurchaseUnits, &PurchaseUnitRequest{Amount: &Amount{CurrencyCode: "USD", Value: "1"}})
+	if err = req.Validate(); !errors.Is(err, pay.MissParamErr) {
+		t.Fatalf("missing reference_id: %v", err)
+	}
+	req.PurchaseUnits = []*PurchaseUnitRequest{{Amount: &Amount{CurrencyCode: "JPY", Value: "100.5"}}}
+	if err = req.Validate(); !errors.Is(err, pay.InvalidParamErr) {
+		t.Fatalf("JPY decimal: %v", err)
+	}
+	req.PurchaseUnits[0].Amount.Value = "100"
+	req.Intent = "SALE"
+	if err = req.Validate(); !errors.Is(err, pay.InvalidParamErr) {
+		t.Fatalf("invalid intent: %v", err)
+	}
+}
+
+func TestOrderCaptureRequest(t *testing.T) {
+	req := &OrderCaptureRequest{}
+	if err := req.Validate(); !errors.Is(err, pay.MissParamErr) {
+		t.Fatalf("missing order_id: %v", err)
+	}
+	req.OrderId = "4X223967G91314611"
+	bm, err := req.BodyMap()
+	if err != nil || len(bm) != 0 {
+		t.Fatalf("bm = %v, err = %v", bm, err)
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	pay "github.com/rwscode/payutil"
 )
 
 // 创建订阅计划（CreateBillingPlan）
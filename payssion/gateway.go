@@ -0,0 +1,96 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payssion
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+type gateway struct {
+	c Client
+}
+
+// NewGateway 将Payssion Client 适配为 pay.Gateway
+//
+//	Charge：pm_id 等渠道参数通过 pay.Order.Extra 传入，ChargeResult.PayUrl 为 redirect_url
+//	Payssion 客户端仅实现了创建支付，其余操作返回 pay.UnsupportedErr，支付结果以异步通知为准
+func NewGateway(client Client) pay.Gateway {
+	return &gateway{c: client}
+}
+
+func (g *gateway) Provider() string {
+	return pay.ProviderPayssion
+}
+
+func (g *gateway) Charge(ctx context.Context, o *pay.Order) (*pay.ChargeResult, error) {
+	currency := strings.ToUpper(o.Currency)
+	if currency == util.NULL {
+		currency = "USD"
+	}
+	description := o.Body
+	if description == util.NULL {
+		description = o.Subject
+	}
+	data := url.Values{}
+	data.Set("order_id", o.OutTradeNo)
+	data.Set("amount", pay.NewMoney(o.Amount, currency).Decimal())
+	data.Set("currency", currency)
+	data.Set("description", description)
+	if o.ReturnUrl != util.NULL {
+		data.Set("return_url", o.ReturnUrl)
+	}
+	for k := range o.Extra {
+		data.Set(k, o.Extra.GetString(k))
+	}
+	if data.Get("pm_id") == util.NULL {
+		return nil, fmt.Errorf("[%w], %v", pay.MissParamErr, "pm_id")
+	}
+	rsp, err := g.c.CreateWithContext(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.ResultCode != 200 {
+		return nil, fmt.Errorf("payssion Create failed: %w", pay.NewAPIError(pay.ProviderPayssion, 0, strconv.Itoa(rsp.ResultCode), "", "", pay.CategoryUnknown))
+	}
+	rs := &pay.ChargeResult{
+		OutTradeNo: o.OutTradeNo,
+		PayUrl:     rsp.RedirectURL,
+		Raw:        rsp,
+	}
+	if id, ok := rsp.Transaction["transaction_id"].(string); ok {
+		rs.TradeNo = id
+	}
+	return rs, nil
+}
+
+func (g *gateway) Query(ctx context.Context, q *pay.OrderQuery) (*pay.OrderResult, error) {
+	return nil, fmt.Errorf("[%w]: payssion query order", pay.UnsupportedErr)
+}
+
+func (g *gateway) Close(ctx context.Context, q *pay.OrderQuery) error {
+	return fmt.Errorf("[%w]: payssion close order", pay.UnsupportedErr)
+}
+
+func (g *gateway) Refund(ctx context.Context, r *pay.Refund) (*pay.RefundResult, error) {
+	return nil, fmt.Errorf("[%w]: payssion refund", pay.UnsupportedErr)
+}
+
+func (g *gateway) QueryRefund(ctx context.Context, q *pay.RefundQuery) (*pay.RefundResult, error) {
+	return nil, fmt.Errorf("[%w]: payssion query refund", pay.UnsupportedErr)
+}
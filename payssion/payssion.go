@@ -13,6 +13,7 @@ package payssion
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
@@ -25,8 +26,15 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/xhttp"
+	"github.com/rwscode/payutil/pkg/xlog"
 )
 
 const (
@@ -40,13 +48,12 @@ var (
 
 func NewClient(apikey, apiSecret string) Client {
 	c := Client{
-		debug:      ioutil.Discard,
-		apiKey:     apikey,
-		apiSecret:  apiSecret,
-		live:       false,
-		httpclient: new(http.Client),
+		debug:     ioutil.Discard,
+		apiKey:    apikey,
+		apiSecret: apiSecret,
+		live:      false,
 	}
-	c.httpclient.Transport = &http.Transport{
+	c.httpclient = &http.Client{Transport: &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
@@ -57,7 +64,7 @@ func NewClient(apikey, apiSecret string) Client {
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
-	}
+	}}
 	return c
 }
 
@@ -66,18 +73,67 @@ type Client struct {
 	apiKey     string
 	apiSecret  string
 	live       bool
-	httpclient *http.Client
+	httpclient xhttp.Doer
+	baseURL    string        // 自定义接口域名，为空时按 live 选择
+	mw         observe.Chain // 中间件链
+	logger     xlog.Logger   // 结构化日志，见 SetLogger()
 }
 
 func (c *Client) SetLive(live bool) {
 	c.live = live
 }
 
+// SetBaseURL 设置接口域名，如 https://www.payssion.com，末尾不带 /，设置后忽略 SetLive()
+// 用于出口代理、本地模拟服务等，传空字符串恢复默认
+func (c *Client) SetBaseURL(url string) {
+	c.baseURL = strings.TrimSuffix(url, "/")
+}
+
+// SetHTTPClient 设置自定义 *http.Client
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	if hc != nil {
+		c.httpclient = hc
+	}
+}
+
+// SetDoer 设置自定义 xhttp.Doer，用于链路追踪、代理、mock 等
+func (c *Client) SetDoer(d xhttp.Doer) {
+	if d != nil {
+		c.httpclient = d
+	}
+}
+
+// SetTLSOptions 设置服务端证书校验配置，如自定义根证书、公钥固定，会替换 SetHTTPClient() 设置的客户端
+func (c *Client) SetTLSOptions(o *xhttp.TLSOptions) {
+	c.httpclient = xhttp.NewHttpClient(&xhttp.TransportConfig{TLSConfig: xhttp.NewTLSConfig(o)})
+}
+
 func (c *Client) Debug(w io.Writer) {
 	c.debug = w
 }
 
-func (c Client) do(r *http.Request) (jsoniter.Any, error) {
+// Use 添加中间件，用于指标统计、链路追踪等，见 observe 包，请在初始化时调用
+func (c *Client) Use(mw ...observe.Middleware) {
+	c.mw = append(c.mw, mw...)
+}
+
+// SetLogger 设置当前客户端的结构化日志，记录每次接口调用（见 observe.Logging）
+//
+//	请求、响应报文仍通过 Debug() 输出
+func (c *Client) SetLogger(l xlog.Logger) {
+	c.logger = l
+}
+
+// resultCode result_code 不为 200 时返回 result_code
+func resultCode(_ int, bs []byte) string {
+	code := json.Get(bs, "result_code").ToInt()
+	if code == 0 || code == 200 {
+		return ""
+	}
+	return strconv.Itoa(code)
+}
+
+func (c Client) do(r *http.Request, orderID string) (jsoniter.Any, error) {
 	buf := new(bytes.Buffer)
 	buf.WriteString("--------------------\n")
 	buf.WriteString(time.Now().Format("2006-01-02 15:04:05\n"))
@@ -86,20 +142,23 @@ func (c Client) do(r *http.Request) (jsoniter.Any, error) {
 	}()
 	b, err := httputil.DumpRequest(r, true)
 	if err == nil {
-		buf.Write(b)
+		buf.WriteString(redact.String(string(b)))
 	}
 	var data jsoniter.Any
-	rsp, err := c.httpclient.Do(r)
-	if err != nil {
-		return data, err
-	}
-	defer rsp.Body.Close()
-	b, err = httputil.DumpResponse(rsp, true)
-	if err == nil {
-		buf.WriteString("\n\n")
-		buf.Write(b)
-	}
-	b, err = ioutil.ReadAll(rsp.Body)
+	call := &observe.Call{Provider: pay.ProviderPayssion, Method: observe.Endpoint(r.Method, r.URL.Path), OutTradeNo: orderID}
+	_, b, err = c.mw.WithLogging(c.logger).DoHTTP(r.Context(), call, func(ctx context.Context) (*http.Response, []byte, error) {
+		rsp, err := c.httpclient.Do(r.WithContext(ctx))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer rsp.Body.Close()
+		if dump, err := httputil.DumpResponse(rsp, true); err == nil {
+			buf.WriteString("\n\n")
+			buf.WriteString(redact.String(string(dump)))
+		}
+		bs, err := ioutil.ReadAll(rsp.Body)
+		return rsp, bs, err
+	}, resultCode)
 	if err != nil {
 		return data, err
 	}
@@ -122,6 +181,11 @@ var (
 
 // Create  pm_id,amount,currency,description,order_id
 func (c Client) Create(data url.Values) (CreateResponse, error) {
+	return c.CreateWithContext(context.Background(), data)
+}
+
+// CreateWithContext 同 Create，ctx 用于请求的超时、取消及链路追踪
+func (c Client) CreateWithContext(ctx context.Context, data url.Values) (CreateResponse, error) {
 	// pm_id alipay_cn tenpay_cn
 	var rsp CreateResponse
 
@@ -139,12 +203,12 @@ func (c Client) Create(data url.Values) (CreateResponse, error) {
 	}
 	data.Set("api_sig", md5sum(strings.Join(sig, "|")))
 	u := fmt.Sprintf("%v/api/v1/payment/create", c.apiHost())
-	r, err := http.NewRequest(http.MethodPost, u, strings.NewReader(data.Encode()))
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(data.Encode()))
 	if err != nil {
 		return rsp, err
 	}
 	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	any, err := c.do(r)
+	any, err := c.do(r, data.Get("order_id"))
 	if err != nil {
 		return rsp, err
 	}
@@ -153,8 +217,10 @@ func (c Client) Create(data url.Values) (CreateResponse, error) {
 }
 
 func (c Client) apiHost() string {
-	switch c.live {
-	case true:
+	switch {
+	case c.baseURL != "":
+		return c.baseURL
+	case c.live:
 		return liveURLHost
 	default:
 		return sandboxURLHost
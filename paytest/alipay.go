@@ -0,0 +1,545 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	pay "github.com/rwscode/payutil"
+	xaes "github.com/rwscode/payutil/pkg/aes"
+	"github.com/rwscode/payutil/pkg/util"
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+// 支付宝交易状态
+const (
+	AlipayWaitBuyerPay = "WAIT_BUYER_PAY"
+	AlipayTradeSuccess = "TRADE_SUCCESS"
+	AlipayTradeClosed  = "TRADE_CLOSED"
+)
+
+// AlipayServer 模拟支付宝开放平台网关 gateway.do
+//
+//	支持 alipay.trade.precreate、create、pay、query、close、refund、fastpay.refund.query，应答按 RSA2 签名，
+//	可使用 client.AutoVerifySign(srv.PublicKeyCert()) 开启同步验签；异步通知可使用 notify.AlipaySource{PublicKey: srv.PublicKey()} 验签
+//	设置 AESKey 后支持接口内容加密（encrypt_type=AES 或 SM4），对应 client.SetAESKey()、client.SetEncryptKey()
+//	同时支持 v3 协议的 /v3/alipay/trade/* 接口（client.SetBaseURL(srv.URL)），与 gateway.do 共用订单状态，应答头 alipay-signature 签名
+type AlipayServer struct {
+	*httptest.Server
+	notifier
+
+	// AppPublicKey 应用公钥，设置后校验请求签名，签名错误返回 isv.invalid-signature
+	AppPublicKey *rsa.PublicKey
+	// AESKey 接口内容加密密钥（base64），设置后按请求的 encrypt_type（AES 或 SM4）解密 biz_content，并加密其应答
+	AESKey string
+	// SellerId 卖家支付宝用户号，异步通知的 seller_id
+	SellerId string
+
+	key    *rsa.PrivateKey
+	cert   *x509.Certificate
+	mu     sync.Mutex
+	trades map[string]*alipayTrade // key: out_trade_no
+	fails  map[string]string       // key: method，value: sub_code
+
+	v3Replies map[string]*alipayV3Reply // key: alipay-request-id
+	v3Nonces  map[string]bool           // 已使用的 Authorization nonce
+}
+
+type alipayTrade struct {
+	AppId      string
+	OutTradeNo string
+	TradeNo    string
+	Subject    string
+	Amount     int64
+	Currency   string // trans_currency，默认 CNY
+	Status     string
+	NotifyURL  string
+	GmtCreate  time.Time
+	GmtPayment time.Time
+	Refunded   int64
+	Refunds    map[string]*alipayRefund // key: out_request_no
+}
+
+// money 按订单币种格式化金额
+func (t *alipayTrade) money(amount int64) string {
+	return pay.NewMoney(amount, t.Currency).Decimal()
+}
+
+type alipayRefund struct {
+	OutRequestNo string
+	Amount       int64
+	GmtRefund    time.Time
+}
+
+// NewAlipayServer 启动支付宝模拟服务
+func NewAlipayServer() *AlipayServer {
+	s := &AlipayServer{
+		SellerId:  "2088000000000001",
+		key:       newRSAKey(),
+		trades:    make(map[string]*alipayTrade),
+		fails:     make(map[string]string),
+		v3Replies: make(map[string]*alipayV3Reply),
+		v3Nonces:  make(map[string]bool),
+	}
+	s.cert = newCert("paytest alipay", &s.key.PublicKey, s.key, nil, false)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// GatewayURL 网关地址，用于 client.SetBaseURL()
+func (s *AlipayServer) GatewayURL() string {
+	return s.URL + "/gateway.do"
+}
+
+// PublicKeyCert 支付宝公钥证书内容，用于 client.AutoVerifySign()、alipay.VerifySignWithCert()
+func (s *AlipayServer) PublicKeyCert() []byte {
+	return certPEM(s.cert)
+}
+
+// PublicKey 支付宝公钥（base64，不含 PEM 头尾），用于 alipay.VerifySign()、alipay.VerifySyncSign()
+func (s *AlipayServer) PublicKey() string {
+	der, _ := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+// OpenData 模拟小程序 my.getPhoneNumber 等返回的加密开放数据，使用 AESKey 按 encryptType 加密并签名，用于 client.DecryptOpenData()
+func (s *AlipayServer) OpenData(encryptType string, data pay.BodyMap) (string, error) {
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := s.contentCrypt(string(bs), encryptType, true)
+	if err != nil {
+		return "", err
+	}
+	bs, err = json.Marshal(map[string]string{
+		"response":     encrypted,
+		"sign":         signSHA256WithRSA(s.key, `"`+encrypted+`"`),
+		"sign_type":    "RSA2",
+		"encrypt_type": encryptType,
+		"charset":      "UTF-8",
+	})
+	return string(bs), err
+}
+
+// FailNext 下一次调用 method 时返回业务错误 sub_code，如 ACQ.SYSTEM_ERROR、ACQ.TRADE_HAS_SUCCESS
+func (s *AlipayServer) FailNext(method, subCode string) {
+	s.mu.Lock()
+	s.fails[method] = subCode
+	s.mu.Unlock()
+}
+
+// TradeStatus 返回订单状态，订单不存在时返回空字符串
+func (s *AlipayServer) TradeStatus(outTradeNo string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t := s.trades[outTradeNo]; t != nil {
+		return t.Status
+	}
+	return util.NULL
+}
+
+// Pay 模拟用户完成支付，订单状态变为 TRADE_SUCCESS，下单时传了 notify_url 则发送异步通知
+func (s *AlipayServer) Pay(outTradeNo string) (*Notification, error) {
+	s.mu.Lock()
+	t := s.trades[outTradeNo]
+	if t == nil || t.Status != AlipayWaitBuyerPay {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("paytest: alipay trade %s not exist or not WAIT_BUYER_PAY", outTradeNo)
+	}
+	t.Status, t.GmtPayment = AlipayTradeSuccess, time.Now()
+	bm := s.notifyBodyMap(t)
+	s.mu.Unlock()
+	if t.NotifyURL == util.NULL {
+		return nil, nil
+	}
+	return s.sendNotify(t.NotifyURL, bm)
+}
+
+// NotifyRefund 发送退款的 trade_status_sync 异步通知（携带 gmt_refund、out_biz_no）
+func (s *AlipayServer) NotifyRefund(outTradeNo, outRequestNo string) (*Notification, error) {
+	s.mu.Lock()
+	t := s.trades[outTradeNo]
+	if t == nil || t.Refunds[outRequestNo] == nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("paytest: alipay refund %s of trade %s not exist", outRequestNo, outTradeNo)
+	}
+	r := t.Refunds[outRequestNo]
+	bm := s.notifyBodyMap(t)
+	bm.Set("gmt_refund", r.GmtRefund.Format(util.TimeLayout)).
+		Set("out_biz_no", r.OutRequestNo).
+		Set("refund_fee", t.money(t.Refunded))
+	s.mu.Unlock()
+	return s.sendNotify(t.NotifyURL, bm)
+}
+
+func (s *AlipayServer) notifyBodyMap(t *alipayTrade) pay.BodyMap {
+	bm := make(pay.BodyMap)
+	bm.Set("notify_time", time.Now().Format(util.TimeLayout)).
+		Set("notify_type", "trade_status_sync").
+		Set("notify_id", newId("")).
+		Set("charset", "utf-8").
+		Set("version", "1.0").
+		Set("trade_no", t.TradeNo).
+		Set("out_trade_no", t.OutTradeNo).
+		Set("subject", t.Subject).
+		Set("trade_status", t.Status).
+		Set("total_amount", t.money(t.Amount)).
+		Set("receipt_amount", t.money(t.Amount)).
+		Set("buyer_pay_amount", t.money(t.Amount)).
+		Set("gmt_create", t.GmtCreate.Format(util.TimeLayout)).
+		Set("seller_id", s.SellerId)
+	if t.AppId != util.NULL {
+		bm.Set("app_id", t.AppId)
+	}
+	if t.Currency != "CNY" {
+		bm.Set("trans_currency", t.Currency)
+	}
+	if !t.GmtPayment.IsZero() {
+		bm.Set("gmt_payment", t.GmtPayment.Format(util.TimeLayout))
+	}
+	return bm
+}
+
+// Notify 签名并发送自定义的异步通知，如 dut_user_sign 签约通知、msg_method 消息
+func (s *AlipayServer) Notify(url string, bm pay.BodyMap) (*Notification, error) {
+	return s.sendNotify(url, bm)
+}
+
+// 异步通知签名不含 sign、sign_type
+func (s *AlipayServer) sendNotify(url string, bm pay.BodyMap) (*Notification, error) {
+	bm.Set("sign", signSHA256WithRSA(s.key, bm.EncodeAliPaySignParams())).
+		Set("sign_type", "RSA2")
+	return s.notify(url, "application/x-www-form-urlencoded;charset=utf-8", nil, []byte(bm.EncodeURLParams()))
+}
+
+func (s *AlipayServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/v3/") {
+		s.serveV3(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// charset 同时出现在 query 与 body 中，以 body 为准
+	form := r.PostForm
+	if r.Method == http.MethodGet {
+		form = r.URL.Query()
+	}
+	req := make(pay.BodyMap)
+	for k := range form {
+		req.Set(k, form.Get(k))
+	}
+	method := req.GetString("method")
+	if s.AppPublicKey != nil {
+		sign := req.GetString("sign")
+		req.Remove("sign")
+		if err := verifySHA256WithRSA(s.AppPublicKey, req.EncodeAliPaySignParams(), sign); err != nil {
+			s.write(w, method, alipayErr("40002", "Invalid Arguments", "isv.invalid-signature", "验签出错"))
+			return
+		}
+	}
+	encryptType := req.GetString("encrypt_type")
+	encrypted := encryptType != util.NULL
+	if encrypted {
+		content, err := s.contentCrypt(req.GetString("biz_content"), encryptType, false)
+		if err != nil {
+			s.write(w, method, alipayErr("40002", "Invalid Arguments", "isv.decryption-error", "解密出错"))
+			return
+		}
+		req.Set("biz_content", content)
+	}
+	biz := make(pay.BodyMap)
+	if content := req.GetString("biz_content"); content != util.NULL {
+		if err := json.Unmarshal([]byte(content), &biz); err != nil {
+			s.write(w, method, alipayErr("40002", "Invalid Arguments", "isv.invalid-parameter", "biz_content 格式错误"))
+			return
+		}
+	}
+	s.mu.Lock()
+	subCode, fail := s.fails[method]
+	delete(s.fails, method)
+	var rsp pay.BodyMap
+	switch {
+	case fail:
+		rsp = alipayBizErr(subCode)
+	case method == "alipay.trade.precreate", method == "alipay.trade.create", method == "alipay.trade.pay":
+		rsp = s.create(method, req, biz)
+	case method == "alipay.trade.query":
+		rsp = s.query(biz)
+	case method == "alipay.trade.close":
+		rsp = s.close(biz)
+	case method == "alipay.trade.refund":
+		rsp = s.refund(biz)
+	case method == "alipay.trade.fastpay.refund.query":
+		rsp = s.refundQuery(biz)
+	default:
+		rsp = alipayErr("40002", "Invalid Arguments", "isv.invalid-method", "不存在的方法名")
+	}
+	s.mu.Unlock()
+	if encrypted {
+		s.writeEncrypted(w, method, encryptType, rsp)
+		return
+	}
+	s.write(w, method, rsp)
+}
+
+// write 应答 {"<method>_response":{...},"sign":"..."}，sign 为对 _response 值原文的 RSA2 签名
+func (s *AlipayServer) write(w http.ResponseWriter, method string, rsp pay.BodyMap) {
+	if rsp.GetString("code") == util.NULL {
+		rsp.Set("code", "10000").Set("msg", "Success")
+	}
+	bs, _ := json.Marshal(rsp)
+	key := strings.ReplaceAll(method, ".", "_") + "_response"
+	if method == util.NULL {
+		key = "error_response"
+	}
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	_, _ = fmt.Fprintf(w, `{"%s":%s,"sign":"%s"}`, key, bs, signSHA256WithRSA(s.key, string(bs)))
+}
+
+// writeEncrypted 内容加密的应答 {"<method>_response":"密文","sign":"..."}，sign 为对含双引号的密文的 RSA2 签名
+func (s *AlipayServer) writeEncrypted(w http.ResponseWriter, method, encryptType string, rsp pay.BodyMap) {
+	if rsp.GetString("code") == util.NULL {
+		rsp.Set("code", "10000").Set("msg", "Success")
+	}
+	bs, _ := json.Marshal(rsp)
+	encrypted, _ := s.contentCrypt(string(bs), encryptType, true)
+	signData := `"` + encrypted + `"`
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	_, _ = fmt.Fprintf(w, `{"%s_response":%s,"sign":"%s"}`, strings.ReplaceAll(method, ".", "_"), signData, signSHA256WithRSA(s.key, signData))
+}
+
+// contentCrypt 支付宝接口内容加解密：AES 或 SM4 的 CBC 模式、全 0 IV、PKCS7 填充，密文 base64
+func (s *AlipayServer) contentCrypt(content, encryptType string, encrypt bool) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(s.AESKey)
+	if err != nil {
+		return util.NULL, err
+	}
+	var block cipher.Block
+	switch encryptType {
+	case "AES":
+		block, err = aes.NewCipher(key)
+	case "SM4":
+		block, err = sm4.NewCipher(key)
+	default:
+		err = fmt.Errorf("unsupported encrypt_type: %s", encryptType)
+	}
+	if err != nil {
+		return util.NULL, err
+	}
+	iv := make([]byte, block.BlockSize())
+	if encrypt {
+		bs := xaes.PKCS7Padding([]byte(content), block.BlockSize())
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(bs, bs)
+		return base64.StdEncoding.EncodeToString(bs), nil
+	}
+	bs, err := base64.StdEncoding.DecodeString(content)
+	if err != nil || len(bs) == 0 || len(bs)%block.BlockSize() != 0 {
+		return util.NULL, fmt.Errorf("invalid encrypted content: %s", content)
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(bs, bs)
+	if n := int(bs[len(bs)-1]); n == 0 || n > block.BlockSize() {
+		return util.NULL, fmt.Errorf("invalid padding")
+	}
+	return string(xaes.PKCS7UnPadding(bs)), nil
+}
+
+func (s *AlipayServer) find(biz pay.BodyMap) *alipayTrade {
+	if no := biz.GetString("out_trade_no"); no != util.NULL {
+		return s.trades[no]
+	}
+	tradeNo := biz.GetString("trade_no")
+	for _, t := range s.trades {
+		if t.TradeNo == tradeNo {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *AlipayServer) create(method string, req, biz pay.BodyMap) pay.BodyMap {
+	outTradeNo := biz.GetString("out_trade_no")
+	currency := biz.GetString("trans_currency")
+	if currency == util.NULL {
+		currency = "CNY"
+	}
+	total, err := pay.ParseMoney(biz.GetString("total_amount"), currency)
+	amount := total.Amount
+	if outTradeNo == util.NULL || err != nil || amount <= 0 {
+		return alipayErr("40002", "Invalid Arguments", "isv.invalid-parameter", "out_trade_no 或 total_amount 无效")
+	}
+	t := s.trades[outTradeNo]
+	if t != nil {
+		if t.Status == AlipayTradeSuccess {
+			return alipayBizErr("ACQ.TRADE_HAS_SUCCESS")
+		}
+		if t.Status == AlipayTradeClosed {
+			return alipayBizErr("ACQ.TRADE_HAS_CLOSE")
+		}
+	} else {
+		t = &alipayTrade{
+			OutTradeNo: outTradeNo,
+			TradeNo:    newId(""),
+			Status:     AlipayWaitBuyerPay,
+			GmtCreate:  time.Now(),
+			Refunds:    make(map[string]*alipayRefund),
+		}
+		s.trades[outTradeNo] = t
+	}
+	t.AppId, t.Subject, t.Amount, t.Currency, t.NotifyURL = req.GetString("app_id"), biz.GetString("subject"), amount, currency, req.GetString("notify_url")
+	rsp := make(pay.BodyMap)
+	rsp.Set("out_trade_no", t.OutTradeNo)
+	switch method {
+	case "alipay.trade.precreate":
+		rsp.Set("qr_code", "https://qr.alipay.com/"+t.TradeNo)
+	case "alipay.trade.create":
+		rsp.Set("trade_no", t.TradeNo)
+	case "alipay.trade.pay":
+		// 付款码支付直接成功
+		t.Status, t.GmtPayment = AlipayTradeSuccess, time.Now()
+		rsp.Set("trade_no", t.TradeNo).
+			Set("total_amount", t.money(t.Amount)).
+			Set("receipt_amount", t.money(t.Amount)).
+			Set("gmt_payment", t.GmtPayment.Format(util.TimeLayout))
+	}
+	return rsp
+}
+
+func (s *AlipayServer) query(biz pay.BodyMap) pay.BodyMap {
+	t := s.find(biz)
+	if t == nil {
+		return alipayBizErr("ACQ.TRADE_NOT_EXIST")
+	}
+	rsp := make(pay.BodyMap)
+	rsp.Set("out_trade_no", t.OutTradeNo).
+		Set("trade_no", t.TradeNo).
+		Set("trade_status", t.Status).
+		Set("total_amount", t.money(t.Amount))
+	if t.Currency != "CNY" {
+		rsp.Set("trans_currency", t.Currency)
+	}
+	if !t.GmtPayment.IsZero() {
+		rsp.Set("send_pay_date", t.GmtPayment.Format(util.TimeLayout)).
+			Set("receipt_amount", t.money(t.Amount)).
+			Set("buyer_pay_amount", t.money(t.Amount))
+	}
+	return rsp
+}
+
+func (s *AlipayServer) close(biz pay.BodyMap) pay.BodyMap {
+	t := s.find(biz)
+	if t == nil {
+		return alipayBizErr("ACQ.TRADE_NOT_EXIST")
+	}
+	if t.Status == AlipayTradeSuccess {
+		return alipayBizErr("ACQ.TRADE_STATUS_ERROR")
+	}
+	t.Status = AlipayTradeClosed
+	rsp := make(pay.BodyMap)
+	rsp.Set("out_trade_no", t.OutTradeNo).Set("trade_no", t.TradeNo)
+	return rsp
+}
+
+func (s *AlipayServer) refund(biz pay.BodyMap) pay.BodyMap {
+	t := s.find(biz)
+	if t == nil {
+		return alipayBizErr("ACQ.TRADE_NOT_EXIST")
+	}
+	refund, err := pay.ParseMoney(biz.GetString("refund_amount"), t.Currency)
+	amount := refund.Amount
+	if err != nil || amount <= 0 {
+		return alipayErr("40002", "Invalid Arguments", "isv.invalid-parameter", "refund_amount 无效")
+	}
+	outRequestNo := biz.GetString("out_request_no")
+	if outRequestNo == util.NULL {
+		outRequestNo = t.OutTradeNo
+	}
+	// 同一 out_request_no 重复请求视为同一笔退款
+	if _, ok := t.Refunds[outRequestNo]; !ok {
+		if t.Status != AlipayTradeSuccess {
+			return alipayBizErr("ACQ.TRADE_STATUS_ERROR")
+		}
+		if t.Refunded+amount > t.Amount {
+			return alipayBizErr("ACQ.REFUND_AMT_NOT_EQUAL_TOTAL")
+		}
+		t.Refunds[outRequestNo] = &alipayRefund{OutRequestNo: outRequestNo, Amount: amount, GmtRefund: time.Now()}
+		t.Refunded += amount
+		if t.Refunded == t.Amount {
+			t.Status = AlipayTradeClosed
+		}
+	}
+	rsp := make(pay.BodyMap)
+	rsp.Set("out_trade_no", t.OutTradeNo).
+		Set("trade_no", t.TradeNo).
+		Set("fund_change", "Y").
+		Set("refund_fee", t.money(t.Refunded)).
+		Set("gmt_refund_pay", t.Refunds[outRequestNo].GmtRefund.Format(util.TimeLayout))
+	if t.Currency != "CNY" {
+		rsp.Set("refund_currency", t.Currency)
+	}
+	return rsp
+}
+
+func (s *AlipayServer) refundQuery(biz pay.BodyMap) pay.BodyMap {
+	t := s.find(biz)
+	if t == nil {
+		return alipayBizErr("ACQ.TRADE_NOT_EXIST")
+	}
+	rsp := make(pay.BodyMap)
+	rsp.Set("out_trade_no", t.OutTradeNo).Set("trade_no", t.TradeNo)
+	// 退款不存在时支付宝仍返回 10000，仅不含退款信息
+	if r := t.Refunds[biz.GetString("out_request_no")]; r != nil {
+		rsp.Set("out_request_no", r.OutRequestNo).
+			Set("refund_status", "REFUND_SUCCESS").
+			Set("total_amount", t.money(t.Amount)).
+			Set("refund_amount", t.money(r.Amount)).
+			Set("gmt_refund_pay", r.GmtRefund.Format(util.TimeLayout))
+	}
+	return rsp
+}
+
+// 支付宝业务错误 sub_msg
+var alipaySubMsg = map[string]string{
+	"ACQ.SYSTEM_ERROR":               "系统错误",
+	"ACQ.TRADE_NOT_EXIST":            "交易不存在",
+	"ACQ.TRADE_HAS_SUCCESS":          "交易已被支付",
+	"ACQ.TRADE_HAS_CLOSE":            "交易已经关闭",
+	"ACQ.TRADE_STATUS_ERROR":         "交易状态不合法",
+	"ACQ.REFUND_AMT_NOT_EQUAL_TOTAL": "退款金额超限",
+	"ACQ.BUYER_BALANCE_NOT_ENOUGH":   "买家余额不足",
+}
+
+func alipayBizErr(subCode string) pay.BodyMap {
+	if subCode == "ACQ.SYSTEM_ERROR" {
+		return alipayErr("20000", "Service Currently Unavailable", subCode, alipaySubMsg[subCode])
+	}
+	return alipayErr("40004", "Business Failed", subCode, alipaySubMsg[subCode])
+}
+
+func alipayErr(code, msg, subCode, subMsg string) pay.BodyMap {
+	rsp := make(pay.BodyMap)
+	rsp.Set("code", code).Set("msg", msg).Set("sub_code", subCode).Set("sub_msg", subMsg)
+	return rsp
+}
@@ -0,0 +1,655 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/alipay"
+	"github.com/rwscode/payutil/notify"
+	"github.com/rwscode/payutil/paytest"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+func TestAlipayServer(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, key := paytest.NewRSAKeyPair()
+	srv.AppPublicKey = &key.PublicKey
+
+	var events []*notify.Event
+	router := notify.NewRouter().On(notify.EventAll, func(ctx context.Context, e *notify.Event) error {
+		events = append(events, e)
+		return nil
+	})
+	notifySrv := httptest.NewServer(router.Handler(&notify.AlipaySource{PublicKey: srv.PublicKey()}))
+	defer notifySrv.Close()
+
+	client, err := alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.GatewayURL())
+	client.AutoVerifySign(srv.PublicKeyCert())
+	metrics := observe.NewMetrics()
+	client.Use(metrics.Middleware())
+	g := alipay.NewGateway(client)
+
+	charge, err := g.Charge(ctx, &pay.Order{OutTradeNo: "A001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, NotifyUrl: notifySrv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if charge.CodeUrl == "" {
+		t.Fatalf("CodeUrl is empty: %+v", charge)
+	}
+	order, err := g.Query(ctx, &pay.OrderQuery{OutTradeNo: "A001"})
+	if err != nil || order.Status != pay.TradeStatusPending || order.Amount != 1000 {
+		t.Fatalf("Query = %+v, %v", order, err)
+	}
+	if _, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "A404"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("Query not exist err = %v", err)
+	}
+	buf := new(bytes.Buffer)
+	_ = metrics.WritePrometheus(buf)
+	for _, want := range []string{
+		`payutil_requests_total{provider="alipay",method="alipay.trade.precreate",status="200",code="",result="success"} 1`,
+		`payutil_requests_total{provider="alipay",method="alipay.trade.query",status="200",code="",result="success"} 1`,
+		`payutil_requests_total{provider="alipay",method="alipay.trade.query",status="200",code="ACQ.TRADE_NOT_EXIST",result="error"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("missing %s in\n%s", want, buf)
+		}
+	}
+
+	nt, err := srv.Pay("A001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(nt.Response) != "success" || len(events) != 1 || events[0].Type != notify.EventPayment || events[0].OutTradeNo != "A001" {
+		t.Fatalf("notify response = %s, events = %+v", nt.Response, events)
+	}
+	if order, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "A001"}); err != nil || order.Status != pay.TradeStatusPaid {
+		t.Fatalf("Query = %+v, %v", order, err)
+	}
+	if err = g.Close(ctx, &pay.OrderQuery{OutTradeNo: "A001"}); err == nil {
+		t.Fatal("Close paid trade should fail")
+	}
+
+	refund, err := g.Refund(ctx, &pay.Refund{OutTradeNo: "A001", OutRefundNo: "R001", Amount: 400})
+	if err != nil || refund.Status != pay.RefundStatusSuccess {
+		t.Fatalf("Refund = %+v, %v", refund, err)
+	}
+	if _, err = g.Refund(ctx, &pay.Refund{OutTradeNo: "A001", OutRefundNo: "R002", Amount: 700}); err == nil {
+		t.Fatal("Refund over amount should fail")
+	}
+	refund, err = g.QueryRefund(ctx, &pay.RefundQuery{OutTradeNo: "A001", OutRefundNo: "R001"})
+	if err != nil || refund.Status != pay.RefundStatusSuccess || refund.Amount != 400 {
+		t.Fatalf("QueryRefund = %+v, %v", refund, err)
+	}
+	if _, err = srv.NotifyRefund("A001", "R001"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Type != notify.EventRefund || events[1].OutRefundNo != "R001" {
+		t.Fatalf("events = %+v", events)
+	}
+	if d, ok := events[1].Data.(*alipay.NotifyEvent); !ok || d.Trade.RefundFee != "4.00" {
+		t.Fatalf("refund event data = %+v", events[1].Data)
+	}
+	// 非交易通知不作为支付事件分发
+	if _, err = srv.Notify(notifySrv.URL, make(pay.BodyMap).Set("notify_type", "dut_user_sign").Set("notify_id", "n1").
+		Set("agreement_no", "20215425001").Set("status", "NORMAL")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srv.Notify(notifySrv.URL, make(pay.BodyMap).Set("msg_method", "alipay.fund.trans.order.changed").Set("notify_id", "n2").
+		Set("biz_content", `{"out_biz_no":"T001","status":"SUCCESS"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 || events[2].Type != notify.EventUnknown || events[2].RawType != "dut_user_sign" ||
+		events[3].Type != notify.EventUnknown || events[3].RawType != "alipay.fund.trans.order.changed" {
+		t.Fatalf("events = %+v", events)
+	}
+
+	srv.FailNext("alipay.trade.query", "ACQ.SYSTEM_ERROR")
+	_, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "A001"})
+	if apiErr, ok := pay.AsAPIError(err); !ok || !apiErr.Retryable {
+		t.Fatalf("FailNext err = %v", err)
+	}
+
+	// 外币订单，退款查询应答不含币种，按 RefundQuery.Currency 解析金额
+	if _, err = g.Charge(ctx, &pay.Order{OutTradeNo: "A002", Subject: "test", Amount: 1000, Currency: "jpy", Scene: pay.SceneQRCode, NotifyUrl: notifySrv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if order, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "A002"}); err != nil || order.Amount != 1000 || order.Currency != "JPY" {
+		t.Fatalf("Query(JPY) = %+v, %v", order, err)
+	}
+	if _, err = srv.Pay("A002"); err != nil {
+		t.Fatal(err)
+	}
+	if refund, err = g.Refund(ctx, &pay.Refund{OutTradeNo: "A002", OutRefundNo: "R003", Amount: 400, Currency: "JPY"}); err != nil || refund.Currency != "JPY" {
+		t.Fatalf("Refund(JPY) = %+v, %v", refund, err)
+	}
+	refund, err = g.QueryRefund(ctx, &pay.RefundQuery{OutTradeNo: "A002", OutRefundNo: "R003", Currency: "JPY"})
+	if err != nil || refund.Amount != 400 || refund.Currency != "JPY" {
+		t.Fatalf("QueryRefund(JPY) = %+v, %v", refund, err)
+	}
+}
+
+func TestAlipayServer_SetLogger(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, key := paytest.NewRSAKeyPair()
+	srv.AppPublicKey = &key.PublicKey
+
+	lines := map[string][]string{}
+	newClient := func(appid string) *alipay.Client {
+		client, err := alipay.NewClient(appid, privateKey, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.SetBaseURL(srv.GatewayURL())
+		client.AutoVerifySign(srv.PublicKeyCert())
+		client.SetLogger(xlog.LoggerFunc(func(_ context.Context, level xlog.LogLevel, msg string, keyvals ...interface{}) {
+			lines[appid] = append(lines[appid], level.String()+" "+xlog.Format(msg, keyvals...))
+		}))
+		return client
+	}
+	a, b := newClient("2016091200494382"), newClient("2021000000000001")
+	if _, err := alipay.NewGateway(a).Query(ctx, &pay.OrderQuery{OutTradeNo: "A404"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("Query err = %v", err)
+	}
+	if _, err := alipay.NewGateway(b).Query(ctx, &pay.OrderQuery{OutTradeNo: "B404"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("Query err = %v", err)
+	}
+	for appid, no := range map[string]string{"2016091200494382": "A404", "2021000000000001": "B404"} {
+		if len(lines[appid]) != 1 {
+			t.Fatalf("lines[%s] = %q", appid, lines[appid])
+		}
+		want := "WARN pay request app_id=" + appid + " provider=alipay method=alipay.trade.query out_trade_no=" + no + " status=200 code=ACQ.TRADE_NOT_EXIST"
+		if !strings.HasPrefix(lines[appid][0], want) {
+			t.Fatalf("got %q, want prefix %q", lines[appid][0], want)
+		}
+	}
+}
+
+func TestAlipayServer_DebugRedact(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, _ := paytest.NewRSAKeyPair()
+	client, err := alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.GatewayURL())
+	client.DebugSwitch = pay.DebugOn
+	var logs []string
+	client.SetLogger(xlog.LoggerFunc(func(_ context.Context, level xlog.LogLevel, msg string, keyvals ...interface{}) {
+		logs = append(logs, xlog.Format(msg, keyvals...))
+	}))
+
+	bm := make(pay.BodyMap)
+	bm.Set("out_biz_no", "T001").Set("trans_amount", "1.00").Set("product_code", "TRANS_ACCOUNT_NO_PWD").Set("biz_scene", "DIRECT_TRANSFER").
+		SetBodyMap("payee_info", func(b pay.BodyMap) {
+			b.Set("identity", "13812345678").Set("identity_type", "ALIPAY_LOGON_ID").
+				Set("cert_no", "110101199001011234").Set("cert_type", "IDENTITY_CARD")
+		})
+	_, _ = client.FundTransUniTransfer(ctx, bm)
+	var found bool
+	for _, l := range logs {
+		if strings.Contains(l, "110101199001011234") || strings.Contains(l, "13812345678") {
+			t.Fatalf("debug log leaks biz_content: %s", l)
+		}
+		found = found || strings.Contains(l, "Alipay_Request")
+	}
+	if !found {
+		t.Fatalf("logs = %q", logs)
+	}
+}
+
+func TestAlipayServer_Registry(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, key := paytest.NewRSAKeyPair()
+	srv.AppPublicKey = &key.PublicKey
+
+	r := pay.NewRegistry(pay.CredentialProviderFunc(func(_ context.Context, provider, appid string) (*pay.Credential, error) {
+		return &pay.Credential{Provider: provider, MerchantId: appid, PrivateKey: privateKey, PublicKey: string(srv.PublicKeyCert()), BaseURL: srv.GatewayURL()}, nil
+	}))
+	metrics := observe.NewMetrics()
+	alipay.Register(r, func(c *alipay.Client) { c.Use(metrics.Middleware()) })
+
+	client, err := alipay.FromRegistry(ctx, r, "2016091200494382")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := alipay.FromRegistry(ctx, r, "2016091200494382"); again != client || client.AppId != "2016091200494382" {
+		t.Fatalf("FromRegistry() = %p, want cached %p", again, client)
+	}
+	if _, err = alipay.NewGateway(client).Query(ctx, &pay.OrderQuery{OutTradeNo: "A404"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("Query err = %v", err)
+	}
+	buf := new(bytes.Buffer)
+	_ = metrics.WritePrometheus(buf)
+	if !strings.Contains(buf.String(), `code="ACQ.TRADE_NOT_EXIST"`) {
+		t.Fatalf("middleware not applied:\n%s", buf)
+	}
+}
+
+func TestAlipayServer_AESKey(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, key := paytest.NewRSAKeyPair()
+	srv.AppPublicKey = &key.PublicKey
+	aesKey := "TDftre9FpItr46e9BVNJcw=="
+	srv.AESKey = aesKey
+
+	client, err := alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.GatewayURL())
+	client.AutoVerifySign(srv.PublicKeyCert())
+	client.SetAESKey(aesKey)
+
+	bm := make(pay.BodyMap)
+	bm.Set("out_trade_no", "E001").Set("subject", "test").Set("total_amount", "0.01")
+	if _, err = client.TradePrecreate(ctx, bm); err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := client.TradeQuery(ctx, make(pay.BodyMap).Set("out_trade_no", "E001"))
+	if err != nil || rsp.Response.OutTradeNo != "E001" || rsp.Response.TradeStatus != paytest.AlipayWaitBuyerPay {
+		t.Fatalf("TradeQuery = %+v, %v", rsp, err)
+	}
+	// 同步验签内容为应答密文
+	if !strings.HasPrefix(rsp.SignData, `"`) {
+		t.Fatalf("SignData = %s, want encrypted content", rsp.SignData)
+	}
+	if ok, err := alipay.VerifySyncSign(srv.PublicKey(), rsp.SignData, rsp.Sign); !ok || err != nil {
+		t.Fatalf("VerifySyncSign() = %v, %v", ok, err)
+	}
+	g := alipay.NewGateway(client)
+	if _, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "E404"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("Query not exist err = %v", err)
+	}
+
+	// 密钥不一致时支付宝返回未加密的错误应答
+	client.SetAESKey("AAAAAAAAAAAAAAAAAAAAAA==")
+	if _, err = client.TradeQuery(ctx, make(pay.BodyMap).Set("out_trade_no", "E001")); err == nil || !strings.Contains(err.Error(), "isv.") {
+		t.Fatalf("TradeQuery(wrong key) err = %v", err)
+	}
+	client.SetAESKey(aesKey)
+
+	// 小程序 my.getPhoneNumber 加密数据
+	content, err := srv.OpenData(alipay.AES, pay.BodyMap{"code": "10000", "msg": "Success", "mobile": "13800000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone := new(alipay.UserPhone)
+	if err = client.DecryptOpenData(content, phone); err != nil || phone.Mobile != "13800000000" {
+		t.Fatalf("DecryptOpenData() = %+v, %v", phone, err)
+	}
+	encrypted, err := alipay.EncryptContent([]byte(`{"code":"10000","msg":"Success","mobile":"13800000000"}`), aesKey, alipay.AES)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 仅密文、缺少 sign、签名错误均拒绝
+	for _, c := range []string{
+		encrypted,
+		`{"response":"` + encrypted + `","sign_type":"RSA2"}`,
+		`{"response":"` + encrypted + `","sign":"aW52YWxpZA==","sign_type":"RSA2","encrypt_type":"AES","charset":"UTF-8"}`,
+	} {
+		if err = client.DecryptOpenData(c, phone); err == nil {
+			t.Fatalf("DecryptOpenData(%s) err = nil", c)
+		}
+	}
+	if err = client.DecryptOpenData(`{"response":"`+encrypted+`","sign":"aW52YWxpZA=="}`, phone); !errors.Is(err, pay.VerifySignatureErr) {
+		t.Fatalf("DecryptOpenData(invalid sign) = %v, want VerifySignatureErr", err)
+	}
+	// 未设置支付宝公钥时不接受未验签的数据
+	unverified, _ := alipay.NewClient("2016091200494382", privateKey, false)
+	unverified.SetAESKey(aesKey)
+	if err = unverified.DecryptOpenData(content, phone); err == nil {
+		t.Fatal("DecryptOpenData() without AutoVerifySign() err = nil")
+	}
+	phone = new(alipay.UserPhone)
+	if err = unverified.DecryptOpenDataUnsigned(encrypted, phone); err != nil || phone.Mobile != "13800000000" {
+		t.Fatalf("DecryptOpenDataUnsigned() = %+v, %v", phone, err)
+	}
+	// 解析失败的错误信息不含解密后的明文
+	invalid, _ := alipay.EncryptContent([]byte(`mobile=13800000000`), aesKey, alipay.AES)
+	if err = client.DecryptOpenDataUnsigned(invalid, phone); !errors.Is(err, pay.UnmarshalErr) || strings.Contains(err.Error(), "13800000000") {
+		t.Fatalf("DecryptOpenDataUnsigned(invalid json) = %v", err)
+	}
+}
+
+func TestAlipayServer_EncryptedResponseFields(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, key := paytest.NewRSAKeyPair()
+	srv.AppPublicKey = &key.PublicKey
+	aesKey := "TDftre9FpItr46e9BVNJcw=="
+	srv.AESKey = aesKey
+	// 应答前插入以 _response 结尾的嵌套字段
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := http.Post(srv.GatewayURL()+"?"+r.URL.RawQuery, r.Header.Get("Content-Type"), r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(res.Body)
+		body := bytes.Replace(buf.Bytes(), []byte(`{`), []byte(`{"extra":{"biz_response":"x","encrypted_response":"y"},`), 1)
+		_, _ = w.Write(body)
+	}))
+	defer proxy.Close()
+
+	client, err := alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(proxy.URL)
+	client.AutoVerifySign(srv.PublicKeyCert())
+	client.SetAESKey(aesKey)
+
+	bm := make(pay.BodyMap)
+	bm.Set("out_trade_no", "E001").Set("subject", "test").Set("total_amount", "0.01")
+	if _, err = client.TradePrecreate(ctx, bm); err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := client.TradeQuery(ctx, make(pay.BodyMap).Set("out_trade_no", "E001"))
+	if err != nil || rsp.Response.OutTradeNo != "E001" {
+		t.Fatalf("TradeQuery = %+v, %v", rsp, err)
+	}
+}
+
+func TestAlipayServer_SM4Key(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, key := paytest.NewRSAKeyPair()
+	srv.AppPublicKey = &key.PublicKey
+	sm4Key := "TDftre9FpItr46e9BVNJcw=="
+	srv.AESKey = sm4Key
+
+	client, err := alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.GatewayURL())
+	client.AutoVerifySign(srv.PublicKeyCert())
+	client.SetEncryptKey(sm4Key, alipay.SM4)
+
+	bm := make(pay.BodyMap)
+	bm.Set("out_trade_no", "S001").Set("subject", "test").Set("total_amount", "0.01")
+	if _, err = client.TradePrecreate(ctx, bm); err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := client.TradeQuery(ctx, make(pay.BodyMap).Set("out_trade_no", "S001"))
+	if err != nil || rsp.Response.OutTradeNo != "S001" {
+		t.Fatalf("TradeQuery = %+v, %v", rsp, err)
+	}
+
+	content, err := srv.OpenData(alipay.SM4, pay.BodyMap{"code": "10000", "msg": "Success", "mobile": "13800000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone := new(alipay.UserPhone)
+	if err = client.DecryptOpenData(content, phone); err != nil || phone.Mobile != "13800000000" {
+		t.Fatalf("DecryptOpenData() = %+v, %v", phone, err)
+	}
+}
+
+func TestAlipayServer_ParseAndVerifyNotify(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, _ := paytest.NewRSAKeyPair()
+	client, err := alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.GatewayURL())
+	client.AutoVerifySign([]byte(srv.PublicKey()))
+
+	var (
+		events []*alipay.NotifyEvent
+		errs   []error
+	)
+	expect := &alipay.NotifyExpect{SellerId: srv.SellerId, TotalAmount: "10"}
+	notifySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e, err := client.ParseAndVerifyNotify(r, expect)
+		if err != nil {
+			errs = append(errs, err)
+			_, _ = w.Write([]byte("fail"))
+			return
+		}
+		events = append(events, e)
+		_, _ = w.Write([]byte("success"))
+	}))
+	defer notifySrv.Close()
+
+	bm := make(pay.BodyMap)
+	bm.Set("out_trade_no", "N001").Set("subject", "test").Set("total_amount", "10.00").Set("notify_url", notifySrv.URL+"?charset=utf-8&out_biz_no=R999")
+	if _, err = client.TradePrecreate(ctx, bm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srv.Pay("N001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.TradeRefund(ctx, make(pay.BodyMap).Set("out_trade_no", "N001").Set("refund_amount", "1").Set("out_request_no", "R001")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srv.NotifyRefund("N001", "R001"); err != nil {
+		t.Fatal(err)
+	}
+	sign := make(pay.BodyMap)
+	sign.Set("notify_type", "dut_user_sign").Set("notify_id", "n1").Set("app_id", client.AppId).
+		Set("agreement_no", "20215425001").Set("external_agreement_no", "E001").Set("status", "NORMAL").
+		Set("seller_id", srv.SellerId).Set("total_amount", "10")
+	if _, err = srv.Notify(notifySrv.URL, sign); err != nil {
+		t.Fatal(err)
+	}
+	expect = nil
+	transfer := make(pay.BodyMap)
+	transfer.Set("msg_method", "alipay.fund.trans.order.changed").Set("notify_id", "n2").Set("app_id", client.AppId).
+		Set("utc_timestamp", "1514210452731").
+		Set("biz_content", `{"out_biz_no":"T001","order_id":"20190801110070000006380000250621","status":"SUCCESS","trans_amount":"1.00"}`)
+	if _, err = srv.Notify(notifySrv.URL, transfer); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 || len(events) != 4 {
+		t.Fatalf("events = %d, errs = %v", len(events), errs)
+	}
+	if e := events[0]; e.Type != alipay.NotifyTradeStatus || e.Trade.TradeStatus != paytest.AlipayTradeSuccess || e.Trade.OutTradeNo != "N001" || e.AppId != client.AppId {
+		t.Fatalf("trade event = %+v, %+v", e, e.Trade)
+	}
+	// notify_url 中的查询参数不参与验签，也不出现在 Values 中
+	if got := events[0].Values; len(got["charset"]) != 1 || got.Get("out_biz_no") != "" {
+		t.Fatalf("Values = %v", got)
+	}
+	if e := events[1]; e.Type != alipay.NotifyRefund || e.Trade.OutBizNo != "R001" || e.Trade.RefundFee != "1.00" {
+		t.Fatalf("refund event = %+v, %+v", e, e.Trade)
+	}
+	if e := events[2]; e.Type != alipay.NotifyAgreementSign || e.Agreement.AgreementNo != "20215425001" {
+		t.Fatalf("agreement event = %+v", e)
+	}
+	if e := events[3]; e.Type != alipay.NotifyTransferChanged || e.Transfer.OutBizNo != "T001" || e.Transfer.Status != "SUCCESS" || e.NotifyTime != "1514210452731" {
+		t.Fatalf("transfer event = %+v", e)
+	}
+
+	// 金额、卖家不一致
+	if err = events[0].Check(&alipay.NotifyExpect{TotalAmount: "9.99"}); !errors.Is(err, pay.NotifyMismatchErr) {
+		t.Fatalf("Check(total_amount) = %v", err)
+	}
+	if err = events[0].Check(&alipay.NotifyExpect{SellerId: "2088000000000002"}); !errors.Is(err, pay.NotifyMismatchErr) {
+		t.Fatalf("Check(seller_id) = %v", err)
+	}
+	// 按 trans_currency 精度比较金额
+	kwd := &alipay.NotifyEvent{BodyMap: make(pay.BodyMap).Set("total_amount", "1.005").Set("trans_currency", "KWD")}
+	if err = kwd.Check(&alipay.NotifyExpect{TotalAmount: "1.005"}); err != nil {
+		t.Fatalf("Check(KWD) = %v", err)
+	}
+	if err = kwd.Check(&alipay.NotifyExpect{TotalAmount: "1.006"}); !errors.Is(err, pay.NotifyMismatchErr) {
+		t.Fatalf("Check(KWD mismatch) = %v", err)
+	}
+	// 请求体中重复的参数
+	dupSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r.Body)
+		buf.WriteString("&total_amount=0.01")
+		r.Body = io.NopCloser(&buf)
+		r.ContentLength = int64(buf.Len())
+		if _, err := client.ParseAndVerifyNotify(r); err != nil {
+			errs = append(errs, err)
+			_, _ = w.Write([]byte("fail"))
+			return
+		}
+		_, _ = w.Write([]byte("success"))
+	}))
+	defer dupSrv.Close()
+	if _, err = srv.Notify(dupSrv.URL, sign); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], pay.VerifySignatureErr) {
+		t.Fatalf("errs = %v, want VerifySignatureErr", errs)
+	}
+	errs = nil
+	// 篡改通知
+	other := paytest.NewAlipayServer()
+	defer other.Close()
+	if _, err = other.Notify(notifySrv.URL, make(pay.BodyMap).Set("notify_type", "trade_status_sync").Set("out_trade_no", "N001")); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], pay.VerifySignatureErr) {
+		t.Fatalf("errs = %v, want VerifySignatureErr", errs)
+	}
+}
+
+func TestAlipayServer_MessageReceiver(t *testing.T) {
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, _ := paytest.NewRSAKeyPair()
+	client, err := alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.AutoVerifySign([]byte(srv.PublicKey()))
+
+	var msgs []*alipay.Message
+	r := alipay.NewMessageReceiver(client).
+		Register("alipay.user.agreement.page.sign", alipay.AgreementNotify{}).
+		On("alipay.fund.trans.order.changed", func(ctx context.Context, msg *alipay.Message) error {
+			msgs = append(msgs, msg)
+			if msg.Data.(*alipay.TransferNotify).Status == "FAIL" {
+				return errors.New("retry later")
+			}
+			return nil
+		}).
+		On(alipay.MessageAll, func(ctx context.Context, msg *alipay.Message) error {
+			msgs = append(msgs, msg)
+			return nil
+		})
+	msgSrv := httptest.NewServer(r)
+	defer msgSrv.Close()
+
+	message := func(method, notifyId, bizContent string) pay.BodyMap {
+		bm := make(pay.BodyMap)
+		return bm.Set("msg_method", method).Set("notify_id", notifyId).Set("app_id", client.AppId).
+			Set("utc_timestamp", "1514210452731").Set("version", "1.1").Set("charset", "utf-8").
+			Set("biz_content", bizContent)
+	}
+	cases := []struct {
+		bm  pay.BodyMap
+		ack string
+	}{
+		{message("alipay.fund.trans.order.changed", "m1", `{"out_biz_no":"T001","status":"SUCCESS","trans_amount":"1.00"}`), alipay.MessageAckSuccess},
+		{message("alipay.fund.trans.order.changed", "m2", `{"out_biz_no":"T002","status":"FAIL"}`), alipay.MessageAckFail},
+		{message("ant.merchant.expand.indirect.zft.rejected", "m3", `{"order_id":"2021001","external_id":"E001","reason":"证照模糊"}`), alipay.MessageAckSuccess},
+		{message("alipay.user.agreement.page.sign", "m4", `{"agreement_no":"20215425001","status":"NORMAL"}`), alipay.MessageAckSuccess},
+		{message("alipay.open.mini.version.audit.passed", "m5", `{"mini_app_id":"2021002"}`), alipay.MessageAckSuccess},
+	}
+	for _, c := range cases {
+		n, err := srv.Notify(msgSrv.URL, c.bm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(n.Response) != c.ack {
+			t.Fatalf("%s ack = %s, want %s", c.bm.GetString("notify_id"), n.Response, c.ack)
+		}
+	}
+	if len(msgs) != 5 {
+		t.Fatalf("messages = %d", len(msgs))
+	}
+	if d := msgs[0].Data.(*alipay.TransferNotify); d.OutBizNo != "T001" || d.TransAmount != "1.00" || msgs[0].NotifyId != "m1" {
+		t.Fatalf("transfer = %+v", d)
+	}
+	if d := msgs[2].Data.(*alipay.ZftAuditMessage); d.OrderId != "2021001" || d.Reason != "证照模糊" {
+		t.Fatalf("zft audit = %+v", d)
+	}
+	if d := msgs[3].Data.(*alipay.AgreementNotify); d.AgreementNo != "20215425001" {
+		t.Fatalf("agreement = %+v", d)
+	}
+	if d := msgs[4].Data.(pay.BodyMap); d.GetString("mini_app_id") != "2021002" {
+		t.Fatalf("unregistered = %+v", d)
+	}
+
+	// 长连接通道的消息
+	bm := message("alipay.fund.trans.order.changed", "m6", `{"out_biz_no":"T003","status":"SUCCESS"}`)
+	bm.Set("sign", "invalid").Set("sign_type", "RSA2")
+	values := make(url.Values)
+	for k := range bm {
+		values.Set(k, bm.GetString(k))
+	}
+	if err = r.Receive(context.Background(), values); !errors.Is(err, pay.VerifySignatureErr) || alipay.MessageAck(err) != alipay.MessageAckFail {
+		t.Fatalf("Receive(invalid sign) = %v", err)
+	}
+	values.Add("notify_id", "m7")
+	if err = r.Receive(context.Background(), values); !errors.Is(err, pay.VerifySignatureErr) || !strings.Contains(err.Error(), "duplicate") {
+		t.Fatalf("Receive(duplicate) = %v", err)
+	}
+
+	// 其它应用的消息（同一支付宝公钥签名）应答 fail，SetAppIds() 后接收
+	other := func() pay.BodyMap {
+		return message("alipay.fund.trans.order.changed", "m9", `{"out_biz_no":"T005","status":"SUCCESS"}`).Set("app_id", "2021000000000001")
+	}
+	if n, err := srv.Notify(msgSrv.URL, other()); err != nil || string(n.Response) != alipay.MessageAckFail || len(msgs) != 5 {
+		t.Fatalf("other app ack = %v, %v, messages = %d", n, err, len(msgs))
+	}
+	r.SetAppIds(client.AppId, "2021000000000001")
+	if n, err := srv.Notify(msgSrv.URL, other()); err != nil || string(n.Response) != alipay.MessageAckSuccess || len(msgs) != 6 {
+		t.Fatalf("allowed app ack = %v, %v, messages = %d", n, err, len(msgs))
+	}
+
+	// 未注册回调的消息应答 fail
+	empty := httptest.NewServer(alipay.NewMessageReceiver(client))
+	defer empty.Close()
+	n, err := srv.Notify(empty.URL, message("alipay.fund.trans.order.changed", "m8", `{"out_biz_no":"T004","status":"SUCCESS"}`))
+	if err != nil || string(n.Response) != alipay.MessageAckFail {
+		t.Fatalf("no handler ack = %v, %v", n, err)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// 支付宝 v3 协议请求头、应答头
+const (
+	alipayV3Scheme    = "ALIPAY-SHA256withRSA"
+	alipayV3RequestID = "alipay-request-id"
+)
+
+// alipayV3Reply 按幂等键 alipay-request-id 保存的应答
+type alipayV3Reply struct {
+	status int
+	body   []byte
+}
+
+// PublicCertSN 支付宝公钥证书SN（md5(issuer + serial_number)），即 v3 应答头 alipay-sn
+func (s *AlipayServer) PublicCertSN() string {
+	return alipayCertSN(s.cert.Issuer.String(), s.cert.SerialNumber.String())
+}
+
+func alipayCertSN(issuer, serialNumber string) string {
+	h := md5.Sum([]byte(issuer + serialNumber))
+	return hex.EncodeToString(h[:])
+}
+
+// serveV3 支付宝 v3 协议：/v3/alipay/trade/query 对应 alipay.trade.query，JSON 请求体，应答头 alipay-signature 签名
+//
+//	设置了 AppPublicKey 时校验 Authorization，nonce 重复使用时返回 401，相同 alipay-request-id 的请求返回首次应答
+func (s *AlipayServer) serveV3(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.AppPublicKey != nil {
+		if err = s.verifyV3Authorization(r, body); err != nil {
+			s.writeV3(w, http.StatusUnauthorized, alipayErr("40002", "Invalid Arguments", "isv.invalid-signature", err.Error()))
+			return
+		}
+	}
+	requestId := r.Header.Get(alipayV3RequestID)
+	s.mu.Lock()
+	if reply := s.v3Replies[requestId]; requestId != util.NULL && reply != nil {
+		s.mu.Unlock()
+		s.writeV3Raw(w, reply.status, reply.body)
+		return
+	}
+	s.mu.Unlock()
+
+	biz := make(pay.BodyMap)
+	if r.Method == http.MethodGet {
+		for k := range r.URL.Query() {
+			biz.Set(k, r.URL.Query().Get(k))
+		}
+	} else if len(body) > 0 {
+		if err = json.Unmarshal(body, &biz); err != nil {
+			s.writeV3(w, http.StatusBadRequest, alipayErr("40002", "Invalid Arguments", "INVALID_PARAMETER", "请求体格式错误"))
+			return
+		}
+	}
+	method := strings.ReplaceAll(strings.TrimPrefix(r.URL.Path, "/v3/"), "/", ".")
+	s.mu.Lock()
+	subCode, fail := s.fails[method]
+	delete(s.fails, method)
+	var rsp pay.BodyMap
+	switch {
+	case fail:
+		rsp = alipayBizErr(subCode)
+	case method == "alipay.trade.precreate", method == "alipay.trade.create", method == "alipay.trade.pay":
+		rsp = s.create(method, biz, biz)
+	case method == "alipay.trade.query":
+		rsp = s.query(biz)
+	case method == "alipay.trade.close":
+		rsp = s.close(biz)
+	case method == "alipay.trade.refund":
+		rsp = s.refund(biz)
+	case method == "alipay.trade.fastpay.refund.query":
+		rsp = s.refundQuery(biz)
+	case method == "alipay.data.dataservice.bill.downloadurl.query":
+		rsp = make(pay.BodyMap)
+		rsp.Set("bill_download_url", s.URL+"/bill/"+biz.GetString("bill_type")+"_"+biz.GetString("bill_date")+".csv.zip")
+	default:
+		rsp = alipayErr("40004", "Business Failed", "INVALID_METHOD", "不存在的接口")
+	}
+	status, bs := alipayV3Response(rsp)
+	if requestId != util.NULL && status != http.StatusInternalServerError {
+		s.v3Replies[requestId] = &alipayV3Reply{status: status, body: bs}
+	}
+	s.mu.Unlock()
+	s.writeV3Raw(w, status, bs)
+}
+
+// alipayV3Response 业务错误转换为 v3 错误应答 {"code":"<sub_code>","message":"<sub_msg>"}
+func alipayV3Response(rsp pay.BodyMap) (int, []byte) {
+	code := rsp.GetString("code")
+	if code == util.NULL || code == "10000" {
+		bs, _ := json.Marshal(rsp)
+		return http.StatusOK, bs
+	}
+	status := http.StatusBadRequest
+	switch {
+	case code == "20000":
+		status = http.StatusInternalServerError
+	case rsp.GetString("sub_code") == "isv.invalid-signature":
+		status = http.StatusUnauthorized
+	}
+	bs, _ := json.Marshal(map[string]string{"code": rsp.GetString("sub_code"), "message": rsp.GetString("sub_msg")})
+	return status, bs
+}
+
+func (s *AlipayServer) writeV3(w http.ResponseWriter, status int, rsp pay.BodyMap) {
+	st, bs := alipayV3Response(rsp)
+	if st != http.StatusOK {
+		status = st
+	}
+	s.writeV3Raw(w, status, bs)
+}
+
+// writeV3Raw 应答签名：alipay-timestamp\nalipay-nonce\n应答内容\n
+func (s *AlipayServer) writeV3Raw(w http.ResponseWriter, status int, body []byte) {
+	ts := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	nonce := util.RandomString(32)
+	h := w.Header()
+	h.Set("Content-Type", "application/json;charset=utf-8")
+	h.Set("alipay-trace-id", newId(""))
+	h.Set("alipay-timestamp", ts)
+	h.Set("alipay-nonce", nonce)
+	h.Set("alipay-sn", s.PublicCertSN())
+	h.Set("alipay-signature", signSHA256WithRSA(s.key, ts+"\n"+nonce+"\n"+string(body)+"\n"))
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// verifyV3Authorization 校验 ALIPAY-SHA256withRSA app_id=,app_cert_sn=,nonce=,timestamp=,sign=
+//
+//	签名内容：app_id=...,timestamp=...\n请求方法\n请求路径（含参数）\n请求体\n[app_auth_token\n]
+func (s *AlipayServer) verifyV3Authorization(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, alipayV3Scheme+" ") {
+		return errors.New("Authorization 格式错误")
+	}
+	auth = strings.TrimPrefix(auth, alipayV3Scheme+" ")
+	i := strings.LastIndex(auth, ",sign=")
+	if i < 0 {
+		return errors.New("Authorization 缺少 sign")
+	}
+	authString, sign := auth[:i], auth[i+len(",sign="):]
+	content := authString + "\n" + r.Method + "\n" + r.URL.RequestURI() + "\n" + string(body) + "\n"
+	if token := r.Header.Get("alipay-app-auth-token"); token != util.NULL {
+		content += token + "\n"
+	}
+	if err := verifySHA256WithRSA(s.AppPublicKey, content, sign); err != nil {
+		return errors.New("验签出错")
+	}
+	var nonce string
+	for _, kv := range strings.Split(authString, ",") {
+		if strings.HasPrefix(kv, "nonce=") {
+			nonce = strings.TrimPrefix(kv, "nonce=")
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.v3Nonces[nonce] {
+		return errors.New("nonce 重复使用")
+	}
+	s.v3Nonces[nonce] = true
+	return nil
+}
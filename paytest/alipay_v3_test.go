@@ -0,0 +1,146 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+	alipayv3 "github.com/rwscode/payutil/alipay/v3"
+	"github.com/rwscode/payutil/paytest"
+	"github.com/rwscode/payutil/pkg/retry"
+)
+
+func TestAlipayServer_V3(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAlipayServer()
+	defer srv.Close()
+	privateKey, _, key := paytest.NewRSAKeyPair()
+	srv.AppPublicKey = &key.PublicKey
+
+	client, err := alipayv3.NewClientV3("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.URL)
+	client.SetAppAuthToken("202301BBd0b4a1e5e0b94f97b0e2e6a7d8c9e000")
+	appCert, _ := paytest.NewClientCert()
+	if err = client.SetCertSnByContent(appCert, srv.PublicKeyCert(), srv.PublicKeyCert()); err != nil {
+		t.Fatal(err)
+	}
+	if client.AliPayPublicCertSN != srv.PublicCertSN() {
+		t.Fatalf("AliPayPublicCertSN = %s, want %s", client.AliPayPublicCertSN, srv.PublicCertSN())
+	}
+	if err = client.AutoVerifySign(srv.PublicKeyCert()); err != nil {
+		t.Fatal(err)
+	}
+
+	var notified pay.BodyMap
+	notifySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bm, err := client.V3ParseNotify(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		notified = bm
+		_, _ = w.Write([]byte("success"))
+	}))
+	defer notifySrv.Close()
+
+	pre, err := client.V3TradePrecreate(ctx, pay.BodyMap{"out_trade_no": "V3001", "total_amount": "10.00", "subject": "test", "notify_url": notifySrv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pre.Code != alipayv3.Success || pre.Response.QrCode == "" || pre.SignInfo.HeaderSN != srv.PublicCertSN() {
+		t.Fatalf("V3TradePrecreate = %+v, %s", pre.Response, pre.Error)
+	}
+	nt, err := srv.Pay("V3001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nt.StatusCode != http.StatusOK || notified.GetString("trade_status") != paytest.AlipayTradeSuccess {
+		t.Fatalf("notify status = %d, %s, bm = %v", nt.StatusCode, nt.Response, notified)
+	}
+	q, err := client.V3TradeQuery(ctx, pay.BodyMap{"out_trade_no": "V3001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Response.TradeStatus != paytest.AlipayTradeSuccess || q.Response.TotalAmount != "10.00" {
+		t.Fatalf("V3TradeQuery = %+v", q.Response)
+	}
+
+	// 相同幂等键的退款只处理一次
+	rctx := alipayv3.WithRequestId(ctx, "refund-V3001-1")
+	for _, outRequestNo := range []string{"R001", "R002"} {
+		rf, err := client.V3TradeRefund(rctx, pay.BodyMap{"out_trade_no": "V3001", "refund_amount": "3.00", "out_request_no": outRequestNo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rf.Code != alipayv3.Success || rf.Response.RefundFee != "3.00" {
+			t.Fatalf("V3TradeRefund %s = %+v, %s", outRequestNo, rf.Response, rf.Error)
+		}
+	}
+	rq, err := client.V3TradeRefundQuery(ctx, pay.BodyMap{"out_trade_no": "V3001", "out_request_no": "R002"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rq.Code != alipayv3.Success || rq.Response.RefundStatus != "" {
+		t.Fatalf("V3TradeRefundQuery R002 = %+v, want no refund", rq.Response)
+	}
+
+	// 业务错误转换为 pay.APIError
+	nq, err := client.V3TradeQuery(ctx, pay.BodyMap{"out_trade_no": "NOT_EXIST"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nq.Code != http.StatusBadRequest || !errors.Is(alipayv3.NewAPIError(nq.Code, nq.Error), pay.OrderNotExistErr) {
+		t.Fatalf("V3TradeQuery NOT_EXIST = %d, %s", nq.Code, nq.Error)
+	}
+
+	// 查询接口按策略重试 SYSTEM_ERROR
+	client.SetRetryPolicy(&retry.Policy{MaxAttempts: 2})
+	srv.FailNext("alipay.trade.query", "ACQ.SYSTEM_ERROR")
+	if q, err = client.V3TradeQuery(ctx, pay.BodyMap{"out_trade_no": "V3001"}); err != nil || q.Code != alipayv3.Success {
+		t.Fatalf("V3TradeQuery retry = %+v, %v", q, err)
+	}
+
+	bill, err := client.V3BillDownloadUrlQuery(ctx, pay.BodyMap{"bill_type": "trade", "bill_date": "2023-01-01"})
+	if err != nil || bill.Response.BillDownloadUrl == "" {
+		t.Fatalf("V3BillDownloadUrlQuery = %+v, %v", bill, err)
+	}
+
+	// 应用私钥不匹配时返回 401
+	_, _, other := paytest.NewRSAKeyPair()
+	srv.AppPublicKey = &other.PublicKey
+	if q, err = client.V3TradeQuery(ctx, pay.BodyMap{"out_trade_no": "V3001"}); err != nil || q.Code != http.StatusUnauthorized {
+		t.Fatalf("V3TradeQuery with wrong key = %+v, %v", q, err)
+	}
+	if !errors.Is(alipayv3.NewAPIError(q.Code, q.Error), pay.AuthFailedErr) {
+		t.Fatalf("NewAPIError(%d, %s) is not AuthFailedErr", q.Code, q.Error)
+	}
+	srv.AppPublicKey = nil
+
+	// 支付宝公钥不匹配时同步验签失败
+	fake := paytest.NewAlipayServer()
+	defer fake.Close()
+	if err = client.AutoVerifySign(fake.PublicKeyCert()); err != nil {
+		t.Fatal(err)
+	}
+	client.AliPayPublicCertSN = ""
+	if _, err = client.V3TradeQuery(ctx, pay.BodyMap{"out_trade_no": "V3001"}); !errors.Is(err, pay.VerifySignatureErr) {
+		t.Fatalf("V3TradeQuery with wrong alipay public key err = %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rwscode/payutil/pkg/jwt"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// AppleTransaction 模拟的 App Store 交易，编码为 JWSTransactionDecodedPayload
+type AppleTransaction struct {
+	TransactionId               string
+	OriginalTransactionId       string // 为空时同 TransactionId
+	ProductId                   string
+	Type                        string // Auto-Renewable Subscription、Consumable、Non-Consumable、Non-Renewing Subscription，默认 Consumable
+	AppAccountToken             string // 商户订单标识（UUID）
+	SubscriptionGroupIdentifier string
+	Quantity                    int
+	PurchaseDate                time.Time
+	ExpiresDate                 time.Time // 订阅到期时间
+	RevocationDate              time.Time // 退款时间
+}
+
+// AppleServer 模拟 App Store Server API 及 App Store Server Notifications V2
+//
+//	使用自建的 ECDSA 根证书 -> 中间证书 -> 叶子证书签发 JWS，x5c 顺序同 Apple，
+//	使用 apple.NewClient().SetBaseURL(srv.URL) 请求，使用 &apple.VerifyOptions{RootCAs: srv.RootCAs()} 验签，
+//	请求 token 需使用 srv.SignConfig() 签名
+type AppleServer struct {
+	*httptest.Server
+	notifier
+
+	BundleID    string
+	IssuerID    string
+	KeyID       string
+	Environment string // 默认 Sandbox
+
+	apiKey       *ecdsa.PrivateKey // App Store Connect API 密钥
+	leafKey      *ecdsa.PrivateKey
+	root         *x509.Certificate
+	intermediate *x509.Certificate
+	leaf         *x509.Certificate
+
+	mu           sync.Mutex
+	transactions map[string]*AppleTransaction // key: transactionId
+}
+
+// NewAppleServer 启动 App Store 模拟服务
+func NewAppleServer(bundleID string) *AppleServer {
+	s := &AppleServer{
+		BundleID:     bundleID,
+		IssuerID:     "57246542-96fe-1a63-e053-0824d011072a",
+		KeyID:        strings.ToUpper(util.RandomString(10)),
+		Environment:  "Sandbox",
+		apiKey:       newECKey(),
+		leafKey:      newECKey(),
+		transactions: make(map[string]*AppleTransaction),
+	}
+	rootKey, interKey := newECKey(), newECKey()
+	s.root = newCert("paytest Apple Root CA", &rootKey.PublicKey, rootKey, nil, true)
+	s.intermediate = newCert("paytest Apple Worldwide Developer Relations", &interKey.PublicKey, rootKey, s.root, true)
+	s.leaf = newCert("paytest Prod ECC Mac App Store and iTunes Store Receipt Signing", &s.leafKey.PublicKey, interKey, s.intermediate, false)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func newECKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("paytest: ecdsa.GenerateKey: %v", err))
+	}
+	return key
+}
+
+// RootCAs 模拟根证书，用于 apple.VerifyOptions、notify.AppleSource.VerifyOptions
+func (s *AppleServer) RootCAs() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.root)
+	return pool
+}
+
+// PrivateKeyPEM App Store Connect API 密钥（.p8 文件内容），用于 apple.SignConfig.ApplePrivateKey
+func (s *AppleServer) PrivateKeyPEM() string {
+	return string(privateKeyPEM(s.apiKey))
+}
+
+// AddTransaction 添加交易，GetTransactionHistory、GetAllSubscriptionStatuses 按 OriginalTransactionId 返回
+func (s *AppleServer) AddTransaction(t *AppleTransaction) {
+	if t.OriginalTransactionId == util.NULL {
+		t.OriginalTransactionId = t.TransactionId
+	}
+	if t.Type == util.NULL {
+		t.Type = "Consumable"
+	}
+	if t.Quantity == 0 {
+		t.Quantity = 1
+	}
+	if t.PurchaseDate.IsZero() {
+		t.PurchaseDate = time.Now()
+	}
+	s.mu.Lock()
+	s.transactions[t.TransactionId] = t
+	s.mu.Unlock()
+}
+
+// Notify 向 url 发送 App Store Server Notifications V2，携带 transactionId 对应交易的 signedTransactionInfo，订阅交易同时携带 signedRenewalInfo
+func (s *AppleServer) Notify(url, notificationType, subtype, transactionId string) (*Notification, error) {
+	s.mu.Lock()
+	t := s.transactions[transactionId]
+	s.mu.Unlock()
+	if t == nil {
+		return nil, fmt.Errorf("paytest: apple transaction %s not exist", transactionId)
+	}
+	data := map[string]interface{}{
+		"bundleId":              s.BundleID,
+		"bundleVersion":         "1",
+		"environment":           s.Environment,
+		"signedTransactionInfo": s.sign(s.transactionClaims(t)),
+	}
+	if t.Type == "Auto-Renewable Subscription" {
+		data["signedRenewalInfo"] = s.sign(s.renewalClaims(t))
+	}
+	payload := jwt.MapClaims{
+		"notificationType":    notificationType,
+		"notificationUUID":    newUUID(),
+		"notificationVersion": "2.0",
+		"signedDate":          time.Now().UnixNano() / 1e6,
+		"data":                data,
+	}
+	if subtype != util.NULL {
+		payload["subtype"] = subtype
+	}
+	body, _ := json.Marshal(map[string]string{"signedPayload": s.sign(payload)})
+	return s.notify(url, "application/json", nil, body)
+}
+
+// sign ES256 JWS，header.x5c 为 [叶子证书, 中间证书, 根证书] 的标准 base64 DER
+func (s *AppleServer) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["x5c"] = []string{
+		base64.StdEncoding.EncodeToString(s.leaf.Raw),
+		base64.StdEncoding.EncodeToString(s.intermediate.Raw),
+		base64.StdEncoding.EncodeToString(s.root.Raw),
+	}
+	jws, err := token.SignedString(s.leafKey)
+	if err != nil {
+		panic(fmt.Sprintf("paytest: sign jws: %v", err))
+	}
+	return jws
+}
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / 1e6
+}
+
+func (s *AppleServer) transactionClaims(t *AppleTransaction) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"transactionId":         t.TransactionId,
+		"originalTransactionId": t.OriginalTransactionId,
+		"bundleId":              s.BundleID,
+		"productId":             t.ProductId,
+		"purchaseDate":          millis(t.PurchaseDate),
+		"originalPurchaseDate":  millis(t.PurchaseDate),
+		"quantity":              t.Quantity,
+		"type":                  t.Type,
+		"inAppOwnershipType":    "PURCHASED",
+		"signedDate":            millis(time.Now()),
+		"environment":           s.Environment,
+	}
+	if t.AppAccountToken != util.NULL {
+		claims["appAccountToken"] = t.AppAccountToken
+	}
+	if t.SubscriptionGroupIdentifier != util.NULL {
+		claims["subscriptionGroupIdentifier"] = t.SubscriptionGroupIdentifier
+		claims["webOrderLineItemId"] = t.TransactionId
+	}
+	if !t.ExpiresDate.IsZero() {
+		claims["expiresDate"] = millis(t.ExpiresDate)
+	}
+	if !t.RevocationDate.IsZero() {
+		claims["revocationDate"] = millis(t.RevocationDate)
+		claims["revocationReason"] = 0
+	}
+	return claims
+}
+
+func (s *AppleServer) renewalClaims(t *AppleTransaction) jwt.MapClaims {
+	return jwt.MapClaims{
+		"originalTransactionId":       t.OriginalTransactionId,
+		"autoRenewProductId":          t.ProductId,
+		"productId":                   t.ProductId,
+		"autoRenewStatus":             1,
+		"recentSubscriptionStartDate": millis(t.PurchaseDate),
+		"signedDate":                  millis(time.Now()),
+		"environment":                 s.Environment,
+	}
+}
+
+// verifyToken 校验 App Store Server API 请求 token：ES256，aud=appstoreconnect-v1，iss、bid、kid 匹配
+func (s *AppleServer) verifyToken(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	claims := jwt.MapClaims{}
+	token, err := new(jwt.Parser).ParseWithClaims(strings.TrimPrefix(auth, "Bearer "), claims, func(token *jwt.Token) (interface{}, error) {
+		return &s.apiKey.PublicKey, nil
+	})
+	return err == nil && token.Valid && token.Header["kid"] == s.KeyID &&
+		claims["aud"] == "appstoreconnect-v1" && claims["iss"] == s.IssuerID && claims["bid"] == s.BundleID
+}
+
+func (s *AppleServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.verifyToken(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var (
+		path = r.URL.Path
+		rsp  interface{}
+	)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/inApps/v1/history/"):
+		var signed []string
+		for _, t := range s.transactions {
+			if t.OriginalTransactionId == strings.TrimPrefix(path, "/inApps/v1/history/") {
+				signed = append(signed, s.sign(s.transactionClaims(t)))
+			}
+		}
+		if len(signed) > 0 {
+			rsp = map[string]interface{}{
+				"appAppleId":         1234567890,
+				"bundleId":           s.BundleID,
+				"environment":        s.Environment,
+				"hasMore":            false,
+				"revision":           util.RandomString(16),
+				"signedTransactions": signed,
+			}
+		}
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/inApps/v1/subscriptions/"):
+		// 仅返回最近一次交易
+		var last *AppleTransaction
+		for _, t := range s.transactions {
+			if t.OriginalTransactionId == strings.TrimPrefix(path, "/inApps/v1/subscriptions/") && (last == nil || t.PurchaseDate.After(last.PurchaseDate)) {
+				last = t
+			}
+		}
+		if last != nil {
+			status := 1
+			if !last.ExpiresDate.IsZero() && last.ExpiresDate.Before(time.Now()) {
+				status = 2
+			}
+			if !last.RevocationDate.IsZero() {
+				status = 5
+			}
+			rsp = map[string]interface{}{
+				"appAppleId":  1234567890,
+				"bundleId":    s.BundleID,
+				"environment": s.Environment,
+				"data": []map[string]interface{}{{
+					"subscriptionGroupIdentifier": last.SubscriptionGroupIdentifier,
+					"lastTransactions": []map[string]interface{}{{
+						"originalTransactionId": last.OriginalTransactionId,
+						"status":                status,
+						"signedTransactionInfo": s.sign(s.transactionClaims(last)),
+						"signedRenewalInfo":     s.sign(s.renewalClaims(last)),
+					}},
+				}},
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if rsp == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errorCode":4040010,"errorMessage":"Transaction id not found."}`))
+		return
+	}
+	bs, _ := json.Marshal(rsp)
+	_, _ = w.Write(bs)
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
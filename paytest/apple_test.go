@@ -0,0 +1,124 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rwscode/payutil/apple"
+	"github.com/rwscode/payutil/notify"
+	"github.com/rwscode/payutil/paytest"
+)
+
+func TestAppleServer(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewAppleServer("com.example.app")
+	defer srv.Close()
+	client := apple.NewClient().SetBaseURL(srv.URL)
+	opts := &apple.VerifyOptions{RootCAs: srv.RootCAs()}
+
+	sc := &apple.SignConfig{
+		IssuerID:        srv.IssuerID,
+		BundleID:        srv.BundleID,
+		AppleKeyID:      srv.KeyID,
+		ApplePrivateKey: srv.PrivateKeyPEM(),
+	}
+	now := time.Now()
+	srv.AddTransaction(&paytest.AppleTransaction{TransactionId: "1000", ProductId: "coins_100", AppAccountToken: "a3f2c1d0-0000-4000-8000-000000000001"})
+	srv.AddTransaction(&paytest.AppleTransaction{
+		TransactionId:               "2000",
+		ProductId:                   "vip_monthly",
+		Type:                        "Auto-Renewable Subscription",
+		SubscriptionGroupIdentifier: "21000000",
+		PurchaseDate:                now.Add(-time.Hour),
+		ExpiresDate:                 now.Add(30 * 24 * time.Hour),
+	})
+
+	history, err := client.GetTransactionHistory(ctx, sc, "1000", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.SignedTransactions) != 1 {
+		t.Fatalf("signedTransactions = %d", len(history.SignedTransactions))
+	}
+	ti := new(apple.TransactionsItem)
+	if _, err = opts.ExtractClaims(string(history.SignedTransactions[0]), ti); err != nil {
+		t.Fatal(err)
+	}
+	// 默认只信任 Apple Root CA - G3
+	if _, err = history.SignedTransactions[0].DecodeSignedTransaction(); err == nil {
+		t.Fatal("DecodeSignedTransaction() should reject untrusted root")
+	}
+	if ti.TransactionId != "1000" || ti.ProductId != "coins_100" {
+		t.Fatalf("transaction = %+v", ti)
+	}
+	if _, err = client.GetTransactionHistory(ctx, sc, "9999", nil, true); err == nil {
+		t.Fatal("GetTransactionHistory of unknown transaction should fail")
+	}
+	bad := *sc
+	bad.AppleKeyID = "WRONGKEYID"
+	if _, err = client.GetTransactionHistory(ctx, &bad, "1000", nil, true); err == nil {
+		t.Fatal("GetTransactionHistory with wrong key id should fail")
+	}
+
+	statuses, err := client.GetAllSubscriptionStatuses(ctx, sc, "2000", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses.Data) != 1 || len(statuses.Data[0].LastTransactions) != 1 {
+		t.Fatalf("statuses = %+v", statuses)
+	}
+	last := statuses.Data[0].LastTransactions[0]
+	if last.Status != 1 {
+		t.Fatalf("status = %d", last.Status)
+	}
+	if _, err = opts.ExtractClaims(last.SignedRenewalInfo, new(apple.RenewalInfo)); err != nil {
+		t.Fatal(err)
+	}
+	info := new(apple.TransactionInfo)
+	if _, err = opts.ExtractClaims(last.SignedTransactionInfo, info); err != nil || info.ExpiresDate == 0 {
+		t.Fatalf("ExtractClaims(signedTransactionInfo) = %+v, %v", info, err)
+	}
+
+	var events []*notify.Event
+	router := notify.NewRouter().On(notify.EventAll, func(ctx context.Context, e *notify.Event) error {
+		events = append(events, e)
+		return nil
+	})
+	source := &notify.AppleSource{VerifyOptions: opts}
+	notifySrv := httptest.NewServer(router.Handler(source))
+	defer notifySrv.Close()
+
+	nt, err := srv.Notify(notifySrv.URL, "ONE_TIME_CHARGE", "", "1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nt.StatusCode != 200 || len(events) != 1 || events[0].Type != notify.EventPayment ||
+		events[0].TradeNo != "1000" || events[0].OutTradeNo != "a3f2c1d0-0000-4000-8000-000000000001" {
+		t.Fatalf("notification = %+v, events = %+v", nt, events)
+	}
+	if _, err = srv.Notify(notifySrv.URL, "DID_RENEW", "", "2000"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Type != notify.EventSubscriptionRenewal || events[1].Data.(*notify.AppleNotification).Renewal == nil {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// 证书链不受信任时验签失败
+	source.VerifyOptions = nil
+	if nt, _ = srv.Notify(notifySrv.URL, "REFUND", "", "1000"); nt.StatusCode == 200 || len(events) != 2 {
+		t.Fatalf("untrusted notification accepted: %+v", nt)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rwscode/payutil/pkg/xhttp"
+)
+
+// AlipayResigner 回放支付宝录制报文（xhttp.Cassette）时使用测试密钥重新签名应答
+//
+//	录制的应答脱敏后支付宝的 sign 失效，回放时对 _response 值原文重新签名并去除 alipay_cert_sn，
+//	客户端使用 client.AutoVerifySign(r.PublicKeyCert()) 开启同步验签
+type AlipayResigner struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+// NewAlipayResigner 生成测试密钥及支付宝公钥证书
+func NewAlipayResigner() *AlipayResigner {
+	r := &AlipayResigner{key: newRSAKey()}
+	r.cert = newCert("paytest alipay", &r.key.PublicKey, r.key, nil, false)
+	return r
+}
+
+// PublicKeyCert 支付宝公钥证书内容，用于 client.AutoVerifySign()
+func (r *AlipayResigner) PublicKeyCert() []byte {
+	return certPEM(r.cert)
+}
+
+// PublicKey 支付宝公钥（base64，不含 PEM 头尾），用于 alipay.VerifySyncSign()
+func (r *AlipayResigner) PublicKey() string {
+	der, _ := x509.MarshalPKIXPublicKey(&r.key.PublicKey)
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+// Resign 用于 xhttp.Cassette.Resign，不含 _response 及 sign 的应答原样返回
+func (r *AlipayResigner) Resign(it *xhttp.Interaction) error {
+	body := it.Response.Body
+	start := strings.Index(body, `_response":`)
+	if start < 0 {
+		return nil
+	}
+	start += len(`_response":`)
+	end := strings.Index(body, `,"alipay_cert_sn":`)
+	if end < 0 {
+		end = strings.Index(body, `,"sign":`)
+	}
+	if end < start {
+		return nil
+	}
+	data := body[start:end]
+	it.Response.SetBody([]byte(body[:start] + data + `,"sign":"` + signSHA256WithRSA(r.key, data) + `"}`))
+	return nil
+}
+
+// WechatV3Resigner 回放微信支付 v3 录制报文（xhttp.Cassette）时使用测试平台证书重新签名应答
+//
+//	下载平台证书接口的应答替换为使用 ApiV3Key 加密的测试平台证书，其余应答使用测试平台证书私钥重新生成
+//	Wechatpay-Timestamp/Nonce/Signature/Serial 头，客户端可直接使用 client.AutoVerifySign(ctx) 开启同步验签
+type WechatV3Resigner struct {
+	ApiV3Key string
+
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+// NewWechatV3Resigner 生成测试平台证书，apiV3Key 为客户端使用的 APIv3 密钥，需为 32 字节
+func NewWechatV3Resigner(apiV3Key string) *WechatV3Resigner {
+	r := &WechatV3Resigner{ApiV3Key: apiV3Key, key: newRSAKey()}
+	r.cert = newCert("paytest wechatpay", &r.key.PublicKey, r.key, nil, false)
+	return r
+}
+
+// SerialNo 测试平台证书序列号
+func (r *WechatV3Resigner) SerialNo() string {
+	return serialNo(r.cert)
+}
+
+// PlatformCert 测试平台证书内容，用于 client.SetPlatformCert()
+func (r *WechatV3Resigner) PlatformCert() []byte {
+	return certPEM(r.cert)
+}
+
+// Resign 用于 xhttp.Cassette.Resign
+func (r *WechatV3Resigner) Resign(it *xhttp.Interaction) error {
+	if u, err := url.Parse(it.Request.URL); err == nil && u.Path == "/v3/certificates" && it.Response.StatusCode == http.StatusOK {
+		rsp, err := wechatV3Certificates(r.cert, r.ApiV3Key)
+		if err != nil {
+			return err
+		}
+		bs, _ := json.Marshal(rsp)
+		it.Response.SetBody(bs)
+	}
+	body, err := it.Response.BodyBytes()
+	if err != nil {
+		return err
+	}
+	if it.Response.Header == nil {
+		it.Response.Header = make(http.Header)
+	}
+	wechatV3Sign(it.Response.Header, r.key, r.cert, body)
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/alipay"
+	"github.com/rwscode/payutil/paytest"
+	"github.com/rwscode/payutil/pkg/xhttp"
+	wechatv3 "github.com/rwscode/payutil/wechat/v3"
+)
+
+func TestAlipayResigner(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "alipay.json")
+	privateKey, _, _ := paytest.NewRSAKeyPair()
+	notifySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("success")) }))
+	defer notifySrv.Close()
+
+	// 录制：模拟服务代替沙箱环境
+	srv := paytest.NewAlipayServer()
+	rec, err := xhttp.NewCassette(path, xhttp.CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.GatewayURL())
+	client.SetDoer(xhttp.WithCassette(nil, rec))
+	client.AutoVerifySign(srv.PublicKeyCert())
+	g := alipay.NewGateway(client)
+	if _, err = g.Charge(ctx, &pay.Order{OutTradeNo: "A001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, NotifyUrl: notifySrv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "A001"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srv.Pay("A001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "A001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	// 回放：不访问网络，使用测试密钥重新签名后验签
+	play, err := xhttp.NewCassette(path, xhttp.CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := paytest.NewAlipayResigner()
+	play.Resign = r.Resign
+	client, err = alipay.NewClient("2016091200494382", privateKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.GatewayURL())
+	client.SetDoer(xhttp.WithCassette(nil, play))
+	client.AutoVerifySign(r.PublicKeyCert())
+	g = alipay.NewGateway(client)
+	charge, err := g.Charge(ctx, &pay.Order{OutTradeNo: "A001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, NotifyUrl: notifySrv.URL})
+	if err != nil || charge.CodeUrl == "" {
+		t.Fatalf("Charge = %+v, %v", charge, err)
+	}
+	// 同一请求按录制顺序回放
+	for _, want := range []pay.TradeStatus{pay.TradeStatusPending, pay.TradeStatusPaid, pay.TradeStatusPaid} {
+		order, err := g.Query(ctx, &pay.OrderQuery{OutTradeNo: "A001"})
+		if err != nil || order.Status != want {
+			t.Fatalf("Query = %+v, %v, want %s", order, err, want)
+		}
+	}
+}
+
+func TestWechatV3Resigner(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "wechat_v3.json")
+	apiV3Key := "Cj5xC9RXf0GFCKWeD9PyY1ZWLgionbvx"
+	_, _, key := paytest.NewRSAKeyPair()
+	newClient := func(srvURL string, d xhttp.Doer) *wechatv3.ClientV3 {
+		client, err := wechatv3.NewClientV3("1368139502", "5B2C4F9A", apiV3Key, paytest.PrivateKeyPEM(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.SetBaseURL(srvURL)
+		client.SetDoer(d)
+		if err = client.AutoVerifySign(ctx); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = client.Close() })
+		return client
+	}
+
+	// 录制
+	srv := paytest.NewWechatV3Server("1368139502", apiV3Key)
+	rec, err := xhttp.NewCassette(path, xhttp.CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(srv.URL, xhttp.WithCassette(nil, rec))
+	g := wechatv3.NewGateway(client, "wx2421b1c4370ec43b")
+	order := &pay.Order{OutTradeNo: "W001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, NotifyUrl: "https://example.com/notify"}
+	if _, err = g.Charge(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "W001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	// 回放：平台证书、应答签名替换为测试平台证书
+	play, err := xhttp.NewCassette(path, xhttp.CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := paytest.NewWechatV3Resigner(apiV3Key)
+	play.Resign = r.Resign
+	client = newClient(srv.URL, xhttp.WithCassette(nil, play))
+	if client.WxSerialNo != r.SerialNo() {
+		t.Fatalf("WxSerialNo = %s, want %s", client.WxSerialNo, r.SerialNo())
+	}
+	g = wechatv3.NewGateway(client, "wx2421b1c4370ec43b")
+	if charge, err := g.Charge(ctx, order); err != nil || charge.CodeUrl == "" {
+		t.Fatalf("Charge = %+v, %v", charge, err)
+	}
+	if o, err := g.Query(ctx, &pay.OrderQuery{OutTradeNo: "W001"}); err != nil || o.Status != pay.TradeStatusPending {
+		t.Fatalf("Query = %+v, %v", o, err)
+	}
+}
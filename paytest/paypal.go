@@ -0,0 +1,397 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// PayPalServer 模拟 PayPal REST API
+//
+//	支持 /v1/oauth2/token、创建订单、订单详情、捕获订单、捕获退款、退款详情，
+//	订单需调用 Approve() 模拟买家批准后才能捕获，NotifyCapture() 向 WebhookURL 发送捕获成功事件
+//
+//	client, err := paypal.NewClient(clientID, secret, false, paypal.WithBaseURL(srv.URL))
+type PayPalServer struct {
+	*httptest.Server
+	notifier
+
+	ClientID string
+	Secret   string
+	// WebhookURL Webhook 地址，用于 NotifyCapture()
+	WebhookURL string
+
+	mu      sync.Mutex
+	tokens  map[string]bool
+	orders  map[string]*paypalOrder  // key: order id
+	refunds map[string]*paypalRefund // key: refund id
+	fails   map[string]int           // key: 接口路径前缀，value: HTTP 状态码
+}
+
+type paypalOrder struct {
+	Id         string
+	Status     string
+	Intent     string
+	Units      []map[string]interface{}
+	Currency   string
+	Value      string
+	CaptureId  string
+	Refunded   int64
+	CreateTime time.Time
+	UpdateTime time.Time
+}
+
+type paypalRefund struct {
+	Id         string
+	OrderId    string
+	Status     string
+	Currency   string
+	Value      string
+	InvoiceId  string
+	CreateTime time.Time
+}
+
+// NewPayPalServer 启动 PayPal 模拟服务
+func NewPayPalServer(clientID, secret string) *PayPalServer {
+	s := &PayPalServer{
+		ClientID: clientID,
+		Secret:   secret,
+		tokens:   make(map[string]bool),
+		orders:   make(map[string]*paypalOrder),
+		refunds:  make(map[string]*paypalRefund),
+		fails:    make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// FailNext 下一次调用 path 前缀匹配的接口时返回 HTTP 状态码，如 500、429
+func (s *PayPalServer) FailNext(path string, status int) {
+	s.mu.Lock()
+	s.fails[path] = status
+	s.mu.Unlock()
+}
+
+// OrderStatus 返回订单状态，订单不存在时返回空字符串
+func (s *PayPalServer) OrderStatus(orderID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o := s.orders[orderID]; o != nil {
+		return o.Status
+	}
+	return util.NULL
+}
+
+// Approve 模拟买家在 approve 链接中批准订单，订单状态由 CREATED 变为 APPROVED
+func (s *PayPalServer) Approve(orderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.orders[orderID]
+	if o == nil || o.Status != "CREATED" {
+		return fmt.Errorf("paytest: paypal order %s not exist or not CREATED", orderID)
+	}
+	o.Status, o.UpdateTime = "APPROVED", time.Now()
+	return nil
+}
+
+// NotifyCapture 向 WebhookURL 发送订单捕获成功的 PAYMENT.CAPTURE.COMPLETED 事件
+func (s *PayPalServer) NotifyCapture(orderID string) (*Notification, error) {
+	s.mu.Lock()
+	o := s.orders[orderID]
+	if o == nil || o.CaptureId == util.NULL {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("paytest: paypal order %s not exist or not captured", orderID)
+	}
+	event, _ := json.Marshal(map[string]interface{}{
+		"id":               "WH-" + strings.ToUpper(util.RandomString(17)),
+		"create_time":      time.Now().UTC().Format(time.RFC3339),
+		"resource_type":    "capture",
+		"event_type":       "PAYMENT.CAPTURE.COMPLETED",
+		"event_version":    "1.0",
+		"resource_version": "2.0",
+		"summary":          "Payment completed for " + o.Value + " " + o.Currency,
+		"resource":         s.captureJSON(o),
+	})
+	s.mu.Unlock()
+	return s.notify(s.WebhookURL, "application/json", nil, event)
+}
+
+// PayPal 错误 name
+var paypalErrName = map[int]string{
+	http.StatusBadRequest:          "INVALID_REQUEST",
+	http.StatusUnauthorized:        "AUTHENTICATION_FAILURE",
+	http.StatusForbidden:           "NOT_AUTHORIZED",
+	http.StatusNotFound:            "RESOURCE_NOT_FOUND",
+	http.StatusUnprocessableEntity: "UNPROCESSABLE_ENTITY",
+	http.StatusTooManyRequests:     "RATE_LIMIT_REACHED",
+	http.StatusInternalServerError: "INTERNAL_SERVER_ERROR",
+	http.StatusServiceUnavailable:  "SERVICE_UNAVAILABLE",
+}
+
+func (s *PayPalServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if path == "/v1/oauth2/token" {
+		s.token(w, r)
+		return
+	}
+	if !s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+		s.writeErr(w, http.StatusUnauthorized, "", "Authentication failed due to invalid authentication credentials or a missing Authorization header.")
+		return
+	}
+	for prefix, status := range s.fails {
+		if strings.HasPrefix(path, prefix) {
+			delete(s.fails, prefix)
+			s.writeErr(w, status, "", http.StatusText(status))
+			return
+		}
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPost && path == "/v2/checkout/orders":
+		s.createOrder(w, body)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/v2/checkout/orders/") && strings.HasSuffix(path, "/capture"):
+		s.capture(w, strings.TrimSuffix(strings.TrimPrefix(path, "/v2/checkout/orders/"), "/capture"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v2/checkout/orders/"):
+		if o := s.orders[strings.TrimPrefix(path, "/v2/checkout/orders/")]; o != nil {
+			s.write(w, http.StatusOK, s.orderJSON(o))
+			return
+		}
+		s.writeErr(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Specified resource ID does not exist.")
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/v2/payments/captures/") && strings.HasSuffix(path, "/refund"):
+		s.refund(w, strings.TrimSuffix(strings.TrimPrefix(path, "/v2/payments/captures/"), "/refund"), body)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v2/payments/refunds/"):
+		if rf := s.refunds[strings.TrimPrefix(path, "/v2/payments/refunds/")]; rf != nil {
+			s.write(w, http.StatusOK, s.refundJSON(rf))
+			return
+		}
+		s.writeErr(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Specified resource ID does not exist.")
+	default:
+		s.writeErr(w, http.StatusNotFound, "", "The specified resource does not exist.")
+	}
+}
+
+func (s *PayPalServer) write(w http.ResponseWriter, status int, rsp interface{}) {
+	bs, _ := json.Marshal(rsp)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Paypal-Debug-Id", newId(""))
+	w.WriteHeader(status)
+	_, _ = w.Write(bs)
+}
+
+// writeErr 应答 {"name","message","debug_id","details":[{"issue"}]}
+func (s *PayPalServer) writeErr(w http.ResponseWriter, status int, issue, message string) {
+	rsp := map[string]interface{}{
+		"name":     paypalErrName[status],
+		"message":  message,
+		"debug_id": newId(""),
+	}
+	if issue != util.NULL {
+		rsp["details"] = []map[string]string{{"issue": issue, "description": message}}
+	}
+	s.write(w, status, rsp)
+}
+
+// token 校验 Basic base64(client_id:secret)，签发 Bearer token
+func (s *PayPalServer) token(w http.ResponseWriter, r *http.Request) {
+	auth := base64.StdEncoding.EncodeToString([]byte(s.ClientID + ":" + s.Secret))
+	if r.Header.Get("Authorization") != "Basic "+auth {
+		s.write(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client", "error_description": "Client Authentication failed"})
+		return
+	}
+	token := "A21AA" + util.RandomString(40)
+	s.tokens[token] = true
+	s.write(w, http.StatusOK, map[string]interface{}{
+		"scope":        "https://uri.paypal.com/services/payments/payment",
+		"access_token": token,
+		"token_type":   "Bearer",
+		"app_id":       "APP-80W284485P519543T",
+		"expires_in":   32400,
+		"nonce":        time.Now().UTC().Format(time.RFC3339) + util.RandomString(16),
+	})
+}
+
+func (s *PayPalServer) createOrder(w http.ResponseWriter, body []byte) {
+	var req struct {
+		Intent        string                   `json:"intent"`
+		PurchaseUnits []map[string]interface{} `json:"purchase_units"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || len(req.PurchaseUnits) == 0 {
+		s.writeErr(w, http.StatusBadRequest, "MISSING_REQUIRED_PARAMETER", "purchase_units is required.")
+		return
+	}
+	amount, _ := req.PurchaseUnits[0]["amount"].(map[string]interface{})
+	currency, _ := amount["currency_code"].(string)
+	value, _ := amount["value"].(string)
+	if currency == util.NULL || value == util.NULL {
+		s.writeErr(w, http.StatusBadRequest, "MISSING_REQUIRED_PARAMETER", "purchase_units[0].amount is required.")
+		return
+	}
+	o := &paypalOrder{
+		Id:         strings.ToUpper(util.RandomString(17)),
+		Status:     "CREATED",
+		Intent:     req.Intent,
+		Units:      req.PurchaseUnits,
+		Currency:   currency,
+		Value:      value,
+		CreateTime: time.Now(),
+	}
+	o.UpdateTime = o.CreateTime
+	s.orders[o.Id] = o
+	s.write(w, http.StatusCreated, s.orderJSON(o))
+}
+
+func (s *PayPalServer) capture(w http.ResponseWriter, orderID string) {
+	o := s.orders[orderID]
+	switch {
+	case o == nil:
+		s.writeErr(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Specified resource ID does not exist.")
+		return
+	case o.Status == "COMPLETED":
+		s.writeErr(w, http.StatusUnprocessableEntity, "ORDER_ALREADY_CAPTURED", "Order already captured.")
+		return
+	case o.Status != "APPROVED":
+		s.writeErr(w, http.StatusUnprocessableEntity, "ORDER_NOT_APPROVED", "Payer has not yet approved the Order for payment.")
+		return
+	}
+	o.Status, o.CaptureId, o.UpdateTime = "COMPLETED", strings.ToUpper(util.RandomString(17)), time.Now()
+	s.write(w, http.StatusCreated, s.orderJSON(o))
+}
+
+func (s *PayPalServer) refund(w http.ResponseWriter, captureID string, body []byte) {
+	var o *paypalOrder
+	for _, v := range s.orders {
+		if v.CaptureId != util.NULL && v.CaptureId == captureID {
+			o = v
+			break
+		}
+	}
+	if o == nil {
+		s.writeErr(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Specified resource ID does not exist.")
+		return
+	}
+	var req struct {
+		Amount *struct {
+			CurrencyCode string `json:"currency_code"`
+			Value        string `json:"value"`
+		} `json:"amount"`
+		InvoiceId string `json:"invoice_id"`
+	}
+	_ = json.Unmarshal(body, &req)
+	value := o.Value
+	if req.Amount != nil && req.Amount.Value != util.NULL {
+		value = req.Amount.Value
+	}
+	total, _ := pay.ParseMoney(o.Value, o.Currency)
+	refund, err := pay.ParseMoney(value, o.Currency)
+	amount := refund.Amount
+	if err != nil || amount <= 0 {
+		s.writeErr(w, http.StatusBadRequest, "INVALID_PARAMETER_VALUE", "The value of a field is invalid.")
+		return
+	}
+	if o.Refunded+amount > total.Amount {
+		s.writeErr(w, http.StatusUnprocessableEntity, "REFUND_AMOUNT_EXCEEDED", "The refund amount must be less than or equal to the capture amount that has not yet been refunded.")
+		return
+	}
+	o.Refunded += amount
+	rf := &paypalRefund{
+		Id:         strings.ToUpper(util.RandomString(17)),
+		OrderId:    o.Id,
+		Status:     "COMPLETED",
+		Currency:   o.Currency,
+		Value:      value,
+		InvoiceId:  req.InvoiceId,
+		CreateTime: time.Now(),
+	}
+	s.refunds[rf.Id] = rf
+	s.write(w, http.StatusCreated, s.refundJSON(rf))
+}
+
+func (s *PayPalServer) link(path, rel, method string) map[string]string {
+	return map[string]string{"href": s.URL + path, "rel": rel, "method": method}
+}
+
+func (s *PayPalServer) captureJSON(o *paypalOrder) map[string]interface{} {
+	status := "COMPLETED"
+	if total, _ := pay.ParseMoney(o.Value, o.Currency); o.Refunded >= total.Amount {
+		status = "REFUNDED"
+	} else if o.Refunded > 0 {
+		status = "PARTIALLY_REFUNDED"
+	}
+	return map[string]interface{}{
+		"id":            o.CaptureId,
+		"status":        status,
+		"amount":        map[string]string{"currency_code": o.Currency, "value": o.Value},
+		"final_capture": true,
+		"links": []map[string]string{
+			s.link("/v2/payments/captures/"+o.CaptureId, "self", http.MethodGet),
+			s.link("/v2/payments/captures/"+o.CaptureId+"/refund", "refund", http.MethodPost),
+			s.link("/v2/checkout/orders/"+o.Id, "up", http.MethodGet),
+		},
+		"create_time": o.UpdateTime.UTC().Format(time.RFC3339),
+		"update_time": o.UpdateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+func (s *PayPalServer) orderJSON(o *paypalOrder) map[string]interface{} {
+	units := make([]map[string]interface{}, 0, len(o.Units))
+	for i, u := range o.Units {
+		unit := make(map[string]interface{}, len(u)+1)
+		for k, v := range u {
+			unit[k] = v
+		}
+		if i == 0 && o.CaptureId != util.NULL {
+			unit["payments"] = map[string]interface{}{"captures": []interface{}{s.captureJSON(o)}}
+		}
+		units = append(units, unit)
+	}
+	links := []map[string]string{s.link("/v2/checkout/orders/"+o.Id, "self", http.MethodGet)}
+	switch o.Status {
+	case "CREATED":
+		links = append(links, map[string]string{"href": s.URL + "/checkoutnow?token=" + o.Id, "rel": "approve", "method": http.MethodGet})
+	case "APPROVED":
+		links = append(links, s.link("/v2/checkout/orders/"+o.Id+"/capture", "capture", http.MethodPost))
+	}
+	return map[string]interface{}{
+		"id":             o.Id,
+		"status":         o.Status,
+		"intent":         o.Intent,
+		"purchase_units": units,
+		"links":          links,
+		"create_time":    o.CreateTime.UTC().Format(time.RFC3339),
+		"update_time":    o.UpdateTime.UTC().Format(time.RFC3339),
+	}
+}
+
+func (s *PayPalServer) refundJSON(rf *paypalRefund) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          rf.Id,
+		"status":      rf.Status,
+		"amount":      map[string]string{"currency_code": rf.Currency, "value": rf.Value},
+		"invoice_id":  rf.InvoiceId,
+		"links":       []map[string]string{s.link("/v2/payments/refunds/"+rf.Id, "self", http.MethodGet)},
+		"create_time": rf.CreateTime.UTC().Format(time.RFC3339),
+		"update_time": rf.CreateTime.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/paypal"
+	"github.com/rwscode/payutil/paytest"
+)
+
+func TestPayPalServer(t *testing.T) {
+	ctx := context.Background()
+	srv := paytest.NewPayPalServer("AZ-client", "EJ-secret")
+	defer srv.Close()
+
+	if _, err := paypal.NewClient("AZ-client", "wrong", false, paypal.WithBaseURL(srv.URL)); err == nil {
+		t.Fatal("NewClient with wrong secret should fail")
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := paypal.NewClientWithContext(canceled, "AZ-client", "EJ-secret", false, paypal.WithBaseURL(srv.URL)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("NewClientWithContext with canceled ctx = %v, want context.Canceled", err)
+	}
+	client, err := paypal.NewClient("AZ-client", "EJ-secret", false, paypal.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := paypal.NewGateway(client)
+
+	charge, err := g.Charge(ctx, &pay.Order{OutTradeNo: "P001", Subject: "test", Amount: 1099, Currency: "USD", Scene: pay.ScenePage})
+	if err != nil || charge.PayUrl == "" || charge.TradeNo == "" {
+		t.Fatalf("Charge = %+v, %v", charge, err)
+	}
+	orderID := charge.TradeNo
+	if _, err = client.OrderCapture(ctx, orderID, nil); err != nil {
+		t.Fatal(err)
+	}
+	capture, err := client.OrderCapture(ctx, orderID, nil)
+	if err != nil || capture.Code != http.StatusUnprocessableEntity || capture.ErrorResponse.Details[0].Issue != "ORDER_NOT_APPROVED" {
+		t.Fatalf("OrderCapture before approve = %+v, %v", capture, err)
+	}
+	if err = srv.Approve(orderID); err != nil {
+		t.Fatal(err)
+	}
+	captured, err := g.(pay.Capturer).Capture(ctx, &pay.OrderQuery{TradeNo: orderID})
+	if err != nil || captured.Status != pay.TradeStatusPaid || captured.OutTradeNo != "P001" {
+		t.Fatalf("Capture = %+v, %v", captured, err)
+	}
+	order, err := g.Query(ctx, &pay.OrderQuery{TradeNo: orderID})
+	if err != nil || order.Status != pay.TradeStatusPaid || order.OutTradeNo != "P001" || order.Amount != 1099 {
+		t.Fatalf("Query = %+v, %v", order, err)
+	}
+	if _, err = g.Query(ctx, &pay.OrderQuery{TradeNo: "NOTEXIST"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("Query not exist err = %v", err)
+	}
+
+	refund, err := g.Refund(ctx, &pay.Refund{TradeNo: orderID, OutRefundNo: "PR001", Amount: 500, Currency: "USD"})
+	if err != nil || refund.Status != pay.RefundStatusSuccess || refund.Amount != 500 {
+		t.Fatalf("Refund = %+v, %v", refund, err)
+	}
+	refund, err = g.QueryRefund(ctx, &pay.RefundQuery{RefundNo: refund.RefundNo})
+	if err != nil || refund.OutRefundNo != "PR001" || refund.Amount != 500 {
+		t.Fatalf("QueryRefund = %+v, %v", refund, err)
+	}
+	if _, err = g.Refund(ctx, &pay.Refund{TradeNo: orderID, OutRefundNo: "PR002", Amount: 600, Currency: "USD"}); err == nil {
+		t.Fatal("Refund over amount should fail")
+	}
+
+	var event map[string]interface{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(bs, &event)
+	}))
+	defer webhook.Close()
+	srv.WebhookURL = webhook.URL
+	if _, err = srv.NotifyCapture(orderID); err != nil {
+		t.Fatal(err)
+	}
+	if event["event_type"] != "PAYMENT.CAPTURE.COMPLETED" {
+		t.Fatalf("webhook event = %v", event)
+	}
+
+	srv.FailNext("/v2/checkout/orders/", http.StatusInternalServerError)
+	_, err = g.Query(ctx, &pay.OrderQuery{TradeNo: orderID})
+	if apiErr, ok := pay.AsAPIError(err); !ok || !apiErr.Retryable {
+		t.Fatalf("FailNext err = %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package paytest 离线模拟支付渠道服务，用于单元测试、CI 及无法访问公网的环境
+//
+//	各模拟服务基于 httptest.Server，按真实渠道的方式签名应答：支付宝 RSA2 sign、微信 v3 Wechatpay-Signature 响应头、
+//	微信 v2 MD5/HMAC-SHA256 XML、Apple JWS，订单状态保存在内存中，可主动触发异步通知。
+//
+//	srv := paytest.NewAlipayServer()
+//	defer srv.Close()
+//	client, _ := alipay.NewClient(appid, privateKey, true)
+//	client.SetBaseURL(srv.GatewayURL())
+//	client.AutoVerifySign(srv.PublicKeyCert())
+//
+//	基于真实沙箱报文的回归测试可使用 xhttp.Cassette 录制、回放，回放时通过 AlipayResigner、WechatV3Resigner 重新签名应答
+//
+//	paytest 只依赖 pay 根包及 pkg 下的工具包，渠道包的测试可直接引用
+package paytest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Notification 模拟服务发出的一次异步通知
+type Notification struct {
+	URL        string      // 通知地址
+	Header     http.Header // 请求头
+	Body       []byte      // 请求体
+	StatusCode int         // 商户应答状态码
+	Response   []byte      // 商户应答内容
+}
+
+// notifier 记录并发送异步通知
+type notifier struct {
+	mu            sync.Mutex
+	notifications []*Notification
+	hc            *http.Client
+}
+
+// Notifications 返回已发出的异步通知
+func (n *notifier) Notifications() []*Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*Notification(nil), n.notifications...)
+}
+
+// SetNotifyClient 设置发送异步通知使用的 *http.Client，默认 http.DefaultClient
+func (n *notifier) SetNotifyClient(hc *http.Client) {
+	n.mu.Lock()
+	n.hc = hc
+	n.mu.Unlock()
+}
+
+func (n *notifier) notify(url, contentType string, header http.Header, body []byte) (*Notification, error) {
+	if url == "" {
+		return nil, fmt.Errorf("paytest: notify url is empty")
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Content-Type", contentType)
+	n.mu.Lock()
+	hc := n.hc
+	n.mu.Unlock()
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	rsp, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	nt := &Notification{URL: url, Header: req.Header, Body: body, StatusCode: res.StatusCode, Response: rsp}
+	n.mu.Lock()
+	n.notifications = append(n.notifications, nt)
+	n.mu.Unlock()
+	return nt, nil
+}
+
+// newRSAKey 生成模拟服务使用的 RSA 密钥，2048 位
+func newRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("paytest: rsa.GenerateKey: %v", err))
+	}
+	return key
+}
+
+// newCert 签发证书，parent 为空时自签名
+func newCert(cn string, pub, signer interface{}, parent *x509.Certificate, isCA bool) *x509.Certificate {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	tpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"paytest"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		tpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+	if parent == nil {
+		parent = tpl
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, pub, signer)
+	if err != nil {
+		panic(fmt.Sprintf("paytest: x509.CreateCertificate: %v", err))
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func certPEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func privateKeyPEM(key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(fmt.Sprintf("paytest: x509.MarshalPKCS8PrivateKey: %v", err))
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// NewRSAKeyPair 生成测试用的 RSA 密钥对，格式同支付宝开放平台：不含 PEM 头尾的 base64
+//
+//	privateKey 为 PKCS8，publicKey 为 PKIX，可直接用于 alipay.NewClient()
+func NewRSAKeyPair() (privateKey string, publicKey string, key *rsa.PrivateKey) {
+	key = newRSAKey()
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	pkix, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	return base64.StdEncoding.EncodeToString(pkcs8), base64.StdEncoding.EncodeToString(pkix), key
+}
+
+// PrivateKeyPEM 私钥的 PKCS8 PEM 内容，如微信商户 API 私钥 apiclient_key.pem
+func PrivateKeyPEM(key *rsa.PrivateKey) string {
+	return string(privateKeyPEM(key))
+}
+
+// NewClientCert 生成测试用的商户 API 证书（PEM），用于微信 v2 退款等需要证书的接口
+func NewClientCert() (certContent, keyContent []byte) {
+	key := newRSAKey()
+	return certPEM(newCert("paytest merchant", &key.PublicKey, key, nil, false)), privateKeyPEM(key)
+}
+
+func signSHA256WithRSA(key *rsa.PrivateKey, data string) string {
+	h := sha256.Sum256([]byte(data))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	if err != nil {
+		panic(fmt.Sprintf("paytest: rsa.SignPKCS1v15: %v", err))
+	}
+	return base64.StdEncoding.EncodeToString(sign)
+}
+
+func verifySHA256WithRSA(pub *rsa.PublicKey, data, sign string) error {
+	bs, err := base64.StdEncoding.DecodeString(sign)
+	if err != nil {
+		return err
+	}
+	h := sha256.Sum256([]byte(data))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, h[:], bs)
+}
+
+// newId 生成渠道单号，如 prefix + 时间 + 随机数
+func newId(prefix string) string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(1e8))
+	return fmt.Sprintf("%s%s%08d", prefix, time.Now().Format("20060102150405"), n.Int64())
+}
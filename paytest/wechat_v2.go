@@ -0,0 +1,428 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/aes"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// WechatV2Server 模拟微信支付 v2（XML）接口
+//
+//	支持 pay/unifiedorder、pay/orderquery、pay/closeorder、secapi/pay/refund、pay/refundquery，
+//	按请求的 sign_type 使用 MD5 或 HMAC-SHA256 校验请求签名并签名应答，
+//	使用 client.SetBaseURL(srv.URL)（client 需为 isProd=true），退款需先 client.AddCertPemFileContent(paytest.NewClientCert())
+type WechatV2Server struct {
+	*httptest.Server
+	notifier
+
+	ApiKey string
+
+	mu      sync.Mutex
+	orders  map[string]*wechatV2Order  // key: out_trade_no
+	refunds map[string]*wechatV2Refund // key: out_refund_no
+	fails   map[string]string          // key: 接口路径，value: err_code
+}
+
+type wechatV2Order struct {
+	AppId         string
+	MchId         string
+	OutTradeNo    string
+	TransactionId string
+	TradeType     string
+	TotalFee      int64
+	FeeType       string
+	State         string
+	NotifyURL     string
+	OpenId        string
+	SignType      string
+	TimeEnd       time.Time
+	Refunded      int64
+}
+
+type wechatV2Refund struct {
+	OutRefundNo string
+	RefundId    string
+	OutTradeNo  string
+	RefundFee   int64
+	Status      string
+	NotifyURL   string
+	SuccessTime time.Time
+}
+
+// NewWechatV2Server 启动微信支付 v2 模拟服务
+func NewWechatV2Server(apiKey string) *WechatV2Server {
+	s := &WechatV2Server{
+		ApiKey:  apiKey,
+		orders:  make(map[string]*wechatV2Order),
+		refunds: make(map[string]*wechatV2Refund),
+		fails:   make(map[string]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// FailNext 下一次调用 path（如 pay/orderquery）时返回 result_code=FAIL 及 err_code，如 SYSTEMERROR、NOTENOUGH
+func (s *WechatV2Server) FailNext(path, errCode string) {
+	s.mu.Lock()
+	s.fails[strings.TrimPrefix(path, "/")] = errCode
+	s.mu.Unlock()
+}
+
+// TradeState 返回订单状态，订单不存在时返回空字符串
+func (s *WechatV2Server) TradeState(outTradeNo string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o := s.orders[outTradeNo]; o != nil {
+		return o.State
+	}
+	return util.NULL
+}
+
+// Pay 模拟用户完成支付，订单状态变为 SUCCESS，并向 notify_url 发送签名的支付结果通知
+func (s *WechatV2Server) Pay(outTradeNo string) (*Notification, error) {
+	s.mu.Lock()
+	o := s.orders[outTradeNo]
+	if o == nil || o.State != WechatNotPay {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("paytest: wechat order %s not exist or not NOTPAY", outTradeNo)
+	}
+	o.State, o.TimeEnd = WechatSuccess, time.Now()
+	bm := s.orderBodyMap(o)
+	bm.Remove("trade_state")
+	bm.Remove("trade_state_desc")
+	bm.Set("return_code", pay.SUCCESS).
+		Set("result_code", pay.SUCCESS).
+		Set("nonce_str", util.RandomString(32))
+	if o.SignType != util.NULL {
+		bm.Set("sign_type", o.SignType)
+	}
+	s.sign(bm, o.SignType)
+	s.mu.Unlock()
+	return s.notify(o.NotifyURL, "text/xml", nil, s.xml(bm))
+}
+
+// CompleteRefund 模拟退款到账，退款状态变为 SUCCESS，申请退款时传了 notify_url 则发送 req_info 加密的退款结果通知
+func (s *WechatV2Server) CompleteRefund(outRefundNo string) (*Notification, error) {
+	s.mu.Lock()
+	r := s.refunds[outRefundNo]
+	if r == nil || r.Status != "PROCESSING" {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("paytest: wechat refund %s not exist or not PROCESSING", outRefundNo)
+	}
+	r.Status, r.SuccessTime = WechatSuccess, time.Now()
+	o := s.orders[r.OutTradeNo]
+	info := make(pay.BodyMap)
+	info.Set("transaction_id", o.TransactionId).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("refund_id", r.RefundId).
+		Set("out_refund_no", r.OutRefundNo).
+		Set("total_fee", o.TotalFee).
+		Set("refund_fee", r.RefundFee).
+		Set("settlement_refund_fee", r.RefundFee).
+		Set("refund_status", r.Status).
+		Set("success_time", r.SuccessTime.Format(util.TimeLayout)).
+		Set("refund_recv_accout", "支付用户零钱").
+		Set("refund_account", "REFUND_SOURCE_RECHARGE_FUNDS").
+		Set("refund_request_source", "API")
+	appId, mchId := o.AppId, o.MchId
+	s.mu.Unlock()
+	if r.NotifyURL == util.NULL {
+		return nil, nil
+	}
+	reqInfo, err := s.encryptReqInfo(s.xml(info))
+	if err != nil {
+		return nil, err
+	}
+	bm := make(pay.BodyMap)
+	bm.Set("return_code", pay.SUCCESS).
+		Set("appid", appId).
+		Set("mch_id", mchId).
+		Set("nonce_str", util.RandomString(32)).
+		Set("req_info", reqInfo)
+	return s.notify(r.NotifyURL, "text/xml", nil, s.xml(bm))
+}
+
+// encryptReqInfo 退款通知 req_info：AES-256-ECB(key=小写 md5(ApiKey))，PKCS7 填充后 base64
+func (s *WechatV2Server) encryptReqInfo(plain []byte) (string, error) {
+	h := md5.Sum([]byte(s.ApiKey))
+	bs, err := aes.ECBEncrypt(plain, []byte(hex.EncodeToString(h[:])))
+	if err != nil {
+		return util.NULL, err
+	}
+	return base64.StdEncoding.EncodeToString(bs), nil
+}
+
+func (s *WechatV2Server) xml(bm pay.BodyMap) []byte {
+	bs, _ := xml.Marshal(bm)
+	return bs
+}
+
+// signature MD5 或 HMAC-SHA256，大写 hex
+func (s *WechatV2Server) signature(bm pay.BodyMap, signType string) string {
+	var h hash.Hash
+	if signType == "HMAC-SHA256" {
+		h = hmac.New(sha256.New, []byte(s.ApiKey))
+	} else {
+		h = md5.New()
+	}
+	h.Write([]byte(bm.EncodeWeChatSignParams(s.ApiKey)))
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+}
+
+func (s *WechatV2Server) sign(bm pay.BodyMap, signType string) {
+	bm.Remove("sign")
+	bm.Set("sign", s.signature(bm, signType))
+}
+
+func (s *WechatV2Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	req := make(pay.BodyMap)
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.write(w, req, wechatV2Fail("FAIL", "XML格式错误"))
+		return
+	}
+	signType := req.GetString("sign_type")
+	sign := req.GetString("sign")
+	req.Remove("sign")
+	if sign != s.signature(req, signType) {
+		s.write(w, req, wechatV2Fail("FAIL", "签名错误"))
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if errCode, ok := s.fails[path]; ok {
+		delete(s.fails, path)
+		s.write(w, req, wechatV2Err(errCode, errCode))
+		return
+	}
+	switch path {
+	case "pay/unifiedorder":
+		s.write(w, req, s.unifiedOrder(req))
+	case "pay/orderquery":
+		o := s.find(req)
+		if o == nil {
+			s.write(w, req, wechatV2Err("ORDERNOTEXIST", "订单不存在"))
+			return
+		}
+		s.write(w, req, s.orderBodyMap(o))
+	case "pay/closeorder":
+		s.write(w, req, s.closeOrder(req))
+	case "secapi/pay/refund":
+		s.write(w, req, s.refund(req))
+	case "pay/refundquery":
+		s.write(w, req, s.refundQuery(req))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// write 应答 XML，业务结果使用请求的 sign_type 签名；return_code=FAIL 时不签名
+func (s *WechatV2Server) write(w http.ResponseWriter, req, rsp pay.BodyMap) {
+	if rsp.GetString("return_code") == util.NULL {
+		rsp.Set("return_code", pay.SUCCESS).Set("return_msg", pay.OK)
+		if rsp.GetString("result_code") == util.NULL {
+			rsp.Set("result_code", pay.SUCCESS)
+		}
+		rsp.Set("appid", req.GetString("appid")).
+			Set("mch_id", req.GetString("mch_id")).
+			Set("nonce_str", util.RandomString(32))
+		s.sign(rsp, req.GetString("sign_type"))
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(s.xml(rsp))
+}
+
+func (s *WechatV2Server) find(req pay.BodyMap) *wechatV2Order {
+	if tid := req.GetString("transaction_id"); tid != util.NULL {
+		for _, o := range s.orders {
+			if o.TransactionId == tid {
+				return o
+			}
+		}
+		return nil
+	}
+	return s.orders[req.GetString("out_trade_no")]
+}
+
+func (s *WechatV2Server) unifiedOrder(req pay.BodyMap) pay.BodyMap {
+	outTradeNo := req.GetString("out_trade_no")
+	totalFee := util.String2Int64(req.GetString("total_fee"))
+	if outTradeNo == util.NULL || totalFee <= 0 {
+		return wechatV2Err("PARAM_ERROR", "out_trade_no 或 total_fee 无效")
+	}
+	o := s.orders[outTradeNo]
+	switch {
+	case o != nil && o.State == WechatSuccess:
+		return wechatV2Err("ORDERPAID", "该订单已支付")
+	case o != nil && o.State == WechatClosed:
+		return wechatV2Err("ORDERCLOSED", "该订单已关闭")
+	case o == nil:
+		o = &wechatV2Order{OutTradeNo: outTradeNo, TransactionId: newId("42"), State: WechatNotPay}
+		s.orders[outTradeNo] = o
+	}
+	o.AppId, o.MchId, o.TradeType = req.GetString("appid"), req.GetString("mch_id"), req.GetString("trade_type")
+	o.TotalFee, o.FeeType, o.NotifyURL = totalFee, req.GetString("fee_type"), req.GetString("notify_url")
+	o.OpenId, o.SignType = req.GetString("openid"), req.GetString("sign_type")
+	if o.FeeType == util.NULL {
+		o.FeeType = "CNY"
+	}
+	prepayId := "wx" + o.TransactionId
+	rsp := make(pay.BodyMap)
+	rsp.Set("trade_type", o.TradeType).Set("prepay_id", prepayId)
+	switch o.TradeType {
+	case "NATIVE":
+		rsp.Set("code_url", "weixin://wxpay/bizpayurl?pr="+prepayId)
+	case "MWEB":
+		rsp.Set("mweb_url", s.URL+"/cgi-bin/mmpayweb-bin/checkmweb?prepay_id="+prepayId)
+	}
+	return rsp
+}
+
+func (s *WechatV2Server) orderBodyMap(o *wechatV2Order) pay.BodyMap {
+	rsp := make(pay.BodyMap)
+	rsp.Set("appid", o.AppId).
+		Set("mch_id", o.MchId).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("transaction_id", o.TransactionId).
+		Set("trade_type", o.TradeType).
+		Set("trade_state", o.State).
+		Set("trade_state_desc", o.State).
+		Set("total_fee", o.TotalFee).
+		Set("fee_type", o.FeeType)
+	if !o.TimeEnd.IsZero() {
+		rsp.Set("openid", o.OpenId).
+			Set("is_subscribe", "N").
+			Set("bank_type", "OTHERS").
+			Set("cash_fee", o.TotalFee).
+			Set("time_end", o.TimeEnd.Format("20060102150405"))
+	}
+	return rsp
+}
+
+func (s *WechatV2Server) closeOrder(req pay.BodyMap) pay.BodyMap {
+	o := s.orders[req.GetString("out_trade_no")]
+	switch {
+	case o == nil:
+		return wechatV2Err("ORDERNOTEXIST", "订单不存在")
+	case o.State == WechatSuccess || o.State == WechatRefund:
+		return wechatV2Err("ORDERPAID", "订单已支付，不能发起关单")
+	}
+	o.State = WechatClosed
+	return make(pay.BodyMap)
+}
+
+func (s *WechatV2Server) refund(req pay.BodyMap) pay.BodyMap {
+	outRefundNo := req.GetString("out_refund_no")
+	rf := s.refunds[outRefundNo]
+	if rf == nil {
+		o := s.find(req)
+		refundFee := util.String2Int64(req.GetString("refund_fee"))
+		switch {
+		case o == nil:
+			return wechatV2Err("ORDERNOTEXIST", "订单不存在")
+		case o.State != WechatSuccess && o.State != WechatRefund:
+			return wechatV2Err("TRADE_STATE_ERROR", "订单状态错误")
+		case outRefundNo == util.NULL || refundFee <= 0 || util.String2Int64(req.GetString("total_fee")) != o.TotalFee:
+			return wechatV2Err("PARAM_ERROR", "out_refund_no、refund_fee 或 total_fee 无效")
+		case o.Refunded+refundFee > o.TotalFee:
+			return wechatV2Err("NOTENOUGH", "退款金额超过订单可退金额")
+		}
+		o.Refunded += refundFee
+		o.State = WechatRefund
+		rf = &wechatV2Refund{
+			OutRefundNo: outRefundNo,
+			RefundId:    newId("50"),
+			OutTradeNo:  o.OutTradeNo,
+			RefundFee:   refundFee,
+			Status:      "PROCESSING",
+			NotifyURL:   req.GetString("notify_url"),
+		}
+		s.refunds[outRefundNo] = rf
+	}
+	o := s.orders[rf.OutTradeNo]
+	rsp := make(pay.BodyMap)
+	rsp.Set("transaction_id", o.TransactionId).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("out_refund_no", rf.OutRefundNo).
+		Set("refund_id", rf.RefundId).
+		Set("refund_fee", rf.RefundFee).
+		Set("total_fee", o.TotalFee).
+		Set("cash_fee", o.TotalFee).
+		Set("cash_refund_fee", rf.RefundFee)
+	return rsp
+}
+
+func (s *WechatV2Server) refundQuery(req pay.BodyMap) pay.BodyMap {
+	var refunds []*wechatV2Refund
+	o := s.find(req)
+	if rf := s.refunds[req.GetString("out_refund_no")]; rf != nil {
+		refunds, o = append(refunds, rf), s.orders[rf.OutTradeNo]
+	} else if o != nil {
+		for _, rf := range s.refunds {
+			if rf.OutTradeNo == o.OutTradeNo {
+				refunds = append(refunds, rf)
+			}
+		}
+	}
+	if o == nil || len(refunds) == 0 {
+		return wechatV2Err("REFUNDNOTEXIST", "退款订单查询失败")
+	}
+	rsp := make(pay.BodyMap)
+	rsp.Set("transaction_id", o.TransactionId).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("total_fee", o.TotalFee).
+		Set("cash_fee", o.TotalFee).
+		Set("refund_count", len(refunds))
+	for i, rf := range refunds {
+		idx := strconv.Itoa(i)
+		rsp.Set("out_refund_no_"+idx, rf.OutRefundNo).
+			Set("refund_id_"+idx, rf.RefundId).
+			Set("refund_fee_"+idx, rf.RefundFee).
+			Set("refund_status_"+idx, rf.Status).
+			Set("refund_recv_accout_"+idx, "支付用户零钱")
+		if !rf.SuccessTime.IsZero() {
+			rsp.Set("refund_success_time_"+idx, rf.SuccessTime.Format(util.TimeLayout))
+		}
+	}
+	return rsp
+}
+
+// wechatV2Fail 通信失败：return_code=FAIL
+func wechatV2Fail(returnCode, returnMsg string) pay.BodyMap {
+	rsp := make(pay.BodyMap)
+	rsp.Set("return_code", returnCode).Set("return_msg", returnMsg)
+	return rsp
+}
+
+// wechatV2Err 业务失败：result_code=FAIL
+func wechatV2Err(errCode, errCodeDes string) pay.BodyMap {
+	rsp := make(pay.BodyMap)
+	rsp.Set("result_code", pay.FAIL).Set("err_code", errCode).Set("err_code_des", errCodeDes)
+	return rsp
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/notify"
+	"github.com/rwscode/payutil/paytest"
+	"github.com/rwscode/payutil/wechat"
+)
+
+func TestWechatV2Server(t *testing.T) {
+	ctx := context.Background()
+	apiKey := "GFDS8j98rewnmgl45wHTt980jg543abc"
+	srv := paytest.NewWechatV2Server(apiKey)
+	defer srv.Close()
+
+	client := wechat.NewClient("wxdaa2ab9ef87b5497", "1368139502", apiKey, true)
+	client.SetBaseURL(srv.URL)
+	if err := client.AddCertPemFileContent(paytest.NewClientCert()); err != nil {
+		t.Fatal(err)
+	}
+	g := wechat.NewGateway(client)
+
+	var events []*notify.Event
+	router := notify.NewRouter().On(notify.EventAll, func(ctx context.Context, e *notify.Event) error {
+		events = append(events, e)
+		return nil
+	})
+	notifySrv := httptest.NewServer(router.Handler(&notify.WechatV2Source{ApiKey: apiKey}))
+	defer notifySrv.Close()
+
+	charge, err := g.Charge(ctx, &pay.Order{OutTradeNo: "V001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, ClientIp: "127.0.0.1", NotifyUrl: notifySrv.URL})
+	if err != nil || charge.CodeUrl == "" {
+		t.Fatalf("Charge = %+v, %v", charge, err)
+	}
+	if _, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "V404"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("Query not exist err = %v", err)
+	}
+	nt, err := srv.Pay("V001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != notify.EventPayment || events[0].OutTradeNo != "V001" {
+		t.Fatalf("notify response = %s, events = %+v", nt.Response, events)
+	}
+	order, err := g.Query(ctx, &pay.OrderQuery{OutTradeNo: "V001"})
+	if err != nil || order.Status != pay.TradeStatusPaid || order.Amount != 1000 {
+		t.Fatalf("Query = %+v, %v", order, err)
+	}
+	if err = g.Close(ctx, &pay.OrderQuery{OutTradeNo: "V001"}); !errors.Is(err, pay.OrderPaidErr) {
+		t.Fatalf("Close paid order err = %v", err)
+	}
+
+	refund, err := g.Refund(ctx, &pay.Refund{OutTradeNo: "V001", OutRefundNo: "VR001", Amount: 300, Total: 1000, NotifyUrl: notifySrv.URL})
+	if err != nil || refund.Status != pay.RefundStatusProcessing || refund.Amount != 300 {
+		t.Fatalf("Refund = %+v, %v", refund, err)
+	}
+	if _, err = g.Refund(ctx, &pay.Refund{OutTradeNo: "V001", OutRefundNo: "VR002", Amount: 800, Total: 1000}); !errors.Is(err, pay.InsufficientFundsErr) {
+		t.Fatalf("Refund over amount err = %v", err)
+	}
+	if _, err = srv.CompleteRefund("VR001"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Type != notify.EventRefund || events[1].OutRefundNo != "VR001" {
+		t.Fatalf("events = %+v", events)
+	}
+	refund, err = g.QueryRefund(ctx, &pay.RefundQuery{OutTradeNo: "V001", OutRefundNo: "VR001"})
+	if err != nil || refund.Status != pay.RefundStatusSuccess || refund.Amount != 300 {
+		t.Fatalf("QueryRefund = %+v, %v", refund, err)
+	}
+	if _, err = g.QueryRefund(ctx, &pay.RefundQuery{OutTradeNo: "V001", OutRefundNo: "VR404"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("QueryRefund not exist err = %v", err)
+	}
+
+	srv.FailNext("pay/orderquery", "SYSTEMERROR")
+	_, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "V001"})
+	if apiErr, ok := pay.AsAPIError(err); !ok || !apiErr.Retryable {
+		t.Fatalf("FailNext err = %v", err)
+	}
+}
@@ -0,0 +1,553 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/aes"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+// 微信支付 v3 交易状态
+const (
+	WechatNotPay  = "NOTPAY"
+	WechatSuccess = "SUCCESS"
+	WechatClosed  = "CLOSED"
+	WechatRefund  = "REFUND"
+)
+
+// WechatV3Server 模拟微信支付 APIv3
+//
+//	支持 Native/JSAPI/APP/H5 下单、查询订单、关闭订单、申请退款、查询退款、下载平台证书，
+//	应答携带 Request-ID、Wechatpay-Timestamp/Nonce/Signature/Serial 头，平台证书使用 APIv3 密钥加密，
+//	可直接使用 client.SetBaseURL(srv.URL) + client.AutoVerifySign(ctx) 开启同步验签，异步通知可使用 notify.WechatV3Source 验签、解密
+type WechatV3Server struct {
+	*httptest.Server
+	notifier
+
+	Mchid    string
+	ApiV3Key string
+	// MerchantPublicKey 商户 API 证书公钥，设置后校验请求头 Authorization 签名，签名错误或 nonce_str 重复使用时返回 401 SIGN_ERROR
+	MerchantPublicKey *rsa.PublicKey
+
+	certMu  sync.RWMutex
+	key     *rsa.PrivateKey
+	cert    *x509.Certificate
+	mu      sync.Mutex
+	orders  map[string]*wechatV3Order  // key: out_trade_no
+	refunds map[string]*wechatV3Refund // key: out_refund_no
+	fails   map[string]string          // key: 接口路径前缀，value: 错误码
+	nonces  map[string]bool            // 已使用的请求 nonce_str
+}
+
+type wechatV3Order struct {
+	AppId         string
+	OutTradeNo    string
+	TransactionId string
+	TradeType     string
+	Description   string
+	Total         int64
+	Currency      string
+	State         string
+	NotifyURL     string
+	OpenId        string
+	SuccessTime   time.Time
+	Refunded      int64
+}
+
+type wechatV3Refund struct {
+	OutRefundNo string
+	RefundId    string
+	OutTradeNo  string
+	Refund      int64
+	Status      string
+	NotifyURL   string
+	CreateTime  time.Time
+	SuccessTime time.Time
+}
+
+// NewWechatV3Server 启动微信支付 v3 模拟服务，apiV3Key 需为 32 字节
+func NewWechatV3Server(mchid, apiV3Key string) *WechatV3Server {
+	s := &WechatV3Server{
+		Mchid:    mchid,
+		ApiV3Key: apiV3Key,
+		orders:   make(map[string]*wechatV3Order),
+		refunds:  make(map[string]*wechatV3Refund),
+		fails:    make(map[string]string),
+		nonces:   make(map[string]bool),
+	}
+	s.RotateCert()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// SerialNo 平台证书序列号
+func (s *WechatV3Server) SerialNo() string {
+	_, cert := s.platform()
+	return serialNo(cert)
+}
+
+// PlatformCert 平台证书内容，用于 client.SetPlatformCert()
+func (s *WechatV3Server) PlatformCert() []byte {
+	_, cert := s.platform()
+	return certPEM(cert)
+}
+
+// RotateCert 更换平台证书，之后的应答、通知使用新证书签名，下载平台证书接口只返回新证书
+func (s *WechatV3Server) RotateCert() {
+	key := newRSAKey()
+	cert := newCert("paytest wechatpay", &key.PublicKey, key, nil, false)
+	s.certMu.Lock()
+	s.key, s.cert = key, cert
+	s.certMu.Unlock()
+}
+
+func (s *WechatV3Server) platform() (*rsa.PrivateKey, *x509.Certificate) {
+	s.certMu.RLock()
+	defer s.certMu.RUnlock()
+	return s.key, s.cert
+}
+
+func serialNo(cert *x509.Certificate) string {
+	return strings.ToUpper(cert.SerialNumber.Text(16))
+}
+
+// FailNext 下一次调用 path 前缀匹配的接口时返回错误码，如 SYSTEM_ERROR、FREQUENCY_LIMITED、NOT_ENOUGH
+func (s *WechatV3Server) FailNext(path, code string) {
+	s.mu.Lock()
+	s.fails[path] = code
+	s.mu.Unlock()
+}
+
+// TradeState 返回订单状态，订单不存在时返回空字符串
+func (s *WechatV3Server) TradeState(outTradeNo string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o := s.orders[outTradeNo]; o != nil {
+		return o.State
+	}
+	return util.NULL
+}
+
+// Pay 模拟用户完成支付，订单状态变为 SUCCESS，并发送 TRANSACTION.SUCCESS 异步通知
+func (s *WechatV3Server) Pay(outTradeNo string) (*Notification, error) {
+	s.mu.Lock()
+	o := s.orders[outTradeNo]
+	if o == nil || o.State != WechatNotPay {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("paytest: wechat order %s not exist or not NOTPAY", outTradeNo)
+	}
+	o.State, o.SuccessTime = WechatSuccess, time.Now()
+	data := s.orderBodyMap(o)
+	s.mu.Unlock()
+	return s.sendNotify(o.NotifyURL, "TRANSACTION.SUCCESS", "transaction", "支付成功", data)
+}
+
+// CompleteRefund 模拟退款到账，退款状态由 PROCESSING 变为 SUCCESS，申请退款时传了 notify_url 则发送 REFUND.SUCCESS 异步通知
+func (s *WechatV3Server) CompleteRefund(outRefundNo string) (*Notification, error) {
+	s.mu.Lock()
+	r := s.refunds[outRefundNo]
+	if r == nil || r.Status != "PROCESSING" {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("paytest: wechat refund %s not exist or not PROCESSING", outRefundNo)
+	}
+	r.Status, r.SuccessTime = WechatSuccess, time.Now()
+	o := s.orders[r.OutTradeNo]
+	data := make(pay.BodyMap)
+	data.Set("mchid", s.Mchid).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("transaction_id", o.TransactionId).
+		Set("out_refund_no", r.OutRefundNo).
+		Set("refund_id", r.RefundId).
+		Set("refund_status", r.Status).
+		Set("success_time", r.SuccessTime.Format(time.RFC3339)).
+		Set("user_received_account", "支付用户零钱").
+		SetBodyMap("amount", func(b pay.BodyMap) {
+			b.Set("total", o.Total).
+				Set("refund", r.Refund).
+				Set("payer_total", o.Total).
+				Set("payer_refund", r.Refund)
+		})
+	s.mu.Unlock()
+	if r.NotifyURL == util.NULL {
+		return nil, nil
+	}
+	return s.sendNotify(r.NotifyURL, "REFUND.SUCCESS", "refund", "退款成功", data)
+}
+
+// sendNotify 发送 APIv3 异步通知，resource 使用 APIv3 密钥 AEAD_AES_256_GCM 加密
+func (s *WechatV3Server) sendNotify(url, eventType, resourceType, summary string, data pay.BodyMap) (*Notification, error) {
+	plain, _ := json.Marshal(data)
+	nonce, cipherText, err := aes.GCMEncrypt(plain, []byte(resourceType), []byte(s.ApiV3Key))
+	if err != nil {
+		return nil, err
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":            newId(""),
+		"create_time":   time.Now().Format(time.RFC3339),
+		"resource_type": "encrypt-resource",
+		"event_type":    eventType,
+		"summary":       summary,
+		"resource": map[string]string{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      base64.StdEncoding.EncodeToString(cipherText),
+			"associated_data": resourceType,
+			"original_type":   resourceType,
+			"nonce":           string(nonce),
+		},
+	})
+	return s.notify(url, "application/json", s.signHeader(body), body)
+}
+
+// signHeader 应答/通知签名：timestamp\nnonce\nbody\n
+func (s *WechatV3Server) signHeader(body []byte) http.Header {
+	key, cert := s.platform()
+	h := make(http.Header)
+	wechatV3Sign(h, key, cert, body)
+	h.Set("Request-ID", newId(""))
+	return h
+}
+
+// wechatV3Sign 设置 Wechatpay-Timestamp/Nonce/Signature/Serial 头
+func wechatV3Sign(h http.Header, key *rsa.PrivateKey, cert *x509.Certificate, body []byte) {
+	ts, nonce := strconv.FormatInt(time.Now().Unix(), 10), util.RandomString(32)
+	h.Set("Wechatpay-Timestamp", ts)
+	h.Set("Wechatpay-Nonce", nonce)
+	h.Set("Wechatpay-Signature", signSHA256WithRSA(key, ts+"\n"+nonce+"\n"+string(body)+"\n"))
+	h.Set("Wechatpay-Serial", serialNo(cert))
+}
+
+var wechatV3AuthRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// verifyAuthorization 校验 WECHATPAY2-SHA256-RSA2048 mchid="",nonce_str="",timestamp="",serial_no="",signature=""
+func (s *WechatV3Server) verifyAuthorization(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "WECHATPAY2-SHA256-RSA2048 ") {
+		return fmt.Errorf("Authorization 格式错误")
+	}
+	fields := make(map[string]string)
+	for _, m := range wechatV3AuthRegexp.FindAllStringSubmatch(auth, -1) {
+		fields[m[1]] = m[2]
+	}
+	str := r.Method + "\n" + r.URL.RequestURI() + "\n" + fields["timestamp"] + "\n" + fields["nonce_str"] + "\n" + string(body) + "\n"
+	if err := verifySHA256WithRSA(s.MerchantPublicKey, str, fields["signature"]); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nonces[fields["nonce_str"]] {
+		return fmt.Errorf("nonce_str 重复使用")
+	}
+	s.nonces[fields["nonce_str"]] = true
+	return nil
+}
+
+func (s *WechatV3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	if s.MerchantPublicKey != nil {
+		if err := s.verifyAuthorization(r, body); err != nil {
+			s.writeErr(w, http.StatusUnauthorized, "SIGN_ERROR", "签名错误")
+			return
+		}
+	}
+	req := make(pay.BodyMap)
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.writeErr(w, http.StatusBadRequest, "PARAM_ERROR", "请求体格式错误")
+			return
+		}
+	}
+	path := r.URL.Path
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for prefix, code := range s.fails {
+		if strings.HasPrefix(path, prefix) {
+			delete(s.fails, prefix)
+			s.writeErr(w, wechatV3ErrStatus[code], code, code)
+			return
+		}
+	}
+	switch {
+	case r.Method == http.MethodGet && path == "/v3/certificates":
+		s.certificates(w)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/v3/pay/transactions/") && !strings.HasSuffix(path, "/close"):
+		s.prepay(w, strings.TrimPrefix(path, "/v3/pay/transactions/"), req)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v3/pay/transactions/out-trade-no/"):
+		s.queryOrder(w, s.orders[strings.TrimPrefix(path, "/v3/pay/transactions/out-trade-no/")])
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v3/pay/transactions/id/"):
+		s.queryOrder(w, s.findOrder(strings.TrimPrefix(path, "/v3/pay/transactions/id/")))
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/close"):
+		s.closeOrder(w, strings.TrimSuffix(strings.TrimPrefix(path, "/v3/pay/transactions/out-trade-no/"), "/close"))
+	case r.Method == http.MethodPost && path == "/v3/refund/domestic/refunds":
+		s.refund(w, req)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v3/refund/domestic/refunds/"):
+		if rf := s.refunds[strings.TrimPrefix(path, "/v3/refund/domestic/refunds/")]; rf != nil {
+			s.write(w, http.StatusOK, s.refundBodyMap(rf))
+			return
+		}
+		s.writeErr(w, http.StatusNotFound, "RESOURCE_NOT_EXISTS", "退款单不存在")
+	default:
+		s.writeErr(w, http.StatusNotFound, "NOT_FOUND", "接口不存在")
+	}
+}
+
+// 错误码对应的 HTTP 状态码
+var wechatV3ErrStatus = map[string]int{
+	"SYSTEM_ERROR":        http.StatusInternalServerError,
+	"FREQUENCY_LIMITED":   http.StatusTooManyRequests,
+	"SIGN_ERROR":          http.StatusUnauthorized,
+	"NOT_ENOUGH":          http.StatusForbidden,
+	"ORDERPAID":           http.StatusForbidden,
+	"ORDER_CLOSED":        http.StatusBadRequest,
+	"ORDER_NOT_EXIST":     http.StatusNotFound,
+	"RESOURCE_NOT_EXISTS": http.StatusNotFound,
+	"PARAM_ERROR":         http.StatusBadRequest,
+	"INVALID_REQUEST":     http.StatusBadRequest,
+}
+
+func (s *WechatV3Server) write(w http.ResponseWriter, status int, rsp interface{}) {
+	var body []byte
+	if rsp != nil {
+		body, _ = json.Marshal(rsp)
+	}
+	for k, v := range s.signHeader(body) {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Request-ID", util.RandomString(32))
+	if body != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func (s *WechatV3Server) writeErr(w http.ResponseWriter, status int, code, message string) {
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	s.write(w, status, map[string]string{"code": code, "message": message})
+}
+
+func (s *WechatV3Server) certificates(w http.ResponseWriter) {
+	_, cert := s.platform()
+	rsp, err := wechatV3Certificates(cert, s.ApiV3Key)
+	if err != nil {
+		s.writeErr(w, http.StatusInternalServerError, "SYSTEM_ERROR", err.Error())
+		return
+	}
+	s.write(w, http.StatusOK, rsp)
+}
+
+// wechatV3Certificates 下载平台证书应答，证书使用 APIv3 密钥加密
+func wechatV3Certificates(cert *x509.Certificate, apiV3Key string) (map[string]interface{}, error) {
+	nonce, cipherText, err := aes.GCMEncrypt(certPEM(cert), []byte("certificate"), []byte(apiV3Key))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"data": []map[string]interface{}{{
+			"serial_no":      serialNo(cert),
+			"effective_time": cert.NotBefore.Local().Format(time.RFC3339),
+			"expire_time":    cert.NotAfter.Local().Format(time.RFC3339),
+			"encrypt_certificate": map[string]string{
+				"algorithm":       "AEAD_AES_256_GCM",
+				"nonce":           string(nonce),
+				"associated_data": "certificate",
+				"ciphertext":      base64.StdEncoding.EncodeToString(cipherText),
+			},
+		}},
+	}, nil
+}
+
+func (s *WechatV3Server) findOrder(transactionId string) *wechatV3Order {
+	for _, o := range s.orders {
+		if o.TransactionId == transactionId {
+			return o
+		}
+	}
+	return nil
+}
+
+func (s *WechatV3Server) prepay(w http.ResponseWriter, tradeType string, req pay.BodyMap) {
+	var amount struct {
+		Total    int64  `json:"total"`
+		Currency string `json:"currency"`
+	}
+	_ = json.Unmarshal([]byte(req.GetString("amount")), &amount)
+	outTradeNo := req.GetString("out_trade_no")
+	if outTradeNo == util.NULL || amount.Total <= 0 {
+		s.writeErr(w, http.StatusBadRequest, "PARAM_ERROR", "out_trade_no 或 amount.total 无效")
+		return
+	}
+	o := s.orders[outTradeNo]
+	switch {
+	case o != nil && o.State == WechatSuccess:
+		s.writeErr(w, http.StatusForbidden, "ORDERPAID", "该订单已支付")
+		return
+	case o != nil && o.State == WechatClosed:
+		s.writeErr(w, http.StatusBadRequest, "ORDER_CLOSED", "该订单已关闭")
+		return
+	case o == nil:
+		o = &wechatV3Order{OutTradeNo: outTradeNo, TransactionId: newId("42"), State: WechatNotPay}
+		s.orders[outTradeNo] = o
+	}
+	o.AppId, o.TradeType, o.Description = req.GetString("appid"), strings.ToUpper(tradeType), req.GetString("description")
+	o.Total, o.Currency, o.NotifyURL = amount.Total, amount.Currency, req.GetString("notify_url")
+	if o.Currency == util.NULL {
+		o.Currency = "CNY"
+	}
+	var payer struct {
+		OpenId string `json:"openid"`
+	}
+	if err := json.Unmarshal([]byte(req.GetString("payer")), &payer); err == nil {
+		o.OpenId = payer.OpenId
+	}
+	prepayId := "wx" + o.TransactionId
+	rsp := make(pay.BodyMap)
+	switch tradeType {
+	case "native":
+		rsp.Set("code_url", "weixin://wxpay/bizpayurl?pr="+prepayId)
+	case "h5":
+		rsp.Set("h5_url", s.URL+"/cgi-bin/mmpayweb-bin/checkmweb?prepay_id="+prepayId)
+	default:
+		rsp.Set("prepay_id", prepayId)
+	}
+	s.write(w, http.StatusOK, rsp)
+}
+
+func (s *WechatV3Server) orderBodyMap(o *wechatV3Order) pay.BodyMap {
+	rsp := make(pay.BodyMap)
+	rsp.Set("appid", o.AppId).
+		Set("mchid", s.Mchid).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("transaction_id", o.TransactionId).
+		Set("trade_type", o.TradeType).
+		Set("trade_state", o.State).
+		Set("trade_state_desc", o.State).
+		SetBodyMap("amount", func(b pay.BodyMap) {
+			b.Set("total", o.Total).Set("currency", o.Currency)
+			if !o.SuccessTime.IsZero() {
+				b.Set("payer_total", o.Total).Set("payer_currency", o.Currency)
+			}
+		})
+	if !o.SuccessTime.IsZero() {
+		rsp.Set("success_time", o.SuccessTime.Format(time.RFC3339)).
+			Set("bank_type", "OTHERS").
+			SetBodyMap("payer", func(b pay.BodyMap) {
+				b.Set("openid", o.OpenId)
+			})
+	}
+	return rsp
+}
+
+func (s *WechatV3Server) queryOrder(w http.ResponseWriter, o *wechatV3Order) {
+	if o == nil {
+		s.writeErr(w, http.StatusNotFound, "ORDER_NOT_EXIST", "订单不存在")
+		return
+	}
+	s.write(w, http.StatusOK, s.orderBodyMap(o))
+}
+
+func (s *WechatV3Server) closeOrder(w http.ResponseWriter, outTradeNo string) {
+	o := s.orders[outTradeNo]
+	switch {
+	case o == nil:
+		s.writeErr(w, http.StatusNotFound, "ORDER_NOT_EXIST", "订单不存在")
+	case o.State == WechatSuccess || o.State == WechatRefund:
+		s.writeErr(w, http.StatusBadRequest, "ORDERPAID", "该订单已支付")
+	default:
+		o.State = WechatClosed
+		s.write(w, http.StatusNoContent, nil)
+	}
+}
+
+func (s *WechatV3Server) refund(w http.ResponseWriter, req pay.BodyMap) {
+	var amount struct {
+		Refund int64 `json:"refund"`
+		Total  int64 `json:"total"`
+	}
+	_ = json.Unmarshal([]byte(req.GetString("amount")), &amount)
+	outRefundNo := req.GetString("out_refund_no")
+	if rf := s.refunds[outRefundNo]; rf != nil {
+		s.write(w, http.StatusOK, s.refundBodyMap(rf))
+		return
+	}
+	o := s.orders[req.GetString("out_trade_no")]
+	if tid := req.GetString("transaction_id"); tid != util.NULL {
+		o = s.findOrder(tid)
+	}
+	switch {
+	case o == nil:
+		s.writeErr(w, http.StatusNotFound, "RESOURCE_NOT_EXISTS", "订单不存在")
+		return
+	case o.State != WechatSuccess && o.State != WechatRefund:
+		s.writeErr(w, http.StatusForbidden, "INVALID_REQUEST", "订单未支付")
+		return
+	case outRefundNo == util.NULL || amount.Refund <= 0 || amount.Total != o.Total:
+		s.writeErr(w, http.StatusBadRequest, "PARAM_ERROR", "out_refund_no 或 amount 无效")
+		return
+	case o.Refunded+amount.Refund > o.Total:
+		s.writeErr(w, http.StatusForbidden, "NOT_ENOUGH", "退款金额超过订单可退金额")
+		return
+	}
+	o.Refunded += amount.Refund
+	o.State = WechatRefund
+	rf := &wechatV3Refund{
+		OutRefundNo: outRefundNo,
+		RefundId:    newId("50"),
+		OutTradeNo:  o.OutTradeNo,
+		Refund:      amount.Refund,
+		Status:      "PROCESSING",
+		NotifyURL:   req.GetString("notify_url"),
+		CreateTime:  time.Now(),
+	}
+	s.refunds[outRefundNo] = rf
+	s.write(w, http.StatusOK, s.refundBodyMap(rf))
+}
+
+func (s *WechatV3Server) refundBodyMap(rf *wechatV3Refund) pay.BodyMap {
+	o := s.orders[rf.OutTradeNo]
+	rsp := make(pay.BodyMap)
+	rsp.Set("refund_id", rf.RefundId).
+		Set("out_refund_no", rf.OutRefundNo).
+		Set("transaction_id", o.TransactionId).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("channel", "ORIGINAL").
+		Set("user_received_account", "支付用户零钱").
+		Set("create_time", rf.CreateTime.Format(time.RFC3339)).
+		Set("status", rf.Status).
+		SetBodyMap("amount", func(b pay.BodyMap) {
+			b.Set("total", o.Total).
+				Set("refund", rf.Refund).
+				Set("payer_total", o.Total).
+				Set("payer_refund", rf.Refund).
+				Set("currency", o.Currency)
+		})
+	if !rf.SuccessTime.IsZero() {
+		rsp.Set("success_time", rf.SuccessTime.Format(time.RFC3339))
+	}
+	return rsp
+}
@@ -0,0 +1,383 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paytest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/notify"
+	"github.com/rwscode/payutil/paytest"
+	"github.com/rwscode/payutil/pkg/idempotency"
+	"github.com/rwscode/payutil/pkg/retry"
+	wechatv3 "github.com/rwscode/payutil/wechat/v3"
+)
+
+func TestWechatV3Server(t *testing.T) {
+	ctx := context.Background()
+	apiV3Key := "Cj5xC9RXf0GFCKWeD9PyY1ZWLgionbvx"
+	srv := paytest.NewWechatV3Server("1368139502", apiV3Key)
+	defer srv.Close()
+	_, _, key := paytest.NewRSAKeyPair()
+	srv.MerchantPublicKey = &key.PublicKey
+
+	client, err := wechatv3.NewClientV3("1368139502", "5B2C4F9A", apiV3Key, paytest.PrivateKeyPEM(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.URL)
+	if err = client.AutoVerifySign(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if client.WxSerialNo != srv.SerialNo() {
+		t.Fatalf("WxSerialNo = %s, want %s", client.WxSerialNo, srv.SerialNo())
+	}
+	g := wechatv3.NewGateway(client, "wx2421b1c4370ec43b")
+
+	var events []*notify.Event
+	router := notify.NewRouter().On(notify.EventAll, func(ctx context.Context, e *notify.Event) error {
+		events = append(events, e)
+		return nil
+	})
+	notifySrv := httptest.NewServer(router.Handler(&notify.WechatV3Source{Client: client}))
+	defer notifySrv.Close()
+
+	charge, err := g.Charge(ctx, &pay.Order{OutTradeNo: "W001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, NotifyUrl: notifySrv.URL})
+	if err != nil || charge.CodeUrl == "" {
+		t.Fatalf("Charge = %+v, %v", charge, err)
+	}
+	if _, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "W404"}); !errors.Is(err, pay.OrderNotExistErr) {
+		t.Fatalf("Query not exist err = %v", err)
+	}
+	nt, err := srv.Pay("W001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nt.StatusCode != 200 || len(events) != 1 || events[0].Type != notify.EventPayment || events[0].OutTradeNo != "W001" {
+		t.Fatalf("notify = %d %s, events = %+v", nt.StatusCode, nt.Response, events)
+	}
+	if _, ok := events[0].Data.(*wechatv3.V3DecryptResult); !ok {
+		t.Fatalf("event data = %T", events[0].Data)
+	}
+	order, err := g.Query(ctx, &pay.OrderQuery{OutTradeNo: "W001"})
+	if err != nil || order.Status != pay.TradeStatusPaid || order.Amount != 1000 {
+		t.Fatalf("Query = %+v, %v", order, err)
+	}
+	if err = g.Close(ctx, &pay.OrderQuery{OutTradeNo: "W001"}); !errors.Is(err, pay.OrderPaidErr) {
+		t.Fatalf("Close paid order err = %v", err)
+	}
+
+	refund, err := g.Refund(ctx, &pay.Refund{OutTradeNo: "W001", OutRefundNo: "WR001", Amount: 300, Total: 1000, NotifyUrl: notifySrv.URL})
+	if err != nil || refund.Status != pay.RefundStatusProcessing {
+		t.Fatalf("Refund = %+v, %v", refund, err)
+	}
+	if _, err = g.Refund(ctx, &pay.Refund{OutTradeNo: "W001", OutRefundNo: "WR002", Amount: 800, Total: 1000}); !errors.Is(err, pay.InsufficientFundsErr) {
+		t.Fatalf("Refund over amount err = %v", err)
+	}
+	if _, err = srv.CompleteRefund("WR001"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Type != notify.EventRefund || events[1].OutRefundNo != "WR001" {
+		t.Fatalf("events = %+v", events)
+	}
+	refund, err = g.QueryRefund(ctx, &pay.RefundQuery{OutRefundNo: "WR001"})
+	if err != nil || refund.Status != pay.RefundStatusSuccess || refund.Amount != 300 {
+		t.Fatalf("QueryRefund = %+v, %v", refund, err)
+	}
+
+	srv.FailNext("/v3/pay/transactions/out-trade-no/", "SYSTEM_ERROR")
+	_, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "W001"})
+	if apiErr, ok := pay.AsAPIError(err); !ok || !apiErr.Retryable || apiErr.RequestId == "" {
+		t.Fatalf("FailNext err = %v", err)
+	}
+
+	// 重试时重新签名，重复的 nonce_str 会被拒绝
+	client.SetRetryPolicy(&retry.Policy{MaxAttempts: 2, InitialInterval: time.Millisecond})
+	srv.FailNext("/v3/pay/transactions/out-trade-no/", "SYSTEM_ERROR")
+	if order, err = g.Query(ctx, &pay.OrderQuery{OutTradeNo: "W001"}); err != nil || order.OutTradeNo != "W001" {
+		t.Fatalf("Query retry = %+v, %v", order, err)
+	}
+}
+
+// lostResponse 记录 POST 请求次数，并丢弃前 drop 次应答，模拟渠道已受理但客户端超时
+type lostResponse struct {
+	posts, drop int
+}
+
+func (d *lostResponse) Do(req *http.Request) (*http.Response, error) {
+	res, err := http.DefaultClient.Do(req)
+	if err != nil || req.Method != http.MethodPost {
+		return res, err
+	}
+	if d.posts++; d.posts <= d.drop {
+		res.Body.Close()
+		return nil, errors.New("read tcp: i/o timeout")
+	}
+	return res, nil
+}
+
+func TestWechatV3Server_Idempotency(t *testing.T) {
+	ctx := context.Background()
+	apiV3Key := "Cj5xC9RXf0GFCKWeD9PyY1ZWLgionbvx"
+	srv := paytest.NewWechatV3Server("1368139502", apiV3Key)
+	defer srv.Close()
+	_, _, key := paytest.NewRSAKeyPair()
+	client, err := wechatv3.NewClientV3("1368139502", "5B2C4F9A", apiV3Key, paytest.PrivateKeyPEM(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.URL)
+	if err = client.AutoVerifySign(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	g := wechatv3.NewGateway(client, "wx2421b1c4370ec43b")
+	notifySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer notifySrv.Close()
+	if _, err = g.Charge(ctx, &pay.Order{OutTradeNo: "W001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, NotifyUrl: notifySrv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srv.Pay("W001"); err != nil {
+		t.Fatal(err)
+	}
+
+	doer := &lostResponse{drop: 1}
+	client.SetDoer(doer)
+	client.SetIdempotency(idempotency.New(idempotency.NewMemoryStore()))
+	bm := make(pay.BodyMap)
+	bm.Set("out_trade_no", "W001").
+		Set("out_refund_no", "WR001").
+		SetBodyMap("amount", func(b pay.BodyMap) {
+			b.Set("refund", 300).Set("total", 1000).Set("currency", "CNY")
+		})
+
+	// 渠道已受理，应答丢失
+	if _, err = client.V3Refund(ctx, bm); err == nil {
+		t.Fatal("V3Refund want timeout error")
+	}
+	// 查询到退款已受理，不再重复发起
+	wxRsp, err := client.V3Refund(ctx, bm)
+	if err != nil || wxRsp.Code != wechatv3.Success || wxRsp.Response.OutRefundNo != "WR001" || doer.posts != 1 {
+		t.Fatalf("V3Refund = %+v, %v, posts = %d", wxRsp, err, doer.posts)
+	}
+	// 重放保存的结果
+	wxRsp, err = client.V3Refund(ctx, bm)
+	if err != nil || wxRsp.Response.RefundId == "" || doer.posts != 1 {
+		t.Fatalf("V3Refund replay = %+v, %v, posts = %d", wxRsp, err, doer.posts)
+	}
+	refund, err := g.QueryRefund(ctx, &pay.RefundQuery{OutRefundNo: "WR001"})
+	if err != nil || refund.Amount != 300 {
+		t.Fatalf("QueryRefund = %+v, %v", refund, err)
+	}
+
+	// 渠道明确拒绝时不保存结果，修正参数后可用同一单号重试
+	bm.Set("out_refund_no", "WR002").SetBodyMap("amount", func(b pay.BodyMap) {
+		b.Set("refund", 800).Set("total", 1000).Set("currency", "CNY")
+	})
+	if wxRsp, err = client.V3Refund(ctx, bm); err != nil || wxRsp.Code != http.StatusForbidden {
+		t.Fatalf("V3Refund over amount = %+v, %v", wxRsp, err)
+	}
+	bm.SetBodyMap("amount", func(b pay.BodyMap) {
+		b.Set("refund", 700).Set("total", 1000).Set("currency", "CNY")
+	})
+	if wxRsp, err = client.V3Refund(ctx, bm); err != nil || wxRsp.Code != wechatv3.Success {
+		t.Fatalf("V3Refund retry = %+v, %v", wxRsp, err)
+	}
+}
+
+func TestWechatV3Server_Close(t *testing.T) {
+	apiV3Key := "Cj5xC9RXf0GFCKWeD9PyY1ZWLgionbvx"
+	srv := paytest.NewWechatV3Server("1368139502", apiV3Key)
+	defer srv.Close()
+	_, _, key := paytest.NewRSAKeyPair()
+	client, err := wechatv3.NewClientV3("1368139502", "5B2C4F9A", apiV3Key, paytest.PrivateKeyPEM(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.URL)
+
+	// 获取平台证书遵循调用方 ctx
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = client.AutoVerifySign(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("AutoVerifySign with canceled ctx = %v, want context.Canceled", err)
+	}
+	if err = client.AutoVerifySign(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Close(); err != nil {
+		t.Fatalf("second Close = %v", err)
+	}
+	// Close 后仍可请求、验签
+	if _, err = client.V3TransactionQueryOrder(context.Background(), wechatv3.OutTradeNo, "W404"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWechatV3Server_CertRefresher(t *testing.T) {
+	ctx := context.Background()
+	apiV3Key := "Cj5xC9RXf0GFCKWeD9PyY1ZWLgionbvx"
+	srv := paytest.NewWechatV3Server("1368139502", apiV3Key)
+	defer srv.Close()
+	_, _, key := paytest.NewRSAKeyPair()
+	client, err := wechatv3.NewClientV3("1368139502", "5B2C4F9A", apiV3Key, paytest.PrivateKeyPEM(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.URL)
+	if err = client.AutoVerifySign(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// 应答携带未知的平台证书序列号时立即刷新
+	r := wechatv3.NewCertRefresher(client)
+	r.Start()
+	if client.CertRefresher() != r {
+		t.Fatal("CertRefresher() should return the started refresher")
+	}
+	srv.RotateCert()
+	g := wechatv3.NewGateway(client, "wx2421b1c4370ec43b")
+	if _, err = g.Charge(ctx, &pay.Order{OutTradeNo: "W001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, NotifyUrl: "https://example.com/notify"}); err != nil {
+		t.Fatal(err)
+	}
+	if st := r.Stats(); st.Refreshes != 1 || client.WxSerialNo != srv.SerialNo() {
+		t.Fatalf("Stats = %+v, WxSerialNo = %s, want %s", st, client.WxSerialNo, srv.SerialNo())
+	}
+
+	// 定时刷新，失败时回调并按 RetryInterval 重试
+	failed := make(chan error, 1)
+	r2 := wechatv3.NewCertRefresher(client)
+	r2.Interval, r2.RetryInterval, r2.Jitter = 20*time.Millisecond, 10*time.Millisecond, 0
+	r2.OnError = func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	}
+	srv.FailNext("/v3/certificates", "SYSTEM_ERROR")
+	r2.Start()
+	if client.CertRefresher() != r2 {
+		t.Fatal("Start() should replace the running refresher")
+	}
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError not called")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for r2.Stats().Refreshes < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Stats = %+v", r2.Stats())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if st := r2.Stats(); st.Failures != 1 || st.LastError != nil {
+		t.Fatalf("Stats = %+v", st)
+	}
+
+	// Close 停止刷新器并等待后台协程退出
+	if err = client.Close(); err != nil || client.CertRefresher() != nil {
+		t.Fatalf("Close = %v, CertRefresher = %v", err, client.CertRefresher())
+	}
+	st := r2.Stats()
+	time.Sleep(60 * time.Millisecond)
+	if r2.Stats().Refreshes != st.Refreshes {
+		t.Fatalf("refresher still running after Close: %+v", r2.Stats())
+	}
+}
+
+// slowDoer 延迟平台证书请求
+type slowDoer struct {
+	delay time.Duration
+}
+
+func (d *slowDoer) Do(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, "/v3/certificates") {
+		time.Sleep(d.delay)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+func TestWechatV3Server_CertRefresherShared(t *testing.T) {
+	ctx := context.Background()
+	apiV3Key := "Cj5xC9RXf0GFCKWeD9PyY1ZWLgionbvx"
+	srv := paytest.NewWechatV3Server("1368139502", apiV3Key)
+	defer srv.Close()
+	_, _, key := paytest.NewRSAKeyPair()
+	client, err := wechatv3.NewClientV3("1368139502", "5B2C4F9A", apiV3Key, paytest.PrivateKeyPEM(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetBaseURL(srv.URL)
+	if err = client.AutoVerifySign(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// 请求进行中启动刷新器，开启自动验签
+	g := wechatv3.NewGateway(client, "wx2421b1c4370ec43b")
+	if _, err = g.Charge(ctx, &pay.Order{OutTradeNo: "W001", Subject: "test", Amount: 1000, Scene: pay.SceneQRCode, NotifyUrl: "https://example.com/notify"}); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	started := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			_, _ = client.V3TransactionQueryOrder(ctx, wechatv3.OutTradeNo, "W001")
+			select {
+			case <-started:
+				return
+			default:
+			}
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	r := wechatv3.NewCertRefresher(client)
+	r.MinInterval = 0
+	r.Start()
+	close(started)
+	wg.Wait()
+
+	// 首个调用方取消不影响其他等待同一次刷新的调用方
+	client.SetDoer(&slowDoer{delay: 50 * time.Millisecond})
+	cctx, cancel := context.WithCancel(ctx)
+	first := make(chan error, 1)
+	go func() { first <- r.Refresh(cctx) }()
+	time.Sleep(10 * time.Millisecond)
+	second := make(chan error, 1)
+	go func() { second <- r.Refresh(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err = <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Refresh = %v", err)
+	}
+	if err = <-second; err != nil {
+		t.Fatalf("waiting Refresh = %v", err)
+	}
+	if st := r.Stats(); st.Refreshes != 1 || st.Failures != 0 {
+		t.Fatalf("Stats = %+v", st)
+	}
+}
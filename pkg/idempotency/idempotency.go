@@ -0,0 +1,210 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package idempotency 客户端幂等保护：按业务单号（out_refund_no、out_batch_no、out_biz_no、PayPal-Request-Id 等）记录每个逻辑操作的结果，
+// 请求超时等结果未知时，再次调用会先查询渠道确认是否已生效，已生效则直接返回，避免重复退款、重复转账
+//
+//	已成功的操作再次调用时直接重放保存的结果，不再请求渠道
+//	渠道明确拒绝（参数错误、余额不足等）时删除记录，允许修正后以同一单号重试
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInProgress 同一幂等键的操作正在其他协程或进程中执行
+var ErrInProgress = errors.New("idempotency: operation in progress")
+
+// Status 操作结果的来源
+type Status int
+
+const (
+	Created   Status = iota + 1 // 本次调用创建成功
+	Replayed                    // 重放已保存的结果，未请求渠道
+	Recovered                   // 上次结果未知，本次查询渠道确认已生效
+)
+
+const (
+	statePending = "pending"
+	stateDone    = "done"
+)
+
+type record struct {
+	State  string          `json:"state"`
+	Owner  string          `json:"owner,omitempty"` // pending 记录的持有者，每次获取租约随机生成，保证 SetIfMatch 比较的记录唯一
+	Lease  time.Time       `json:"lease,omitempty"` // pending 记录的租约到期时间，到期前其他调用返回 ErrInProgress
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Operation 一次需要幂等保护的写操作
+type Operation struct {
+	// Create 发起请求，返回需要保存的结果
+	//	err 为 nil：成功，保存结果供重放
+	//	Rejected(err)：渠道明确拒绝，请求未生效，删除记录
+	//	其他 err：结果未知（超时、5xx、应答验签失败等），保留记录，下次调用先 Query
+	Create func(ctx context.Context) (result interface{}, err error)
+	// Query 查询上次结果未知的请求是否已在渠道生效，found 为 true 时保存 result 并不再 Create
+	//	可为 nil，表示渠道自身按幂等键去重（如 PayPal-Request-Id），直接重新 Create
+	Query func(ctx context.Context) (result interface{}, found bool, err error)
+}
+
+// Guard 幂等保护
+type Guard struct {
+	Store Store
+	TTL   time.Duration // 记录保留时长，默认 24h，应不短于业务单号的重试周期
+	Lease time.Duration // 单次执行的租约，默认 2min，应大于请求超时时间，进程崩溃后租约到期即可重新执行
+}
+
+func New(s Store) *Guard {
+	return &Guard{Store: s}
+}
+
+// Key 生成幂等键，如 Key("wechat", mchid, "refund", outRefundNo)
+func Key(parts ...string) string {
+	return strings.Join(parts, ":")
+}
+
+// Do 以 key 幂等执行 op
+//
+//	Status 为 Replayed 时，保存的结果通过 json 解码到 out；Created、Recovered 时结果由 op 自行返回给调用方
+func (g *Guard) Do(ctx context.Context, key string, out interface{}, op Operation) (Status, error) {
+	bs, ok, err := g.Store.Get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("idempotency: get %s: %w", key, err)
+	}
+	owner := newOwner()
+	pending, _ := json.Marshal(&record{State: statePending, Owner: owner, Lease: time.Now().Add(g.lease())})
+	var query bool
+	if ok {
+		rec := new(record)
+		if err = json.Unmarshal(bs, rec); err != nil {
+			return 0, fmt.Errorf("idempotency: decode %s: %w", key, err)
+		}
+		if rec.State == stateDone {
+			if out != nil {
+				if err = json.Unmarshal(rec.Result, out); err != nil {
+					return 0, fmt.Errorf("idempotency: decode %s: %w", key, err)
+				}
+			}
+			return Replayed, nil
+		}
+		if time.Now().Before(rec.Lease) {
+			return 0, ErrInProgress
+		}
+		// 上次结果未知或执行中断，仅当记录仍为读取到的值时续租，并发调用只有一个成功，续租后先查询
+		if ok, err = g.Store.SetIfMatch(ctx, key, bs, pending, g.ttl()); err != nil {
+			return 0, fmt.Errorf("idempotency: set %s: %w", key, err)
+		}
+		if !ok {
+			return 0, ErrInProgress
+		}
+		query = true
+	} else {
+		if ok, err = g.Store.SetNX(ctx, key, pending, g.ttl()); err != nil {
+			return 0, fmt.Errorf("idempotency: set %s: %w", key, err)
+		}
+		if !ok {
+			return 0, ErrInProgress
+		}
+	}
+
+	if query && op.Query != nil {
+		result, found, err := op.Query(ctx)
+		if err != nil {
+			g.release(ctx, key, owner, pending)
+			return 0, err
+		}
+		if found {
+			g.done(ctx, key, result)
+			return Recovered, nil
+		}
+	}
+	result, err := op.Create(ctx)
+	if err == nil {
+		g.done(ctx, key, result)
+		return Created, nil
+	}
+	var rejected *rejectedError
+	if errors.As(err, &rejected) {
+		_ = g.Store.Del(ctx, key)
+		return 0, rejected.err
+	}
+	g.release(ctx, key, owner, pending)
+	return 0, err
+}
+
+func newOwner() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// release 结束租约并保留 pending 记录，下次调用先查询；租约已被其他调用接管时不做修改
+func (g *Guard) release(ctx context.Context, key, owner string, pending []byte) {
+	bs, _ := json.Marshal(&record{State: statePending, Owner: owner})
+	_, _ = g.Store.SetIfMatch(ctx, key, pending, bs, g.ttl())
+}
+
+// done 保存成功结果；保存失败时 pending 记录仍在，租约到期后的调用会先查询，不会重复执行
+func (g *Guard) done(ctx context.Context, key string, result interface{}) {
+	if bs, err := json.Marshal(result); err == nil {
+		_ = g.save(ctx, key, &record{State: stateDone, Result: bs})
+	}
+}
+
+func (g *Guard) save(ctx context.Context, key string, rec *record) error {
+	bs, _ := json.Marshal(rec)
+	if err := g.Store.Set(ctx, key, bs, g.ttl()); err != nil {
+		return fmt.Errorf("idempotency: set %s: %w", key, err)
+	}
+	return nil
+}
+
+func (g *Guard) ttl() time.Duration {
+	if g.TTL > 0 {
+		return g.TTL
+	}
+	return 24 * time.Hour
+}
+
+func (g *Guard) lease() time.Duration {
+	if g.Lease > 0 {
+		return g.Lease
+	}
+	return 2 * time.Minute
+}
+
+type rejectedError struct {
+	err error
+}
+
+func (e *rejectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rejectedError) Unwrap() error {
+	return e.err
+}
+
+// Rejected 标记渠道已明确拒绝请求、请求未生效，Guard 会删除记录并返回原始 err
+func Rejected(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &rejectedError{err: err}
+}
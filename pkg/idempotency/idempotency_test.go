@@ -0,0 +1,199 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type refund struct {
+	RefundId string `json:"refund_id"`
+}
+
+func TestGuardDo(t *testing.T) {
+	ctx := context.Background()
+	g := New(NewMemoryStore())
+	var creates, queries int
+	timeout := errors.New("read tcp: i/o timeout")
+	createErr := timeout
+	op := Operation{
+		Create: func(ctx context.Context) (interface{}, error) {
+			creates++
+			return &refund{RefundId: "R1"}, createErr
+		},
+		Query: func(ctx context.Context) (interface{}, bool, error) {
+			queries++
+			return &refund{RefundId: "R1"}, true, nil
+		},
+	}
+
+	// 超时，结果未知
+	if _, err := g.Do(ctx, Key("wechat", "1900000109", "refund", "R001"), nil, op); err != timeout {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	// 再次调用先查询，已生效则不再创建
+	status, err := g.Do(ctx, Key("wechat", "1900000109", "refund", "R001"), nil, op)
+	if err != nil || status != Recovered || creates != 1 || queries != 1 {
+		t.Fatalf("status = %v, err = %v, creates = %d, queries = %d", status, err, creates, queries)
+	}
+	// 之后直接重放
+	out := new(refund)
+	status, err = g.Do(ctx, Key("wechat", "1900000109", "refund", "R001"), out, op)
+	if err != nil || status != Replayed || out.RefundId != "R1" || creates != 1 || queries != 1 {
+		t.Fatalf("status = %v, err = %v, out = %+v", status, err, out)
+	}
+
+	// 明确拒绝：删除记录，允许重试
+	rejected := errors.New("NOT_ENOUGH")
+	createErr = Rejected(rejected)
+	if _, err = g.Do(ctx, "k2", nil, op); err != rejected {
+		t.Fatalf("err = %v, want rejected", err)
+	}
+	createErr = nil
+	if status, err = g.Do(ctx, "k2", nil, op); err != nil || status != Created || queries != 1 {
+		t.Fatalf("status = %v, err = %v, queries = %d", status, err, queries)
+	}
+}
+
+func TestGuardInProgress(t *testing.T) {
+	ctx := context.Background()
+	g := New(NewMemoryStore())
+	started, release := make(chan struct{}), make(chan struct{})
+	go g.Do(ctx, "k", nil, Operation{Create: func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}})
+	<-started
+	defer close(release)
+	if _, err := g.Do(ctx, "k", nil, Operation{}); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("err = %v, want ErrInProgress", err)
+	}
+}
+
+// barrierStore 所有调用方都读取到记录后才返回，使并发调用读取到同一条过期记录
+type barrierStore struct {
+	Store
+	wg *sync.WaitGroup
+}
+
+func (s *barrierStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, ok, err := s.Store.Get(ctx, key)
+	if s.wg != nil {
+		s.wg.Done()
+		s.wg.Wait()
+	}
+	return v, ok, err
+}
+
+func TestGuardLeaseTakeover(t *testing.T) {
+	ctx := context.Background()
+	store := &barrierStore{Store: NewMemoryStore()}
+	g := New(store)
+	timeout := errors.New("read tcp: i/o timeout")
+	if _, err := g.Do(ctx, "k", nil, Operation{Create: func(ctx context.Context) (interface{}, error) { return nil, timeout }}); err != timeout {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+
+	// 租约已结束，并发重试只有一个接管并查询、创建
+	var queries, creates int32
+	op := Operation{
+		Create: func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&creates, 1)
+			return &refund{RefundId: "R1"}, nil
+		},
+		Query: func(ctx context.Context) (interface{}, bool, error) {
+			atomic.AddInt32(&queries, 1)
+			time.Sleep(10 * time.Millisecond)
+			return nil, false, nil
+		},
+	}
+	var wg, barrier sync.WaitGroup
+	var inProgress int32
+	barrier.Add(10)
+	store.wg = &barrier
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := g.Do(ctx, "k", nil, op); errors.Is(err, ErrInProgress) {
+				atomic.AddInt32(&inProgress, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if queries != 1 || creates != 1 || inProgress != 9 {
+		t.Fatalf("queries = %d, creates = %d, inProgress = %d", queries, creates, inProgress)
+	}
+}
+
+func testSetIfMatch(t *testing.T, s Store) {
+	ctx := context.Background()
+	if ok, err := s.SetIfMatch(ctx, "cas", []byte("v1"), []byte("v2"), time.Hour); ok || err != nil {
+		t.Fatalf("SetIfMatch missing = %v, %v", ok, err)
+	}
+	if err := s.Set(ctx, "cas", []byte("v1"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.SetIfMatch(ctx, "cas", []byte("v0"), []byte("v2"), time.Hour); ok || err != nil {
+		t.Fatalf("SetIfMatch mismatch = %v, %v", ok, err)
+	}
+	if ok, err := s.SetIfMatch(ctx, "cas", []byte("v1"), []byte("v2"), time.Hour); !ok || err != nil {
+		t.Fatalf("SetIfMatch = %v, %v", ok, err)
+	}
+	if v, _, _ := s.Get(ctx, "cas"); string(v) != "v2" {
+		t.Fatalf("Get after SetIfMatch = %s", v)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testSetIfMatch(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.SetNX(ctx, "k", []byte("v1"), time.Hour); !ok || err != nil {
+		t.Fatalf("SetNX = %v, %v", ok, err)
+	}
+	if ok, err := s.SetNX(ctx, "k", []byte("v2"), time.Hour); ok || err != nil {
+		t.Fatalf("SetNX existing = %v, %v", ok, err)
+	}
+	if v, ok, err := s.Get(ctx, "k"); string(v) != "v1" || !ok || err != nil {
+		t.Fatalf("Get = %s, %v, %v", v, ok, err)
+	}
+	if err = s.Set(ctx, "k", []byte("v3"), time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok, err := s.Get(ctx, "k"); ok || err != nil {
+		t.Fatalf("Get expired = %v, %v", ok, err)
+	}
+	if ok, err := s.SetNX(ctx, "k", []byte("v4"), 0); !ok || err != nil {
+		t.Fatalf("SetNX after expire = %v, %v", ok, err)
+	}
+	if err = s.Del(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.Get(ctx, "k"); ok {
+		t.Fatal("Get after Del")
+	}
+	testSetIfMatch(t, s)
+}
@@ -0,0 +1,280 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store 幂等记录存储，语义与 Redis 命令一致，可直接基于 Redis 实现：
+//
+//	Get        -> GET key，key 不存在时 ok 为 false
+//	SetNX      -> SET key value NX PX ttl，key 已存在时返回 false
+//	SetIfMatch -> 当前值等于 old 时 SET key value PX ttl，否则返回 false，需原子执行，如 Lua 脚本：
+//		EVAL "if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3]) end return false" 1 key old value ttl
+//	              或 WATCH key、GET key 比较后 MULTI、SET、EXEC，EXEC 返回 nil 时视为不匹配
+//	Set        -> SET key value PX ttl
+//	Del        -> DEL key
+type Store interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (ok bool, err error)
+	SetIfMatch(ctx context.Context, key string, old, value []byte, ttl time.Duration) (ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// MemoryStore 进程内存储，适用于单实例部署
+type MemoryStore struct {
+	mu sync.Mutex
+	m  map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value  []byte
+	expire time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{m: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.get(key)
+	return e.value, ok, nil
+}
+
+func (s *MemoryStore) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.get(key); ok {
+		return false, nil
+	}
+	s.m[key] = memoryEntry{value: value, expire: expireAt(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) SetIfMatch(_ context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.get(key); !ok || !bytes.Equal(e.value, old) {
+		return false, nil
+	}
+	s.m[key] = memoryEntry{value: value, expire: expireAt(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	s.m[key] = memoryEntry{value: value, expire: expireAt(ttl)}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Del(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.m, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) get(key string) (memoryEntry, bool) {
+	e, ok := s.m[key]
+	if ok && expired(e.expire) {
+		delete(s.m, key)
+		return memoryEntry{}, false
+	}
+	return e, ok
+}
+
+// FileStore 文件存储，每个 key 一个文件，进程重启后记录仍然有效，同一目录可供同一台机器上的多个进程共享
+type FileStore struct {
+	dir string
+}
+
+type fileEntry struct {
+	Value  []byte    `json:"value"`
+	Expire time.Time `json:"expire,omitempty"`
+}
+
+// NewFileStore 以 dir 为存储目录，目录不存在时自动创建
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	e, ok, err := s.read(s.path(key))
+	return e.Value, ok, err
+}
+
+func (s *FileStore) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	path := s.path(key)
+	tmp, err := s.writeTemp(value, ttl)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+	for i := 0; i < 2; i++ {
+		// link 在目标已存在时失败，保证多进程下只有一个写入成功
+		if err = os.Link(tmp, path); err == nil {
+			return true, nil
+		}
+		if !os.IsExist(err) {
+			return false, err
+		}
+		if _, ok, err := s.read(path); err != nil || ok {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// SetIfMatch 通过 key 对应的 .lock 文件（O_EXCL 创建）互斥，锁被其他进程持有时返回 false，超过 fileLockStale 的锁视为进程崩溃遗留并清理
+func (s *FileStore) SetIfMatch(_ context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	path := s.path(key)
+	unlock, ok, err := s.lock(path + ".lock")
+	if err != nil || !ok {
+		return false, err
+	}
+	defer unlock()
+	e, ok, err := s.read(path)
+	if err != nil || !ok || !bytes.Equal(e.Value, old) {
+		return false, err
+	}
+	tmp, err := s.writeTemp(value, ttl)
+	if err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	return true, nil
+}
+
+// fileLockStale 锁文件的最长持有时间
+const fileLockStale = 10 * time.Second
+
+func (s *FileStore) lock(path string) (unlock func(), ok bool, err error) {
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, true, nil
+		}
+		if !os.IsExist(err) {
+			return nil, false, err
+		}
+		fi, err := os.Stat(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, false, err
+		}
+		if err == nil && time.Since(fi.ModTime()) < fileLockStale {
+			return nil, false, nil
+		}
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, false, err
+		}
+	}
+	return nil, false, nil
+}
+
+func (s *FileStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	tmp, err := s.writeTemp(value, ttl)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, s.path(key)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Del(_ context.Context, key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// read 读取记录，已过期的记录会被删除并返回 ok 为 false
+func (s *FileStore) read(path string) (e fileEntry, ok bool, err error) {
+	bs, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return e, false, nil
+	}
+	if err != nil {
+		return e, false, err
+	}
+	if err = json.Unmarshal(bs, &e); err != nil {
+		return e, false, err
+	}
+	if expired(e.Expire) {
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fileEntry{}, false, err
+		}
+		return fileEntry{}, false, nil
+	}
+	return e, true, nil
+}
+
+func (s *FileStore) writeTemp(value []byte, ttl time.Duration) (string, error) {
+	bs, err := json.Marshal(&fileEntry{Value: value, Expire: expireAt(ttl)})
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err = f.Write(bs); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("idempotency: write file store: %w", err)
+	}
+	return f.Name(), nil
+}
+
+func expireAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func expired(t time.Time) bool {
+	return !t.IsZero() && !time.Now().Before(t)
+}
@@ -16,6 +16,8 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
+
+	pay "github.com/rwscode/payutil"
 )
 
 // Implements the RSA family of signing methods signing methods
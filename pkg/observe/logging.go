@@ -0,0 +1,56 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observe
+
+import (
+	"context"
+
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+// Logging 返回日志中间件，每次调用结束后输出一条结构化日志
+//
+//	级别：成功为 Debug，HTTP 状态码非 2xx 或渠道返回错误结果码为 Warn，请求出错为 Error
+//	字段：provider、method、out_trade_no、request_id、status、code、duration、error，为空的字段不输出
+func Logging(l xlog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			err := next(ctx, call)
+			level := xlog.DebugLevel
+			switch {
+			case err != nil:
+				level = xlog.ErrorLevel
+			case call.Failed(nil):
+				level = xlog.WarnLevel
+			}
+			kv := []interface{}{"provider", call.Provider, "method", call.Method}
+			if call.OutTradeNo != "" {
+				kv = append(kv, "out_trade_no", call.OutTradeNo)
+			}
+			if call.RequestID != "" {
+				kv = append(kv, "request_id", call.RequestID)
+			}
+			if call.StatusCode > 0 {
+				kv = append(kv, "status", call.StatusCode)
+			}
+			if call.Code != "" {
+				kv = append(kv, "code", call.Code)
+			}
+			kv = append(kv, "duration", call.Duration)
+			if err != nil {
+				kv = append(kv, "error", err.Error())
+			}
+			l.Log(ctx, level, "pay request", kv...)
+			return err
+		}
+	}
+}
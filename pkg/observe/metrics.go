@@ -0,0 +1,175 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observe
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets 默认耗时直方图分桶（秒）
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics 进程内的请求指标，以 Prometheus 文本格式输出，无需依赖 Prometheus 客户端库
+//
+//	payutil_requests_total{provider,method,status,code,result}：请求次数，result 为 success 或 error，见 Call.Failed()
+//	payutil_request_duration_seconds{provider,method}：请求耗时直方图
+//
+//	m := observe.NewMetrics()
+//	client.Use(m.Middleware())
+//	http.Handle("/metrics/payutil", m)
+type Metrics struct {
+	mu        sync.Mutex
+	buckets   []float64
+	requests  map[requestKey]uint64
+	durations map[endpointKey]*histogram
+}
+
+type endpointKey struct {
+	provider, method string
+}
+
+type requestKey struct {
+	endpointKey
+	status, code, result string
+}
+
+type histogram struct {
+	counts []uint64 // 各分桶计数（不累加）
+	count  uint64
+	sum    float64
+}
+
+// NewMetrics 初始化请求指标，buckets 为耗时直方图分桶（秒，升序），为空时使用 DefaultBuckets
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	bs := append([]float64(nil), buckets...)
+	sort.Float64s(bs)
+	return &Metrics{
+		buckets:   bs,
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[endpointKey]*histogram),
+	}
+}
+
+// Middleware 返回统计请求次数及耗时的中间件
+func (m *Metrics) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			err := next(ctx, call)
+			m.Observe(call, err)
+			return err
+		}
+	}
+}
+
+// Observe 记录一次调用
+func (m *Metrics) Observe(call *Call, err error) {
+	ek := endpointKey{provider: call.Provider, method: call.Method}
+	result := "success"
+	if call.Failed(err) {
+		result = "error"
+	}
+	rk := requestKey{endpointKey: ek, status: strconv.Itoa(call.StatusCode), code: call.Code, result: result}
+	seconds := call.Duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[rk]++
+	h := m.durations[ek]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.durations[ek] = h
+	}
+	for i, b := range m.buckets {
+		if seconds <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// WritePrometheus 以 Prometheus 文本格式输出指标
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bw := bufio.NewWriter(w)
+
+	rks := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		rks = append(rks, k)
+	}
+	sort.Slice(rks, func(i, j int) bool {
+		a, b := rks[i], rks[j]
+		if a.endpointKey != b.endpointKey {
+			return a.endpointKey.less(b.endpointKey)
+		}
+		return a.status+"\x00"+a.code+"\x00"+a.result < b.status+"\x00"+b.code+"\x00"+b.result
+	})
+	fmt.Fprintln(bw, "# HELP payutil_requests_total Number of payment provider API calls.")
+	fmt.Fprintln(bw, "# TYPE payutil_requests_total counter")
+	for _, k := range rks {
+		fmt.Fprintf(bw, "payutil_requests_total{provider=%s,method=%s,status=%s,code=%s,result=%s} %d\n",
+			quote(k.provider), quote(k.method), quote(k.status), quote(k.code), quote(k.result), m.requests[k])
+	}
+
+	eks := make([]endpointKey, 0, len(m.durations))
+	for k := range m.durations {
+		eks = append(eks, k)
+	}
+	sort.Slice(eks, func(i, j int) bool { return eks[i].less(eks[j]) })
+	fmt.Fprintln(bw, "# HELP payutil_request_duration_seconds Latency of payment provider API calls, including retries.")
+	fmt.Fprintln(bw, "# TYPE payutil_request_duration_seconds histogram")
+	for _, k := range eks {
+		h := m.durations[k]
+		labels := "provider=" + quote(k.provider) + ",method=" + quote(k.method)
+		var cum uint64
+		for i, b := range m.buckets {
+			cum += h.counts[i]
+			fmt.Fprintf(bw, "payutil_request_duration_seconds_bucket{%s,le=%s} %d\n", labels, quote(strconv.FormatFloat(b, 'g', -1, 64)), cum)
+		}
+		fmt.Fprintf(bw, "payutil_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(bw, "payutil_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "payutil_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP 实现 http.Handler，输出 Prometheus 文本格式指标
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WritePrometheus(w)
+}
+
+func (k endpointKey) less(o endpointKey) bool {
+	if k.provider != o.provider {
+		return k.provider < o.provider
+	}
+	return k.method < o.method
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(v string) string {
+	return `"` + labelReplacer.Replace(v) + `"`
+}
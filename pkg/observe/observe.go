@@ -0,0 +1,127 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observe 渠道接口调用的中间件链，用于指标统计、链路追踪、日志等
+//
+//	各渠道客户端通过 client.Use(mw ...observe.Middleware) 注册中间件，每次接口调用（含重试）经过一次中间件链，
+//	中间件可读取渠道、接口、耗时、HTTP 状态码及渠道结果码，见 Call
+//	开箱即用的中间件：NewMetrics() 提供 Prometheus 格式的请求计数及耗时直方图，Tracing() 适配 OpenTelemetry 等链路追踪，Logging() 输出结构化日志
+package observe
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+// Call 一次渠道接口调用
+type Call struct {
+	Provider   string        // 渠道标识，如 pay.ProviderAlipay
+	Method     string        // 接口，如支付宝 alipay.trade.query，微信V3 GET /v3/pay/transactions/out-trade-no/{id}，见 Endpoint()
+	StatusCode int           // HTTP 状态码，未收到响应时为 0
+	Code       string        // 渠道结果码，成功时为空，如支付宝 sub_code、微信 code、PayPal issue
+	Duration   time.Duration // 耗时，含重试
+	OutTradeNo string        // 商户订单号，请求中不含时为空
+	RequestID  string        // 渠道返回的请求 ID，如微信 Request-ID、PayPal Paypal-Debug-Id
+}
+
+// Failed 返回调用是否失败：err 不为空、HTTP 状态码非 2xx 或渠道返回了错误结果码
+func (c *Call) Failed(err error) bool {
+	return err != nil || c.StatusCode < 200 || c.StatusCode > 299 || c.Code != ""
+}
+
+// Handler 执行一次渠道接口调用
+type Handler func(ctx context.Context, call *Call) error
+
+// Middleware 包装 Handler，可在调用前后读取 Call，返回 error 时中断调用
+type Middleware func(next Handler) Handler
+
+// Chain 中间件链，按添加顺序由外向内执行，零值可用
+type Chain []Middleware
+
+// Do 通过中间件链执行 h
+func (c Chain) Do(ctx context.Context, call *Call, h Handler) error {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i](h)
+	}
+	return h(ctx, call)
+}
+
+// WithLogging 返回在最外层添加 Logging(l) 的中间件链，l 为空时返回 c
+func (c Chain) WithLogging(l xlog.Logger) Chain {
+	if l == nil {
+		return c
+	}
+	return append(Chain{Logging(l)}, c...)
+}
+
+// DoHTTP 通过中间件链执行 send，记录耗时、HTTP 状态码，code 不为空时用于从响应中解析渠道结果码
+//
+//	中间件链为空时直接执行 send，不解析结果码
+func (c Chain) DoHTTP(ctx context.Context, call *Call, send func(ctx context.Context) (*http.Response, []byte, error), code func(status int, bs []byte) string) (res *http.Response, bs []byte, err error) {
+	if len(c) == 0 {
+		return send(ctx)
+	}
+	err = c.Do(ctx, call, func(ctx context.Context, call *Call) error {
+		start := time.Now()
+		var e error
+		res, bs, e = send(ctx)
+		call.Duration = time.Since(start)
+		if res != nil {
+			call.StatusCode = res.StatusCode
+			if code != nil {
+				call.Code = code(res.StatusCode, bs)
+			}
+		}
+		return e
+	})
+	return res, bs, err
+}
+
+// Endpoint 返回 "METHOD /path" 格式的接口标识，用于 REST 接口的 Call.Method
+//
+//	去掉域名及查询参数，形如订单号的路径段（含 2 个及以上数字，或全为数字）替换为 {id}，避免指标维度过多
+//	如 GET https://api.mch.weixin.qq.com/v3/pay/transactions/out-trade-no/20230101123456?mchid=1 -> GET /v3/pay/transactions/out-trade-no/{id}
+func Endpoint(method, path string) string {
+	if strings.Contains(path, "://") {
+		if u, err := url.Parse(path); err == nil {
+			path = u.Path
+		}
+	}
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if isID(seg) {
+			segs[i] = "{id}"
+		}
+	}
+	path = strings.Join(segs, "/")
+	if method == "" {
+		return path
+	}
+	return method + " " + path
+}
+
+func isID(seg string) bool {
+	digits := 0
+	for _, r := range seg {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits >= 2 || (digits > 0 && digits == len(seg))
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+func TestEndpoint(t *testing.T) {
+	tests := map[[2]string]string{
+		{"GET", "https://api.mch.weixin.qq.com/v3/pay/transactions/out-trade-no/GZ20230101123456?mchid=1230000109"}: "GET /v3/pay/transactions/out-trade-no/{id}",
+		{"POST", "/v3/pay/transactions/h5"}:                                       "POST /v3/pay/transactions/h5",
+		{"GET", "/v2/checkout/orders/5O190127TN364715T"}:                          "GET /v2/checkout/orders/{id}",
+		{"GET", "/inApps/v1/history/2000000123456789"}:                            "GET /inApps/v1/history/{id}",
+		{"", "https://qpay.qq.com/cgi-bin/pay/qpay_order_query.cgi"}:              "/cgi-bin/pay/qpay_order_query.cgi",
+		{"GET", "/v3/applyment4sub/applyment/business_code/W001"}:                 "GET /v3/applyment4sub/applyment/business_code/{id}",
+		{"POST", "/v3/refund/domestic/refunds"}:                                   "POST /v3/refund/domestic/refunds",
+		{"GET", "/v3/marketing/favor/users/oUpF8uMuAJO_M2pxb1Q9zNjWeS6o/coupons"}: "GET /v3/marketing/favor/users/{id}/coupons",
+	}
+	for in, want := range tests {
+		if got := Endpoint(in[0], in[1]); got != want {
+			t.Errorf("Endpoint(%s, %s) = %s, want %s", in[0], in[1], got, want)
+		}
+	}
+}
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *testSpan) RecordError(err error)                      { s.err = err }
+func (s *testSpan) End()                                       { s.ended = true }
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+type spanKey struct{}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &testSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func TestChain_DoHTTP(t *testing.T) {
+	var (
+		order  []string
+		m      = NewMetrics(0.1, 1)
+		tracer = new(testTracer)
+	)
+	logger := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) error {
+				order = append(order, name+">")
+				err := next(ctx, call)
+				order = append(order, "<"+name)
+				return err
+			}
+		}
+	}
+	chain := Chain{logger("a"), m.Middleware(), Tracing(tracer), logger("b")}
+	code := func(status int, bs []byte) string {
+		if status != http.StatusOK {
+			return string(bs)
+		}
+		return ""
+	}
+	send := func(status int, body string, err error) func(ctx context.Context) (*http.Response, []byte, error) {
+		return func(ctx context.Context) (*http.Response, []byte, error) {
+			if ctx.Value(spanKey{}) == nil {
+				t.Error("span context not passed to send")
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			return &http.Response{StatusCode: status}, []byte(body), nil
+		}
+	}
+
+	ctx := context.Background()
+	res, bs, err := chain.DoHTTP(ctx, &Call{Provider: "wechat", Method: "GET /v3/pay/transactions/id/{id}"}, send(200, "{}", nil), code)
+	if err != nil || res.StatusCode != 200 || string(bs) != "{}" {
+		t.Fatalf("DoHTTP() = %v, %s, %v", res, bs, err)
+	}
+	if strings.Join(order, "") != "a>b><b<a" {
+		t.Fatalf("order = %v", order)
+	}
+	if _, _, err = chain.DoHTTP(ctx, &Call{Provider: "wechat", Method: "GET /v3/pay/transactions/id/{id}"}, send(404, "ORDER_NOT_EXIST", nil), code); err != nil {
+		t.Fatal(err)
+	}
+	netErr := errors.New("dial tcp: connection refused")
+	if _, _, err = chain.DoHTTP(ctx, &Call{Provider: "alipay", Method: "alipay.trade.query"}, send(0, "", netErr), code); err != netErr {
+		t.Fatalf("err = %v", err)
+	}
+
+	if len(tracer.spans) != 3 || !tracer.spans[0].ended || tracer.spans[0].err != nil ||
+		tracer.spans[1].attrs[AttrResultCode] != "ORDER_NOT_EXIST" || tracer.spans[1].attrs[AttrStatusCode] != 404 || tracer.spans[1].err == nil ||
+		tracer.spans[2].name != "alipay alipay.trade.query" || tracer.spans[2].err != netErr {
+		t.Fatalf("spans = %+v %+v %+v", tracer.spans[0], tracer.spans[1], tracer.spans[2])
+	}
+
+	buf := new(bytes.Buffer)
+	if err = m.WritePrometheus(buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`payutil_requests_total{provider="alipay",method="alipay.trade.query",status="0",code="",result="error"} 1`,
+		`payutil_requests_total{provider="wechat",method="GET /v3/pay/transactions/id/{id}",status="200",code="",result="success"} 1`,
+		`payutil_requests_total{provider="wechat",method="GET /v3/pay/transactions/id/{id}",status="404",code="ORDER_NOT_EXIST",result="error"} 1`,
+		`payutil_request_duration_seconds_bucket{provider="wechat",method="GET /v3/pay/transactions/id/{id}",le="0.1"} 2`,
+		`payutil_request_duration_seconds_bucket{provider="wechat",method="GET /v3/pay/transactions/id/{id}",le="+Inf"} 2`,
+		`payutil_request_duration_seconds_count{provider="alipay",method="alipay.trade.query"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in\n%s", want, out)
+		}
+	}
+
+	// 中间件链为空时直接发送，不解析结果码
+	called := false
+	_, _, err = Chain(nil).DoHTTP(ctx, &Call{}, func(ctx context.Context) (*http.Response, []byte, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil, nil
+	}, func(int, []byte) string { called = true; return "" })
+	if err != nil || called {
+		t.Fatalf("err = %v, code called = %v", err, called)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var lines []string
+	l := xlog.LoggerFunc(func(_ context.Context, level xlog.LogLevel, msg string, keyvals ...interface{}) {
+		lines = append(lines, level.String()+" "+xlog.Format(msg, keyvals...))
+	})
+	chain := Chain{Logging(l)}
+	send := func(status int, err error) func(ctx context.Context) (*http.Response, []byte, error) {
+		return func(ctx context.Context) (*http.Response, []byte, error) {
+			if err != nil {
+				return nil, nil, err
+			}
+			return &http.Response{StatusCode: status}, nil, nil
+		}
+	}
+	ctx := context.Background()
+	_, _, _ = chain.DoHTTP(ctx, &Call{Provider: "wechat", Method: "POST /v3/pay/transactions/jsapi", OutTradeNo: "GZ001", RequestID: "08F7"}, send(200, nil), nil)
+	_, _, _ = chain.DoHTTP(ctx, &Call{Provider: "wechat", Method: "POST /v3/pay/transactions/jsapi"}, send(500, nil), nil)
+	_, _, _ = chain.DoHTTP(ctx, &Call{Provider: "alipay", Method: "alipay.trade.query"}, send(0, errors.New("timeout")), nil)
+
+	if len(lines) != 3 ||
+		!strings.HasPrefix(lines[0], "DEBUG pay request provider=wechat method=\"POST /v3/pay/transactions/jsapi\" out_trade_no=GZ001 request_id=08F7 status=200 duration=") ||
+		!strings.HasPrefix(lines[1], "WARN ") || !strings.Contains(lines[1], "status=500") ||
+		!strings.HasPrefix(lines[2], "ERROR ") || !strings.HasSuffix(lines[2], "error=timeout") {
+		t.Fatalf("lines = %q", lines)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observe
+
+import (
+	"context"
+	"strconv"
+)
+
+// Tracer 链路追踪，可适配 OpenTelemetry、SkyWalking 等，如 OpenTelemetry：
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, name string) (context.Context, observe.Span) {
+//		ctx, span := o.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttribute(key string, value interface{}) {
+//		s.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//	}
+//
+//	func (s otelSpan) RecordError(err error) {
+//		s.Span.RecordError(err)
+//		s.SetStatus(codes.Error, err.Error())
+//	}
+//
+//	func (s otelSpan) End() { s.Span.End() }
+//
+//	client.Use(observe.Tracing(otelTracer{otel.Tracer("payutil")}))
+//
+// 如需向渠道透传 traceparent 等请求头，请配合 client.SetDoer() 使用 otelhttp.NewTransport() 等
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// Span 一次渠道接口调用对应的 span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// span 属性
+const (
+	AttrProvider   = "pay.provider"
+	AttrMethod     = "pay.method"
+	AttrResultCode = "pay.result_code"
+	AttrStatusCode = "http.status_code"
+)
+
+// Tracing 返回链路追踪中间件，每次调用创建名为 "provider method" 的 span，如 "alipay alipay.trade.query"
+//
+//	属性：pay.provider、pay.method、http.status_code、pay.result_code；调用失败（见 Call.Failed()）时记录错误
+func Tracing(t Tracer) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			ctx, span := t.Start(ctx, call.Provider+" "+call.Method)
+			defer span.End()
+			span.SetAttribute(AttrProvider, call.Provider)
+			span.SetAttribute(AttrMethod, call.Method)
+			err := next(ctx, call)
+			if call.StatusCode > 0 {
+				span.SetAttribute(AttrStatusCode, call.StatusCode)
+			}
+			if call.Code != "" {
+				span.SetAttribute(AttrResultCode, call.Code)
+			}
+			switch {
+			case err != nil:
+				span.RecordError(err)
+			case call.Failed(nil):
+				span.RecordError(&callError{call: call})
+			}
+			return err
+		}
+	}
+}
+
+type callError struct {
+	call *Call
+}
+
+func (e *callError) Error() string {
+	if e.call.Code != "" {
+		return e.call.Provider + " " + e.call.Method + ": " + e.call.Code
+	}
+	return e.call.Provider + " " + e.call.Method + ": http status " + strconv.Itoa(e.call.StatusCode)
+}
@@ -0,0 +1,251 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redact 调试日志脱敏，遮盖请求、响应中的密钥、签名凭证及个人敏感信息
+//
+//	按字段名匹配 JSON（"field":"value"）、XML（<field>value</field>，含 CDATA）及表单、URL 参数（field=value）中的字段值，
+//	字段名不区分大小写，默认字段见 DefaultFields，可通过 SetFields()、AddFields() 配置
+//	字符串值或表单参数值本身为 JSON 时（如支付宝 biz_content），先解码再按字段脱敏
+//	微信V3 使用平台证书加密的敏感字段（如 user_name、id_card_number，以及分账接收方 name 等），按密文特征（300 字符以上的 base64）遮盖，与字段名无关
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultFields 默认脱敏字段
+var DefaultFields = []string{
+	// 密钥、凭证
+	"access_token", "refresh_token", "app_auth_token", "auth_token", "client_secret", "secret", "password", "pwd",
+	"api_key", "apikey", "private_key", "ciphertext", "receipt-data",
+	// 用户标识、联系方式
+	"openid", "sub_openid", "sp_openid", "buyer_logon_id", "buyer_open_id", "logon_id", "identity",
+	"mobile", "mobile_phone", "phone", "phone_number", "national_number", "telephone", "email", "email_address",
+	"contact_email", "payer_email", "address", "address_line_1", "address_line_2",
+	// 姓名、证件
+	"user_name", "real_name", "true_name", "re_user_name", "enc_true_name", "payer_name", "given_name", "surname",
+	"contact_name", "account_name", "id_card_name", "id_doc_name", "legal_person",
+	"cert_no", "cert_num", "id_card_number", "id_doc_number", "id_number", "contact_id_number", "contact_id_card_number",
+	// 银行卡、账户
+	"enc_bank_no", "bank_no", "bank_card_no", "card_no", "card_number", "account_no", "account_number", "bank_account",
+	"payee_account",
+}
+
+var (
+	mu     sync.RWMutex
+	jsonRe *regexp.Regexp
+	xmlRe  *regexp.Regexp
+	formRe *regexp.Regexp
+	fields []string
+
+	signatureRe = regexp.MustCompile(`signature="[^"]*"`)
+	// 支付宝V3 ALIPAY-SHA256withRSA app_id=,nonce=,timestamp=,sign=
+	signRe = regexp.MustCompile(`(^|,)sign=[^,]*`)
+	// 微信V3 RSA 加密的敏感字段密文，2048 位密钥为 344 字符的 base64
+	encryptedRe = regexp.MustCompile(`"([A-Za-z0-9+/]{300,}={0,2})"`)
+	// 值为 JSON 的字符串，如 JsonBody() 中的 "biz_content":"{\"cert_no\":\"...\"}"
+	nestedJSONRe = regexp.MustCompile(`"((?:\{|\[)(?:[^"\\]|\\.)*(?:\}|\]))"`)
+	// 值为 URL 编码 JSON 的表单参数，如 EncodeURLParams() 中的 biz_content=%7B%22cert_no%22...
+	nestedFormRe = regexp.MustCompile(`(?:^|[?&\s])[^=&\s]+=((?:%7[Bb]|%5[Bb])[^&\s]*)`)
+)
+
+func init() {
+	SetFields(DefaultFields...)
+}
+
+// SetFields 设置脱敏字段，替换默认字段，不传参数时不按字段脱敏（Authorization 等请求头、微信V3 密文仍会遮盖）
+func SetFields(fs ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	fields = append([]string(nil), fs...)
+	if len(fields) == 0 {
+		jsonRe, xmlRe, formRe = nil, nil, nil
+		return
+	}
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = regexp.QuoteMeta(f)
+	}
+	alt := strings.Join(quoted, "|")
+	jsonRe = regexp.MustCompile(`(?i)"(?:` + alt + `)"\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	xmlRe = regexp.MustCompile(`(?i)<(?:` + alt + `)>(<!\[CDATA\[[\s\S]*?\]\]>|[^<]*)</`)
+	formRe = regexp.MustCompile(`(?im)(?:^|[?&])(?:` + alt + `)=([^&\s]*)`)
+}
+
+// AddFields 在当前脱敏字段基础上追加字段
+func AddFields(fs ...string) {
+	mu.RLock()
+	all := append(append([]string(nil), fields...), fs...)
+	mu.RUnlock()
+	SetFields(all...)
+}
+
+// String 脱敏 JSON、XML、表单或 URL 参数格式的字符串，用于请求体、响应体、签名串等调试日志
+func String(s string) string {
+	mu.RLock()
+	jr, xr, fr := jsonRe, xmlRe, formRe
+	mu.RUnlock()
+	if s == "" {
+		return s
+	}
+	s = replaceValue(encryptedRe, s, Mask)
+	if jr == nil {
+		return s
+	}
+	s = replaceValue(nestedJSONRe, s, nestedJSON)
+	s = replaceValue(nestedFormRe, s, nestedForm)
+	s = replaceValue(jr, s, Mask)
+	s = replaceValue(xr, s, func(v string) string {
+		if strings.HasPrefix(v, "<![CDATA[") {
+			return "<![CDATA[" + Mask(strings.TrimSuffix(strings.TrimPrefix(v, "<![CDATA["), "]]>")) + "]]>"
+		}
+		return Mask(v)
+	})
+	return replaceValue(fr, s, Mask)
+}
+
+// nestedJSON 解码 JSON 字符串值（不含双引号），脱敏后重新编码
+func nestedJSON(v string) string {
+	var inner string
+	if json.Unmarshal([]byte(`"`+v+`"`), &inner) != nil {
+		return v
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if enc.Encode(String(inner)) != nil {
+		return v
+	}
+	bs := bytes.TrimSpace(buf.Bytes())
+	return string(bs[1 : len(bs)-1])
+}
+
+// nestedForm URL 解码表单参数值，脱敏后重新编码
+func nestedForm(v string) string {
+	inner, err := url.QueryUnescape(v)
+	if err != nil {
+		return v
+	}
+	return url.QueryEscape(String(inner))
+}
+
+// Values 返回脱敏后的表单参数副本：脱敏字段遮盖参数值，其余参数值按 String() 处理（如支付宝 biz_content 中的 JSON）
+func Values(vs url.Values) url.Values {
+	mu.RLock()
+	fs := fields
+	mu.RUnlock()
+	c := make(url.Values, len(vs))
+	for k, v := range vs {
+		masked := false
+		for _, f := range fs {
+			if strings.EqualFold(k, f) {
+				masked = true
+				break
+			}
+		}
+		c[k] = make([]string, len(v))
+		for i, e := range v {
+			if masked {
+				c[k][i] = Mask(e)
+			} else {
+				c[k][i] = String(e)
+			}
+		}
+	}
+	return c
+}
+
+// replaceValue 将 re 第 1 个分组匹配的字段值替换为 fn(value)
+func replaceValue(re *regexp.Regexp, s string, fn func(string) string) string {
+	idx := re.FindAllStringSubmatchIndex(s, -1)
+	if len(idx) == 0 {
+		return s
+	}
+	var (
+		b    strings.Builder
+		last int
+	)
+	b.Grow(len(s))
+	for _, m := range idx {
+		start, end := m[2], m[3]
+		if start < 0 || start == end {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(fn(s[start:end]))
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Mask 遮盖 v 的中间部分，保留首尾各 1/4（最多 4 个字符），不足 4 个字符时全部遮盖
+//
+//	如 13812345678 -> 13***78，张三 -> ***
+func Mask(v string) string {
+	if v == "" {
+		return v
+	}
+	rs := []rune(v)
+	keep := len(rs) / 4
+	if keep > 4 {
+		keep = 4
+	}
+	if keep == 0 {
+		return "***"
+	}
+	return string(rs[:keep]) + "***" + string(rs[len(rs)-keep:])
+}
+
+// Authorization 遮盖 Authorization 请求头的凭证
+//
+//	Basic 凭证全部遮盖，微信V3 WECHATPAY2-SHA256-RSA2048 仅遮盖 signature，支付宝V3 ALIPAY-SHA256withRSA 仅遮盖 sign，
+//	Bearer 等其他凭证见 Mask()
+func Authorization(v string) string {
+	i := strings.IndexByte(v, ' ')
+	if i < 0 {
+		return Mask(v)
+	}
+	scheme, cred := v[:i], v[i+1:]
+	switch {
+	case strings.EqualFold(scheme, "Basic"):
+		return scheme + " ***"
+	case strings.Contains(cred, `signature="`):
+		return scheme + " " + signatureRe.ReplaceAllString(cred, `signature="***"`)
+	case signRe.MatchString(cred):
+		return scheme + " " + signRe.ReplaceAllString(cred, "${1}sign=***")
+	}
+	return scheme + " " + Mask(cred)
+}
+
+// Header 返回遮盖了 Authorization、Cookie 等请求头的副本
+func Header(h http.Header) http.Header {
+	c := h.Clone()
+	for k, vs := range c {
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "Proxy-Authorization":
+			for i, v := range vs {
+				vs[i] = Authorization(v)
+			}
+		case "Cookie", "Set-Cookie":
+			for i := range vs {
+				vs[i] = "***"
+			}
+		}
+	}
+	return c
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	cipher := strings.Repeat("AbCd", 85) + "Ef=="
+	tests := map[string]string{
+		`{"openid":"oUpF8uMuAJO_M2pxb1Q9zNjWeS6o","amount":{"total":100}}`:                `{"openid":"oUpF***eS6o","amount":{"total":100}}`,
+		`{"payer": {"Mobile" : "13812345678"}, "description":"test"}`:                     `{"payer": {"Mobile" : "13***78"}, "description":"test"}`,
+		`{"receivers":[{"name":"` + cipher + `","type":"PERSONAL_OPENID"}]}`:              `{"receivers":[{"name":"AbCd***Ef==","type":"PERSONAL_OPENID"}]}`,
+		`<xml><enc_true_name><![CDATA[张三丰先生]]></enc_true_name><mch_id>123</mch_id></xml>`: `<xml><enc_true_name><![CDATA[张***生]]></enc_true_name><mch_id>123</mch_id></xml>`,
+		`<xml><openid>oUpF8uMuAJO</openid></xml>`:                                         `<xml><openid>oU***JO</openid></xml>`,
+		"GET\n/v3/pay/transactions?mchid=1&openid=oUpF8uMuAJO\n1690000000":                "GET\n/v3/pay/transactions?mchid=1&openid=oU***JO\n1690000000",
+		"app_id=2016&access_token=abcdefgh12345678&charset=utf-8":                         "app_id=2016&access_token=abcd***5678&charset=utf-8",
+		`{"name":"UNPROCESSABLE_ENTITY"}`:                                                 `{"name":"UNPROCESSABLE_ENTITY"}`,
+		`{"openid":""}`:                                                                   `{"openid":""}`,
+	}
+	for in, want := range tests {
+		if got := String(in); got != want {
+			t.Errorf("String(%s)\n got %s\nwant %s", in, got, want)
+		}
+	}
+
+	defer SetFields(DefaultFields...)
+	SetFields()
+	if got := String(`{"openid":"oUpF8uMuAJO"}`); got != `{"openid":"oUpF8uMuAJO"}` {
+		t.Fatalf("String() with no fields = %s", got)
+	}
+	AddFields("out_trade_no")
+	if got := String(`{"openid":"oUpF8uMuAJO","out_trade_no":"T20230101"}`); got != `{"openid":"oUpF8uMuAJO","out_trade_no":"T2***01"}` {
+		t.Fatalf("String() with out_trade_no = %s", got)
+	}
+}
+
+func TestString_NestedBizContent(t *testing.T) {
+	// alipay.fund.trans.uni.transfer 的 biz_content
+	bizContent := `{"out_biz_no":"T20230101","trans_amount":"1.00","product_code":"TRANS_ACCOUNT_NO_PWD","biz_scene":"DIRECT_TRANSFER",` +
+		`"payee_info":{"identity":"13812345678","identity_type":"ALIPAY_LOGON_ID","name":"张三","cert_no":"110101199001011234","cert_type":"IDENTITY_CARD"}}`
+	params := map[string]string{
+		"app_id":      "2016091200494382",
+		"method":      "alipay.fund.trans.uni.transfer",
+		"charset":     "utf-8",
+		"biz_content": bizContent,
+	}
+	jsonBody, _ := json.Marshal(params)
+	form := make(url.Values)
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	for name, body := range map[string]string{"json": string(jsonBody), "form": form.Encode()} {
+		got := String(body)
+		if strings.Contains(got, "110101199001011234") || strings.Contains(got, "13812345678") {
+			t.Fatalf("String(%s) leaks biz_content: %s", name, got)
+		}
+		if !strings.Contains(got, "T20230101") || !strings.Contains(got, "alipay.fund.trans.uni.transfer") {
+			t.Fatalf("String(%s) = %s", name, got)
+		}
+	}
+	// 仅遮盖字段值，其余内容不变
+	want := `{"biz_content":"{\"payee_info\":{\"cert_no\":\"1101***1234\"}}"}`
+	if got := String(`{"biz_content":"{\"payee_info\":{\"cert_no\":\"110101199001011234\"}}"}`); got != want {
+		t.Fatalf("String() = %s, want %s", got, want)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	tests := map[string]string{
+		"Basic QVotY2xpZW50OkVKLXNlY3JldA==":     "Basic ***",
+		"Bearer A21AAFEpH4PsADK7qSS7pSRsgzfENtu": "Bearer A21A***ENtu",
+		"ALIPAY-SHA256withRSA app_id=2014060600164699,nonce=abc,timestamp=1689306000000,sign=e1RZXR2cm7sBgWC8pp+q/4ZS": "ALIPAY-SHA256withRSA app_id=2014060600164699,nonce=abc,timestamp=1689306000000,sign=***",
+		`WECHATPAY2-SHA256-RSA2048 mchid="1900009191",nonce_str="abc",signature="uOVRnA4qG/MNnYzdQxJanN+zU+lTgIcnU9BxGw5dKjK+VdEUz2FeIoC+D5sB/LN+nGzX3hfZg6r5wT1pl2ZobmIc6p0ldN7J6yDgUzbX8Uk3sD4a4eZVPTBvqNDoUqcYMlZ9uuDdCvNv4TM3c1WzsXUrExwVkI1XO5jCNbgDJ25nkT/c1gIFvqoogl7MdSFGc4W4xZsqCItnqbypR3RuGIlR9h9vlRsy7zJR9PBI83X8alLDIfR1ukt1P7tMnmogZ0cuDY8cZsd8ZlCgLadmvej58SLsIkVxFJ8XyUgx9FmutKSYTmYtWBZ0+tNvfGmbXU7cob8H/4nLBiCwIUFluw==",timestamp="1554208460",serial_no="1DDE55AD98ED71D6EDD4A4A16996DE7B47773A8C"`: `WECHATPAY2-SHA256-RSA2048 mchid="1900009191",nonce_str="abc",signature="***",timestamp="1554208460",serial_no="1DDE55AD98ED71D6EDD4A4A16996DE7B47773A8C"`,
+	}
+	for in, want := range tests {
+		if got := Authorization(in); got != want {
+			t.Errorf("Authorization(%s) = %s, want %s", in, got, want)
+		}
+	}
+	h := http.Header{"Authorization": {"Basic abc"}, "Set-Cookie": {"sid=1"}, "Paypal-Debug-Id": {"f1a2"}}
+	got := Header(h)
+	if got.Get("Authorization") != "Basic ***" || got.Get("Set-Cookie") != "***" || got.Get("Paypal-Debug-Id") != "f1a2" || h.Get("Authorization") != "Basic abc" {
+		t.Fatalf("Header() = %v, original %v", got, h)
+	}
+}
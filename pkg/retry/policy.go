@@ -0,0 +1,248 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Policy 重试策略：指数退避 + full jitter
+//
+//	第 n 次重试前等待 [0, min(MaxInterval, InitialInterval*Multiplier^n)) 内的随机时长
+type Policy struct {
+	MaxAttempts     int                  // 最大尝试次数（含首次），默认 3
+	InitialInterval time.Duration        // 首次退避上限，默认 100ms
+	MaxInterval     time.Duration        // 单次退避上限，默认 2s
+	Multiplier      float64              // 退避倍数，默认 2
+	MaxElapsedTime  time.Duration        // 总耗时上限，超过后不再重试，默认 0 不限制
+	Classifier      func(err error) bool // 判断 err 是否可重试，默认 IsRetryable，幂等调用默认 IsRetryableIdempotent
+
+	idempotent bool
+}
+
+// NewPolicy 返回默认重试策略：最多 3 次，退避 100ms 起，上限 2s，总耗时不超过 10s
+func NewPolicy() *Policy {
+	return &Policy{
+		MaxAttempts:     3,
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     2 * time.Second,
+		Multiplier:      2,
+		MaxElapsedTime:  10 * time.Second,
+	}
+}
+
+// Idempotent 返回标记为幂等调用的策略副本，p 为 nil 时返回 nil
+//
+//	幂等调用额外重试连接重置、读超时、5xx 等请求可能已送达的错误，等同于 ctx 经过 WithIdempotent
+func (p *Policy) Idempotent() *Policy {
+	if p == nil {
+		return nil
+	}
+	cp := *p
+	cp.idempotent = true
+	return &cp
+}
+
+func (p *Policy) isIdempotent(ctx context.Context) bool {
+	return (p != nil && p.idempotent) || IsIdempotent(ctx)
+}
+
+// Do 按策略执行 fn，直到成功、错误不可重试、次数耗尽、超过总耗时或 ctx 结束
+//
+//	p 为 nil 时只执行一次
+//	fn 失败后 ctx 已结束时返回 ctx.Err()，其余情况返回最后一次 fn 的错误
+func (p *Policy) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if p == nil {
+		return fn(ctx)
+	}
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	classifier := p.Classifier
+	if classifier == nil {
+		classifier = IsRetryable
+		if p.isIdempotent(ctx) {
+			classifier = IsRetryableIdempotent
+		}
+	}
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt+1 >= maxAttempts || !classifier(err) {
+			return err
+		}
+		wait := p.Backoff(attempt)
+		if p.MaxElapsedTime > 0 && time.Since(start)+wait > p.MaxElapsedTime {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// DoHTTP 按策略执行 http 请求，请求未发出的网络错误会重试
+//
+//	幂等调用（见 Idempotent、WithIdempotent）另外重试其余网络错误、5xx 以及 retryBody 返回 true 的响应
+//
+//	重试结束时若最后一次拿到了响应，返回该响应且 err 为 nil，由调用方按原逻辑处理状态码和业务错误
+//	retryBody 用于识别渠道系统繁忙，如微信 SYSTEMERROR、支付宝 ACQ.SYSTEM_ERROR，可为 nil
+func (p *Policy) DoHTTP(ctx context.Context, send func(ctx context.Context) (*http.Response, []byte, error), retryBody func(bs []byte) bool) (res *http.Response, bs []byte, err error) {
+	idempotent := p.isIdempotent(ctx)
+	err = p.Do(ctx, func(ctx context.Context) error {
+		var e error
+		if res, bs, e = send(ctx); e != nil {
+			res, bs = nil, nil
+			return e
+		}
+		if !idempotent {
+			return nil
+		}
+		if res.StatusCode >= http.StatusInternalServerError {
+			return Retryable(fmt.Errorf("http status code: %d", res.StatusCode))
+		}
+		if retryBody != nil && retryBody(bs) {
+			return Retryable(errors.New("system busy"))
+		}
+		return nil
+	})
+	if res != nil {
+		return res, bs, nil
+	}
+	return nil, nil, err
+}
+
+// Backoff 返回第 attempt 次（从 0 开始）重试前的等待时长
+func (p *Policy) Backoff(attempt int) time.Duration {
+	initial, max, multiplier := p.InitialInterval, p.MaxInterval, p.Multiplier
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 2 * time.Second
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	ceil := float64(initial)
+	for i := 0; i < attempt && ceil < float64(max); i++ {
+		ceil *= multiplier
+	}
+	if ceil > float64(max) {
+		ceil = float64(max)
+	}
+	return time.Duration(rand.Int63n(int64(ceil) + 1))
+}
+
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// Retryable 将 err 标记为可重试，如 5xx、渠道系统繁忙
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+// IsRetryable 默认的可重试判断：Retryable 标记的错误，以及域名解析失败、连接被拒绝等请求确定未发出的错误
+//
+//	连接重置、读超时等错误发生时请求可能已被渠道处理，非幂等调用重试可能重复扣款、重复退款，不重试
+//	ctx 取消、证书校验失败等错误不重试
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var re *retryableError
+	if errors.As(err, &re) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var de *net.DNSError
+	if errors.As(err, &de) {
+		return true
+	}
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+// IsRetryableIdempotent 幂等调用的可重试判断：IsRetryable 之外，连接重置、超时等网络错误，以及 Temporary() 为 true 的错误
+func IsRetryableIdempotent(err error) bool {
+	if IsRetryable(err) {
+		return true
+	}
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	// 如 *pay.APIError，渠道系统繁忙、频率限制时可重试
+	var te interface{ Temporary() bool }
+	return errors.As(err, &te) && te.Temporary()
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent 标记本次调用可安全重试
+//
+//	查询、下载账单、获取证书等幂等接口在设置了重试策略后默认重试，创建订单、退款等非幂等接口需通过此方法显式开启
+//	请确认重复请求不会重复扣款，如使用相同的商户订单号、退款单号
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// IsIdempotent 返回 ctx 是否通过 WithIdempotent 标记了可安全重试
+func IsIdempotent(ctx context.Context) bool {
+	v, _ := ctx.Value(idempotentKey{}).(bool)
+	return v
+}
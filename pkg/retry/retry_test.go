@@ -12,7 +12,16 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -28,3 +37,166 @@ func TestRetry(t *testing.T) {
 		xlog.Error(err)
 	}
 }
+
+func TestPolicy_Do(t *testing.T) {
+	p := &Policy{MaxAttempts: 4, InitialInterval: time.Millisecond, MaxInterval: 2 * time.Millisecond}
+	var n int
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		if n++; n < 3 {
+			return Retryable(errors.New("busy"))
+		}
+		return nil
+	})
+	if err != nil || n != 3 {
+		t.Fatalf("err = %v, n = %d", err, n)
+	}
+
+	// 不可重试的错误只执行一次
+	n = 0
+	permanent := errors.New("invalid param")
+	if err = p.Do(context.Background(), func(ctx context.Context) error { n++; return permanent }); err != permanent || n != 1 {
+		t.Fatalf("err = %v, n = %d", err, n)
+	}
+
+	// 次数耗尽返回最后一次错误
+	n = 0
+	err = p.Do(context.Background(), func(ctx context.Context) error { n++; return Retryable(fmt.Errorf("busy %d", n)) })
+	if err == nil || err.Error() != "busy 4" {
+		t.Fatalf("err = %v", err)
+	}
+
+	// ctx 取消后停止重试
+	ctx, cancel := context.WithCancel(context.Background())
+	n = 0
+	err = (&Policy{MaxAttempts: 10, InitialInterval: time.Hour, MaxInterval: time.Hour}).Do(ctx, func(ctx context.Context) error {
+		n++
+		cancel()
+		return Retryable(errors.New("busy"))
+	})
+	if !errors.Is(err, context.Canceled) || n != 1 {
+		t.Fatalf("err = %v, n = %d", err, n)
+	}
+
+	// nil Policy 只执行一次
+	n = 0
+	_ = (*Policy)(nil).Do(context.Background(), func(ctx context.Context) error { n++; return Retryable(errors.New("busy")) })
+	if n != 1 {
+		t.Fatalf("n = %d", n)
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := &Policy{InitialInterval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond, Multiplier: 2}
+	for attempt, ceil := range []time.Duration{10, 20, 40, 50, 50} {
+		for i := 0; i < 100; i++ {
+			if d := p.Backoff(attempt); d < 0 || d > ceil*time.Millisecond {
+				t.Fatalf("Backoff(%d) = %v, ceil %v", attempt, d, ceil*time.Millisecond)
+			}
+		}
+	}
+}
+
+func TestPolicy_MaxElapsedTime(t *testing.T) {
+	p := &Policy{MaxAttempts: 100, InitialInterval: 20 * time.Millisecond, MaxInterval: 20 * time.Millisecond, MaxElapsedTime: 50 * time.Millisecond}
+	start := time.Now()
+	_ = p.Do(context.Background(), func(ctx context.Context) error { return Retryable(errors.New("busy")) })
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("elapsed %v", elapsed)
+	}
+}
+
+func TestPolicy_DoHTTP(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&n, 1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			_, _ = w.Write([]byte("SYSTEM_ERROR"))
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer ts.Close()
+	send := func(ctx context.Context) (*http.Response, []byte, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer res.Body.Close()
+		bs, err := ioutil.ReadAll(res.Body)
+		return res, bs, err
+	}
+	retryBody := func(bs []byte) bool { return string(bs) == "SYSTEM_ERROR" }
+	p := &Policy{MaxAttempts: 3, InitialInterval: time.Millisecond}
+	res, bs, err := p.Idempotent().DoHTTP(context.Background(), send, retryBody)
+	if err != nil || res.StatusCode != http.StatusOK || string(bs) != "ok" || n != 3 {
+		t.Fatalf("err = %v, bs = %s, n = %d", err, bs, n)
+	}
+
+	// 非幂等调用拿到响应后不重试
+	atomic.StoreInt32(&n, 0)
+	res, _, err = p.DoHTTP(context.Background(), send, retryBody)
+	if err != nil || res.StatusCode != http.StatusBadGateway || n != 1 {
+		t.Fatalf("err = %v, res = %+v, n = %d", err, res, n)
+	}
+	atomic.StoreInt32(&n, 0)
+	res, _, err = p.DoHTTP(WithIdempotent(context.Background()), send, retryBody)
+	if err != nil || res.StatusCode != http.StatusOK || n != 3 {
+		t.Fatalf("err = %v, res = %+v, n = %d", err, res, n)
+	}
+
+	// 次数耗尽时返回最后一次响应
+	atomic.StoreInt32(&n, 0)
+	p.MaxAttempts = 1
+	res, _, err = p.DoHTTP(context.Background(), send, retryBody)
+	if err != nil || res.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v, res = %+v", err, res)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err        error
+		want       bool
+		idempotent bool
+	}{
+		{nil, false, false},
+		{errors.New("invalid"), false, false},
+		{Retryable(errors.New("busy")), true, true},
+		{fmt.Errorf("wrap: %w", Retryable(errors.New("busy"))), true, true},
+		{context.Canceled, false, false},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true, true},
+		{&net.DNSError{Err: "no such host", Name: "api.mch.weixin.qq.com"}, true, true},
+		// 请求可能已送达，仅幂等调用重试
+		{io.ErrUnexpectedEOF, false, true},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, false, true},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), false, true},
+		{fmt.Errorf("wrap: %w", tempErr(true)), false, true},
+		{tempErr(false), false, false},
+	}
+	for _, c := range cases {
+		if got := IsRetryable(c.err); got != c.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+		if got := IsRetryableIdempotent(c.err); got != c.idempotent {
+			t.Errorf("IsRetryableIdempotent(%v) = %v, want %v", c.err, got, c.idempotent)
+		}
+	}
+}
+
+type tempErr bool
+
+func (e tempErr) Error() string   { return "temp" }
+func (e tempErr) Temporary() bool { return bool(e) }
+
+func TestWithIdempotent(t *testing.T) {
+	ctx := context.Background()
+	if IsIdempotent(ctx) {
+		t.Fatal("background ctx is idempotent")
+	}
+	if !IsIdempotent(WithIdempotent(ctx)) {
+		t.Fatal("WithIdempotent not marked")
+	}
+}
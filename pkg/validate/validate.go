@@ -0,0 +1,216 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validate 按 struct tag 校验请求参数
+//
+//	tag 格式：`validate:"required,max=64,oneof=bar_code security_code"`，规则以逗号分隔：
+//	required：不能为零值，切片不能为空
+//	min=N、max=N、len=N：字符串为字符数（rune），切片为元素个数，数字为数值
+//	gt=N：数字必须大于 N
+//	oneof=A B C：取值必须为其中之一
+//	非 required 字段为零值时跳过校验；结构体、结构体指针及其切片字段递归校验
+//	字段名取 json tag，嵌套字段以 . 连接，如 amount.total、purchase_units[0].amount.value
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError 字段校验失败
+type FieldError struct {
+	Field string // 字段名，如 amount.total
+	Rule  string // 校验规则，如 required、max
+	Param string // 规则参数，如 max=64 中的 64
+}
+
+func (e *FieldError) Error() string {
+	switch e.Rule {
+	case "required":
+		return e.Field + " is required"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", e.Field, e.Param)
+	}
+	return fmt.Sprintf("%s must satisfy %s=%s", e.Field, e.Rule, e.Param)
+}
+
+// Missing 是否为缺少必填字段
+func (e *FieldError) Missing() bool {
+	return e.Rule == "required"
+}
+
+// Struct 校验 v（结构体或结构体指针），返回第一个不满足规则的字段，类型为 *FieldError
+func Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return validateStruct(rv, "")
+}
+
+func validateStruct(rv reflect.Value, prefix string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fv := rv.Field(i)
+		// 匿名嵌入的结构体字段按同一层级校验
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := validateStruct(fv, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+		name := prefix + fieldName(f)
+		if err := validateField(fv, name, f.Tag.Get("validate")); err != nil {
+			return err
+		}
+		if err := dive(fv, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+// dive 递归校验结构体、结构体指针及其切片
+func dive(fv reflect.Value, name string) error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return nil
+		}
+		return dive(fv.Elem(), name)
+	case reflect.Struct:
+		return validateStruct(fv, name+".")
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := dive(fv.Index(i), name+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateField(fv reflect.Value, name, tag string) error {
+	if tag == "" || tag == "-" {
+		return nil
+	}
+	rules := strings.Split(tag, ",")
+	if isZero(fv) {
+		for _, r := range rules {
+			if r == "required" {
+				return &FieldError{Field: name, Rule: "required"}
+			}
+		}
+		return nil
+	}
+	for fv.Kind() == reflect.Ptr {
+		fv = fv.Elem()
+	}
+	for _, r := range rules {
+		rule, param := r, ""
+		if i := strings.IndexByte(r, '='); i >= 0 {
+			rule, param = r[:i], r[i+1:]
+		}
+		if !check(fv, rule, param) {
+			return &FieldError{Field: name, Rule: rule, Param: param}
+		}
+	}
+	return nil
+}
+
+func isZero(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return fv.IsNil()
+	}
+	return fv.IsZero()
+}
+
+func check(fv reflect.Value, rule, param string) bool {
+	switch rule {
+	case "required", "omitempty":
+		return true
+	case "oneof":
+		s := fmt.Sprint(fv.Interface())
+		for _, o := range strings.Fields(param) {
+			if s == o {
+				return true
+			}
+		}
+		return false
+	case "min", "max", "len", "gt":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: invalid rule %s=%s", rule, param))
+		}
+		size, ok := measure(fv, rule)
+		if !ok {
+			return true
+		}
+		switch rule {
+		case "min":
+			return size >= n
+		case "max":
+			return size <= n
+		case "len":
+			return size == n
+		default:
+			return size > n
+		}
+	}
+	panic("validate: unknown rule " + rule)
+}
+
+// measure 字符串返回字符数，切片返回元素个数，数字返回数值；gt 仅适用于数字
+func measure(fv reflect.Value, rule string) (float64, bool) {
+	switch fv.Kind() {
+	case reflect.String:
+		if rule == "gt" {
+			return 0, false
+		}
+		return float64(utf8.RuneCountInString(fv.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if rule == "gt" {
+			return 0, false
+		}
+		return float64(fv.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"errors"
+	"testing"
+)
+
+type amount struct {
+	Total    int64  `json:"total" validate:"required,gt=0"`
+	Currency string `json:"currency,omitempty" validate:"oneof=CNY"`
+}
+
+type item struct {
+	Name string `json:"name" validate:"required,max=4"`
+}
+
+type order struct {
+	OutTradeNo string   `json:"out_trade_no" validate:"required,min=6,max=32"`
+	Subject    string   `json:"subject" validate:"max=3"`
+	Amount     *amount  `json:"amount" validate:"required"`
+	Items      []*item  `json:"items" validate:"max=2"`
+	Tags       []string `json:"tags,omitempty" validate:"len=2"`
+	Note       string   `json:"-" validate:"max=1"`
+}
+
+func TestStruct(t *testing.T) {
+	valid := func() *order {
+		return &order{OutTradeNo: "202301010001", Subject: "商品名", Amount: &amount{Total: 1}}
+	}
+	if err := Struct(valid()); err != nil {
+		t.Fatalf("valid order: %v", err)
+	}
+	tests := []struct {
+		name  string
+		edit  func(o *order)
+		field string
+		rule  string
+	}{
+		{"required", func(o *order) { o.OutTradeNo = "" }, "out_trade_no", "required"},
+		{"min", func(o *order) { o.OutTradeNo = "12345" }, "out_trade_no", "min"},
+		{"max counts runes", func(o *order) { o.Subject = "商品名称" }, "subject", "max"},
+		{"required pointer", func(o *order) { o.Amount = nil }, "amount", "required"},
+		{"nested required", func(o *order) { o.Amount.Total = 0 }, "amount.total", "required"},
+		{"gt", func(o *order) { o.Amount.Total = -1 }, "amount.total", "gt"},
+		{"oneof", func(o *order) { o.Amount.Currency = "USD" }, "amount.currency", "oneof"},
+		{"slice max", func(o *order) { o.Items = []*item{{"a"}, {"b"}, {"c"}} }, "items", "max"},
+		{"slice element", func(o *order) { o.Items = []*item{{"a"}, {"abcde"}} }, "items[1].name", "max"},
+		{"len", func(o *order) { o.Tags = []string{"a"} }, "tags", "len"},
+		{"json ignored uses go name", func(o *order) { o.Note = "ab" }, "Note", "max"},
+	}
+	for _, tt := range tests {
+		o := valid()
+		tt.edit(o)
+		var fe *FieldError
+		if err := Struct(o); !errors.As(err, &fe) || fe.Field != tt.field || fe.Rule != tt.rule {
+			t.Errorf("%s: got %v, want %s %s", tt.name, err, tt.field, tt.rule)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/rwscode/payutil/pkg/redact"
+)
+
+// ErrCassetteMiss 回放模式下没有匹配的录制请求
+var ErrCassetteMiss = errors.New("xhttp: no matching cassette interaction")
+
+// DefaultCassetteIgnore 匹配请求时默认忽略的字段：每次请求都会变化的时间戳、随机串、签名
+var DefaultCassetteIgnore = []string{"timestamp", "nonce_str", "sign"}
+
+// CassetteMode 录制、回放模式
+type CassetteMode int
+
+const (
+	CassetteReplay CassetteMode = iota // 回放录制的应答，不发送请求
+	CassetteRecord                     // 发送真实请求并录制
+)
+
+// Cassette 录制、回放渠道接口的 http 请求，用于基于真实报文编写离线回归测试
+//
+//	录制模式通过 Doer 发送真实请求（如沙箱环境），脱敏后保存请求、应答，Save() 写入文件
+//	回放模式不发送请求，按 method + path + 归一化的 URL 参数、请求体匹配录制的应答，同一请求录制多次时按顺序回放
+//	脱敏后渠道的应答签名（支付宝 sign、微信V3 Wechatpay-Signature）失效，回放时通过 Resign 使用测试密钥重新签名，
+//	见 paytest.AlipayResigner、paytest.WechatV3Resigner
+//
+//	c, err := xhttp.NewCassette("testdata/trade_query.json", xhttp.CassetteReplay)
+//	r := paytest.NewAlipayResigner()
+//	c.Resign = r.Resign
+//	client.SetDoer(xhttp.WithCassette(nil, c))
+//	client.AutoVerifySign(r.PublicKeyCert())
+type Cassette struct {
+	// Redact 录制时脱敏，回放时对请求做同样处理后再匹配，默认 RedactInteraction
+	Redact func(it *Interaction)
+	// Resign 回放时修改应答，如使用测试密钥重新签名，为空时原样回放
+	Resign func(it *Interaction) error
+	// IgnoreFields 匹配时忽略的 URL 参数、表单、JSON、XML 字段，默认 DefaultCassetteIgnore
+	IgnoreFields []string
+
+	path         string
+	mode         CassetteMode
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// Interaction 一次录制的请求、应答
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest 录制的请求
+type CassetteRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// CassetteResponse 录制的应答，Body 不是 UTF-8 文本（如账单压缩包）时 Encoding 为 base64
+type CassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Encoding   string      `json:"encoding,omitempty"`
+}
+
+// BodyBytes 返回应答原始内容
+func (r *CassetteResponse) BodyBytes() ([]byte, error) {
+	if r.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(r.Body)
+	}
+	return []byte(r.Body), nil
+}
+
+// SetBody 设置应答内容，非 UTF-8 文本使用 base64 保存
+func (r *CassetteResponse) SetBody(bs []byte) {
+	if utf8.Valid(bs) {
+		r.Body, r.Encoding = string(bs), ""
+		return
+	}
+	r.Body, r.Encoding = base64.StdEncoding.EncodeToString(bs), "base64"
+}
+
+// NewCassette 初始化录制、回放，回放模式读取 path 文件，录制模式在 Save() 时覆盖 path 文件
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode}
+	if mode == CassetteRecord {
+		return c, nil
+	}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(bs, &c.interactions); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// Mode 返回录制、回放模式
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Save 将录制的请求、应答写入文件，回放模式下忽略
+func (c *Cassette) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+	c.mu.Lock()
+	bs, err := json.MarshalIndent(c.interactions, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(bs, '\n'), 0o644)
+}
+
+// RedactInteraction 默认脱敏：遮盖 Authorization 等请求头，按 redact 包的字段脱敏 URL 参数、请求体、应答内容
+func RedactInteraction(it *Interaction) {
+	if u, err := url.Parse(it.Request.URL); err == nil && u.RawQuery != "" {
+		u.RawQuery = redact.String(u.RawQuery)
+		it.Request.URL = u.String()
+	}
+	it.Request.Header = redact.Header(it.Request.Header)
+	it.Request.Body = redactBody(it.Request.Header, it.Request.Body)
+	it.Response.Header = redact.Header(it.Response.Header)
+	if it.Response.Encoding == "" {
+		it.Response.Body = redact.String(it.Response.Body)
+	}
+}
+
+// redactBody 脱敏请求体，表单请求按参数解析后脱敏再编码，参数值中的 JSON（如支付宝 biz_content）按字段脱敏
+func redactBody(h http.Header, body string) string {
+	if strings.HasPrefix(h.Get("Content-Type"), types[TypeForm]) {
+		if vs, err := url.ParseQuery(body); err == nil {
+			return redact.Values(vs).Encode()
+		}
+	}
+	return redact.String(body)
+}
+
+// WithCassette 返回录制、回放 http 请求的 Doer，d 为 nil 时使用 DefaultHttpClient()，回放模式下不使用 d
+func WithCassette(d Doer, c *Cassette) Doer {
+	if d == nil {
+		d = defaultHttpClient
+	}
+	return &cassetteDoer{d: d, c: c}
+}
+
+type cassetteDoer struct {
+	d Doer
+	c *Cassette
+}
+
+func (cd *cassetteDoer) Do(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	it := &Interaction{Request: CassetteRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+		Body:   string(reqBody),
+	}}
+	if cd.c.mode == CassetteRecord {
+		return cd.c.record(cd.d, req, it)
+	}
+	return cd.c.replay(req, it)
+}
+
+func (c *Cassette) record(d Doer, req *http.Request, it *Interaction) (*http.Response, error) {
+	res, err := d.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(bs))
+	it.Response = CassetteResponse{StatusCode: res.StatusCode, Header: res.Header.Clone()}
+	it.Response.SetBody(bs)
+	c.redact(it)
+	c.mu.Lock()
+	c.interactions = append(c.interactions, it)
+	c.mu.Unlock()
+	return res, nil
+}
+
+func (c *Cassette) replay(req *http.Request, it *Interaction) (*http.Response, error) {
+	c.redact(it)
+	key := c.key(&it.Request)
+	c.mu.Lock()
+	matched := -1
+	for i, rec := range c.interactions {
+		if c.key(&rec.Request) != key {
+			continue
+		}
+		matched = i
+		if !c.used[i] {
+			break
+		}
+	}
+	if matched < 0 {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s %s", ErrCassetteMiss, req.Method, req.URL.Path)
+	}
+	c.used[matched] = true
+	rec := *c.interactions[matched]
+	c.mu.Unlock()
+
+	rec.Response.Header = rec.Response.Header.Clone()
+	if c.Resign != nil {
+		if err := c.Resign(&rec); err != nil {
+			return nil, fmt.Errorf("cassette resign %s %s: %w", req.Method, req.URL.Path, err)
+		}
+	}
+	bs, err := rec.Response.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	header := rec.Response.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(bs)))
+	return &http.Response{
+		Status:        strconv.Itoa(rec.Response.StatusCode) + " " + http.StatusText(rec.Response.StatusCode),
+		StatusCode:    rec.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(bs)),
+		ContentLength: int64(len(bs)),
+		Request:       req,
+	}, nil
+}
+
+func (c *Cassette) redact(it *Interaction) {
+	if c.Redact != nil {
+		c.Redact(it)
+		return
+	}
+	RedactInteraction(it)
+}
+
+// key 请求的匹配键：method + path + 归一化的 URL 参数 + 归一化的请求体
+func (c *Cassette) key(r *CassetteRequest) string {
+	ignore := c.IgnoreFields
+	if ignore == nil {
+		ignore = DefaultCassetteIgnore
+	}
+	path, query := r.URL, ""
+	if u, err := url.Parse(r.URL); err == nil {
+		path = u.Path
+		query = normalizeForm(u.Query(), ignore)
+	}
+	return r.Method + " " + path + "?" + query + "\n" + normalizeBody(r.Body, ignore)
+}
+
+// normalizeBody 去除忽略字段，JSON 按 key 排序，表单按参数名排序
+func normalizeBody(body string, ignore []string) string {
+	trimmed := strings.TrimSpace(body)
+	switch {
+	case trimmed == "":
+		return ""
+	case strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "["):
+		var v interface{}
+		if err := json.Unmarshal([]byte(trimmed), &v); err != nil {
+			return body
+		}
+		bs, _ := json.Marshal(dropJSONFields(v, ignore))
+		return string(bs)
+	case strings.HasPrefix(trimmed, "<"):
+		for _, f := range ignore {
+			re := regexp.MustCompile(`(?s)<` + regexp.QuoteMeta(f) + `>.*?</` + regexp.QuoteMeta(f) + `>`)
+			trimmed = re.ReplaceAllString(trimmed, "")
+		}
+		return trimmed
+	}
+	values, err := url.ParseQuery(trimmed)
+	if err != nil {
+		return body
+	}
+	return normalizeForm(values, ignore)
+}
+
+func normalizeForm(values url.Values, ignore []string) string {
+	for _, f := range ignore {
+		values.Del(f)
+	}
+	// Encode 按参数名排序
+	return values.Encode()
+}
+
+func dropJSONFields(v interface{}, ignore []string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, f := range ignore {
+			delete(t, f)
+		}
+		for k, e := range t {
+			t[k] = dropJSONFields(e, ignore)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = dropJSONFields(e, ignore)
+		}
+	}
+	return v
+}
+
+// readRequestBody 读取请求体并恢复，使请求可继续发送
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	bs, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(bs))
+	return bs, nil
+}
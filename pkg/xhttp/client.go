@@ -34,6 +34,7 @@ import (
 
 type Client struct {
 	HttpClient       *http.Client
+	doer             Doer
 	Transport        *http.Transport
 	Header           http.Header
 	Timeout          time.Duration
@@ -50,17 +51,10 @@ type Client struct {
 	err              error
 }
 
-// NewClient , default tls.Config{InsecureSkipVerify: true}
+// NewClient 初始化请求，默认使用共享连接池的 DefaultHttpClient()，校验服务端证书
 func NewClient() (client *Client) {
 	client = &Client{
-		HttpClient: &http.Client{
-			Timeout: 60 * time.Second,
-			Transport: &http.Transport{
-				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-				DisableKeepAlives: true,
-				Proxy:             http.ProxyFromEnvironment,
-			},
-		},
+		HttpClient:    defaultHttpClient,
 		Transport:     nil,
 		Header:        make(http.Header),
 		bodySize:      10, // default is 10MB
@@ -75,6 +69,16 @@ func (c *Client) SetTransport(transport *http.Transport) (client *Client) {
 	return c
 }
 
+// SetDoer 设置发送请求的 Doer，d 为 nil 时使用 HttpClient
+func (c *Client) SetDoer(d Doer) (client *Client) {
+	if d != nil {
+		c.doer = d
+	}
+	return c
+}
+
+// SetTLSConfig 使用一次性 Transport 发送请求，不复用连接
+// 需复用连接时，请使用 NewHttpClient() 或 WithClientCert() 并缓存返回的 Doer
 func (c *Client) SetTLSConfig(tlsCfg *tls.Config) (client *Client) {
 	c.Transport = &http.Transport{TLSClientConfig: tlsCfg, DisableKeepAlives: true, Proxy: http.ProxyFromEnvironment}
 	return c
@@ -310,16 +314,10 @@ func (c *Client) EndBytes(ctx context.Context) (res *http.Response, bs []byte, e
 		}
 		req.Header = c.Header
 		req.Header.Set("Content-Type", c.ContentType)
-		if c.Transport != nil {
-			c.HttpClient.Transport = c.Transport
-		}
 		if c.Host != "" {
 			req.Host = c.Host
 		}
-		if c.Timeout > 0 {
-			c.HttpClient.Timeout = c.Timeout
-		}
-		res, err = c.HttpClient.Do(req)
+		res, err = c.do(req)
 		if err != nil {
 			return err
 		}
@@ -337,6 +335,30 @@ func (c *Client) EndBytes(ctx context.Context) (res *http.Response, bs []byte, e
 	return res, bs, nil
 }
 
+// do 发送请求，Transport、Timeout 仅作用于本次请求，不修改共享的 http.Client
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	d := c.doer
+	if d == nil {
+		d = c.HttpClient
+	}
+	if c.Transport == nil && c.Timeout <= 0 {
+		return d.Do(req)
+	}
+	hc, ok := d.(*http.Client)
+	if !ok {
+		// 自定义 Doer 自行管理 Transport、Timeout
+		return d.Do(req)
+	}
+	cp := *hc
+	if c.Transport != nil {
+		cp.Transport = c.Transport
+	}
+	if c.Timeout > 0 {
+		cp.Timeout = c.Timeout
+	}
+	return cp.Do(req)
+}
+
 func FormatURLParam(body map[string]interface{}) (urlParam string) {
 	var (
 		buf  strings.Builder
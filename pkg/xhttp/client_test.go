@@ -15,6 +15,11 @@ import (
 	"context"
 	pay "github.com/rwscode/payutil"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -78,3 +83,50 @@ func TestHttpUploadFile(t *testing.T) {
 	}
 	xlog.Debugf("%+v", rsp)
 }
+
+func TestClient_ConnReuse(t *testing.T) {
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	ts.Config.ConnState = func(c net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	for i := 0; i < 3; i++ {
+		client := NewClient()
+		client.Timeout = 5 * time.Second
+		if _, _, err := client.Get(ts.URL).EndBytes(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("conns = %d, want 1", n)
+	}
+	if DefaultHttpClient().Timeout != defaultTimeout {
+		t.Fatalf("shared client timeout modified: %v", DefaultHttpClient().Timeout)
+	}
+}
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestClient_SetDoer(t *testing.T) {
+	var got string
+	d := doerFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.URL.String()
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("ok")), Header: make(http.Header)}, nil
+	})
+	_, bs, err := NewClient().SetDoer(d).Get("https://api.example.com/ping").EndBytes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "https://api.example.com/ping" || string(bs) != "ok" {
+		t.Fatalf("got %s, body %s", got, bs)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xhttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// WithFailover 返回主域名无法连接时改用备用域名重发请求的 Doer，如微信支付 api.mch.weixin.qq.com -> api2.mch.weixin.qq.com
+//
+//	primary、backup 为接口域名，如 https://api.mch.weixin.qq.com，仅请求 primary 域名时生效
+//	仅在 DNS 解析失败、拒绝连接、连接超时等请求未发出的错误时切换，请求发出后的错误（如读取超时）不切换，避免重复提交
+//	d 为 nil 时使用 DefaultHttpClient()
+func WithFailover(d Doer, primary, backup string) Doer {
+	if d == nil {
+		d = defaultHttpClient
+	}
+	p, err := url.Parse(primary)
+	if err != nil || p.Host == "" {
+		return d
+	}
+	b, err := url.Parse(backup)
+	if err != nil || b.Host == "" {
+		return d
+	}
+	return &failoverDoer{d: d, primary: p, backup: b}
+}
+
+type failoverDoer struct {
+	d       Doer
+	primary *url.URL
+	backup  *url.URL
+}
+
+func (f *failoverDoer) Do(req *http.Request) (*http.Response, error) {
+	res, err := f.d.Do(req)
+	if err == nil || req.URL.Host != f.primary.Host || req.URL.Scheme != f.primary.Scheme ||
+		req.Context().Err() != nil || !IsDialError(err) {
+		return res, err
+	}
+	r := req.Clone(req.Context())
+	r.URL.Scheme, r.URL.Host = f.backup.Scheme, f.backup.Host
+	if req.Host == f.primary.Host {
+		r.Host = ""
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return res, err
+		}
+		body, e := req.GetBody()
+		if e != nil {
+			return res, err
+		}
+		r.Body = body
+	}
+	return f.d.Do(r)
+}
+
+// IsDialError 返回 err 是否为建立连接阶段的错误：DNS 解析失败、拒绝连接、连接超时等，此时请求未发送到服务端
+func IsDialError(err error) bool {
+	var de *net.DNSError
+	if errors.As(err, &de) {
+		return true
+	}
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
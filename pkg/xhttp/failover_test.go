@@ -0,0 +1,66 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xhttp
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithFailover(t *testing.T) {
+	var hosts []string
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := ioutil.ReadAll(r.Body)
+		hosts = append(hosts, r.Host)
+		_, _ = w.Write(bs)
+	}))
+	defer backup.Close()
+
+	// 已关闭的端口，连接被拒绝
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := "http://" + ln.Addr().String()
+	_ = ln.Close()
+
+	d := WithFailover(nil, primary, backup.URL)
+	_, bs, err := NewClient().SetDoer(d).Type(TypeXML).Post(primary + "/pay/orderquery").SendString("<xml>1</xml>").EndBytes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "<xml>1</xml>" || len(hosts) != 1 || hosts[0] != backup.Listener.Addr().String() {
+		t.Fatalf("body = %s, hosts = %v", bs, hosts)
+	}
+
+	// 非 primary 域名不切换
+	if _, _, err = NewClient().SetDoer(WithFailover(nil, "http://api.example.com", backup.URL)).Get(primary).EndBytes(ctx); err == nil || !IsDialError(err) {
+		t.Fatalf("err = %v, want dial error", err)
+	}
+	if len(hosts) != 1 {
+		t.Fatalf("hosts = %v", hosts)
+	}
+
+	// 请求已发出后的错误不切换
+	calls := 0
+	d = WithFailover(doerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, errors.New("read: connection reset by peer")
+	}), primary, backup.URL)
+	if _, _, err = NewClient().SetDoer(d).Get(primary).EndBytes(ctx); err == nil || calls != 1 {
+		t.Fatalf("err = %v, calls = %d", err, calls)
+	}
+}
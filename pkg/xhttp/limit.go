@@ -0,0 +1,357 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRateLimited = errors.New("xhttp: rate limited")
+	ErrCircuitOpen = errors.New("xhttp: circuit breaker is open")
+)
+
+// Family 接口族，同一接口族共享令牌桶和熔断状态，如账单下载、交易、营销
+type Family struct {
+	Name     string   // 接口族名称，如 "bill"、"transaction"、"marketing"
+	Prefixes []string // 接口标识前缀，为空时匹配所有接口；接口标识同 observe.Call.Method，如 "alipay.data.dataservice.bill"、"GET /v3/bill/"
+	Rate     float64  // 每秒请求数，<= 0 时不限流
+	Burst    int      // 令牌桶容量，默认 ceil(Rate)
+}
+
+// BreakerConfig 熔断配置
+type BreakerConfig struct {
+	Failures    int           // 连续失败（5xx、超时、连接失败）达到该次数时熔断，<= 0 时不熔断
+	OpenTimeout time.Duration // 熔断时长，到期后进入半开状态放行探测请求，默认 30s
+	HalfOpenMax int           // 半开状态允许的并发探测请求数，默认 1
+}
+
+// BreakerState 熔断状态
+type BreakerState int
+
+const (
+	StateClosed   BreakerState = iota // 正常放行
+	StateOpen                         // 熔断中，请求直接返回 ErrCircuitOpen
+	StateHalfOpen                     // 半开，放行少量探测请求，成功则恢复，失败则继续熔断
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Limiter 按接口族限流、熔断，每次 http 请求（含重试）消耗一个令牌
+//
+//	接口按 families 顺序匹配，未匹配的接口归入 "default" 接口族，不限流但参与熔断
+//	不同接口族互不影响，如账单下载的批量任务不会耗尽交易接口的配额，交易接口熔断也不影响查询账单
+//	nil 可用，表示不限流、不熔断
+type Limiter struct {
+	families []Family
+	breaker  BreakerConfig
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	breakers map[string]*breaker
+}
+
+func NewLimiter(cfg BreakerConfig, families ...Family) *Limiter {
+	return &Limiter{
+		families: families,
+		breaker:  cfg,
+		buckets:  make(map[string]*bucket),
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Family 返回接口所属的接口族名称
+func (l *Limiter) Family(endpoint string) string {
+	if f := l.match(endpoint); f != nil {
+		return f.Name
+	}
+	return "default"
+}
+
+func (l *Limiter) match(endpoint string) *Family {
+	for i := range l.families {
+		f := &l.families[i]
+		if len(f.Prefixes) == 0 {
+			return f
+		}
+		for _, p := range f.Prefixes {
+			if strings.HasPrefix(endpoint, p) {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// State 返回接口族当前的熔断状态
+func (l *Limiter) State(family string) BreakerState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b := l.breakers[family]; b != nil {
+		if b.state == StateOpen && time.Since(b.openedAt) >= l.openTimeout() {
+			return StateHalfOpen
+		}
+		return b.state
+	}
+	return StateClosed
+}
+
+// Wrap 返回经过限流、熔断的 send，用于 retry.Policy.DoHTTP 等，l 为 nil 时返回 send
+//
+//	限流：等待令牌，ctx 截止前拿不到令牌时立即返回 ErrRateLimited，不发送请求
+//	熔断：熔断中返回 ErrCircuitOpen，不发送请求；5xx、超时、连接失败计为失败，其余响应计为成功
+func (l *Limiter) Wrap(endpoint string, send func(ctx context.Context) (*http.Response, []byte, error)) func(ctx context.Context) (*http.Response, []byte, error) {
+	if l == nil {
+		return send
+	}
+	return func(ctx context.Context) (*http.Response, []byte, error) {
+		done, err := l.acquire(ctx, endpoint)
+		if err != nil {
+			return nil, nil, err
+		}
+		res, bs, err := send(ctx)
+		done(isFailure(res, err))
+		return res, bs, err
+	}
+}
+
+// acquire 检查熔断状态并等待令牌，返回的 done 用于记录请求结果
+func (l *Limiter) acquire(ctx context.Context, endpoint string) (done func(failed bool), err error) {
+	f := l.match(endpoint)
+	name := "default"
+	if f != nil {
+		name = f.Name
+	}
+	l.mu.Lock()
+	b := l.breakers[name]
+	if b == nil && l.breaker.Failures > 0 {
+		b = new(breaker)
+		l.breakers[name] = b
+	}
+	if b != nil {
+		if err = b.allow(time.Now(), l.openTimeout(), l.halfOpenMax()); err != nil {
+			l.mu.Unlock()
+			return nil, err
+		}
+	}
+	done = func(failed bool) {
+		if b == nil {
+			return
+		}
+		l.mu.Lock()
+		b.done(failed, time.Now(), l.breaker.Failures)
+		l.mu.Unlock()
+	}
+	// 未发出请求时归还半开状态的探测名额
+	cancel := func() {
+		if b == nil {
+			return
+		}
+		l.mu.Lock()
+		b.cancel()
+		l.mu.Unlock()
+	}
+	var wait time.Duration
+	if f != nil && f.Rate > 0 {
+		bk := l.buckets[name]
+		if bk == nil {
+			bk = newBucket(f.Rate, f.Burst)
+			l.buckets[name] = bk
+		}
+		wait = bk.reserve(time.Now())
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			bk.cancel()
+			wait = -1
+		}
+	}
+	l.mu.Unlock()
+
+	if wait < 0 {
+		cancel()
+		return nil, ErrRateLimited
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			cancel()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return done, nil
+}
+
+func (l *Limiter) openTimeout() time.Duration {
+	if l.breaker.OpenTimeout > 0 {
+		return l.breaker.OpenTimeout
+	}
+	return 30 * time.Second
+}
+
+func (l *Limiter) halfOpenMax() int {
+	if l.breaker.HalfOpenMax > 0 {
+		return l.breaker.HalfOpenMax
+	}
+	return 1
+}
+
+// isFailure 5xx、超时、连接失败计为熔断失败，调用方主动取消不计入
+func isFailure(res *http.Response, err error) bool {
+	if err == nil {
+		return res != nil && res.StatusCode >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.DeadlineExceeded) || IsDialError(err) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// bucket 令牌桶
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Ceil(rate)
+	}
+	return &bucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// reserve 预占一个令牌，返回需要等待的时长
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel 归还 reserve 预占的令牌
+func (b *bucket) cancel() {
+	b.tokens++
+}
+
+// breaker 熔断器，由 Limiter.mu 保护
+type breaker struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+func (b *breaker) allow(now time.Time, openTimeout time.Duration, halfOpenMax int) error {
+	if b.state == StateOpen {
+		if now.Sub(b.openedAt) < openTimeout {
+			return ErrCircuitOpen
+		}
+		b.state, b.probes = StateHalfOpen, 0
+	}
+	if b.state == StateHalfOpen {
+		if b.probes >= halfOpenMax {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+func (b *breaker) cancel() {
+	if b.state == StateHalfOpen {
+		b.probes--
+	}
+}
+
+func (b *breaker) done(failed bool, now time.Time, threshold int) {
+	switch b.state {
+	case StateHalfOpen:
+		b.probes--
+		if failed {
+			b.state, b.openedAt = StateOpen, now
+			return
+		}
+		b.state, b.failures = StateClosed, 0
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		if b.failures++; b.failures >= threshold {
+			b.state, b.openedAt = StateOpen, now
+		}
+	}
+}
+
+type endpointKey struct{}
+
+// WithEndpoint 设置本次请求的接口标识，供 WithLimiter 匹配接口族
+func WithEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+// WithLimiter 返回经过 l 限流、熔断的 Doer，用于未经渠道客户端发出的请求
+//
+//	接口标识取自 WithEndpoint(ctx)，未设置时为 "METHOD /path"
+//	d 为 nil 时使用 DefaultHttpClient()
+func WithLimiter(d Doer, l *Limiter) Doer {
+	if d == nil {
+		d = defaultHttpClient
+	}
+	if l == nil {
+		return d
+	}
+	return &limitDoer{d: d, l: l}
+}
+
+type limitDoer struct {
+	d Doer
+	l *Limiter
+}
+
+func (ld *limitDoer) Do(req *http.Request) (*http.Response, error) {
+	endpoint, _ := req.Context().Value(endpointKey{}).(string)
+	if endpoint == "" {
+		endpoint = req.Method + " " + req.URL.Path
+	}
+	done, err := ld.l.acquire(req.Context(), endpoint)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ld.d.Do(req)
+	done(isFailure(res, err))
+	return res, err
+}
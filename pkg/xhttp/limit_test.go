@@ -0,0 +1,117 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLimiterBreaker(t *testing.T) {
+	l := NewLimiter(BreakerConfig{Failures: 3, OpenTimeout: 20 * time.Millisecond}, Family{Name: "bill", Prefixes: []string{"GET /v3/bill/"}})
+	status := http.StatusServiceUnavailable
+	var calls int
+	send := l.Wrap("POST /v3/pay/transactions/native", func(ctx context.Context) (*http.Response, []byte, error) {
+		calls++
+		return &http.Response{StatusCode: status}, nil, nil
+	})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, _, _ = send(ctx)
+	}
+	if _, _, err := send(ctx); !errors.Is(err, ErrCircuitOpen) || calls != 3 {
+		t.Fatalf("err = %v, calls = %d, want ErrCircuitOpen after 3 failures", err, calls)
+	}
+	if s := l.State("default"); s != StateOpen {
+		t.Fatalf("state = %s", s)
+	}
+	// 其他接口族不受影响
+	bill := l.Wrap("GET /v3/bill/tradebill", func(ctx context.Context) (*http.Response, []byte, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil, nil
+	})
+	if _, _, err := bill(ctx); err != nil {
+		t.Fatalf("bill err = %v", err)
+	}
+
+	// 半开：探测失败继续熔断，探测成功恢复
+	time.Sleep(25 * time.Millisecond)
+	if s := l.State("default"); s != StateHalfOpen {
+		t.Fatalf("state = %s, want half-open", s)
+	}
+	if _, _, err := send(ctx); err != nil || calls != 4 {
+		t.Fatalf("probe err = %v, calls = %d", err, calls)
+	}
+	if _, _, err := send(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen after failed probe", err)
+	}
+	time.Sleep(25 * time.Millisecond)
+	status = http.StatusOK
+	for i := 0; i < 3; i++ {
+		if _, _, err := send(ctx); err != nil {
+			t.Fatalf("err = %v after successful probe", err)
+		}
+	}
+	if s := l.State("default"); s != StateClosed {
+		t.Fatalf("state = %s, want closed", s)
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	l := NewLimiter(BreakerConfig{},
+		Family{Name: "bill", Prefixes: []string{"alipay.data.dataservice.bill"}, Rate: 10, Burst: 1},
+		Family{Name: "transaction", Prefixes: []string{"alipay.trade."}, Rate: 1000},
+	)
+	if f := l.Family("alipay.trade.pay"); f != "transaction" {
+		t.Fatalf("Family = %s", f)
+	}
+	ok := func(ctx context.Context) (*http.Response, []byte, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil, nil
+	}
+	bill := l.Wrap("alipay.data.dataservice.bill.downloadurl.query", ok)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := bill(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 令牌耗尽，等待约 100ms 超过 ctx 截止时间
+	if _, _, err := bill(ctx); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	// 交易接口不受账单下载影响
+	trade := l.Wrap("alipay.trade.pay", ok)
+	for i := 0; i < 100; i++ {
+		if _, _, err := trade(ctx); err != nil {
+			t.Fatalf("trade err = %v", err)
+		}
+	}
+	start := time.Now()
+	if _, _, err := bill(context.Background()); err != nil || time.Since(start) < 50*time.Millisecond {
+		t.Fatalf("err = %v, waited %s", err, time.Since(start))
+	}
+}
+
+func TestWithLimiter(t *testing.T) {
+	l := NewLimiter(BreakerConfig{Failures: 1}, Family{Name: "bill", Prefixes: []string{"bill"}})
+	d := WithLimiter(doerFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody}, nil
+	}), l)
+	req, _ := http.NewRequestWithContext(WithEndpoint(context.Background(), "bill"), http.MethodGet, "http://127.0.0.1/x", nil)
+	if _, err := d.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Do(req); !errors.Is(err, ErrCircuitOpen) || l.State("bill") != StateOpen {
+		t.Fatalf("err = %v, state = %s", err, l.State("bill"))
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xhttp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+)
+
+// TLSOptions 服务端证书校验配置
+type TLSOptions struct {
+	InsecureSkipVerify bool           // 跳过服务端证书校验，存在中间人攻击风险，仅用于测试环境
+	RootCAs            *x509.CertPool // 自定义根证书，为空时使用系统根证书
+	SPKIPins           []string       // 公钥固定，值为 SPKIHash()，证书链中任一证书匹配即通过，为空时不校验
+}
+
+// NewTLSConfig 根据 TLSOptions 生成 *tls.Config，o 为 nil 时使用系统根证书校验服务端证书
+func NewTLSConfig(o *TLSOptions) *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o == nil {
+		return cfg
+	}
+	cfg.InsecureSkipVerify = o.InsecureSkipVerify
+	cfg.RootCAs = o.RootCAs
+	if len(o.SPKIPins) > 0 {
+		pins := make(map[string]bool, len(o.SPKIPins))
+		for _, pin := range o.SPKIPins {
+			pins[pin] = true
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, pins)
+		}
+	}
+	return cfg
+}
+
+// SPKIHash 返回证书公钥固定值：base64(sha256(SubjectPublicKeyInfo))
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func verifyPins(cs tls.ConnectionState, pins map[string]bool) error {
+	// 已校验证书链时只匹配可信链上的证书，跳过校验时匹配服务端下发的证书
+	certs := cs.PeerCertificates
+	if len(cs.VerifiedChains) > 0 {
+		certs = nil
+		for _, chain := range cs.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+	}
+	for _, cert := range certs {
+		if pins[SPKIHash(cert)] {
+			return nil
+		}
+	}
+	return fmt.Errorf("xhttp: %s certificate public key does not match any pin", cs.ServerName)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xhttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTLSServer(t *testing.T) (*httptest.Server, *x509.CertPool) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(ts.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	return ts, pool
+}
+
+func tlsGet(d Doer, url string) (string, error) {
+	_, bs, err := NewClient().SetDoer(d).Get(url).EndBytes(ctx)
+	return string(bs), err
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	ts, pool := newTLSServer(t)
+
+	// 默认校验服务端证书，自签名证书不可信
+	if _, err := tlsGet(nil, ts.URL); err == nil {
+		t.Fatal("default client trusted a self-signed certificate")
+	}
+	cases := []struct {
+		name string
+		opts *TLSOptions
+		ok   bool
+	}{
+		{"root ca", &TLSOptions{RootCAs: pool}, true},
+		{"insecure", &TLSOptions{InsecureSkipVerify: true}, true},
+		{"pin match", &TLSOptions{RootCAs: pool, SPKIPins: []string{SPKIHash(ts.Certificate())}}, true},
+		{"pin mismatch", &TLSOptions{RootCAs: pool, SPKIPins: []string{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}, false},
+		{"insecure pin mismatch", &TLSOptions{InsecureSkipVerify: true, SPKIPins: []string{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}, false},
+	}
+	for _, c := range cases {
+		hc := NewHttpClient(&TransportConfig{TLSConfig: NewTLSConfig(c.opts)})
+		_, err := tlsGet(hc, ts.URL)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: err = %v, want ok = %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestWithClientCert(t *testing.T) {
+	ts, pool := newTLSServer(t)
+	ts.TLS.ClientAuth = tls.RequireAnyClientCert
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "merchant"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	base := NewHttpClient(&TransportConfig{TLSConfig: NewTLSConfig(&TLSOptions{RootCAs: pool})})
+	d, err := WithClientCert(base, []tls.Certificate{cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := tlsGet(d, ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "merchant" {
+		t.Fatalf("body = %s", body)
+	}
+	// 基础客户端不受影响
+	if len(base.Transport.(*http.Transport).TLSClientConfig.Certificates) != 0 {
+		t.Fatal("base transport modified")
+	}
+	// 无法加载证书的 Doer
+	if _, err = WithClientCert(doerFunc(base.Do), []tls.Certificate{cert}); !errors.Is(err, ErrClientCertUnsupported) {
+		t.Fatalf("custom doer err = %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xhttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const defaultTimeout = 60 * time.Second
+
+// Doer 发送 http 请求，*http.Client 已实现该接口
+// 可注入自定义实现，用于链路追踪、代理、mock 等
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// TransportConfig 连接池配置，零值字段使用默认值
+type TransportConfig struct {
+	MaxIdleConns        int                                   // 所有 host 最大空闲连接数，默认 100
+	MaxIdleConnsPerHost int                                   // 每个 host 最大空闲连接数，默认 20
+	MaxConnsPerHost     int                                   // 每个 host 最大连接数，默认 0 不限制
+	IdleConnTimeout     time.Duration                         // 空闲连接超时时间，默认 90s
+	TLSConfig           *tls.Config                           // 默认 NewTLSConfig(nil)，校验服务端证书
+	Proxy               func(*http.Request) (*url.URL, error) // 默认 http.ProxyFromEnvironment
+}
+
+// NewTransport 初始化支持连接复用、HTTP/2 的 *http.Transport，cfg 可为 nil
+func NewTransport(cfg *TransportConfig) *http.Transport {
+	if cfg == nil {
+		cfg = new(TransportConfig)
+	}
+	t := &http.Transport{
+		Proxy: cfg.Proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSClientConfig:       cfg.TLSConfig,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if t.Proxy == nil {
+		t.Proxy = http.ProxyFromEnvironment
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = NewTLSConfig(nil)
+	}
+	if t.MaxIdleConns <= 0 {
+		t.MaxIdleConns = 100
+	}
+	if t.MaxIdleConnsPerHost <= 0 {
+		t.MaxIdleConnsPerHost = 20
+	}
+	if t.IdleConnTimeout <= 0 {
+		t.IdleConnTimeout = 90 * time.Second
+	}
+	return t
+}
+
+// NewHttpClient 使用连接池配置初始化 *http.Client，超时时间 60s，cfg 可为 nil
+func NewHttpClient(cfg *TransportConfig) *http.Client {
+	return &http.Client{Timeout: defaultTimeout, Transport: NewTransport(cfg)}
+}
+
+var defaultHttpClient = NewHttpClient(nil)
+
+// DefaultHttpClient 返回所有 Client 共享的默认 *http.Client
+func DefaultHttpClient() *http.Client {
+	return defaultHttpClient
+}
+
+// ErrClientCertUnsupported Doer 无法加载客户端证书，如自定义 Doer、非 *http.Transport 的 RoundTripper
+var ErrClientCertUnsupported = errors.New("xhttp: doer does not support client certificate")
+
+// WithClientCert 返回携带客户端证书的 Doer，用于微信、QQ 等需要双向证书的请求，返回值请缓存复用
+//
+//	d 为 nil 时基于默认连接池配置新建 *http.Client
+//	d 为 *http.Client 时复制其 Transport（需为 *http.Transport 或 nil），保留其证书校验配置并加载客户端证书
+//	其他 Doer 无法加载证书，返回 ErrClientCertUnsupported，避免请求时因缺少证书失败
+func WithClientCert(d Doer, certs []tls.Certificate) (Doer, error) {
+	if d == nil {
+		d = defaultHttpClient
+	}
+	hc, ok := d.(*http.Client)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrClientCertUnsupported, d)
+	}
+	var t *http.Transport
+	switch rt := hc.Transport.(type) {
+	case nil:
+		t = NewTransport(nil)
+	case *http.Transport:
+		t = rt.Clone()
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = NewTLSConfig(nil)
+		}
+	default:
+		return nil, fmt.Errorf("%w: transport %T", ErrClientCertUnsupported, rt)
+	}
+	t.TLSClientConfig.Certificates = certs
+	c := *hc
+	c.Transport = t
+	return &c, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xlog
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Logger 分级、结构化日志接口，keyvals 为成对的 key/value
+//
+//	可通过 client.SetLogger() 为每个商户客户端单独设置，不再共用全局 logger
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc 函数形式的 Logger
+type LoggerFunc func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+
+func (f LoggerFunc) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	f(ctx, level, msg, keyvals...)
+}
+
+// String 返回日志级别名称
+func (l LogLevel) String() string {
+	switch l {
+	case ErrorLevel:
+		return "ERROR"
+	case WarnLevel:
+		return "WARN"
+	case InfoLevel:
+		return "INFO"
+	case DebugLevel:
+		return "DEBUG"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// With 返回一个总是携带 keyvals 字段的 Logger
+func With(l Logger, keyvals ...interface{}) Logger {
+	if l == nil || len(keyvals) == 0 {
+		return l
+	}
+	if w, ok := l.(*withLogger); ok {
+		kv := make([]interface{}, 0, len(w.keyvals)+len(keyvals))
+		kv = append(append(kv, w.keyvals...), keyvals...)
+		return &withLogger{l: w.l, keyvals: kv}
+	}
+	return &withLogger{l: l, keyvals: keyvals}
+}
+
+type withLogger struct {
+	l       Logger
+	keyvals []interface{}
+}
+
+func (w *withLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	kv := make([]interface{}, 0, len(w.keyvals)+len(keyvals))
+	kv = append(append(kv, w.keyvals...), keyvals...)
+	w.l.Log(ctx, level, msg, kv...)
+}
+
+// Std 将结构化日志以 "msg k=v ..." 形式输出到全局 xlog（SetDebugLog 等设置的 logger）
+var Std Logger = LoggerFunc(func(_ context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	line := Format(msg, keyvals...)
+	switch level {
+	case ErrorLevel:
+		Errorf("%s", line)
+	case WarnLevel:
+		Warnf("%s", line)
+	case InfoLevel:
+		Infof("%s", line)
+	default:
+		Debugf("%s", line)
+	}
+})
+
+// Logf 按 format 输出一条日志：l 不为空时输出到 l（去掉颜色控制符），否则输出到全局 xlog
+//
+//	供各渠道客户端输出调试日志
+func Logf(l Logger, level LogLevel, format string, args ...interface{}) {
+	if l == nil {
+		switch level {
+		case ErrorLevel:
+			errLog.LogOut(nil, &format, args...)
+		case WarnLevel:
+			warnLog.LogOut(nil, &format, args...)
+		case InfoLevel:
+			infoLog.LogOut(nil, &format, args...)
+		default:
+			debugLog.LogOut(nil, &format, args...)
+		}
+		return
+	}
+	l.Log(context.Background(), level, colorRegex.ReplaceAllString(fmt.Sprintf(format, args...), ""))
+}
+
+var colorRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Nop 丢弃所有日志
+var Nop Logger = LoggerFunc(func(context.Context, LogLevel, string, ...interface{}) {})
+
+// Format 将 msg 与 keyvals 格式化为 "msg k1=v1 k2=v2"，值含空白时加引号
+func Format(msg string, keyvals ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(fmt.Sprint(keyvals[i]))
+		b.WriteByte('=')
+		s := fmt.Sprint(v)
+		if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+			s = fmt.Sprintf("%q", s)
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xlog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithAndFormat(t *testing.T) {
+	var (
+		gotLevel LogLevel
+		gotMsg   string
+	)
+	l := With(LoggerFunc(func(_ context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+		gotLevel, gotMsg = level, Format(msg, keyvals...)
+	}), "provider", "alipay")
+	l = With(l, "app_id", "2021")
+	l.Log(context.Background(), WarnLevel, "request", "code", "ACQ.SYSTEM_ERROR", "error", "read tcp: timeout")
+
+	want := `request provider=alipay app_id=2021 code=ACQ.SYSTEM_ERROR error="read tcp: timeout"`
+	if gotLevel != WarnLevel || gotMsg != want {
+		t.Fatalf("got %s %q, want WARN %q", gotLevel, gotMsg, want)
+	}
+	if s := Format("", "k"); s != "k=(MISSING)" {
+		t.Fatalf("Format odd keyvals = %q", s)
+	}
+}
+
+func TestLogf(t *testing.T) {
+	var got string
+	Logf(LoggerFunc(func(_ context.Context, _ LogLevel, msg string, _ ...interface{}) {
+		got = msg
+	}), DebugLevel, "Alipay_Response: %s%d %s%s", Red, 200, Reset, "{}")
+	if got != "Alipay_Response: 200 {}" {
+		t.Fatalf("Logf() msg = %q", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package xlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger 将 *slog.Logger 适配为 Logger，zap、logrus 等可通过其 slog.Handler 接入
+//
+//	l 为 nil 时使用 slog.Default()
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s *slogLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s.l.Log(ctx, SlogLevel(level), msg, keyvals...)
+}
+
+// SlogLevel 将 LogLevel 转换为 slog.Level
+func SlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case ErrorLevel:
+		return slog.LevelError
+	case WarnLevel:
+		return slog.LevelWarn
+	case InfoLevel:
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
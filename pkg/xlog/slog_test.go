@@ -0,0 +1,36 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package xlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewSlogLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := With(NewSlogLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))), "provider", "wechat")
+	l.Log(context.Background(), ErrorLevel, "request", "mchid", "1230000109")
+
+	out := buf.String()
+	for _, s := range []string{"level=ERROR", "msg=request", "provider=wechat", "mchid=1230000109"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("slog output %q missing %q", out, s)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpem
+
+import (
+	"crypto/ecdsa"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/tjfoc/gmsm/sm2"
+	gmx509 "github.com/tjfoc/gmsm/x509"
+)
+
+// DecodeSM2PrivateKey 解析国密 SM2 私钥，支持 PKCS8 及 SEC1（EC PRIVATE KEY）格式，忽略 PEM 块类型
+func DecodeSM2PrivateKey(pemContent []byte) (privateKey *sm2.PrivateKey, err error) {
+	block, _ := pem.Decode(pemContent)
+	if block == nil {
+		return nil, fmt.Errorf("pem.Decode(%s)：pemContent decode error", pemContent)
+	}
+	if privateKey, err = gmx509.ParsePKCS8UnecryptedPrivateKey(block.Bytes); err == nil {
+		return privateKey, nil
+	}
+	if privateKey, err = gmx509.ParseSm2PrivateKey(block.Bytes); err != nil {
+		return nil, fmt.Errorf("SM2私钥解析出错：%w", err)
+	}
+	return privateKey, nil
+}
+
+// DecodeSM2PublicKey 解析国密 SM2 公钥，支持 PUBLIC KEY 及 SM2 公钥证书（CERTIFICATE）
+func DecodeSM2PublicKey(pemContent []byte) (publicKey *sm2.PublicKey, err error) {
+	block, _ := pem.Decode(pemContent)
+	if block == nil {
+		return nil, fmt.Errorf("pem.Decode(%s)：pemContent decode error", pemContent)
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := gmx509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("gmx509.ParseCertificate：%w", err)
+		}
+		pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != sm2.P256Sm2() {
+			return nil, fmt.Errorf("公钥证书不是SM2证书 [%s]", pemContent)
+		}
+		return &sm2.PublicKey{Curve: pub.Curve, X: pub.X, Y: pub.Y}, nil
+	case "PUBLIC KEY":
+		if publicKey, err = gmx509.ParseSm2PublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("gmx509.ParseSm2PublicKey：%w", err)
+		}
+		if publicKey.X == nil {
+			return nil, fmt.Errorf("SM2公钥解析出错 [%s]", pemContent)
+		}
+		return publicKey, nil
+	}
+	return nil, fmt.Errorf("不支持的SM2公钥类型：%s", block.Type)
+}
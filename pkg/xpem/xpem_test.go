@@ -12,9 +12,15 @@
 package xpem
 
 import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/rwscode/payutil/pkg/xlog"
+	"github.com/tjfoc/gmsm/sm2"
+	gmx509 "github.com/tjfoc/gmsm/x509"
 )
 
 var (
@@ -56,3 +62,43 @@ func TestDecodePrivateKey(t *testing.T) {
 	}
 	xlog.Info("decode ok")
 }
+
+func TestDecodeSM2Key(t *testing.T) {
+	priKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priPem, err := gmx509.WritePrivateKeyToPem(priKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPem, err := gmx509.WritePublicKeyToPem(&priKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &gmx509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		Subject:            pkix.Name{CommonName: "payutil"},
+		NotBefore:          time.Now().Add(-time.Hour),
+		NotAfter:           time.Now().Add(time.Hour),
+		SignatureAlgorithm: gmx509.SM2WithSM3,
+	}
+	certPem, err := gmx509.CreateCertificateToPem(tpl, tpl, &priKey.PublicKey, priKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeSM2PrivateKey(priPem)
+	if err != nil || decoded.D.Cmp(priKey.D) != 0 {
+		t.Fatalf("DecodeSM2PrivateKey() = %v, %v", decoded, err)
+	}
+	for _, c := range [][]byte{pubPem, certPem} {
+		pub, err := DecodeSM2PublicKey(c)
+		if err != nil || pub.X.Cmp(priKey.X) != 0 || pub.Y.Cmp(priKey.Y) != 0 {
+			t.Fatalf("DecodeSM2PublicKey(%s) = %v, %v", c, pub, err)
+		}
+	}
+	if _, err = DecodeSM2PrivateKey([]byte(PrivateKeyContent)); err == nil {
+		t.Fatal("DecodeSM2PrivateKey(rsa key) want error")
+	}
+}
@@ -12,15 +12,20 @@
 package qq
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/retry"
 	"github.com/rwscode/payutil/pkg/util"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xlog"
@@ -34,6 +39,15 @@ type Client struct {
 	DebugSwitch pay.DebugSwitch
 	certificate *tls.Certificate
 	mu          sync.RWMutex
+	hc          xhttp.Doer       // 自定义 Doer，为空时使用 xhttp 共享连接池
+	certOf      *tls.Certificate // certTLS 对应的 certificate
+	certTLS     *tls.Config      // 证书请求复用的 tls.Config
+	certHc      xhttp.Doer       // 证书请求复用的 Doer
+	retry       *retry.Policy    // 重试策略，为空时不重试
+	limiter     *xhttp.Limiter   // 按接口族限流、熔断，为空时不限制
+	baseURL     string           // 自定义接口域名，为空时使用 qpay.qq.com、api.qpay.qq.com
+	mw          observe.Chain    // 中间件链
+	logger      xlog.Logger      // 结构化日志，为空时使用全局 xlog
 }
 
 // 初始化QQ客户端（正式环境）
@@ -54,6 +68,169 @@ func (q *Client) SetBodySize(sizeMB int) {
 	}
 }
 
+// SetHTTPClient 设置自定义 *http.Client，默认使用 xhttp 共享连接池
+// 需要证书的接口会复制其 Transport，保留其证书校验配置并加载商户证书
+func (q *Client) SetHTTPClient(hc *http.Client) {
+	if hc == nil {
+		q.SetDoer(nil)
+		return
+	}
+	q.SetDoer(hc)
+}
+
+// SetDoer 设置自定义 xhttp.Doer，用于链路追踪、代理、mock 等，传 nil 恢复默认
+// 注意：非 *http.Client 的 Doer 无法加载商户证书，调用需要证书的接口时返回 xhttp.ErrClientCertUnsupported
+func (q *Client) SetDoer(d xhttp.Doer) {
+	q.mu.Lock()
+	q.hc, q.certHc = d, nil
+	q.mu.Unlock()
+}
+
+// SetTLSOptions 设置服务端证书校验配置，如自定义根证书、公钥固定，会替换 SetHTTPClient() 设置的客户端
+func (q *Client) SetTLSOptions(o *xhttp.TLSOptions) {
+	q.SetDoer(xhttp.NewHttpClient(&xhttp.TransportConfig{TLSConfig: xhttp.NewTLSConfig(o)}))
+}
+
+// SetBaseURL 设置接口域名，末尾不带 /，设置后 qpay.qq.com、api.qpay.qq.com 的接口均请求该域名
+// 用于出口代理、本地模拟服务等，传空字符串恢复默认
+func (q *Client) SetBaseURL(url string) {
+	q.baseURL = strings.TrimSuffix(url, "/")
+}
+
+func (q *Client) apiURL(url string) string {
+	if q.baseURL == util.NULL {
+		return url
+	}
+	for _, host := range []string{hostQpay, hostApiQpay} {
+		if strings.HasPrefix(url, host+"/") {
+			return q.baseURL + strings.TrimPrefix(url, host)
+		}
+	}
+	return url
+}
+
+// SetRetryPolicy 设置重试策略，默认不重试
+// 查询、下载账单等接口按策略重试网络错误、5xx 及 SYSTEMERROR
+// 其余接口（下单、退款、发红包等）需通过 retry.WithIdempotent(ctx) 显式开启
+func (q *Client) SetRetryPolicy(p *retry.Policy) {
+	q.retry = p
+}
+
+// SetLimiter 设置按接口族的限流、熔断，每次 http 请求（含重试）经过 l，接口标识同 observe.Call.Method
+//
+//	limiter := xhttp.NewLimiter(xhttp.BreakerConfig{Failures: 5},
+//		xhttp.Family{Name: "bill", Prefixes: []string{"/cgi-bin/sp_download/"}, Rate: 1},
+//		xhttp.Family{Name: "transaction", Rate: 100},
+//	)
+//	client.SetLimiter(limiter)
+func (q *Client) SetLimiter(l *xhttp.Limiter) {
+	q.limiter = l
+}
+
+// retryPolicy url 为查询、下载类接口时可重试，如订单查询、下载账单
+func (q *Client) retryPolicy(ctx context.Context, url string) *retry.Policy {
+	if strings.Contains(url, "query") || strings.Contains(url, "download") || retry.IsIdempotent(ctx) {
+		return q.retry.Idempotent()
+	}
+	return nil
+}
+
+// 返回 err_code 为 SYSTEMERROR 时可重试
+func isSystemError(bs []byte) bool {
+	if !bytes.Contains(bs, []byte("SYSTEMERROR")) {
+		return false
+	}
+	var rsp struct {
+		ErrCode string `xml:"err_code"`
+	}
+	return xml.Unmarshal(bs, &rsp) == nil && rsp.ErrCode == "SYSTEMERROR"
+}
+
+// Use 添加中间件，用于指标统计、链路追踪等，见 observe 包，请在初始化时调用
+func (q *Client) Use(mw ...observe.Middleware) {
+	q.mw = append(q.mw, mw...)
+}
+
+// SetLogger 设置当前客户端的结构化日志，日志携带 mch_id 字段，并记录每次接口调用（见 observe.Logging）
+//
+//	调试日志（DebugSwitch 开启时）也输出到该 logger，可使用 xlog.NewSlogLogger() 接入 slog、zap、logrus
+func (q *Client) SetLogger(l xlog.Logger) {
+	q.logger = xlog.With(l, "mch_id", q.MchId)
+}
+
+func (q *Client) debugf(format string, args ...interface{}) {
+	xlog.Logf(xlog.With(q.logger, "provider", pay.ProviderQQ), xlog.DebugLevel, format, args...)
+}
+
+// outTradeNo 请求中的商户订单号（红包为 mch_billno），用于日志
+func outTradeNo(bm pay.BodyMap) string {
+	if no := bm.GetString("out_trade_no"); no != util.NULL {
+		return no
+	}
+	return bm.GetString("mch_billno")
+}
+
+// do 经过中间件链，按 policy 发送请求
+func (q *Client) do(ctx context.Context, url, outTradeNo string, policy *retry.Policy, send func(ctx context.Context) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	call := &observe.Call{Provider: pay.ProviderQQ, Method: observe.Endpoint(util.NULL, url), OutTradeNo: outTradeNo}
+	return q.mw.WithLogging(q.logger).DoHTTP(ctx, call, func(ctx context.Context) (*http.Response, []byte, error) {
+		return policy.DoHTTP(ctx, q.limiter.Wrap(call.Method, send), isSystemError)
+	}, resultCode)
+}
+
+// resultCode QQ业务结果码，通信或业务失败时返回 err_code，无 err_code 时返回 return_code、result_code
+func resultCode(_ int, bs []byte) string {
+	var rsp struct {
+		ReturnCode string `xml:"return_code"`
+		ResultCode string `xml:"result_code"`
+		ErrCode    string `xml:"err_code"`
+	}
+	if xml.Unmarshal(bs, &rsp) != nil {
+		return util.NULL
+	}
+	switch {
+	case rsp.ErrCode != util.NULL:
+		return rsp.ErrCode
+	case rsp.ReturnCode != util.NULL && rsp.ReturnCode != "SUCCESS":
+		return rsp.ReturnCode
+	case rsp.ResultCode != util.NULL && rsp.ResultCode != "SUCCESS":
+		return rsp.ResultCode
+	}
+	return util.NULL
+}
+
+// httpClient 返回请求客户端，tlsConfig 为 addCertConfig 返回的证书配置时复用连接池
+//
+//	自定义 Doer 无法加载商户证书时返回 xhttp.ErrClientCertUnsupported
+func (q *Client) httpClient(tlsConfig *tls.Config) (*xhttp.Client, error) {
+	c := xhttp.NewClient()
+	if q.bodySize > 0 {
+		c.SetBodySize(q.bodySize)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case tlsConfig == nil:
+		c.SetDoer(q.hc)
+	case tlsConfig == q.certTLS:
+		if q.certHc == nil {
+			d, err := xhttp.WithClientCert(q.hc, tlsConfig.Certificates)
+			if err != nil {
+				return nil, err
+			}
+			q.certHc = d
+		}
+		c.SetDoer(q.certHc)
+	default:
+		// 一次性 Transport 仅对 *http.Client 生效
+		if _, ok := q.hc.(*http.Client); q.hc != nil && !ok {
+			return nil, fmt.Errorf("%w: %T", xhttp.ErrClientCertUnsupported, q.hc)
+		}
+		c.SetDoer(q.hc).SetTLSConfig(tlsConfig)
+	}
+	return c, nil
+}
+
 // 向QQ发送Post请求，对于本库未提供的QQ API，可自行实现，通过此方法发送请求
 // bm：请求参数的BodyMap
 // url：完整url地址，例如：https://qpay.qq.com/cgi-bin/pay/qpay_unified_order.cgi
@@ -244,7 +421,7 @@ func (q *Client) AccRoll(ctx context.Context, bm pay.BodyMap) (qqRsp string, err
 
 // 向QQ发送请求
 func (q *Client) doQQ(ctx context.Context, bm pay.BodyMap, url string, tlsConfig *tls.Config) (bs []byte, err error) {
-
+	url = q.apiURL(url)
 	if bm.GetString("mch_id") == util.NULL {
 		bm.Set("mch_id", q.MchId)
 	}
@@ -257,22 +434,19 @@ func (q *Client) doQQ(ctx context.Context, bm pay.BodyMap, url string, tlsConfig
 		bm.Set("sign", sign)
 	}
 
-	httpClient := xhttp.NewClient()
-	if q.bodySize > 0 {
-		httpClient.SetBodySize(q.bodySize)
-	}
-	if tlsConfig != nil {
-		httpClient.SetTLSConfig(tlsConfig)
+	httpClient, err := q.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
 	}
 	if q.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("QQ_Request: %s", bm.JsonBody())
+		q.debugf("QQ_Request: %s", redact.String(bm.JsonBody()))
 	}
-	res, bs, err := httpClient.Type(xhttp.TypeXML).Post(url).SendString(generateXml(bm)).EndBytes(ctx)
+	res, bs, err := q.do(ctx, url, outTradeNo(bm), q.retryPolicy(ctx, url), httpClient.Type(xhttp.TypeXML).Post(url).SendString(generateXml(bm)).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if q.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("QQ_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		q.debugf("QQ_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -285,6 +459,7 @@ func (q *Client) doQQ(ctx context.Context, bm pay.BodyMap, url string, tlsConfig
 
 // Get请求、正式
 func (q *Client) doQQGet(ctx context.Context, bm pay.BodyMap, url, signType string) (bs []byte, err error) {
+	url = q.apiURL(url)
 	if bm.GetString("mch_id") == util.NULL {
 		bm.Set("mch_id", q.MchId)
 	}
@@ -293,21 +468,22 @@ func (q *Client) doQQGet(ctx context.Context, bm pay.BodyMap, url, signType stri
 	bm.Set("sign", sign)
 
 	if q.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("QQ_Request: %s", bm.JsonBody())
+		q.debugf("QQ_Request: %s", redact.String(bm.JsonBody()))
 	}
+	policy := q.retryPolicy(ctx, url)
 	param := bm.EncodeURLParams()
 	url = url + "?" + param
 
-	httpClient := xhttp.NewClient()
-	if q.bodySize > 0 {
-		httpClient.SetBodySize(q.bodySize)
+	httpClient, err := q.httpClient(nil)
+	if err != nil {
+		return nil, err
 	}
-	res, bs, err := httpClient.Get(url).EndBytes(ctx)
+	res, bs, err := q.do(ctx, url, outTradeNo(bm), policy, httpClient.Get(url).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if q.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("QQ_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		q.debugf("QQ_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -319,7 +495,7 @@ func (q *Client) doQQGet(ctx context.Context, bm pay.BodyMap, url, signType stri
 }
 
 func (q *Client) doQQRed(ctx context.Context, bm pay.BodyMap, url string, tlsConfig *tls.Config) (bs []byte, err error) {
-
+	url = q.apiURL(url)
 	if bm.GetString("mch_id") == util.NULL {
 		bm.Set("mch_id", q.MchId)
 	}
@@ -328,22 +504,19 @@ func (q *Client) doQQRed(ctx context.Context, bm pay.BodyMap, url string, tlsCon
 		bm.Set("sign", sign)
 	}
 
-	httpClient := xhttp.NewClient()
-	if tlsConfig != nil {
-		httpClient.SetTLSConfig(tlsConfig)
-	}
-	if q.bodySize > 0 {
-		httpClient.SetBodySize(q.bodySize)
+	httpClient, err := q.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
 	}
 	if q.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("QQ_Request: %s", bm.JsonBody())
+		q.debugf("QQ_Request: %s", redact.String(bm.JsonBody()))
 	}
-	res, bs, err := httpClient.Type(xhttp.TypeXML).Post(url).SendString(generateXml(bm)).EndBytes(ctx)
+	res, bs, err := q.do(ctx, url, outTradeNo(bm), q.retryPolicy(ctx, url), httpClient.Type(xhttp.TypeXML).Post(url).SendString(generateXml(bm)).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if q.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("QQ_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		q.debugf("QQ_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
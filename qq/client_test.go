@@ -77,3 +77,20 @@ func TestClient_DownloadRedListFile(t *testing.T) {
 	}
 	xlog.Debug("qqRsp:", file)
 }
+
+func TestClient_SetBaseURL(t *testing.T) {
+	c := NewClient(mchId, apiKey)
+	if got := c.apiURL(orderQuery); got != orderQuery {
+		t.Fatalf("apiURL() = %s", got)
+	}
+	c.SetBaseURL("http://127.0.0.1:8080/")
+	for url, want := range map[string]string{
+		orderQuery:                      "http://127.0.0.1:8080/cgi-bin/pay/qpay_order_query.cgi",
+		refund:                          "http://127.0.0.1:8080/cgi-bin/pay/qpay_refund.cgi",
+		"https://graph.qq.com/oauth2.0": "https://graph.qq.com/oauth2.0",
+	} {
+		if got := c.apiURL(url); got != want {
+			t.Fatalf("apiURL(%s) = %s, want %s", url, got, want)
+		}
+	}
+}
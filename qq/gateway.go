@@ -0,0 +1,224 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qq
+
+import (
+	"context"
+	"fmt"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+type gateway struct {
+	q *Client
+}
+
+// NewGateway 将QQ钱包 Client 适配为 pay.Gateway
+//
+//	SceneQRCode：NATIVE，SceneJSAPI：JSAPI，SceneApp：APP
+//	注意：Refund 需先调用 client.AddCertFilePath() 等方法添加证书，并通过 pay.Refund.Extra 传入 op_user_id、op_user_passwd
+func NewGateway(client *Client) pay.Gateway {
+	return &gateway{q: client}
+}
+
+func (g *gateway) Provider() string {
+	return pay.ProviderQQ
+}
+
+func (g *gateway) Charge(ctx context.Context, o *pay.Order) (*pay.ChargeResult, error) {
+	var tradeType string
+	switch o.Scene {
+	case pay.SceneQRCode:
+		tradeType = TradeType_Native
+	case pay.SceneJSAPI:
+		tradeType = TradeType_JsApi
+	case pay.SceneApp:
+		tradeType = TradeType_App
+	default:
+		return nil, fmt.Errorf("[%w]: qq scene %q", pay.UnsupportedErr, o.Scene)
+	}
+	body := o.Body
+	if body == util.NULL {
+		body = o.Subject
+	}
+	bm := make(pay.BodyMap)
+	bm.Set("nonce_str", util.RandomString(32)).
+		Set("body", body).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("total_fee", o.Amount).
+		Set("spbill_create_ip", o.ClientIp).
+		Set("notify_url", o.NotifyUrl).
+		Set("trade_type", tradeType)
+	if o.Currency != util.NULL {
+		bm.Set("fee_type", o.Currency)
+	}
+	bm.Merge(o.Extra)
+	qqRsp, err := g.q.UnifiedOrder(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	if err = bizErr("UnifiedOrder", qqRsp.ReturnCode, qqRsp.ReturnMsg, qqRsp.ResultCode, qqRsp.ErrCode, qqRsp.ErrCodeDes); err != nil {
+		return nil, err
+	}
+	return &pay.ChargeResult{
+		OutTradeNo: o.OutTradeNo,
+		CodeUrl:    qqRsp.CodeUrl,
+		PrepayId:   qqRsp.PrepayId,
+		Raw:        qqRsp,
+	}, nil
+}
+
+func (g *gateway) Query(ctx context.Context, q *pay.OrderQuery) (*pay.OrderResult, error) {
+	qqRsp, err := g.q.OrderQuery(ctx, tradeNoBodyMap(q.OutTradeNo, q.TradeNo))
+	if err != nil {
+		return nil, err
+	}
+	if err = bizErr("OrderQuery", qqRsp.ReturnCode, qqRsp.ReturnMsg, qqRsp.ResultCode, qqRsp.ErrCode, qqRsp.ErrCodeDes); err != nil {
+		return nil, err
+	}
+	rs := &pay.OrderResult{
+		OutTradeNo: qqRsp.OutTradeNo,
+		TradeNo:    qqRsp.TransactionId,
+		Status:     tradeState(qqRsp.TradeState),
+		RawStatus:  qqRsp.TradeState,
+		Amount:     util.String2Int64(qqRsp.TotalFee),
+		Currency:   "CNY",
+		Raw:        qqRsp,
+	}
+	if qqRsp.FeeType != util.NULL {
+		rs.Currency = qqRsp.FeeType
+	}
+	return rs, nil
+}
+
+func (g *gateway) Close(ctx context.Context, q *pay.OrderQuery) error {
+	bm := make(pay.BodyMap)
+	bm.Set("nonce_str", util.RandomString(32)).
+		Set("out_trade_no", q.OutTradeNo)
+	qqRsp, err := g.q.CloseOrder(ctx, bm)
+	if err != nil {
+		return err
+	}
+	return bizErr("CloseOrder", qqRsp.ReturnCode, qqRsp.ReturnMsg, qqRsp.ResultCode, qqRsp.ErrCode, qqRsp.ErrCodeDes)
+}
+
+func (g *gateway) Refund(ctx context.Context, r *pay.Refund) (*pay.RefundResult, error) {
+	bm := tradeNoBodyMap(r.OutTradeNo, r.TradeNo)
+	bm.Set("out_refund_no", r.OutRefundNo).
+		Set("refund_fee", r.Amount)
+	bm.Merge(r.Extra)
+	qqRsp, err := g.q.Refund(ctx, bm, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = bizErr("Refund", qqRsp.ReturnCode, qqRsp.ReturnMsg, qqRsp.ResultCode, qqRsp.ErrCode, qqRsp.ErrCodeDes); err != nil {
+		return nil, err
+	}
+	return &pay.RefundResult{
+		OutTradeNo:  qqRsp.OutTradeNo,
+		TradeNo:     qqRsp.TransactionId,
+		OutRefundNo: qqRsp.OutRefundNo,
+		RefundNo:    qqRsp.RefundId,
+		Status:      pay.RefundStatusProcessing, // 申请成功，退款结果以退款查询为准
+		Amount:      util.String2Int64(qqRsp.RefundFee),
+		Currency:    "CNY",
+		Raw:         qqRsp,
+	}, nil
+}
+
+func (g *gateway) QueryRefund(ctx context.Context, q *pay.RefundQuery) (*pay.RefundResult, error) {
+	bm := tradeNoBodyMap(q.OutTradeNo, q.TradeNo)
+	if q.OutRefundNo != util.NULL {
+		bm.Set("out_refund_no", q.OutRefundNo)
+	}
+	if q.RefundNo != util.NULL {
+		bm.Set("refund_id", q.RefundNo)
+	}
+	qqRsp, err := g.q.RefundQuery(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	if err = bizErr("RefundQuery", qqRsp.ReturnCode, qqRsp.ReturnMsg, qqRsp.ResultCode, qqRsp.ErrCode, qqRsp.ErrCodeDes); err != nil {
+		return nil, err
+	}
+	rs := &pay.RefundResult{
+		OutTradeNo:  qqRsp.OutTradeNo,
+		TradeNo:     qqRsp.TransactionId,
+		OutRefundNo: qqRsp.OutRefundNo0,
+		RefundNo:    qqRsp.RefundId0,
+		RawStatus:   qqRsp.RefundStatus0,
+		Amount:      util.String2Int64(qqRsp.RefundFee0),
+		Currency:    "CNY",
+		Raw:         qqRsp,
+	}
+	// 响应中最多含两笔退款记录，指定的退款单号匹配第二笔时取第二笔
+	if (q.OutRefundNo != util.NULL && qqRsp.OutRefundNo1 == q.OutRefundNo) ||
+		(q.RefundNo != util.NULL && qqRsp.RefundId1 == q.RefundNo) {
+		rs.OutRefundNo, rs.RefundNo, rs.RawStatus = qqRsp.OutRefundNo1, qqRsp.RefundId1, qqRsp.RefundStatus1
+		rs.Amount = util.String2Int64(qqRsp.RefundFee1)
+	}
+	rs.Status = refundStatus(rs.RawStatus)
+	if qqRsp.FeeType != util.NULL {
+		rs.Currency = qqRsp.FeeType
+	}
+	return rs, nil
+}
+
+func tradeNoBodyMap(outTradeNo, transactionId string) pay.BodyMap {
+	bm := make(pay.BodyMap)
+	bm.Set("nonce_str", util.RandomString(32))
+	if outTradeNo != util.NULL {
+		bm.Set("out_trade_no", outTradeNo)
+	}
+	if transactionId != util.NULL {
+		bm.Set("transaction_id", transactionId)
+	}
+	return bm
+}
+
+func tradeState(state string) pay.TradeStatus {
+	switch state {
+	case "NOTPAY", "USERPAYING":
+		return pay.TradeStatusPending
+	case "SUCCESS":
+		return pay.TradeStatusPaid
+	case "CLOSED", "REVOKED":
+		return pay.TradeStatusClosed
+	case "REFUND":
+		return pay.TradeStatusRefunded
+	case "PAYERROR":
+		return pay.TradeStatusFailed
+	default:
+		return pay.TradeStatusUnknown
+	}
+}
+
+func refundStatus(status string) pay.RefundStatus {
+	switch status {
+	case "PROCESSING", "NOTSURE":
+		return pay.RefundStatusProcessing
+	case "SUCCESS":
+		return pay.RefundStatusSuccess
+	case "FAIL", "CHANGE":
+		return pay.RefundStatusFailed
+	default:
+		return pay.RefundStatusUnknown
+	}
+}
+
+func bizErr(api, returnCode, returnMsg, resultCode, errCode, errCodeDes string) error {
+	if returnCode != pay.SUCCESS || resultCode != pay.SUCCESS {
+		return fmt.Errorf("qq %s failed: %w", api, NewAPIError(returnCode, returnMsg, errCode, errCodeDes))
+	}
+	return nil
+}
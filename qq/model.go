@@ -12,6 +12,10 @@
 package qq
 
 const (
+	// 接口域名
+	hostQpay    = "https://qpay.qq.com"
+	hostApiQpay = "https://api.qpay.qq.com"
+
 	// URL
 	unifiedOrder  = "https://qpay.qq.com/cgi-bin/pay/qpay_unified_order.cgi"              // 统一下单
 	microPay      = "https://qpay.qq.com/cgi-bin/pay/qpay_micro_pay.cgi"                  // 提交付款码支付
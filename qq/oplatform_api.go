@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/xhttp"
 )
 
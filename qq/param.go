@@ -123,14 +123,17 @@ func GetReleaseSign(apiKey string, signType string, bm pay.BodyMap) (sign string
 
 func (q *Client) addCertConfig(certFile, keyFile, pkcs12File interface{}) (tlsConfig *tls.Config, err error) {
 	if certFile == nil && keyFile == nil && pkcs12File == nil {
-		q.mu.RLock()
-		defer q.mu.RUnlock()
+		q.mu.Lock()
+		defer q.mu.Unlock()
 		if q.certificate != nil {
-			tlsConfig = &tls.Config{
-				Certificates:       []tls.Certificate{*q.certificate},
-				InsecureSkipVerify: true,
+			// 证书未变更时复用 tls.Config，以复用证书请求的连接池
+			if q.certTLS == nil || q.certOf != q.certificate {
+				q.certOf, q.certHc = q.certificate, nil
+				q.certTLS = &tls.Config{
+					Certificates: []tls.Certificate{*q.certificate},
+				}
 			}
-			return tlsConfig, nil
+			return q.certTLS, nil
 		}
 		return nil, errors.New("cert parse failed")
 	}
@@ -176,8 +179,7 @@ func (q *Client) addCertConfig(certFile, keyFile, pkcs12File interface{}) (tlsCo
 			return nil, fmt.Errorf("tls.LoadX509KeyPair：%w", err)
 		}
 		tlsConfig = &tls.Config{
-			Certificates:       []tls.Certificate{certificate},
-			InsecureSkipVerify: true,
+			Certificates: []tls.Certificate{certificate},
 		}
 		return tlsConfig, nil
 	}
@@ -0,0 +1,281 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+// ClientBuilder 根据凭证构建渠道客户端，如 alipay.Register()、wechat/v3.Register() 注册的构建方法
+type ClientBuilder func(ctx context.Context, c *Credential) (interface{}, error)
+
+// Registry 多商户客户端注册表，按 渠道 + 商户号 缓存客户端
+//
+//	首次获取时通过 CredentialProvider 读取凭证并构建客户端，同一商户并发获取只构建一次
+//	凭证轮换（Reload、Refresh 或 CredentialNotifier 通知）时重新构建并原子替换缓存的客户端，
+//	已取出的旧客户端不受影响，进行中的请求可正常完成；旧客户端实现了 io.Closer 时调用 Close() 停止其后台任务（如平台证书自动刷新）
+//	各渠道的类型化获取方法见 alipay.FromRegistry()、wechat/v3.FromRegistry()
+type Registry struct {
+	cp       CredentialProvider
+	mu       sync.RWMutex
+	builders map[string]ClientBuilder
+	entries  map[registryKey]*registryEntry
+	loading  map[registryKey]*registryCall
+}
+
+type registryKey struct {
+	provider   string
+	merchantId string
+}
+
+type registryEntry struct {
+	client  interface{}
+	version string
+}
+
+type registryCall struct {
+	done    chan struct{}
+	entry   *registryEntry
+	err     error
+	removed bool // 构建期间调用了 Remove()，结果不再缓存
+}
+
+// NewRegistry 初始化注册表，cp 实现了 CredentialNotifier 时，收到凭证轮换通知后自动重建对应客户端
+func NewRegistry(cp CredentialProvider) *Registry {
+	r := &Registry{
+		cp:       cp,
+		builders: make(map[string]ClientBuilder),
+		entries:  make(map[registryKey]*registryEntry),
+		loading:  make(map[registryKey]*registryCall),
+	}
+	if n, ok := cp.(CredentialNotifier); ok {
+		n.Notify(func(provider, merchantId string) {
+			if err := r.Reload(context.Background(), provider, merchantId); err != nil {
+				xlog.Errorf("Registry.Reload(%s, %s), err:%+v", provider, merchantId, err)
+			}
+		})
+	}
+	return r
+}
+
+// Register 注册渠道客户端构建方法，请在初始化时调用
+func (r *Registry) Register(provider string, b ClientBuilder) *Registry {
+	r.mu.Lock()
+	r.builders[provider] = b
+	r.mu.Unlock()
+	return r
+}
+
+// Client 获取商户客户端，未缓存时读取凭证并构建
+//
+//	provider：渠道标识，如 ProviderAlipay
+//	merchantId：商户标识，支付宝为 app_id，微信为 mchid
+func (r *Registry) Client(ctx context.Context, provider, merchantId string) (interface{}, error) {
+	k := registryKey{provider: provider, merchantId: merchantId}
+	r.mu.RLock()
+	e, ok := r.entries[k]
+	r.mu.RUnlock()
+	if ok {
+		return e.client, nil
+	}
+	e, err := r.load(ctx, k, false)
+	if err != nil {
+		return nil, err
+	}
+	return e.client, nil
+}
+
+// Reload 重新读取商户凭证，凭证版本变化时重建并替换客户端，未缓存时直接构建
+//
+//	构建失败时保留原客户端
+func (r *Registry) Reload(ctx context.Context, provider, merchantId string) error {
+	_, err := r.load(ctx, registryKey{provider: provider, merchantId: merchantId}, true)
+	return err
+}
+
+// Refresh 重新读取所有已缓存商户的凭证，重建凭证版本变化的客户端，返回第一个错误
+func (r *Registry) Refresh(ctx context.Context) (err error) {
+	r.mu.RLock()
+	keys := make([]registryKey, 0, len(r.entries))
+	for k := range r.entries {
+		keys = append(keys, k)
+	}
+	r.mu.RUnlock()
+	for _, k := range keys {
+		if _, e := r.load(ctx, k, true); e != nil {
+			xlog.Errorf("Registry.Refresh(%s, %s), err:%+v", k.provider, k.merchantId, e)
+			if err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// AutoRefresh 每隔 interval 调用一次 Refresh()，直到 ctx 结束，适用于不支持主动通知的凭证来源，如文件、环境变量
+//
+//	go registry.AutoRefresh(ctx, time.Minute)
+func (r *Registry) AutoRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = r.Refresh(ctx)
+		}
+	}
+}
+
+// Remove 移除缓存的商户客户端，如商户解约，下次 Client() 时重新构建
+//
+//	进行中的构建结果不再缓存，等待该构建的调用返回 CredentialNotFoundErr
+func (r *Registry) Remove(provider, merchantId string) {
+	k := registryKey{provider: provider, merchantId: merchantId}
+	r.mu.Lock()
+	old := r.entries[k]
+	delete(r.entries, k)
+	if c, ok := r.loading[k]; ok {
+		c.removed = true
+	}
+	r.mu.Unlock()
+	closeEntry(old)
+}
+
+// closeEntry 关闭被替换或移除的客户端
+func closeEntry(e *registryEntry) {
+	if e == nil {
+		return
+	}
+	if c, ok := e.client.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			xlog.Errorf("Registry close client, err:%+v", err)
+		}
+	}
+}
+
+// load 读取凭证并构建客户端，同一商户并发调用只执行一次
+//
+//	reload 为 false 且已缓存时直接返回缓存；为 true 时凭证版本未变化则保留原客户端
+//	reload 为 true 时等待进行中的构建结束后重新读取凭证，避免进行中的读取早于凭证轮换而错过新凭证
+//	reload 为 false 时等待进行中的构建，构建失败（如轮换期间读取凭证出错）但仍有缓存时返回缓存
+//	构建在保留 ctx 值、不继承其取消的独立 ctx 上执行（最长 registryLoadTimeout），调用方取消只结束自身的等待
+func (r *Registry) load(ctx context.Context, k registryKey, reload bool) (*registryEntry, error) {
+	r.mu.Lock()
+	for {
+		c, ok := r.loading[k]
+		if !ok {
+			break
+		}
+		r.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		r.mu.Lock()
+		if !reload {
+			if e, ok := r.entries[k]; ok {
+				r.mu.Unlock()
+				return e, nil
+			}
+			if c.err != nil {
+				r.mu.Unlock()
+				return nil, c.err
+			}
+		}
+	}
+	old, ok := r.entries[k]
+	if ok && !reload {
+		r.mu.Unlock()
+		return old, nil
+	}
+	b := r.builders[k.provider]
+	c := &registryCall{done: make(chan struct{})}
+	r.loading[k] = c
+	r.mu.Unlock()
+
+	go r.doLoad(detachedContext{ctx}, k, b, old, c)
+	select {
+	case <-c.done:
+		return c.entry, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// doLoad 构建客户端并替换缓存，完成后关闭 c.done
+func (r *Registry) doLoad(ctx context.Context, k registryKey, b ClientBuilder, old *registryEntry, c *registryCall) {
+	ctx, cancel := context.WithTimeout(ctx, registryLoadTimeout)
+	defer cancel()
+	c.entry, c.err = r.build(ctx, k, b, old)
+
+	var discard *registryEntry
+	r.mu.Lock()
+	if c.err == nil && c.removed {
+		// Remove() 已关闭 old，丢弃新构建的客户端
+		if c.entry != old {
+			discard = c.entry
+		}
+		c.entry, c.err = nil, fmt.Errorf("[%w]: %s client(%s) removed while loading", CredentialNotFoundErr, k.provider, k.merchantId)
+	}
+	if c.err == nil {
+		r.entries[k] = c.entry
+	}
+	delete(r.loading, k)
+	r.mu.Unlock()
+	closeEntry(discard)
+	if c.err == nil && old != nil && old != c.entry {
+		closeEntry(old)
+	}
+	close(c.done)
+}
+
+// registryLoadTimeout 单次读取凭证并构建客户端的最长时间
+const registryLoadTimeout = 30 * time.Second
+
+// detachedContext 保留 ctx 的值（如链路追踪），不继承其取消与截止时间
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) { return }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (r *Registry) build(ctx context.Context, k registryKey, b ClientBuilder, old *registryEntry) (*registryEntry, error) {
+	if b == nil {
+		return nil, fmt.Errorf("[%w]: provider %s not registered", UnsupportedErr, k.provider)
+	}
+	cred, err := r.cp.Credential(ctx, k.provider, k.merchantId)
+	if err != nil {
+		return nil, err
+	}
+	version := cred.version()
+	if old != nil && old.version == version {
+		return old, nil
+	}
+	client, err := b(ctx, cred)
+	if err != nil {
+		return nil, fmt.Errorf("build %s client(%s): %w", k.provider, k.merchantId, err)
+	}
+	return &registryEntry{client: client, version: version}, nil
+}
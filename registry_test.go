@@ -0,0 +1,263 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testClient struct {
+	key    string
+	closed int32
+}
+
+func (c *testClient) Close() error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+type testNotifier struct {
+	mu    sync.Mutex
+	creds map[string]*Credential
+	fn    func(provider, merchantId string)
+}
+
+func (n *testNotifier) Credential(_ context.Context, provider, merchantId string) (*Credential, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	c, ok := n.creds[merchantId]
+	if !ok {
+		return nil, CredentialNotFoundErr
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (n *testNotifier) Notify(fn func(provider, merchantId string)) { n.fn = fn }
+
+func (n *testNotifier) rotate(merchantId, key string) {
+	n.mu.Lock()
+	n.creds[merchantId] = &Credential{MerchantId: merchantId, PrivateKey: key}
+	n.mu.Unlock()
+	n.fn(ProviderAlipay, merchantId)
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	cp := &testNotifier{creds: map[string]*Credential{"A": {MerchantId: "A", PrivateKey: "k1"}}}
+	var builds int32
+	r := NewRegistry(cp).Register(ProviderAlipay, func(_ context.Context, c *Credential) (interface{}, error) {
+		atomic.AddInt32(&builds, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &testClient{key: c.PrivateKey}, nil
+	})
+
+	// 并发获取只构建一次
+	var wg sync.WaitGroup
+	clients := make([]interface{}, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], _ = r.Client(ctx, ProviderAlipay, "A")
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range clients {
+		if c != clients[0] || c.(*testClient).key != "k1" {
+			t.Fatalf("clients = %v", clients)
+		}
+	}
+	if builds != 1 {
+		t.Fatalf("builds = %d, want 1", builds)
+	}
+
+	// 凭证未变化时不重建
+	if err := r.Refresh(ctx); err != nil || builds != 1 {
+		t.Fatalf("Refresh() = %v, builds = %d", err, builds)
+	}
+
+	// 凭证轮换后替换客户端，已取出的旧客户端不受影响，后台任务被关闭
+	old := clients[0].(*testClient)
+	cp.rotate("A", "k2")
+	c, _ := r.Client(ctx, ProviderAlipay, "A")
+	if c.(*testClient).key != "k2" || old.key != "k1" || builds != 2 {
+		t.Fatalf("after rotate client = %+v, old = %+v, builds = %d", c, old, builds)
+	}
+	if old.closed != 1 || c.(*testClient).closed != 0 {
+		t.Fatalf("after rotate old.closed = %d, new.closed = %d", old.closed, c.(*testClient).closed)
+	}
+	r.Remove(ProviderAlipay, "A")
+	if c.(*testClient).closed != 1 {
+		t.Fatalf("after Remove closed = %d, want 1", c.(*testClient).closed)
+	}
+
+	if _, err := r.Client(ctx, ProviderAlipay, "B"); !errors.Is(err, CredentialNotFoundErr) {
+		t.Fatalf("Client(B) err = %v", err)
+	}
+	if _, err := r.Client(ctx, ProviderWechat, "A"); !errors.Is(err, UnsupportedErr) {
+		t.Fatalf("Client(wechat) err = %v", err)
+	}
+}
+
+func TestRegistry_LoadRace(t *testing.T) {
+	ctx := context.Background()
+	cp := &testNotifier{creds: map[string]*Credential{"A": {MerchantId: "A", PrivateKey: "k1"}}}
+	var (
+		started = make(chan struct{}, 1)
+		release = make(chan struct{})
+		built   []*testClient
+		mu      sync.Mutex
+	)
+	r := NewRegistry(cp).Register(ProviderAlipay, func(_ context.Context, c *Credential) (interface{}, error) {
+		mu.Lock()
+		first := len(built) == 0
+		tc := &testClient{key: c.PrivateKey}
+		built = append(built, tc)
+		mu.Unlock()
+		if first {
+			started <- struct{}{}
+			<-release
+		}
+		return tc, nil
+	})
+
+	// 进行中的构建读取的是旧凭证，Reload 需重新读取
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Client(ctx, ProviderAlipay, "A")
+		done <- err
+	}()
+	<-started
+	reloaded := make(chan struct{})
+	go func() {
+		cp.rotate("A", "k2")
+		close(reloaded)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	<-reloaded
+	c, _ := r.Client(ctx, ProviderAlipay, "A")
+	if c.(*testClient).key != "k2" {
+		t.Fatalf("after Reload key = %s, want k2", c.(*testClient).key)
+	}
+
+	// 构建期间 Remove，结果不再缓存并被关闭
+	r.Remove(ProviderAlipay, "A")
+	mu.Lock()
+	built = nil
+	mu.Unlock()
+	release = make(chan struct{})
+	go func() {
+		_, err := r.Client(ctx, ProviderAlipay, "A")
+		done <- err
+	}()
+	<-started
+	r.Remove(ProviderAlipay, "A")
+	close(release)
+	if err := <-done; !errors.Is(err, CredentialNotFoundErr) {
+		t.Fatalf("Client() removed while loading err = %v", err)
+	}
+	if built[0].closed != 1 {
+		t.Fatalf("removed client closed = %d, want 1", built[0].closed)
+	}
+	if c, _ = r.Client(ctx, ProviderAlipay, "A"); c == built[0] || len(built) != 2 {
+		t.Fatalf("Client() after Remove = %+v, builds = %d", c, len(built))
+	}
+}
+
+func TestRegistry_LoadCancel(t *testing.T) {
+	cp := &testNotifier{creds: map[string]*Credential{"A": {MerchantId: "A", PrivateKey: "k1"}}}
+	var (
+		started = make(chan struct{}, 1)
+		release = make(chan struct{})
+	)
+	r := NewRegistry(cp).Register(ProviderAlipay, func(ctx context.Context, c *Credential) (interface{}, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return &testClient{key: c.PrivateKey}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+
+	// 首个调用方取消不影响等待同一构建的其它调用方
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := r.Client(ctx, ProviderAlipay, "A")
+		first <- err
+	}()
+	<-started
+	second := make(chan error, 1)
+	go func() {
+		_, err := r.Client(context.Background(), ProviderAlipay, "A")
+		second <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled Client() err = %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Fatalf("waiting Client() err = %v", err)
+	}
+}
+
+func TestFileCredentialProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ProviderAlipay), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(dir, ProviderAlipay, "2021000000000001.json"), []byte(`{"private_key":"MIIE","is_prod":true,"certs":{"app_cert":"CERT"}}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &FileCredentialProvider{Dir: dir}
+	c, err := p.Credential(context.Background(), ProviderAlipay, "2021000000000001")
+	if err != nil || c.MerchantId != "2021000000000001" || c.PrivateKey != "MIIE" || !c.IsProd || c.Certs[CertAlipayApp] != "CERT" {
+		t.Fatalf("Credential() = %+v, %v", c, err)
+	}
+	if _, err = p.Credential(context.Background(), ProviderAlipay, "404"); !errors.Is(err, CredentialNotFoundErr) {
+		t.Fatalf("not found err = %v", err)
+	}
+	if _, err = p.Credential(context.Background(), ProviderAlipay, "../alipay/2021000000000001"); !errors.Is(err, InvalidParamErr) {
+		t.Fatalf("path traversal err = %v", err)
+	}
+}
+
+func TestEnvCredentialProvider(t *testing.T) {
+	t.Setenv("PAY_WECHAT_1230000109_PRIVATE_KEY", "MIIE")
+	t.Setenv("PAY_WECHAT_1230000109_SERIAL_NO", "5B2C4F9A")
+	t.Setenv("PAY_WECHAT_1230000109_IS_PROD", "true")
+	t.Setenv("PAY_WECHAT_1230000109_CERT_PLATFORM_SERIAL_NO", "7132D72A")
+	p := new(EnvCredentialProvider)
+	c, err := p.Credential(context.Background(), ProviderWechat, "1230000109")
+	if err != nil || c.PrivateKey != "MIIE" || c.SerialNo != "5B2C4F9A" || !c.IsProd || c.Certs["platform_serial_no"] != "7132D72A" {
+		t.Fatalf("Credential() = %+v, %v", c, err)
+	}
+	if _, err = p.Credential(context.Background(), ProviderWechat, "404"); !errors.Is(err, CredentialNotFoundErr) {
+		t.Fatalf("not found err = %v", err)
+	}
+}
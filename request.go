@@ -0,0 +1,59 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rwscode/payutil/pkg/validate"
+)
+
+// Validator 强类型请求参数，Validate() 在发起网络请求前校验必填项、长度及枚举值
+type Validator interface {
+	Validate() error
+}
+
+// ValidateStruct 按 validate tag 校验请求结构体
+//
+//	缺少必填字段返回 MissParamErr，其余不满足规则的返回 InvalidParamErr
+func ValidateStruct(v interface{}) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+	var fe *validate.FieldError
+	if errors.As(err, &fe) && fe.Missing() {
+		return fmt.Errorf("[%w], %s", MissParamErr, fe.Field)
+	}
+	return fmt.Errorf("[%w]: %v", InvalidParamErr, err)
+}
+
+// ToBodyMap 校验请求结构体后按 json tag 转为 BodyMap，以便调用现有 BodyMap 接口
+func ToBodyMap(req Validator) (BodyMap, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	bs, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("[%w]: %v", MarshalErr, err)
+	}
+	bm := make(BodyMap)
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	if err = dec.Decode(&bm); err != nil {
+		return nil, fmt.Errorf("[%w]: %v", UnmarshalErr, err)
+	}
+	return bm, nil
+}
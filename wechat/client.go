@@ -12,15 +12,20 @@
 package wechat
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/retry"
 	"github.com/rwscode/payutil/pkg/util"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xlog"
@@ -36,6 +41,15 @@ type Client struct {
 	DebugSwitch pay.DebugSwitch
 	Certificate *tls.Certificate
 	mu          sync.RWMutex
+	hc          xhttp.Doer       // 自定义 Doer，为空时使用 xhttp 共享连接池
+	certOf      *tls.Certificate // certTLS 对应的 Certificate
+	certTLS     *tls.Config      // 证书请求复用的 tls.Config
+	certHc      xhttp.Doer       // 证书请求复用的 Doer
+	retry       *retry.Policy    // 重试策略，为空时不重试
+	limiter     *xhttp.Limiter   // 按接口族限流、熔断，为空时不限制
+	noFailover  bool             // 关闭主域名无法连接时切换备用域名
+	mw          observe.Chain    // 中间件链
+	logger      xlog.Logger      // 结构化日志，为空时使用全局 xlog
 }
 
 // 初始化微信客户端 V2
@@ -60,6 +74,177 @@ func (w *Client) SetBodySize(sizeMB int) {
 	}
 }
 
+// SetHTTPClient 设置自定义 *http.Client，默认使用 xhttp 共享连接池
+// 需要证书的接口会复制其 Transport，保留其证书校验配置并加载商户证书
+func (w *Client) SetHTTPClient(hc *http.Client) {
+	if hc == nil {
+		w.SetDoer(nil)
+		return
+	}
+	w.SetDoer(hc)
+}
+
+// SetDoer 设置自定义 xhttp.Doer，用于链路追踪、代理、mock 等，传 nil 恢复默认
+// 注意：非 *http.Client 的 Doer 无法加载商户证书，调用需要证书的接口时返回 xhttp.ErrClientCertUnsupported
+func (w *Client) SetDoer(d xhttp.Doer) {
+	w.mu.Lock()
+	w.hc, w.certHc = d, nil
+	w.mu.Unlock()
+}
+
+// SetTLSOptions 设置服务端证书校验配置，如自定义根证书、公钥固定，会替换 SetHTTPClient() 设置的客户端
+func (w *Client) SetTLSOptions(o *xhttp.TLSOptions) {
+	w.SetDoer(xhttp.NewHttpClient(&xhttp.TransportConfig{TLSConfig: xhttp.NewTLSConfig(o)}))
+}
+
+// SetBaseURL 设置接口域名，如 https://api.mch.weixin.qq.com/，用于出口代理、本地模拟服务（paytest）等
+// 同 SetCountry()，传空字符串恢复默认
+func (w *Client) SetBaseURL(url string) {
+	if url != util.NULL && !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+	w.mu.Lock()
+	w.BaseURL = url
+	w.mu.Unlock()
+}
+
+// SetFailover 设置主域名 api.mch.weixin.qq.com 无法连接时是否自动切换备用域名 api2.mch.weixin.qq.com 重发请求，默认开启
+// 仅在 DNS 解析失败、拒绝连接等请求未发出的错误时切换，其他域名不受影响
+func (w *Client) SetFailover(enable bool) {
+	w.mu.Lock()
+	w.noFailover = !enable
+	w.mu.Unlock()
+}
+
+// SetRetryPolicy 设置重试策略，默认不重试
+// 查询、下载账单等接口按策略重试网络错误、5xx 及 SYSTEMERROR
+// 其余接口（下单、退款、企业付款等）需通过 retry.WithIdempotent(ctx) 显式开启
+func (w *Client) SetRetryPolicy(p *retry.Policy) {
+	w.retry = p
+}
+
+// SetLimiter 设置按接口族的限流、熔断，每次 http 请求（含重试）经过 l，接口标识同 observe.Call.Method
+//
+//	limiter := xhttp.NewLimiter(xhttp.BreakerConfig{Failures: 5},
+//		xhttp.Family{Name: "bill", Prefixes: []string{"pay/downloadbill", "pay/downloadfundflow"}, Rate: 1},
+//		xhttp.Family{Name: "transaction", Rate: 100},
+//	)
+//	client.SetLimiter(limiter)
+func (w *Client) SetLimiter(l *xhttp.Limiter) {
+	w.limiter = l
+}
+
+// retryPolicy path 为查询、下载类接口时可重试，如 pay/orderquery、pay/downloadbill
+func (w *Client) retryPolicy(ctx context.Context, path string) *retry.Policy {
+	if strings.Contains(path, "query") || strings.Contains(path, "download") || strings.Contains(path, "/get") || retry.IsIdempotent(ctx) {
+		return w.retry.Idempotent()
+	}
+	return nil
+}
+
+// 返回 err_code 为 SYSTEMERROR 时可重试
+func isSystemError(bs []byte) bool {
+	if !bytes.Contains(bs, []byte("SYSTEMERROR")) {
+		return false
+	}
+	var rsp struct {
+		ErrCode string `xml:"err_code"`
+	}
+	return xml.Unmarshal(bs, &rsp) == nil && rsp.ErrCode == "SYSTEMERROR"
+}
+
+// Use 添加中间件，用于指标统计、链路追踪等，见 observe 包，请在初始化时调用
+func (w *Client) Use(mw ...observe.Middleware) {
+	w.mw = append(w.mw, mw...)
+}
+
+// SetLogger 设置当前客户端的结构化日志，日志携带 mch_id 字段，并记录每次接口调用（见 observe.Logging）
+//
+//	调试日志（DebugSwitch 开启时）也输出到该 logger，可使用 xlog.NewSlogLogger() 接入 slog、zap、logrus
+func (w *Client) SetLogger(l xlog.Logger) {
+	w.logger = xlog.With(l, "mch_id", w.MchId)
+}
+
+func (w *Client) debugf(format string, args ...interface{}) {
+	xlog.Logf(xlog.With(w.logger, "provider", pay.ProviderWechatV2), xlog.DebugLevel, format, args...)
+}
+
+// outTradeNo 请求中的商户订单号（企业付款为 partner_trade_no），用于日志
+func outTradeNo(bm pay.BodyMap) string {
+	if no := bm.GetString("out_trade_no"); no != util.NULL {
+		return no
+	}
+	return bm.GetString("partner_trade_no")
+}
+
+// do 经过中间件链，按 policy 发送请求
+func (w *Client) do(ctx context.Context, path, outTradeNo string, policy *retry.Policy, send func(ctx context.Context) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	call := &observe.Call{Provider: pay.ProviderWechatV2, Method: path, OutTradeNo: outTradeNo}
+	return w.mw.WithLogging(w.logger).DoHTTP(ctx, call, func(ctx context.Context) (*http.Response, []byte, error) {
+		return policy.DoHTTP(ctx, w.limiter.Wrap(call.Method, send), isSystemError)
+	}, resultCode)
+}
+
+// resultCode 微信业务结果码，通信或业务失败时返回 err_code，无 err_code 时返回 return_code、result_code
+func resultCode(_ int, bs []byte) string {
+	var rsp struct {
+		ReturnCode string `xml:"return_code"`
+		ResultCode string `xml:"result_code"`
+		ErrCode    string `xml:"err_code"`
+	}
+	if xml.Unmarshal(bs, &rsp) != nil {
+		return util.NULL
+	}
+	switch {
+	case rsp.ErrCode != util.NULL:
+		return rsp.ErrCode
+	case rsp.ReturnCode != util.NULL && rsp.ReturnCode != "SUCCESS":
+		return rsp.ReturnCode
+	case rsp.ResultCode != util.NULL && rsp.ResultCode != "SUCCESS":
+		return rsp.ResultCode
+	}
+	return util.NULL
+}
+
+// httpClient 返回请求客户端，tlsConfig 为 addCertConfig 返回的证书配置时复用连接池
+//
+//	自定义 Doer 无法加载商户证书时返回 xhttp.ErrClientCertUnsupported
+func (w *Client) httpClient(tlsConfig *tls.Config) (*xhttp.Client, error) {
+	c := xhttp.NewClient()
+	if w.bodySize > 0 {
+		c.SetBodySize(w.bodySize)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case tlsConfig == nil:
+		c.SetDoer(w.failover(w.hc))
+	case tlsConfig == w.certTLS:
+		if w.certHc == nil {
+			d, err := xhttp.WithClientCert(w.hc, tlsConfig.Certificates)
+			if err != nil {
+				return nil, err
+			}
+			w.certHc = d
+		}
+		c.SetDoer(w.failover(w.certHc))
+	default:
+		// 一次性 Transport 仅对 *http.Client 生效
+		if _, ok := w.hc.(*http.Client); w.hc != nil && !ok {
+			return nil, fmt.Errorf("%w: %T", xhttp.ErrClientCertUnsupported, w.hc)
+		}
+		c.SetDoer(w.hc).SetTLSConfig(tlsConfig)
+	}
+	return c, nil
+}
+
+func (w *Client) failover(d xhttp.Doer) xhttp.Doer {
+	if w.noFailover {
+		return d
+	}
+	return xhttp.WithFailover(d, baseUrlCh, baseUrlCh2)
+}
+
 // 向微信发送Post请求，对于本库未提供的微信API，可自行实现，通过此方法发送请求
 // bm：请求参数的BodyMap
 // path：接口地址去掉baseURL的path，例如：url为https://api.mch.weixin.qq.com/pay/micropay，只需传 pay/micropay
@@ -205,18 +390,19 @@ func (w *Client) doSanBoxPost(ctx context.Context, bm pay.BodyMap, path string)
 	}
 	req := GenerateXml(bm)
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", req)
+		w.debugf("Wechat_Request: %s", redact.String(req))
 	}
-	httpClient := xhttp.NewClient().Type(xhttp.TypeXML)
-	if w.bodySize > 0 {
-		httpClient.SetBodySize(w.bodySize)
+	httpClient, err := w.httpClient(nil)
+	if err != nil {
+		return nil, err
 	}
-	res, bs, err := httpClient.Post(url).SendString(req).EndBytes(ctx)
+	httpClient.Type(xhttp.TypeXML)
+	res, bs, err := w.do(ctx, path, outTradeNo(bm), w.retryPolicy(ctx, path), httpClient.Post(url).SendString(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -241,26 +427,26 @@ func (w *Client) doProdPost(ctx context.Context, bm pay.BodyMap, path string, tl
 		bm.Set("sign", sign)
 	}
 
-	httpClient := xhttp.NewClient()
-	if w.IsProd && tlsConfig != nil {
-		httpClient.SetTLSConfig(tlsConfig)
+	if !w.IsProd {
+		tlsConfig = nil
 	}
-	if w.bodySize > 0 {
-		httpClient.SetBodySize(w.bodySize)
+	httpClient, err := w.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
 	}
 	if w.BaseURL != util.NULL {
 		url = w.BaseURL + path
 	}
 	req := GenerateXml(bm)
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", req)
+		w.debugf("Wechat_Request: %s", redact.String(req))
 	}
-	res, bs, err := httpClient.Type(xhttp.TypeXML).Post(url).SendString(req).EndBytes(ctx)
+	res, bs, err := w.do(ctx, path, outTradeNo(bm), w.retryPolicy(ctx, path), httpClient.Type(xhttp.TypeXML).Post(url).SendString(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -273,26 +459,26 @@ func (w *Client) doProdPost(ctx context.Context, bm pay.BodyMap, path string, tl
 
 func (w *Client) doProdPostPure(ctx context.Context, bm pay.BodyMap, path string, tlsConfig *tls.Config) (bs []byte, err error) {
 	var url = baseUrlCh + path
-	httpClient := xhttp.NewClient()
-	if w.IsProd && tlsConfig != nil {
-		httpClient.SetTLSConfig(tlsConfig)
+	if !w.IsProd {
+		tlsConfig = nil
 	}
-	if w.bodySize > 0 {
-		httpClient.SetBodySize(w.bodySize)
+	httpClient, err := w.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
 	}
 	if w.BaseURL != util.NULL {
 		url = w.BaseURL + path
 	}
 	req := GenerateXml(bm)
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", req)
+		w.debugf("Wechat_Request: %s", redact.String(req))
 	}
-	res, bs, err := httpClient.Type(xhttp.TypeXML).Post(url).SendString(req).EndBytes(ctx)
+	res, bs, err := w.do(ctx, path, outTradeNo(bm), w.retryPolicy(ctx, path), httpClient.Type(xhttp.TypeXML).Post(url).SendString(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -320,20 +506,20 @@ func (w *Client) doProdGet(ctx context.Context, bm pay.BodyMap, path, signType s
 	}
 
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", bm.JsonBody())
+		w.debugf("Wechat_Request: %s", redact.String(bm.JsonBody()))
 	}
 	param := bm.EncodeURLParams()
 	url = url + "?" + param
-	httpClient := xhttp.NewClient()
-	if w.bodySize > 0 {
-		httpClient.SetBodySize(w.bodySize)
+	httpClient, err := w.httpClient(nil)
+	if err != nil {
+		return nil, err
 	}
-	res, bs, err := httpClient.Get(url).EndBytes(ctx)
+	res, bs, err := w.do(ctx, path, outTradeNo(bm), w.retryPolicy(ctx, path), httpClient.Get(url).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -0,0 +1,69 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"strings"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// NewAPIError 将微信支付 v2应答的 return_code、err_code 转换为 *pay.APIError
+//
+//	return_code 为 FAIL 时 Code 为 return_code，Message 为 return_msg；否则 Code 为 err_code，Message 为 err_code_des
+func NewAPIError(returnCode, returnMsg, errCode, errCodeDes string) *pay.APIError {
+	if returnCode != pay.SUCCESS {
+		return pay.NewAPIError(pay.ProviderWechatV2, 0, returnCode, "", returnMsg, returnMsgCategory(returnMsg))
+	}
+	return pay.NewAPIError(pay.ProviderWechatV2, 0, errCode, "", errCodeDes, errCodeCategory[errCode])
+}
+
+// 文档：https://pay.weixin.qq.com/wiki/doc/api/native.php?chapter=9_2 错误码
+var errCodeCategory = map[string]pay.ErrorCategory{
+	"ORDERPAID":             pay.CategoryOrderPaid,
+	"ORDERCLOSED":           pay.CategoryOrderClosed,
+	"ORDERREVERSED":         pay.CategoryOrderClosed,
+	"ORDERNOTEXIST":         pay.CategoryOrderNotExist,
+	"REFUNDNOTEXIST":        pay.CategoryOrderNotExist,
+	"NOTENOUGH":             pay.CategoryInsufficientFunds,
+	"NOT_ENOUGH":            pay.CategoryInsufficientFunds,
+	"BALANCE_NOT_ENOUGH":    pay.CategoryInsufficientFunds,
+	"FREQ_LIMIT":            pay.CategoryThrottled,
+	"FREQUENCY_LIMITED":     pay.CategoryThrottled,
+	"SIGNERROR":             pay.CategoryAuthFailed,
+	"NOAUTH":                pay.CategoryAuthFailed,
+	"APPID_NOT_EXIST":       pay.CategoryAuthFailed,
+	"MCHID_NOT_EXIST":       pay.CategoryAuthFailed,
+	"APPID_MCHID_NOT_MATCH": pay.CategoryAuthFailed,
+	"PARAM_ERROR":           pay.CategoryInvalidParam,
+	"LACK_PARAMS":           pay.CategoryInvalidParam,
+	"XML_FORMAT_ERROR":      pay.CategoryInvalidParam,
+	"INVALID_REQUEST":       pay.CategoryInvalidParam,
+	"OUT_TRADE_NO_USED":     pay.CategoryInvalidParam,
+	"SYSTEMERROR":           pay.CategorySystemError,
+	"BIZERR_NEED_RETRY":     pay.CategorySystemError,
+}
+
+// return_code 为 FAIL 时没有错误码，按 return_msg 识别
+func returnMsgCategory(returnMsg string) pay.ErrorCategory {
+	switch {
+	case strings.Contains(returnMsg, "签名"), strings.Contains(strings.ToLower(returnMsg), "sign"):
+		return pay.CategoryAuthFailed
+	case strings.Contains(returnMsg, "频率"):
+		return pay.CategoryThrottled
+	case strings.Contains(returnMsg, "繁忙"), strings.Contains(returnMsg, "系统错误"):
+		return pay.CategorySystemError
+	case strings.Contains(returnMsg, "参数"):
+		return pay.CategoryInvalidParam
+	}
+	return pay.CategoryUnknown
+}
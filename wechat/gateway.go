@@ -0,0 +1,291 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+type gateway struct {
+	w *Client
+}
+
+// NewGateway 将微信支付 v2 Client 适配为 pay.Gateway
+//
+//	SceneQRCode：NATIVE，SceneJSAPI：JSAPI，SceneApp：APP，SceneWap：MWEB
+//	注意：Refund 需先调用 client 添加证书的相关方法添加证书
+func NewGateway(client *Client) pay.Gateway {
+	return &gateway{w: client}
+}
+
+func (g *gateway) Provider() string {
+	return pay.ProviderWechatV2
+}
+
+func (g *gateway) Charge(ctx context.Context, o *pay.Order) (*pay.ChargeResult, error) {
+	var tradeType string
+	switch o.Scene {
+	case pay.SceneQRCode:
+		tradeType = TradeType_Native
+	case pay.SceneJSAPI:
+		tradeType = TradeType_JsApi
+	case pay.SceneApp:
+		tradeType = TradeType_App
+	case pay.SceneWap:
+		tradeType = TradeType_H5
+	default:
+		return nil, fmt.Errorf("[%w]: wechat v2 scene %q", pay.UnsupportedErr, o.Scene)
+	}
+	body := o.Body
+	if body == util.NULL {
+		body = o.Subject
+	}
+	bm := make(pay.BodyMap)
+	bm.Set("nonce_str", util.RandomString(32)).
+		Set("body", body).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("total_fee", o.Amount).
+		Set("spbill_create_ip", o.ClientIp).
+		Set("notify_url", o.NotifyUrl).
+		Set("trade_type", tradeType).
+		Set("sign_type", SignType_MD5)
+	if o.Currency != util.NULL {
+		bm.Set("fee_type", o.Currency)
+	}
+	if o.OpenId != util.NULL {
+		bm.Set("openid", o.OpenId)
+	}
+	bm.Merge(o.Extra)
+	wxRsp, err := g.w.UnifiedOrder(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	if err = bizErr("UnifiedOrder", wxRsp.ReturnCode, wxRsp.ReturnMsg, wxRsp.ResultCode, wxRsp.ErrCode, wxRsp.ErrCodeDes); err != nil {
+		return nil, err
+	}
+	rs := &pay.ChargeResult{
+		OutTradeNo: o.OutTradeNo,
+		CodeUrl:    wxRsp.CodeUrl,
+		PayUrl:     wxRsp.MwebUrl,
+		PrepayId:   wxRsp.PrepayId,
+		Raw:        wxRsp,
+	}
+	appId, signType := bm.GetString("appid"), bm.GetString("sign_type")
+	ts, nonceStr := strconv.FormatInt(time.Now().Unix(), 10), util.RandomString(32)
+	switch o.Scene {
+	case pay.SceneJSAPI:
+		pkg := "prepay_id=" + wxRsp.PrepayId
+		bs, _ := json.Marshal(map[string]string{
+			"appId":     appId,
+			"timeStamp": ts,
+			"nonceStr":  nonceStr,
+			"package":   pkg,
+			"signType":  signType,
+			"paySign":   GetJsapiPaySign(appId, nonceStr, pkg, signType, ts, g.w.ApiKey),
+		})
+		rs.PayParams = string(bs)
+	case pay.SceneApp:
+		bs, _ := json.Marshal(map[string]string{
+			"appid":     appId,
+			"partnerid": g.w.MchId,
+			"prepayid":  wxRsp.PrepayId,
+			"package":   "Sign=WXPay",
+			"noncestr":  nonceStr,
+			"timestamp": ts,
+			"sign":      GetAppPaySign(appId, g.w.MchId, nonceStr, wxRsp.PrepayId, signType, ts, g.w.ApiKey),
+		})
+		rs.PayParams = string(bs)
+	}
+	return rs, nil
+}
+
+func (g *gateway) Query(ctx context.Context, q *pay.OrderQuery) (*pay.OrderResult, error) {
+	bm := tradeNoBodyMap(q.OutTradeNo, q.TradeNo)
+	wxRsp, _, err := g.w.QueryOrder(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	if err = bizErr("QueryOrder", wxRsp.ReturnCode, wxRsp.ReturnMsg, wxRsp.ResultCode, wxRsp.ErrCode, wxRsp.ErrCodeDes); err != nil {
+		return nil, err
+	}
+	rs := &pay.OrderResult{
+		OutTradeNo: wxRsp.OutTradeNo,
+		TradeNo:    wxRsp.TransactionId,
+		Status:     tradeState(wxRsp.TradeState),
+		RawStatus:  wxRsp.TradeState,
+		Amount:     util.String2Int64(wxRsp.TotalFee),
+		Currency:   "CNY",
+		Raw:        wxRsp,
+	}
+	if wxRsp.FeeType != util.NULL {
+		rs.Currency = wxRsp.FeeType
+	}
+	return rs, nil
+}
+
+func (g *gateway) Close(ctx context.Context, q *pay.OrderQuery) error {
+	bm := make(pay.BodyMap)
+	bm.Set("nonce_str", util.RandomString(32)).
+		Set("out_trade_no", q.OutTradeNo)
+	wxRsp, err := g.w.CloseOrder(ctx, bm)
+	if err != nil {
+		return err
+	}
+	return bizErr("CloseOrder", wxRsp.ReturnCode, wxRsp.ReturnMsg, wxRsp.ResultCode, wxRsp.ErrCode, wxRsp.ErrCodeDes)
+}
+
+func (g *gateway) Refund(ctx context.Context, r *pay.Refund) (*pay.RefundResult, error) {
+	bm := tradeNoBodyMap(r.OutTradeNo, r.TradeNo)
+	bm.Set("out_refund_no", r.OutRefundNo).
+		Set("total_fee", r.Total).
+		Set("refund_fee", r.Amount)
+	if r.Currency != util.NULL {
+		bm.Set("refund_fee_type", r.Currency)
+	}
+	if r.Reason != util.NULL {
+		bm.Set("refund_desc", r.Reason)
+	}
+	if r.NotifyUrl != util.NULL {
+		bm.Set("notify_url", r.NotifyUrl)
+	}
+	bm.Merge(r.Extra)
+	wxRsp, _, err := g.w.Refund(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	if err = bizErr("Refund", wxRsp.ReturnCode, wxRsp.ReturnMsg, wxRsp.ResultCode, wxRsp.ErrCode, wxRsp.ErrCodeDes); err != nil {
+		return nil, err
+	}
+	rs := &pay.RefundResult{
+		OutTradeNo:  wxRsp.OutTradeNo,
+		TradeNo:     wxRsp.TransactionId,
+		OutRefundNo: wxRsp.OutRefundNo,
+		RefundNo:    wxRsp.RefundId,
+		Status:      pay.RefundStatusProcessing, // 申请成功，退款结果以退款查询或退款通知为准
+		Amount:      util.String2Int64(wxRsp.RefundFee),
+		Currency:    "CNY",
+		Raw:         wxRsp,
+	}
+	if wxRsp.FeeType != util.NULL {
+		rs.Currency = wxRsp.FeeType
+	}
+	return rs, nil
+}
+
+func (g *gateway) QueryRefund(ctx context.Context, q *pay.RefundQuery) (*pay.RefundResult, error) {
+	bm := tradeNoBodyMap(q.OutTradeNo, q.TradeNo)
+	if q.OutRefundNo != util.NULL {
+		bm.Set("out_refund_no", q.OutRefundNo)
+	}
+	if q.RefundNo != util.NULL {
+		bm.Set("refund_id", q.RefundNo)
+	}
+	wxRsp, resBm, err := g.w.QueryRefund(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	if err = bizErr("QueryRefund", wxRsp.ReturnCode, wxRsp.ReturnMsg, wxRsp.ResultCode, wxRsp.ErrCode, wxRsp.ErrCodeDes); err != nil {
+		return nil, err
+	}
+	// 一笔订单可能有多笔退款，按 out_refund_no/refund_id 匹配，未指定时取第一笔
+	n := 0
+	if q.OutRefundNo != util.NULL || q.RefundNo != util.NULL {
+		n = -1
+		for i := 0; i < util.String2Int(resBm.GetString("refund_count")); i++ {
+			idx := strconv.Itoa(i)
+			if (q.OutRefundNo != util.NULL && resBm.GetString("out_refund_no_"+idx) == q.OutRefundNo) ||
+				(q.RefundNo != util.NULL && resBm.GetString("refund_id_"+idx) == q.RefundNo) {
+				n = i
+				break
+			}
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("wechat QueryRefund failed: %w",
+				NewAPIError(pay.SUCCESS, "", "REFUNDNOTEXIST", fmt.Sprintf("refund %s%s not exist", q.OutRefundNo, q.RefundNo)))
+		}
+	}
+	idx := strconv.Itoa(n)
+	status := resBm.GetString("refund_status_" + idx)
+	rs := &pay.RefundResult{
+		OutTradeNo:  wxRsp.OutTradeNo,
+		TradeNo:     wxRsp.TransactionId,
+		OutRefundNo: resBm.GetString("out_refund_no_" + idx),
+		RefundNo:    resBm.GetString("refund_id_" + idx),
+		Status:      refundStatus(status),
+		RawStatus:   status,
+		Amount:      util.String2Int64(resBm.GetString("refund_fee_" + idx)),
+		Currency:    "CNY",
+		Raw:         wxRsp,
+	}
+	if wxRsp.FeeType != util.NULL {
+		rs.Currency = wxRsp.FeeType
+	}
+	return rs, nil
+}
+
+func tradeNoBodyMap(outTradeNo, transactionId string) pay.BodyMap {
+	bm := make(pay.BodyMap)
+	bm.Set("nonce_str", util.RandomString(32))
+	if outTradeNo != util.NULL {
+		bm.Set("out_trade_no", outTradeNo)
+	}
+	if transactionId != util.NULL {
+		bm.Set("transaction_id", transactionId)
+	}
+	return bm
+}
+
+func tradeState(state string) pay.TradeStatus {
+	switch state {
+	case "NOTPAY", "USERPAYING":
+		return pay.TradeStatusPending
+	case "SUCCESS":
+		return pay.TradeStatusPaid
+	case "CLOSED", "REVOKED":
+		return pay.TradeStatusClosed
+	case "REFUND":
+		return pay.TradeStatusRefunded
+	case "PAYERROR":
+		return pay.TradeStatusFailed
+	default:
+		return pay.TradeStatusUnknown
+	}
+}
+
+func refundStatus(status string) pay.RefundStatus {
+	switch status {
+	case "PROCESSING":
+		return pay.RefundStatusProcessing
+	case "SUCCESS":
+		return pay.RefundStatusSuccess
+	case "REFUNDCLOSE":
+		return pay.RefundStatusClosed
+	case "CHANGE":
+		return pay.RefundStatusFailed
+	default:
+		return pay.RefundStatusUnknown
+	}
+}
+
+func bizErr(api, returnCode, returnMsg, resultCode, errCode, errCodeDes string) error {
+	if returnCode != pay.SUCCESS || resultCode != pay.SUCCESS {
+		return fmt.Errorf("wechat %s failed: %w", api, NewAPIError(returnCode, returnMsg, errCode, errCodeDes))
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/redact"
 	"github.com/rwscode/payutil/pkg/util"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xlog"
@@ -42,7 +43,11 @@ func (w *Client) Transfer(ctx context.Context, bm pay.BodyMap) (wxRsp *Transfers
 	}
 	bm.Set("sign", GetReleaseSign(w.ApiKey, SignType_MD5, bm))
 
-	httpClient := xhttp.NewClient().SetTLSConfig(tlsConfig).Type(xhttp.TypeXML)
+	httpClient, err := w.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Type(xhttp.TypeXML)
 	if w.BaseURL != util.NULL {
 		w.mu.RLock()
 		url = w.BaseURL + transfers
@@ -50,14 +55,14 @@ func (w *Client) Transfer(ctx context.Context, bm pay.BodyMap) (wxRsp *Transfers
 	}
 	req := GenerateXml(bm)
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", req)
+		w.debugf("Wechat_Request: %s", redact.String(req))
 	}
-	res, bs, err := httpClient.Post(url).SendString(req).EndBytes(ctx)
+	res, bs, err := w.do(ctx, transfers, outTradeNo(bm), w.retryPolicy(ctx, transfers), httpClient.Post(url).SendString(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -88,7 +93,11 @@ func (w *Client) GetTransferInfo(ctx context.Context, bm pay.BodyMap) (wxRsp *Tr
 	}
 	bm.Set("sign", GetReleaseSign(w.ApiKey, SignType_MD5, bm))
 
-	httpClient := xhttp.NewClient().SetTLSConfig(tlsConfig).Type(xhttp.TypeXML)
+	httpClient, err := w.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Type(xhttp.TypeXML)
 	if w.BaseURL != util.NULL {
 		w.mu.RLock()
 		url = w.BaseURL + getTransferInfo
@@ -96,14 +105,14 @@ func (w *Client) GetTransferInfo(ctx context.Context, bm pay.BodyMap) (wxRsp *Tr
 	}
 	req := GenerateXml(bm)
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", req)
+		w.debugf("Wechat_Request: %s", redact.String(req))
 	}
-	res, bs, err := httpClient.Post(url).SendString(req).EndBytes(ctx)
+	res, bs, err := w.do(ctx, getTransferInfo, outTradeNo(bm), w.retryPolicy(ctx, getTransferInfo), httpClient.Post(url).SendString(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -136,7 +145,11 @@ func (w *Client) PayBank(ctx context.Context, bm pay.BodyMap) (wxRsp *PayBankRes
 	}
 	bm.Set("sign", GetReleaseSign(w.ApiKey, SignType_MD5, bm))
 
-	httpClient := xhttp.NewClient().SetTLSConfig(tlsConfig).Type(xhttp.TypeXML)
+	httpClient, err := w.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Type(xhttp.TypeXML)
 	if w.BaseURL != util.NULL {
 		w.mu.RLock()
 		url = w.BaseURL + payBank
@@ -144,14 +157,14 @@ func (w *Client) PayBank(ctx context.Context, bm pay.BodyMap) (wxRsp *PayBankRes
 	}
 	req := GenerateXml(bm)
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", req)
+		w.debugf("Wechat_Request: %s", redact.String(req))
 	}
-	res, bs, err := httpClient.Post(url).SendString(req).EndBytes(ctx)
+	res, bs, err := w.do(ctx, payBank, outTradeNo(bm), w.retryPolicy(ctx, payBank), httpClient.Post(url).SendString(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -180,7 +193,11 @@ func (w *Client) QueryBank(ctx context.Context, bm pay.BodyMap) (wxRsp *QueryBan
 	}
 	bm.Set("sign", GetReleaseSign(w.ApiKey, SignType_MD5, bm))
 
-	httpClient := xhttp.NewClient().SetTLSConfig(tlsConfig).Type(xhttp.TypeXML)
+	httpClient, err := w.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Type(xhttp.TypeXML)
 	if w.BaseURL != util.NULL {
 		w.mu.RLock()
 		url = w.BaseURL + queryBank
@@ -188,14 +205,14 @@ func (w *Client) QueryBank(ctx context.Context, bm pay.BodyMap) (wxRsp *QueryBan
 	}
 	req := GenerateXml(bm)
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", req)
+		w.debugf("Wechat_Request: %s", redact.String(req))
 	}
-	res, bs, err := httpClient.Post(url).SendString(req).EndBytes(ctx)
+	res, bs, err := w.do(ctx, queryBank, outTradeNo(bm), w.retryPolicy(ctx, queryBank), httpClient.Post(url).SendString(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
@@ -224,17 +241,21 @@ func (w *Client) GetRSAPublicKey(ctx context.Context, bm pay.BodyMap) (wxRsp *RS
 	}
 	bm.Set("sign", GetReleaseSign(w.ApiKey, bm.GetString("sign_type"), bm))
 
-	httpClient := xhttp.NewClient().SetTLSConfig(tlsConfig).Type(xhttp.TypeXML)
+	httpClient, err := w.httpClient(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Type(xhttp.TypeXML)
 	req := GenerateXml(bm)
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Request: %s", req)
+		w.debugf("Wechat_Request: %s", redact.String(req))
 	}
-	res, bs, err := httpClient.Post(url).SendString(req).EndBytes(ctx)
+	res, bs, err := w.do(ctx, getPublicKey, outTradeNo(bm), w.retryPolicy(ctx, getPublicKey), httpClient.Post(url).SendString(req).EndBytes)
 	if err != nil {
 		return nil, err
 	}
 	if w.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, string(bs))
+		w.debugf("Wechat_Response: %s%d %s%s", xlog.Red, res.StatusCode, xlog.Reset, redact.String(string(bs)))
 	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", res.StatusCode)
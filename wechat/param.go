@@ -99,14 +99,17 @@ func (w *Client) addCertFileContentOrPath(certFile, keyFile, pkcs12File interfac
 
 func (w *Client) addCertConfig(certFile, keyFile, pkcs12File interface{}) (tlsConfig *tls.Config, err error) {
 	if certFile == nil && keyFile == nil && pkcs12File == nil {
-		w.mu.RLock()
-		defer w.mu.RUnlock()
+		w.mu.Lock()
+		defer w.mu.Unlock()
 		if w.Certificate != nil {
-			tlsConfig = &tls.Config{
-				Certificates:       []tls.Certificate{*w.Certificate},
-				InsecureSkipVerify: true,
+			// 证书未变更时复用 tls.Config，以复用证书请求的连接池
+			if w.certTLS == nil || w.certOf != w.Certificate {
+				w.certOf, w.certHc = w.Certificate, nil
+				w.certTLS = &tls.Config{
+					Certificates: []tls.Certificate{*w.Certificate},
+				}
 			}
-			return tlsConfig, nil
+			return w.certTLS, nil
 		}
 		return nil, errors.New("cert parse failed or nil")
 	}
@@ -152,8 +155,7 @@ func (w *Client) addCertConfig(certFile, keyFile, pkcs12File interface{}) (tlsCo
 			return nil, fmt.Errorf("tls.LoadX509KeyPair：%w", err)
 		}
 		tlsConfig = &tls.Config{
-			Certificates:       []tls.Certificate{certificate},
-			InsecureSkipVerify: true,
+			Certificates: []tls.Certificate{certificate},
 		}
 		return tlsConfig, nil
 	}
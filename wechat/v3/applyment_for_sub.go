@@ -45,7 +45,7 @@ func (c *ClientV3) V3Apply4SubSubmit(ctx context.Context, bm pay.BodyMap) (*Appl
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 通过业务申请编号查询申请状态API
@@ -71,7 +71,7 @@ func (c *ClientV3) V3Apply4SubQueryByBusinessCode(ctx context.Context, businessC
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 通过申请单号查询申请状态API
@@ -98,7 +98,7 @@ func (c *ClientV3) V3Apply4SubQueryByApplyId(ctx context.Context, applyId string
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 修改结算账号 API
@@ -125,7 +125,7 @@ func (c *ClientV3) V3Apply4SubModifySettlement(ctx context.Context, bm pay.BodyM
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询结算账户 API
@@ -152,5 +152,5 @@ func (c *ClientV3) V3Apply4SubQuerySettlement(ctx context.Context, subMchId stri
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -47,7 +47,7 @@ func (c *ClientV3) V3BankSearchBank(ctx context.Context, accountNo string) (wxRs
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询支持个人业务的银行列表
@@ -77,7 +77,7 @@ func (c *ClientV3) V3BankSearchPersonalList(ctx context.Context, limit, offset i
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询支持对公业务的银行列表
@@ -107,7 +107,7 @@ func (c *ClientV3) V3BankSearchCorporateList(ctx context.Context, limit, offset
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询省份列表
@@ -133,7 +133,7 @@ func (c *ClientV3) V3BankSearchProvinceList(ctx context.Context) (wxRsp *BankSea
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询城市列表
@@ -160,7 +160,7 @@ func (c *ClientV3) V3BankSearchCityList(ctx context.Context, provinceCode int) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询支行列表
@@ -190,5 +190,5 @@ func (c *ClientV3) V3BankSearchBranchList(ctx context.Context, bankAliasCode str
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
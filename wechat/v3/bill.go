@@ -58,7 +58,7 @@ func (c *ClientV3) V3BillTradeBill(ctx context.Context, bm pay.BodyMap) (wxRsp *
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 申请资金账单API
@@ -95,7 +95,7 @@ func (c *ClientV3) V3BillFundFlowBill(ctx context.Context, bm pay.BodyMap) (wxRs
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 申请特约商户资金账单API
@@ -137,7 +137,7 @@ func (c *ClientV3) V3BillEcommerceFundFlowBill(ctx context.Context, bm pay.BodyM
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 申请单个子商户资金账单API
@@ -173,7 +173,7 @@ func (c *ClientV3) V3BillSubFundFlowBill(ctx context.Context, bm pay.BodyMap) (w
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 下载账单API
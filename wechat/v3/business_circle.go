@@ -39,7 +39,7 @@ func (c *ClientV3) V3BusinessPointsSync(ctx context.Context, bm pay.BodyMap) (wx
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 商圈积分授权查询
@@ -67,5 +67,5 @@ func (c *ClientV3) V3BusinessAuthPointsQuery(ctx context.Context, appid, openid
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -21,7 +21,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"runtime"
 	"sort"
 	"sync"
 	"time"
@@ -29,7 +28,6 @@ import (
 	pay "github.com/rwscode/payutil"
 	"github.com/rwscode/payutil/pkg/aes"
 	"github.com/rwscode/payutil/pkg/errgroup"
-	"github.com/rwscode/payutil/pkg/retry"
 	"github.com/rwscode/payutil/pkg/util"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xlog"
@@ -155,8 +153,8 @@ func (c *ClientV3) WxPublicKeyMap() (wxPublicKeyMap map[string]*rsa.PublicKey) {
 }
 
 // 获取证书Map集并选择最新的有效证书序列号
-func (c *ClientV3) GetAndSelectNewestCert() (serialNo string, snCertMap map[string]string, err error) {
-	certs, err := c.getPlatformCerts()
+func (c *ClientV3) GetAndSelectNewestCert(ctx context.Context) (serialNo string, snCertMap map[string]string, err error) {
+	certs, err := c.getPlatformCerts(ctx)
 	if err != nil {
 		return pay.NULL, nil, err
 	}
@@ -219,7 +217,7 @@ func (c *ClientV3) GetAndSelectNewestCert() (serialNo string, snCertMap map[stri
 //   - 加密请求消息中的敏感信息时，使用最新的平台证书（即：证书启用时间较晚的证书）
 //
 // 文档说明：https://pay.weixin.qq.com/wiki/doc/apiv3/apis/wechatpay5_1.shtml
-func (c *ClientV3) getPlatformCerts() (certs *PlatformCertRsp, err error) {
+func (c *ClientV3) getPlatformCerts(ctx context.Context) (certs *PlatformCertRsp, err error) {
 	var (
 		eg = new(errgroup.Group)
 		mu sync.Mutex
@@ -230,7 +228,7 @@ func (c *ClientV3) getPlatformCerts() (certs *PlatformCertRsp, err error) {
 		return nil, err
 	}
 
-	res, _, bs, err := c.doProdGet(c.ctx, v3GetCerts, authorization)
+	res, _, bs, err := c.doProdGet(ctx, v3GetCerts, authorization)
 	if err != nil {
 		return nil, err
 	}
@@ -282,42 +280,31 @@ func (c *ClientV3) decryptCerts(ciphertext, nonce, additional string) (wxCerts s
 	return string(decrypt), nil
 }
 
-func (c *ClientV3) autoCheckCertProc() {
-	xlog.Info("auto refresh wechat platform public key")
-	defer func() {
-		if r := recover(); r != nil {
-			buf := make([]byte, 64<<10)
-			buf = buf[:runtime.Stack(buf, false)]
-			xlog.Errorf("autoCheckCertProc: panic recovered: %s\n%s", r, buf)
-			// 重启
-			c.autoCheckCertProc()
+// refreshCerts 获取最新平台证书并替换客户端证书集，merge 为 true 时保留已有的证书
+func (c *ClientV3) refreshCerts(ctx context.Context, merge bool) error {
+	serialNo, snCertMap, err := c.GetAndSelectNewestCert(ctx)
+	if err != nil {
+		return err
+	}
+	snPkMap := make(map[string]*rsa.PublicKey)
+	for sn, cert := range snCertMap {
+		pubKey, err := xpem.DecodePublicKey([]byte(cert))
+		if err != nil {
+			return err
 		}
-	}()
-	for {
-		time.Sleep(time.Hour * 12)
-		err := retry.Retry(func() error {
-			serialNo, snCertMap, err := c.GetAndSelectNewestCert()
-			if err != nil {
-				return err
-			}
-			snPkMap := make(map[string]*rsa.PublicKey)
-			for sn, cert := range snCertMap {
-				pubKey, err := xpem.DecodePublicKey([]byte(cert))
-				if err != nil {
-					return err
-				}
+		snPkMap[sn] = pubKey
+	}
+	c.rwMu.Lock()
+	if merge {
+		for sn, pubKey := range c.SnCertMap {
+			if _, ok := snPkMap[sn]; !ok {
 				snPkMap[sn] = pubKey
 			}
-			c.rwMu.Lock()
-			c.SnCertMap = snPkMap
-			c.WxSerialNo = serialNo
-			c.wxPublicKey = snPkMap[serialNo]
-			c.rwMu.Unlock()
-			return nil
-		}, 3, time.Second)
-		if err != nil {
-			xlog.Errorf("c.GetAndSelectNewestCert()，err:%+v", err)
-			continue
 		}
 	}
+	c.SnCertMap = snPkMap
+	c.WxSerialNo = serialNo
+	c.wxPublicKey = snPkMap[serialNo]
+	c.rwMu.Unlock()
+	return nil
 }
@@ -12,14 +12,21 @@
 package wechat
 
 import (
+	"bytes"
 	"context"
 	"crypto/rsa"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/idempotency"
+	"github.com/rwscode/payutil/pkg/observe"
+	"github.com/rwscode/payutil/pkg/redact"
+	"github.com/rwscode/payutil/pkg/retry"
 	"github.com/rwscode/payutil/pkg/util"
 	"github.com/rwscode/payutil/pkg/xhttp"
 	"github.com/rwscode/payutil/pkg/xlog"
@@ -37,9 +44,18 @@ type ClientV3 struct {
 	rwMu        sync.RWMutex
 	privateKey  *rsa.PrivateKey
 	wxPublicKey *rsa.PublicKey
-	ctx         context.Context
 	DebugSwitch pay.DebugSwitch
 	SnCertMap   map[string]*rsa.PublicKey // key: serial_no
+	hc          xhttp.Doer                // 自定义 Doer，为空时使用 xhttp 共享连接池
+	retry       *retry.Policy             // 重试策略，为空时不重试
+	limiter     *xhttp.Limiter            // 按接口族限流、熔断，为空时不限制
+	baseURL     string                    // 自定义接口域名，为空时使用 https://api.mch.weixin.qq.com
+	noFailover  bool                      // 关闭主域名无法连接时切换备用域名
+	mw          observe.Chain             // 中间件链
+	logger      xlog.Logger               // 结构化日志，为空时使用全局 xlog
+	idempotency *idempotency.Guard        // 退款、转账的幂等保护，为空时不启用
+	refreshMu   sync.Mutex
+	refresher   *CertRefresher // 运行中的平台证书刷新器，Close() 时停止
 }
 
 // NewClientV3 初始化微信客户端 V3
@@ -60,40 +76,226 @@ func NewClientV3(mchid, serialNo, apiV3Key, privateKey string) (client *ClientV3
 		SerialNo:    serialNo,
 		ApiV3Key:    []byte(apiV3Key),
 		privateKey:  priKey,
-		ctx:         context.Background(),
 		DebugSwitch: pay.DebugOff,
 	}
 	return client, nil
 }
 
 // AutoVerifySign 开启请求完自动验签功能（默认不开启，推荐开启）
-// 开启自动验签，自动开启每12小时一次轮询，请求最新证书操作
-func (c *ClientV3) AutoVerifySign(autoRefresh ...bool) (err error) {
-	wxSerialNo, certMap, err := c.GetAndSelectNewestCert()
-	if err != nil {
+// 开启自动验签，使用默认配置的 CertRefresher 每12小时（随机抖动）刷新一次平台证书，客户端不再使用时需调用 Close() 停止
+// 需自定义刷新间隔、失败回调时，可调用 AutoVerifySign(ctx, false) 后自行启动 wechat.NewCertRefresher()
+// ctx 仅用于本次获取平台证书，不影响后台刷新
+func (c *ClientV3) AutoVerifySign(ctx context.Context, autoRefresh ...bool) (err error) {
+	if err = c.refreshCerts(ctx, true); err != nil {
 		return err
 	}
-	if len(c.SnCertMap) <= 0 {
-		c.SnCertMap = make(map[string]*rsa.PublicKey)
-	}
-	for sn, cert := range certMap {
-		// decode cert
-		pubKey, err := xpem.DecodePublicKey([]byte(cert))
-		if err != nil {
-			return err
-		}
-		c.SnCertMap[sn] = pubKey
-	}
-	c.WxSerialNo = wxSerialNo
-	c.wxPublicKey = c.SnCertMap[wxSerialNo]
 	if len(autoRefresh) == 1 && !autoRefresh[0] {
 		return
 	}
-	c.autoSign = true
-	go c.autoCheckCertProc()
+	if c.CertRefresher() == nil {
+		NewCertRefresher(c).Start()
+	}
 	return
 }
 
+// CertRefresher 返回运行中的平台证书刷新器，未启动时返回 nil
+func (c *ClientV3) CertRefresher() *CertRefresher {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.refresher
+}
+
+// Close 停止运行中的平台证书刷新器，可重复调用
+//
+//	Close 后客户端仍可正常请求、验签，平台证书不再定期更新
+func (c *ClientV3) Close() error {
+	c.refreshMu.Lock()
+	r := c.refresher
+	c.refresher = nil
+	c.refreshMu.Unlock()
+	if r != nil {
+		r.Stop()
+	}
+	return nil
+}
+
+// swapRefresher 设置运行中的刷新器，返回原刷新器
+func (c *ClientV3) swapRefresher(r *CertRefresher) (old *CertRefresher) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	old, c.refresher = c.refresher, r
+	return old
+}
+
+// clearRefresher r 为运行中的刷新器时清除
+func (c *ClientV3) clearRefresher(r *CertRefresher) {
+	c.refreshMu.Lock()
+	if c.refresher == r {
+		c.refresher = nil
+	}
+	c.refreshMu.Unlock()
+}
+
+// SetBaseURL 设置接口域名，如备用域名 https://api2.mch.weixin.qq.com，末尾不带 /
+// 用于出口代理、本地模拟服务（paytest）等，传空字符串恢复默认
+func (c *ClientV3) SetBaseURL(url string) {
+	c.baseURL = strings.TrimSuffix(url, "/")
+}
+
+func (c *ClientV3) apiURL(path string) string {
+	if c.baseURL != util.NULL {
+		return c.baseURL + path
+	}
+	return v3BaseUrlCh + path
+}
+
+// SetFailover 设置主域名 api.mch.weixin.qq.com 无法连接时是否自动切换备用域名 api2.mch.weixin.qq.com 重发请求，默认开启
+// 仅在 DNS 解析失败、拒绝连接等请求未发出的错误时切换，SetBaseURL() 设置的其他域名不受影响
+func (c *ClientV3) SetFailover(enable bool) {
+	c.noFailover = !enable
+}
+
+func (c *ClientV3) doer() xhttp.Doer {
+	if c.noFailover {
+		return c.hc
+	}
+	return xhttp.WithFailover(c.hc, v3BaseUrlCh, v3BaseUrlCh2)
+}
+
+// SetHTTPClient 设置自定义 *http.Client，默认使用 xhttp 共享连接池
+func (c *ClientV3) SetHTTPClient(hc *http.Client) {
+	if hc == nil {
+		c.hc = nil
+		return
+	}
+	c.hc = hc
+}
+
+// SetDoer 设置自定义 xhttp.Doer，用于链路追踪、代理、mock 等，传 nil 恢复默认
+func (c *ClientV3) SetDoer(d xhttp.Doer) {
+	c.hc = d
+}
+
+// SetTLSOptions 设置服务端证书校验配置，如自定义根证书、公钥固定，会替换 SetHTTPClient() 设置的客户端
+func (c *ClientV3) SetTLSOptions(o *xhttp.TLSOptions) {
+	c.hc = xhttp.NewHttpClient(&xhttp.TransportConfig{TLSConfig: xhttp.NewTLSConfig(o)})
+}
+
+// SetRetryPolicy 设置重试策略，默认不重试
+// GET 请求（查询、下载账单、获取平台证书等）按策略重试网络错误、5xx 及 SYSTEM_ERROR
+// 其余请求（下单、退款等）需通过 retry.WithIdempotent(ctx) 显式开启
+func (c *ClientV3) SetRetryPolicy(p *retry.Policy) {
+	c.retry = p
+}
+
+// SetLimiter 设置按接口族的限流、熔断，每次 http 请求（含重试）经过 l，接口标识同 observe.Call.Method
+//
+//	limiter := xhttp.NewLimiter(xhttp.BreakerConfig{Failures: 5},
+//		xhttp.Family{Name: "bill", Prefixes: []string{"GET /v3/bill/", "GET /v3/billdownload/"}, Rate: 1},
+//		xhttp.Family{Name: "marketing", Prefixes: []string{"POST /v3/marketing/"}, Rate: 20},
+//		xhttp.Family{Name: "transaction", Rate: 100},
+//	)
+//	client.SetLimiter(limiter)
+func (c *ClientV3) SetLimiter(l *xhttp.Limiter) {
+	c.limiter = l
+}
+
+func (c *ClientV3) retryPolicy(ctx context.Context, idempotent bool) *retry.Policy {
+	if idempotent || retry.IsIdempotent(ctx) {
+		return c.retry.Idempotent()
+	}
+	return nil
+}
+
+// 微信返回 SYSTEM_ERROR 时可重试
+func isSystemError(bs []byte) bool {
+	if !bytes.Contains(bs, []byte("SYSTEM_ERROR")) {
+		return false
+	}
+	var rsp struct {
+		Code string `json:"code"`
+	}
+	return json.Unmarshal(bs, &rsp) == nil && rsp.Code == "SYSTEM_ERROR"
+}
+
+// Use 添加中间件，用于指标统计、链路追踪等，见 observe 包，请在初始化时调用
+func (c *ClientV3) Use(mw ...observe.Middleware) {
+	c.mw = append(c.mw, mw...)
+}
+
+// SetLogger 设置当前客户端的结构化日志，日志携带 mchid 字段，并记录每次接口调用（见 observe.Logging）
+//
+//	调试日志（DebugSwitch 开启时）、平台证书自动刷新日志也输出到该 logger，可使用 xlog.NewSlogLogger() 接入 slog、zap、logrus
+func (c *ClientV3) SetLogger(l xlog.Logger) {
+	c.logger = xlog.With(l, "mchid", c.Mchid)
+}
+
+func (c *ClientV3) debugf(format string, args ...interface{}) {
+	c.logf(xlog.DebugLevel, format, args...)
+}
+
+func (c *ClientV3) logf(level xlog.LogLevel, format string, args ...interface{}) {
+	xlog.Logf(xlog.With(c.logger, "provider", pay.ProviderWechat), level, format, args...)
+}
+
+// outTradeNo 请求中的商户订单号，取自请求体或路径 /out-trade-no/{out_trade_no}，用于日志
+func outTradeNo(path string, bm pay.BodyMap) string {
+	if no := bm.GetString("out_trade_no"); no != util.NULL {
+		return no
+	}
+	const seg = "/out-trade-no/"
+	if i := strings.Index(path, seg); i >= 0 {
+		no := path[i+len(seg):]
+		if j := strings.IndexAny(no, "/?"); j >= 0 {
+			no = no[:j]
+		}
+		return no
+	}
+	return util.NULL
+}
+
+// do 经过中间件链，按 policy 发送请求，bm 为请求体，用于日志记录商户订单号
+func (c *ClientV3) do(ctx context.Context, method, path string, bm pay.BodyMap, policy *retry.Policy, send func(ctx context.Context) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	call := &observe.Call{Provider: pay.ProviderWechat, Method: observe.Endpoint(method, path), OutTradeNo: outTradeNo(path, bm)}
+	return c.mw.WithLogging(c.logger).DoHTTP(ctx, call, func(ctx context.Context) (*http.Response, []byte, error) {
+		res, bs, err := policy.DoHTTP(ctx, c.limiter.Wrap(call.Method, send), isSystemError)
+		if res != nil {
+			call.RequestID = res.Header.Get(HeaderRequestID)
+		}
+		return res, bs, err
+	}, resultCode)
+}
+
+// resign 包装 send，首次请求使用调用方生成的 authorization，重试时使用新的时间戳、随机串重新签名，避免被微信按重放拒绝
+//
+//	Request-ID 等其他请求头在重试时保持不变
+func (c *ClientV3) resign(hc *xhttp.Client, authorization, method, path string, signBm pay.BodyMap, send func(ctx context.Context) (*http.Response, []byte, error)) func(ctx context.Context) (*http.Response, []byte, error) {
+	attempt := 0
+	return func(ctx context.Context) (*http.Response, []byte, error) {
+		if attempt++; attempt > 1 {
+			auth, err := c.authorization(method, path, signBm)
+			if err != nil {
+				return nil, nil, err
+			}
+			authorization = auth
+		}
+		hc.Header.Set(HeaderAuthorization, authorization)
+		return send(ctx)
+	}
+}
+
+// resultCode 微信V3 错误码，HTTP 状态码非 2xx 时返回应答中的 code
+func resultCode(status int, bs []byte) string {
+	if status >= http.StatusOK && status < http.StatusMultipleChoices {
+		return util.NULL
+	}
+	var rsp struct {
+		Code string `json:"code"`
+	}
+	_ = json.Unmarshal(bs, &rsp)
+	return rsp.Code
+}
+
 // SetBodySize 设置http response body size(MB)
 func (c *ClientV3) SetBodySize(sizeMB int) {
 	if sizeMB > 0 {
@@ -102,23 +304,22 @@ func (c *ClientV3) SetBodySize(sizeMB int) {
 }
 
 func (c *ClientV3) doProdPostWithHeader(ctx context.Context, headerMap map[string]string, bm pay.BodyMap, path, authorization string) (res *http.Response, si *SignInfo, bs []byte, err error) {
-	var url = v3BaseUrlCh + path
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.doer())
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_V3_RequestBody: %s", bm.JsonBody())
-		xlog.Debugf("Wechat_V3_Authorization: %s", authorization)
+		c.debugf("Wechat_V3_RequestBody: %s", redact.String(bm.JsonBody()))
+		c.debugf("Wechat_V3_Authorization: %s", redact.Authorization(authorization))
 	}
 	for k, v := range headerMap {
 		httpClient.Header.Add(k, v)
 	}
-	httpClient.Header.Add(HeaderAuthorization, authorization)
 	httpClient.Header.Add(HeaderRequestID, fmt.Sprintf("%s-%d", util.RandomString(21), time.Now().Unix()))
 	httpClient.Header.Add(HeaderSerial, c.WxSerialNo)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Post(url).SendBodyMap(bm).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodPost, url, bm, c.retryPolicy(ctx, false), c.resign(httpClient, authorization, http.MethodPost, path, bm, httpClient.Type(xhttp.TypeJSON).Post(url).SendBodyMap(bm).EndBytes))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -127,31 +328,31 @@ func (c *ClientV3) doProdPostWithHeader(ctx context.Context, headerMap map[strin
 		HeaderNonce:     res.Header.Get(HeaderNonce),
 		HeaderSignature: res.Header.Get(HeaderSignature),
 		HeaderSerial:    res.Header.Get(HeaderSerial),
+		HeaderRequestId: res.Header.Get(HeaderRequestID),
 		SignBody:        string(bs),
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("Wechat_Headers: %#v", res.Header)
-		xlog.Debugf("Wechat_SignInfo: %#v", si)
+		c.debugf("Wechat_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("Wechat_Headers: %#v", redact.Header(res.Header))
+		c.debugf("Wechat_SignInfo: %#v", si.redacted())
 	}
 	return res, si, bs, nil
 }
 
 func (c *ClientV3) doProdPost(ctx context.Context, bm pay.BodyMap, path, authorization string) (res *http.Response, si *SignInfo, bs []byte, err error) {
-	var url = v3BaseUrlCh + path
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.doer())
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_V3_RequestBody: %s", bm.JsonBody())
-		xlog.Debugf("Wechat_V3_Authorization: %s", authorization)
+		c.debugf("Wechat_V3_RequestBody: %s", redact.String(bm.JsonBody()))
+		c.debugf("Wechat_V3_Authorization: %s", redact.Authorization(authorization))
 	}
-	httpClient.Header.Add(HeaderAuthorization, authorization)
 	httpClient.Header.Add(HeaderRequestID, fmt.Sprintf("%s-%d", util.RandomString(21), time.Now().Unix()))
 	httpClient.Header.Add(HeaderSerial, c.WxSerialNo)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Post(url).SendBodyMap(bm).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodPost, url, bm, c.retryPolicy(ctx, false), c.resign(httpClient, authorization, http.MethodPost, path, bm, httpClient.Type(xhttp.TypeJSON).Post(url).SendBodyMap(bm).EndBytes))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -160,31 +361,31 @@ func (c *ClientV3) doProdPost(ctx context.Context, bm pay.BodyMap, path, authori
 		HeaderNonce:     res.Header.Get(HeaderNonce),
 		HeaderSignature: res.Header.Get(HeaderSignature),
 		HeaderSerial:    res.Header.Get(HeaderSerial),
+		HeaderRequestId: res.Header.Get(HeaderRequestID),
 		SignBody:        string(bs),
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("Wechat_Headers: %#v", res.Header)
-		xlog.Debugf("Wechat_SignInfo: %#v", si)
+		c.debugf("Wechat_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("Wechat_Headers: %#v", redact.Header(res.Header))
+		c.debugf("Wechat_SignInfo: %#v", si.redacted())
 	}
 	return res, si, bs, nil
 }
 
 func (c *ClientV3) doProdGet(ctx context.Context, uri, authorization string) (res *http.Response, si *SignInfo, bs []byte, err error) {
-	var url = v3BaseUrlCh + uri
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(uri)
+	httpClient := xhttp.NewClient().SetDoer(c.doer())
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_V3_Url: %s", url)
-		xlog.Debugf("Wechat_V3_Authorization: %s", authorization)
+		c.debugf("Wechat_V3_Url: %s", redact.String(url))
+		c.debugf("Wechat_V3_Authorization: %s", redact.Authorization(authorization))
 	}
-	httpClient.Header.Add(HeaderAuthorization, authorization)
 	httpClient.Header.Add(HeaderRequestID, fmt.Sprintf("%s-%d", util.RandomString(21), time.Now().Unix()))
 	httpClient.Header.Add(HeaderSerial, c.WxSerialNo)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Get(url).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodGet, url, nil, c.retryPolicy(ctx, true), c.resign(httpClient, authorization, http.MethodGet, uri, nil, httpClient.Type(xhttp.TypeJSON).Get(url).EndBytes))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -193,31 +394,31 @@ func (c *ClientV3) doProdGet(ctx context.Context, uri, authorization string) (re
 		HeaderNonce:     res.Header.Get(HeaderNonce),
 		HeaderSignature: res.Header.Get(HeaderSignature),
 		HeaderSerial:    res.Header.Get(HeaderSerial),
+		HeaderRequestId: res.Header.Get(HeaderRequestID),
 		SignBody:        string(bs),
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("Wechat_Headers: %#v", res.Header)
-		xlog.Debugf("Wechat_SignInfo: %#v", si)
+		c.debugf("Wechat_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("Wechat_Headers: %#v", redact.Header(res.Header))
+		c.debugf("Wechat_SignInfo: %#v", si.redacted())
 	}
 	return res, si, bs, nil
 }
 
 func (c *ClientV3) doProdPut(ctx context.Context, bm pay.BodyMap, path, authorization string) (res *http.Response, si *SignInfo, bs []byte, err error) {
-	var url = v3BaseUrlCh + path
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.doer())
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_V3_RequestBody: %s", bm.JsonBody())
-		xlog.Debugf("Wechat_V3_Authorization: %s", authorization)
+		c.debugf("Wechat_V3_RequestBody: %s", redact.String(bm.JsonBody()))
+		c.debugf("Wechat_V3_Authorization: %s", redact.Authorization(authorization))
 	}
-	httpClient.Header.Add(HeaderAuthorization, authorization)
 	httpClient.Header.Add(HeaderRequestID, fmt.Sprintf("%s-%d", util.RandomString(21), time.Now().Unix()))
 	httpClient.Header.Add(HeaderSerial, c.WxSerialNo)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Put(url).SendBodyMap(bm).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodPut, url, bm, c.retryPolicy(ctx, false), c.resign(httpClient, authorization, http.MethodPut, path, bm, httpClient.Type(xhttp.TypeJSON).Put(url).SendBodyMap(bm).EndBytes))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -226,31 +427,31 @@ func (c *ClientV3) doProdPut(ctx context.Context, bm pay.BodyMap, path, authoriz
 		HeaderNonce:     res.Header.Get(HeaderNonce),
 		HeaderSignature: res.Header.Get(HeaderSignature),
 		HeaderSerial:    res.Header.Get(HeaderSerial),
+		HeaderRequestId: res.Header.Get(HeaderRequestID),
 		SignBody:        string(bs),
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("Wechat_Headers: %#v", res.Header)
-		xlog.Debugf("Wechat_SignInfo: %#v", si)
+		c.debugf("Wechat_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("Wechat_Headers: %#v", redact.Header(res.Header))
+		c.debugf("Wechat_SignInfo: %#v", si.redacted())
 	}
 	return res, si, bs, nil
 }
 
 func (c *ClientV3) doProdDelete(ctx context.Context, bm pay.BodyMap, path, authorization string) (res *http.Response, si *SignInfo, bs []byte, err error) {
-	var url = v3BaseUrlCh + path
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.doer())
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_V3_RequestBody: %s", bm.JsonBody())
-		xlog.Debugf("Wechat_V3_Authorization: %s", authorization)
+		c.debugf("Wechat_V3_RequestBody: %s", redact.String(bm.JsonBody()))
+		c.debugf("Wechat_V3_Authorization: %s", redact.Authorization(authorization))
 	}
-	httpClient.Header.Add(HeaderAuthorization, authorization)
 	httpClient.Header.Add(HeaderRequestID, fmt.Sprintf("%s-%d", util.RandomString(21), time.Now().Unix()))
 	httpClient.Header.Add(HeaderSerial, c.WxSerialNo)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Delete(url).SendBodyMap(bm).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodDelete, url, bm, c.retryPolicy(ctx, false), c.resign(httpClient, authorization, http.MethodDelete, path, bm, httpClient.Type(xhttp.TypeJSON).Delete(url).SendBodyMap(bm).EndBytes))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -259,31 +460,31 @@ func (c *ClientV3) doProdDelete(ctx context.Context, bm pay.BodyMap, path, autho
 		HeaderNonce:     res.Header.Get(HeaderNonce),
 		HeaderSignature: res.Header.Get(HeaderSignature),
 		HeaderSerial:    res.Header.Get(HeaderSerial),
+		HeaderRequestId: res.Header.Get(HeaderRequestID),
 		SignBody:        string(bs),
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("Wechat_Headers: %#v", res.Header)
-		xlog.Debugf("Wechat_SignInfo: %#v", si)
+		c.debugf("Wechat_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("Wechat_Headers: %#v", redact.Header(res.Header))
+		c.debugf("Wechat_SignInfo: %#v", si.redacted())
 	}
 	return res, si, bs, nil
 }
 
 func (c *ClientV3) doProdPostFile(ctx context.Context, bm pay.BodyMap, path, authorization string) (res *http.Response, si *SignInfo, bs []byte, err error) {
-	var url = v3BaseUrlCh + path
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.doer())
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_V3_RequestBody: %s", bm.GetString("meta"))
-		xlog.Debugf("Wechat_V3_Authorization: %s", authorization)
+		c.debugf("Wechat_V3_RequestBody: %s", redact.String(bm.GetString("meta")))
+		c.debugf("Wechat_V3_Authorization: %s", redact.Authorization(authorization))
 	}
-	httpClient.Header.Add(HeaderAuthorization, authorization)
 	httpClient.Header.Add(HeaderRequestID, fmt.Sprintf("%s-%d", util.RandomString(21), time.Now().Unix()))
 	httpClient.Header.Add(HeaderSerial, c.WxSerialNo)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeMultipartFormData).Post(url).SendMultipartBodyMap(bm).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodPost, url, bm, c.retryPolicy(ctx, false), c.resign(httpClient, authorization, http.MethodPost, path, bm.GetBodyMap("meta"), httpClient.Type(xhttp.TypeMultipartFormData).Post(url).SendMultipartBodyMap(bm).EndBytes))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -292,31 +493,31 @@ func (c *ClientV3) doProdPostFile(ctx context.Context, bm pay.BodyMap, path, aut
 		HeaderNonce:     res.Header.Get(HeaderNonce),
 		HeaderSignature: res.Header.Get(HeaderSignature),
 		HeaderSerial:    res.Header.Get(HeaderSerial),
+		HeaderRequestId: res.Header.Get(HeaderRequestID),
 		SignBody:        string(bs),
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("Wechat_Headers: %#v", res.Header)
-		xlog.Debugf("Wechat_SignInfo: %#v", si)
+		c.debugf("Wechat_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("Wechat_Headers: %#v", redact.Header(res.Header))
+		c.debugf("Wechat_SignInfo: %#v", si.redacted())
 	}
 	return res, si, bs, nil
 }
 
 func (c *ClientV3) doProdPatch(ctx context.Context, bm pay.BodyMap, path, authorization string) (res *http.Response, si *SignInfo, bs []byte, err error) {
-	var url = v3BaseUrlCh + path
-	httpClient := xhttp.NewClient()
+	var url = c.apiURL(path)
+	httpClient := xhttp.NewClient().SetDoer(c.doer())
 	if c.bodySize > 0 {
 		httpClient.SetBodySize(c.bodySize)
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_V3_RequestBody: %s", bm.JsonBody())
-		xlog.Debugf("Wechat_V3_Authorization: %s", authorization)
+		c.debugf("Wechat_V3_RequestBody: %s", redact.String(bm.JsonBody()))
+		c.debugf("Wechat_V3_Authorization: %s", redact.Authorization(authorization))
 	}
-	httpClient.Header.Add(HeaderAuthorization, authorization)
 	httpClient.Header.Add(HeaderRequestID, fmt.Sprintf("%s-%d", util.RandomString(21), time.Now().Unix()))
 	httpClient.Header.Add(HeaderSerial, c.WxSerialNo)
 	httpClient.Header.Add("Accept", "*/*")
-	res, bs, err = httpClient.Type(xhttp.TypeJSON).Patch(url).SendBodyMap(bm).EndBytes(ctx)
+	res, bs, err = c.do(ctx, http.MethodPatch, url, bm, c.retryPolicy(ctx, false), c.resign(httpClient, authorization, http.MethodPatch, path, bm, httpClient.Type(xhttp.TypeJSON).Patch(url).SendBodyMap(bm).EndBytes))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -325,12 +526,20 @@ func (c *ClientV3) doProdPatch(ctx context.Context, bm pay.BodyMap, path, author
 		HeaderNonce:     res.Header.Get(HeaderNonce),
 		HeaderSignature: res.Header.Get(HeaderSignature),
 		HeaderSerial:    res.Header.Get(HeaderSerial),
+		HeaderRequestId: res.Header.Get(HeaderRequestID),
 		SignBody:        string(bs),
 	}
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_Response: %d > %s", res.StatusCode, string(bs))
-		xlog.Debugf("Wechat_Headers: %#v", res.Header)
-		xlog.Debugf("Wechat_SignInfo: %#v", si)
+		c.debugf("Wechat_Response: %d > %s", res.StatusCode, redact.String(string(bs)))
+		c.debugf("Wechat_Headers: %#v", redact.Header(res.Header))
+		c.debugf("Wechat_SignInfo: %#v", si.redacted())
 	}
 	return res, si, bs, nil
 }
+
+// redacted 返回 SignBody 脱敏后的副本，用于调试日志
+func (s *SignInfo) redacted() SignInfo {
+	r := *s
+	r.SignBody = redact.String(r.SignBody)
+	return r
+}
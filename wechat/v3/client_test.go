@@ -79,7 +79,7 @@ func TestMain(m *testing.M) {
 	// client.SetPlatformCert([]byte(""), "")
 
 	// 启用自动同步返回验签，并定时更新微信平台API证书
-	err = client.AutoVerifySign()
+	err = client.AutoVerifySign(ctx)
 	if err != nil {
 		xlog.Error(err)
 		return
@@ -110,7 +110,7 @@ func TestGetPlatformCertsWithoutClient(t *testing.T) {
 }
 
 func TestGetAndSelectNewestCert(t *testing.T) {
-	serialNo, snCertMap, err := client.GetAndSelectNewestCert()
+	serialNo, snCertMap, err := client.GetAndSelectNewestCert(ctx)
 	if err != nil {
 		xlog.Error(err)
 		return
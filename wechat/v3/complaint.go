@@ -47,7 +47,7 @@ func (c *ClientV3) V3ComplaintNotifyUrlCreate(ctx context.Context, url string) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询投诉通知回调地址API
@@ -74,7 +74,7 @@ func (c *ClientV3) V3ComplaintNotifyUrlQuery(ctx context.Context) (wxRsp *Compla
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 更新投诉通知回调地址API
@@ -103,7 +103,7 @@ func (c *ClientV3) V3ComplaintNotifyUrlUpdate(ctx context.Context, url string) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 删除投诉通知回调地址API
@@ -126,7 +126,7 @@ func (c *ClientV3) V3ComplaintNotifyUrlDelete(ctx context.Context) (wxRsp *Empty
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 商户上传反馈图片API
@@ -161,7 +161,7 @@ func (c *ClientV3) V3ComplaintUploadImage(ctx context.Context, fileName, fileSha
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询投诉单列表API
@@ -189,7 +189,7 @@ func (c *ClientV3) V3ComplaintList(ctx context.Context, bm pay.BodyMap) (wxRsp *
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询投诉协商历史API
@@ -217,7 +217,7 @@ func (c *ClientV3) V3ComplaintNegotiationHistory(ctx context.Context, complaintI
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询投诉单详情API
@@ -245,7 +245,7 @@ func (c *ClientV3) V3ComplaintDetail(ctx context.Context, complaintId string) (w
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 提交回复API
@@ -269,7 +269,7 @@ func (c *ClientV3) V3ComplaintResponse(ctx context.Context, complaintId string,
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 反馈处理完成API
@@ -293,5 +293,5 @@ func (c *ClientV3) V3ComplaintComplete(ctx context.Context, complaintId string,
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -42,7 +42,7 @@ func (c *ClientV3) V3DiscountCardApply(ctx context.Context, bm pay.BodyMap) (wxR
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 增加用户记录API
@@ -69,7 +69,7 @@ func (c *ClientV3) V3DiscountCardAddUser(ctx context.Context, bm pay.BodyMap) (w
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询先享卡订单API
@@ -96,5 +96,5 @@ func (c *ClientV3) V3DiscountCardQuery(ctx context.Context, outCardCode string)
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -43,7 +43,7 @@ func (c *ClientV3) V3EcommerceApply(ctx context.Context, bm pay.BodyMap) (*Ecomm
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询申请状态API
@@ -79,7 +79,7 @@ func (c *ClientV3) V3EcommerceApplyStatus(ctx context.Context, applyId int64, ou
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 请求分账API
@@ -106,7 +106,7 @@ func (c *ClientV3) V3EcommerceProfitShare(ctx context.Context, bm pay.BodyMap) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询分账结果API
@@ -134,7 +134,7 @@ func (c *ClientV3) V3EcommerceProfitShareQuery(ctx context.Context, bm pay.BodyM
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 请求分账回退API
@@ -161,7 +161,7 @@ func (c *ClientV3) V3EcommerceProfitShareReturn(ctx context.Context, bm pay.Body
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询分账回退结果API
@@ -189,7 +189,7 @@ func (c *ClientV3) V3EcommerceProfitShareReturnResult(ctx context.Context, bm pa
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 完结分账API
@@ -216,7 +216,7 @@ func (c *ClientV3) V3EcommerceProfitShareFinish(ctx context.Context, bm pay.Body
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询订单剩余待分金额API
@@ -244,7 +244,7 @@ func (c *ClientV3) V3EcommerceProfitShareUnsplitAmount(ctx context.Context, tran
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 添加分账接收方API
@@ -271,7 +271,7 @@ func (c *ClientV3) V3EcommerceProfitShareAddReceiver(ctx context.Context, bm pay
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 删除分账接收方API
@@ -298,7 +298,7 @@ func (c *ClientV3) V3EcommerceProfitShareDeleteReceiver(ctx context.Context, bm
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 请求补差API
@@ -325,7 +325,7 @@ func (c *ClientV3) V3EcommerceSubsidies(ctx context.Context, bm pay.BodyMap) (*E
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 请求补差回退API
@@ -352,7 +352,7 @@ func (c *ClientV3) V3EcommerceSubsidiesReturn(ctx context.Context, bm pay.BodyMa
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 取消补差API
@@ -379,5 +379,5 @@ func (c *ClientV3) V3EcommerceSubsidiesCancel(ctx context.Context, bm pay.BodyMa
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
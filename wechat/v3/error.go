@@ -0,0 +1,93 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// ErrResponse 微信支付 v3 错误应答
+// 文档：https://pay.weixin.qq.com/wiki/doc/apiv3/wechatpay/wechatpay2_0.shtml
+type ErrResponse struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Detail  json.RawMessage `json:"detail,omitempty"`
+}
+
+// NewAPIError 将接口返回的 Code（HTTP 状态码）及 Error（应答内容）转换为 *pay.APIError
+//
+//	wxRsp, err := client.V3TransactionQueryOrder(ctx, wechat.OutTradeNo, outTradeNo)
+//	if err == nil && wxRsp.Code != wechat.Success {
+//		err = wechat.NewAPIErrorWithSignInfo(wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+//	}
+func NewAPIError(statusCode int, body string) *pay.APIError {
+	var rsp ErrResponse
+	msg := body
+	if json.Unmarshal([]byte(body), &rsp) == nil && rsp.Message != "" {
+		msg = rsp.Message
+	}
+	return pay.NewAPIError(pay.ProviderWechat, statusCode, rsp.Code, "", msg, errCategory(statusCode, rsp.Code))
+}
+
+// NewAPIErrorWithSignInfo 同 NewAPIError，RequestId 取自应答头 Request-ID（si.HeaderRequestId），si 可为 nil
+func NewAPIErrorWithSignInfo(statusCode int, body string, si *SignInfo) *pay.APIError {
+	e := NewAPIError(statusCode, body)
+	if si != nil {
+		e.RequestId = si.HeaderRequestId
+	}
+	return e
+}
+
+// 文档：https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_4_2.shtml 错误码
+var codeCategory = map[string]pay.ErrorCategory{
+	"ORDERPAID":             pay.CategoryOrderPaid,
+	"ORDER_PAID":            pay.CategoryOrderPaid,
+	"ORDER_CLOSED":          pay.CategoryOrderClosed,
+	"ORDER_NOT_EXIST":       pay.CategoryOrderNotExist,
+	"ORDERNOTEXIST":         pay.CategoryOrderNotExist,
+	"RESOURCE_NOT_EXISTS":   pay.CategoryOrderNotExist,
+	"NOTENOUGH":             pay.CategoryInsufficientFunds,
+	"NOT_ENOUGH":            pay.CategoryInsufficientFunds,
+	"FREQUENCY_LIMITED":     pay.CategoryThrottled,
+	"FREQUENCY_LIMIT":       pay.CategoryThrottled,
+	"RATELIMIT_EXCEEDED":    pay.CategoryThrottled,
+	"SIGN_ERROR":            pay.CategoryAuthFailed,
+	"NO_AUTH":               pay.CategoryAuthFailed,
+	"APPID_MCHID_NOT_MATCH": pay.CategoryAuthFailed,
+	"MCH_NOT_EXISTS":        pay.CategoryAuthFailed,
+	"PARAM_ERROR":           pay.CategoryInvalidParam,
+	"INVALID_REQUEST":       pay.CategoryInvalidParam,
+	"SYSTEM_ERROR":          pay.CategorySystemError,
+	"BANKERROR":             pay.CategorySystemError,
+}
+
+func errCategory(statusCode int, code string) pay.ErrorCategory {
+	if c, ok := codeCategory[code]; ok {
+		return c
+	}
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return pay.CategoryAuthFailed
+	case statusCode == http.StatusTooManyRequests:
+		return pay.CategoryThrottled
+	case statusCode == http.StatusBadRequest:
+		return pay.CategoryInvalidParam
+	case statusCode == http.StatusNotFound:
+		return pay.CategoryOrderNotExist
+	case statusCode >= http.StatusInternalServerError:
+		return pay.CategorySystemError
+	}
+	return pay.CategoryUnknown
+}
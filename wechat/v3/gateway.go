@@ -0,0 +1,264 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/util"
+)
+
+type gateway struct {
+	c     *ClientV3
+	appid string
+}
+
+// NewGateway 将微信支付 v3 ClientV3 适配为 pay.Gateway
+//
+//	appid：下单使用的公众号/小程序/移动应用 appid
+//	SceneQRCode：Native下单，SceneJSAPI：JSAPI/小程序下单，SceneApp：APP下单，SceneWap：H5下单
+func NewGateway(client *ClientV3, appid string) pay.Gateway {
+	return &gateway{c: client, appid: appid}
+}
+
+func (g *gateway) Provider() string {
+	return pay.ProviderWechat
+}
+
+func (g *gateway) Charge(ctx context.Context, o *pay.Order) (*pay.ChargeResult, error) {
+	description := o.Body
+	if description == util.NULL {
+		description = o.Subject
+	}
+	bm := make(pay.BodyMap)
+	bm.Set("appid", g.appid).
+		Set("description", description).
+		Set("out_trade_no", o.OutTradeNo).
+		Set("notify_url", o.NotifyUrl).
+		SetBodyMap("amount", func(b pay.BodyMap) {
+			b.Set("total", o.Amount).
+				Set("currency", currencyOrCNY(o.Currency))
+		})
+	rs := &pay.ChargeResult{OutTradeNo: o.OutTradeNo}
+	switch o.Scene {
+	case pay.SceneQRCode:
+		bm.Merge(o.Extra)
+		wxRsp, err := g.c.V3TransactionNative(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		if wxRsp.Code != Success {
+			return nil, gatewayErr("V3TransactionNative", wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+		}
+		rs.CodeUrl, rs.Raw = wxRsp.Response.CodeUrl, wxRsp
+	case pay.SceneJSAPI:
+		bm.SetBodyMap("payer", func(b pay.BodyMap) {
+			b.Set("openid", o.OpenId)
+		}).Merge(o.Extra)
+		wxRsp, err := g.c.V3TransactionJsapi(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		if wxRsp.Code != Success {
+			return nil, gatewayErr("V3TransactionJsapi", wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+		}
+		jsapi, err := g.c.PaySignOfJSAPI(bm.GetString("appid"), wxRsp.Response.PrepayId)
+		if err != nil {
+			return nil, err
+		}
+		bs, _ := json.Marshal(jsapi)
+		rs.PrepayId, rs.PayParams, rs.Raw = wxRsp.Response.PrepayId, string(bs), wxRsp
+	case pay.SceneApp:
+		bm.Merge(o.Extra)
+		wxRsp, err := g.c.V3TransactionApp(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		if wxRsp.Code != Success {
+			return nil, gatewayErr("V3TransactionApp", wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+		}
+		app, err := g.c.PaySignOfApp(bm.GetString("appid"), wxRsp.Response.PrepayId)
+		if err != nil {
+			return nil, err
+		}
+		bs, _ := json.Marshal(app)
+		rs.PrepayId, rs.PayParams, rs.Raw = wxRsp.Response.PrepayId, string(bs), wxRsp
+	case pay.SceneWap:
+		bm.SetBodyMap("scene_info", func(b pay.BodyMap) {
+			b.Set("payer_client_ip", o.ClientIp).
+				SetBodyMap("h5_info", func(b pay.BodyMap) {
+					b.Set("type", "Wap")
+				})
+		}).Merge(o.Extra)
+		wxRsp, err := g.c.V3TransactionH5(ctx, bm)
+		if err != nil {
+			return nil, err
+		}
+		if wxRsp.Code != Success {
+			return nil, gatewayErr("V3TransactionH5", wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+		}
+		rs.PayUrl, rs.Raw = wxRsp.Response.H5Url, wxRsp
+	default:
+		return nil, fmt.Errorf("[%w]: wechat v3 scene %q", pay.UnsupportedErr, o.Scene)
+	}
+	return rs, nil
+}
+
+func (g *gateway) Query(ctx context.Context, q *pay.OrderQuery) (*pay.OrderResult, error) {
+	orderNoType, orderNo := OutTradeNo, q.OutTradeNo
+	if q.TradeNo != util.NULL {
+		orderNoType, orderNo = TransactionId, q.TradeNo
+	}
+	wxRsp, err := g.c.V3TransactionQueryOrder(ctx, orderNoType, orderNo)
+	if err != nil {
+		return nil, err
+	}
+	if wxRsp.Code != Success {
+		return nil, gatewayErr("V3TransactionQueryOrder", wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+	}
+	r := wxRsp.Response
+	rs := &pay.OrderResult{
+		OutTradeNo: r.OutTradeNo,
+		TradeNo:    r.TransactionId,
+		Status:     tradeState(r.TradeState),
+		RawStatus:  r.TradeState,
+		Currency:   "CNY",
+		Raw:        wxRsp,
+	}
+	if r.Amount != nil {
+		rs.Amount = int64(r.Amount.Total)
+		rs.Currency = currencyOrCNY(r.Amount.Currency)
+	}
+	return rs, nil
+}
+
+func (g *gateway) Close(ctx context.Context, q *pay.OrderQuery) error {
+	if q.OutTradeNo == util.NULL {
+		return fmt.Errorf("[%w], %v", pay.MissParamErr, "out_trade_no")
+	}
+	wxRsp, err := g.c.V3TransactionCloseOrder(ctx, q.OutTradeNo)
+	if err != nil {
+		return err
+	}
+	if wxRsp.Code != Success {
+		return gatewayErr("V3TransactionCloseOrder", wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+	}
+	return nil
+}
+
+func (g *gateway) Refund(ctx context.Context, r *pay.Refund) (*pay.RefundResult, error) {
+	bm := make(pay.BodyMap)
+	if r.TradeNo != util.NULL {
+		bm.Set("transaction_id", r.TradeNo)
+	} else {
+		bm.Set("out_trade_no", r.OutTradeNo)
+	}
+	bm.Set("out_refund_no", r.OutRefundNo).
+		SetBodyMap("amount", func(b pay.BodyMap) {
+			b.Set("refund", r.Amount).
+				Set("total", r.Total).
+				Set("currency", currencyOrCNY(r.Currency))
+		})
+	if r.Reason != util.NULL {
+		bm.Set("reason", r.Reason)
+	}
+	if r.NotifyUrl != util.NULL {
+		bm.Set("notify_url", r.NotifyUrl)
+	}
+	bm.Merge(r.Extra)
+	wxRsp, err := g.c.V3Refund(ctx, bm)
+	if err != nil {
+		return nil, err
+	}
+	if wxRsp.Code != Success {
+		return nil, gatewayErr("V3Refund", wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+	}
+	return refundResult((*RefundQueryResponse)(wxRsp.Response), wxRsp), nil
+}
+
+func (g *gateway) QueryRefund(ctx context.Context, q *pay.RefundQuery) (*pay.RefundResult, error) {
+	if q.OutRefundNo == util.NULL {
+		return nil, fmt.Errorf("[%w], %v", pay.MissParamErr, "out_refund_no")
+	}
+	wxRsp, err := g.c.V3RefundQuery(ctx, q.OutRefundNo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if wxRsp.Code != Success {
+		return nil, gatewayErr("V3RefundQuery", wxRsp.Code, wxRsp.Error, wxRsp.SignInfo)
+	}
+	return refundResult(wxRsp.Response, wxRsp), nil
+}
+
+func refundResult(r *RefundQueryResponse, raw interface{}) *pay.RefundResult {
+	rs := &pay.RefundResult{
+		OutTradeNo:  r.OutTradeNo,
+		TradeNo:     r.TransactionId,
+		OutRefundNo: r.OutRefundNo,
+		RefundNo:    r.RefundId,
+		Status:      refundStatus(r.Status),
+		RawStatus:   r.Status,
+		Currency:    "CNY",
+		Raw:         raw,
+	}
+	if r.Amount != nil {
+		rs.Amount = int64(r.Amount.Refund)
+		rs.Currency = currencyOrCNY(r.Amount.Currency)
+	}
+	return rs
+}
+
+func tradeState(state string) pay.TradeStatus {
+	switch state {
+	case "NOTPAY", "USERPAYING":
+		return pay.TradeStatusPending
+	case "SUCCESS":
+		return pay.TradeStatusPaid
+	case "CLOSED", "REVOKED":
+		return pay.TradeStatusClosed
+	case "REFUND":
+		return pay.TradeStatusRefunded
+	case "PAYERROR":
+		return pay.TradeStatusFailed
+	default:
+		return pay.TradeStatusUnknown
+	}
+}
+
+func refundStatus(status string) pay.RefundStatus {
+	switch status {
+	case "PROCESSING":
+		return pay.RefundStatusProcessing
+	case "SUCCESS":
+		return pay.RefundStatusSuccess
+	case "CLOSED":
+		return pay.RefundStatusClosed
+	case "ABNORMAL":
+		return pay.RefundStatusFailed
+	default:
+		return pay.RefundStatusUnknown
+	}
+}
+
+func currencyOrCNY(currency string) string {
+	if currency == util.NULL {
+		return "CNY"
+	}
+	return currency
+}
+
+func gatewayErr(api string, code int, body string, si *SignInfo) error {
+	return fmt.Errorf("wechat v3 %s failed: %w", api, NewAPIErrorWithSignInfo(code, body, si))
+}
@@ -42,7 +42,7 @@ func (c *ClientV3) V3GoldPlanManage(ctx context.Context, bm pay.BodyMap) (wxRsp
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 商家小票管理API
@@ -68,7 +68,7 @@ func (c *ClientV3) V3GoldPlanBillManage(ctx context.Context, bm pay.BodyMap) (wx
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 同业过滤标签管理API
@@ -90,7 +90,7 @@ func (c *ClientV3) V3GoldPlanFilterManage(ctx context.Context, bm pay.BodyMap) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 开通广告展示API
@@ -112,7 +112,7 @@ func (c *ClientV3) V3GoldPlanOpenAdShow(ctx context.Context, bm pay.BodyMap) (wx
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 关闭广告展示API
@@ -134,5 +134,5 @@ func (c *ClientV3) V3GoldPlanCloseAdShow(ctx context.Context, bm pay.BodyMap) (w
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -0,0 +1,133 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/idempotency"
+)
+
+// 渠道已返回 4xx，请求未生效
+var errRejected = errors.New("wechat: request rejected")
+
+// SetIdempotency 开启退款（V3Refund）、商家转账（V3Transfer）的幂等保护，g 为 nil 时关闭
+//
+//	按 mchid + out_refund_no / out_batch_no 记录结果，已成功的请求再次调用直接返回保存的结果（不含 SignInfo）
+//	请求超时等结果未知时，再次调用先按商户单号查询，已受理则直接返回，否则重新发起
+//	client.SetIdempotency(idempotency.New(idempotency.NewMemoryStore()))
+func (c *ClientV3) SetIdempotency(g *idempotency.Guard) {
+	c.idempotency = g
+}
+
+// wxOutcome 将应答转换为 idempotency.Operation.Create 的结果：200 成功，4xx 渠道已拒绝，其余结果未知
+func wxOutcome(code int, err error) error {
+	switch {
+	case err != nil:
+		return err
+	case code == Success:
+		return nil
+	case code < http.StatusInternalServerError:
+		return idempotency.Rejected(errRejected)
+	}
+	return fmt.Errorf("http status code: %d", code)
+}
+
+func (c *ClientV3) idempotentRefund(ctx context.Context, bm pay.BodyMap) (wxRsp *RefundRsp, err error) {
+	var (
+		outRefundNo = bm.GetString("out_refund_no")
+		stored      = new(RefundOrderResponse)
+		created     bool
+		rspErr      error
+	)
+	status, err := c.idempotency.Do(ctx, idempotency.Key(pay.ProviderWechat, c.Mchid, "refund", outRefundNo), stored, idempotency.Operation{
+		Create: func(ctx context.Context) (interface{}, error) {
+			created = true
+			wxRsp, rspErr = c.v3Refund(ctx, bm)
+			if rspErr != nil {
+				return nil, rspErr
+			}
+			return wxRsp.Response, wxOutcome(wxRsp.Code, nil)
+		},
+		Query: func(ctx context.Context) (interface{}, bool, error) {
+			var qm pay.BodyMap
+			if subMchid := bm.GetString("sub_mchid"); subMchid != pay.NULL {
+				qm = pay.BodyMap{"sub_mchid": subMchid}
+			}
+			q, err := c.V3RefundQuery(ctx, outRefundNo, qm)
+			if err != nil {
+				return nil, false, err
+			}
+			switch q.Code {
+			case Success:
+				rsp := RefundOrderResponse(*q.Response)
+				wxRsp = &RefundRsp{Code: Success, SignInfo: q.SignInfo, Response: &rsp}
+				return wxRsp.Response, true, nil
+			case http.StatusNotFound:
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("query refund %s: http status code: %d, %s", outRefundNo, q.Code, q.Error)
+		},
+	})
+	switch {
+	case status == idempotency.Replayed:
+		return &RefundRsp{Code: Success, Response: stored}, nil
+	case created:
+		return wxRsp, rspErr
+	}
+	return wxRsp, err
+}
+
+func (c *ClientV3) idempotentTransfer(ctx context.Context, bm pay.BodyMap) (wxRsp *TransferRsp, err error) {
+	var (
+		outBatchNo = bm.GetString("out_batch_no")
+		stored     = new(Transfer)
+		created    bool
+		rspErr     error
+	)
+	status, err := c.idempotency.Do(ctx, idempotency.Key(pay.ProviderWechat, c.Mchid, "transfer", outBatchNo), stored, idempotency.Operation{
+		Create: func(ctx context.Context) (interface{}, error) {
+			created = true
+			wxRsp, rspErr = c.v3Transfer(ctx, bm)
+			if rspErr != nil {
+				return nil, rspErr
+			}
+			return wxRsp.Response, wxOutcome(wxRsp.Code, nil)
+		},
+		Query: func(ctx context.Context) (interface{}, bool, error) {
+			q, err := c.V3TransferMerchantQuery(ctx, outBatchNo, pay.BodyMap{"need_query_detail": false})
+			if err != nil {
+				return nil, false, err
+			}
+			switch {
+			case q.Code == Success && q.Response.TransferBatch != nil:
+				batch := q.Response.TransferBatch
+				wxRsp = &TransferRsp{Code: Success, SignInfo: q.SignInfo, Response: &Transfer{OutBatchNo: batch.OutBatchNo, BatchId: batch.BatchId, CreateTime: batch.CreateTime}}
+				return wxRsp.Response, true, nil
+			case q.Code == http.StatusNotFound:
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("query transfer %s: http status code: %d, %s", outBatchNo, q.Code, q.Error)
+		},
+	})
+	switch {
+	case status == idempotency.Replayed:
+		return &TransferRsp{Code: Success, Response: stored}, nil
+	case created:
+		return wxRsp, rspErr
+	}
+	return wxRsp, err
+}
@@ -43,7 +43,7 @@ func (c *ClientV3) V3BusiFavorBatchCreate(ctx context.Context, bm pay.BodyMap) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询商家券详情
@@ -71,7 +71,7 @@ func (c *ClientV3) V3BusiFavorBatchDetail(ctx context.Context, stockId string) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 核销用户券
@@ -98,7 +98,7 @@ func (c *ClientV3) V3BusiFavorUse(ctx context.Context, bm pay.BodyMap) (wxRsp *B
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 根据过滤条件查询用户券
@@ -126,7 +126,7 @@ func (c *ClientV3) V3BusiFavorUserCoupons(ctx context.Context, openid string, bm
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询用户单张券详情
@@ -154,7 +154,7 @@ func (c *ClientV3) V3BusiFavorUserCouponDetail(ctx context.Context, openid, coup
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 上传预存code
@@ -182,7 +182,7 @@ func (c *ClientV3) V3BusiFavorCodeUpload(ctx context.Context, stockId string, bm
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 设置商家券事件通知地址
@@ -209,7 +209,7 @@ func (c *ClientV3) V3BusiFavorCallbackUrlSet(ctx context.Context, bm pay.BodyMap
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询商家券事件通知地址
@@ -237,7 +237,7 @@ func (c *ClientV3) V3BusiFavorCallbackUrl(ctx context.Context, mchid string) (wx
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 关联订单信息
@@ -264,7 +264,7 @@ func (c *ClientV3) V3BusiFavorAssociate(ctx context.Context, bm pay.BodyMap) (wx
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 取消关联订单信息
@@ -291,7 +291,7 @@ func (c *ClientV3) V3BusiFavorDisassociate(ctx context.Context, bm pay.BodyMap)
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 修改批次预算
@@ -319,7 +319,7 @@ func (c *ClientV3) V3BusiFavorBatchUpdate(ctx context.Context, stockId string, b
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 修改商家券基本信息
@@ -343,7 +343,7 @@ func (c *ClientV3) V3BusiFavorInfoUpdate(ctx context.Context, stockId string, bm
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 发放消费卡
@@ -370,7 +370,7 @@ func (c *ClientV3) V3BusiFavorSend(ctx context.Context, cardId string, bm pay.Bo
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 申请退券
@@ -397,7 +397,7 @@ func (c *ClientV3) V3BusiFavorReturn(ctx context.Context, bm pay.BodyMap) (wxRsp
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 使券失效
@@ -424,7 +424,7 @@ func (c *ClientV3) V3BusiFavorDeactivate(ctx context.Context, bm pay.BodyMap) (w
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 营销补差付款
@@ -451,7 +451,7 @@ func (c *ClientV3) V3BusiFavorSubsidyPay(ctx context.Context, bm pay.BodyMap) (w
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询营销补差付款单详情
@@ -479,5 +479,5 @@ func (c *ClientV3) V3BusiFavorSubsidyPayDetail(ctx context.Context, subsidyRecei
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
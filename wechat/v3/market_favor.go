@@ -45,7 +45,7 @@ func (c *ClientV3) V3FavorBatchCreate(ctx context.Context, bm pay.BodyMap) (wxRs
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 发放代金券批次
@@ -73,7 +73,7 @@ func (c *ClientV3) V3FavorBatchGrant(ctx context.Context, openid string, bm pay.
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 激活代金券批次
@@ -103,7 +103,7 @@ func (c *ClientV3) V3FavorBatchStart(ctx context.Context, stockId, stockCreatorM
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 条件查询批次列表
@@ -131,7 +131,7 @@ func (c *ClientV3) V3FavorBatchList(ctx context.Context, bm pay.BodyMap) (wxRsp
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询批次详情
@@ -159,7 +159,7 @@ func (c *ClientV3) V3FavorBatchDetail(ctx context.Context, stockId, stockCreator
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询代金券详情
@@ -187,7 +187,7 @@ func (c *ClientV3) V3FavorDetail(ctx context.Context, appid, couponId, openid st
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询代金券可用商户
@@ -218,7 +218,7 @@ func (c *ClientV3) V3FavorMerchant(ctx context.Context, stockId, stockCreatorMch
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询代金券可用单品
@@ -249,7 +249,7 @@ func (c *ClientV3) V3FavorItems(ctx context.Context, stockId, stockCreatorMchid
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 根据商户号查用户的券
@@ -277,7 +277,7 @@ func (c *ClientV3) V3FavorUserCoupons(ctx context.Context, openid string, bm pay
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 下载批次核销明细
@@ -305,7 +305,7 @@ func (c *ClientV3) V3FavorUseFlowDownload(ctx context.Context, stockId string) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 下载批次退款明细
@@ -333,7 +333,7 @@ func (c *ClientV3) V3FavorRefundFlowDownload(ctx context.Context, stockId string
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 设置消息通知地址
@@ -360,7 +360,7 @@ func (c *ClientV3) V3FavorCallbackUrlSet(ctx context.Context, bm pay.BodyMap) (w
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 暂停代金券批次
@@ -390,7 +390,7 @@ func (c *ClientV3) V3FavorBatchPause(ctx context.Context, stockId, stockCreatorM
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 重启代金券批次
@@ -420,5 +420,5 @@ func (c *ClientV3) V3FavorBatchRestart(ctx context.Context, stockId, stockCreato
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
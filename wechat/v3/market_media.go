@@ -53,5 +53,5 @@ func (c *ClientV3) V3FavorMediaUploadImage(ctx context.Context, fileName, fileSh
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -43,7 +43,7 @@ func (c *ClientV3) V3PartnershipsBuild(ctx context.Context, idempotencyKey strin
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 终止合作关系
@@ -70,7 +70,7 @@ func (c *ClientV3) V3PartnershipsTerminate(ctx context.Context, idempotencyKey s
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询合作关系列表
@@ -98,5 +98,5 @@ func (c *ClientV3) V3PartnershipsList(ctx context.Context, bm pay.BodyMap) (wxRs
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -53,7 +53,7 @@ func (c *ClientV3) V3MediaUploadImage(ctx context.Context, fileName, fileSha256
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 视频上传API
@@ -88,5 +88,5 @@ func (c *ClientV3) V3MediaUploadVideo(ctx context.Context, fileName, fileSha256
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
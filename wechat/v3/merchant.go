@@ -46,7 +46,7 @@ func (c *ClientV3) V3EcommerceBalance(ctx context.Context, subMchid string, bm p
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询二级商户账户日终余额
@@ -75,7 +75,7 @@ func (c *ClientV3) V3EcommerceDayBalance(ctx context.Context, subMchid, date str
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询账户实时余额
@@ -104,7 +104,7 @@ func (c *ClientV3) V3MerchantBalance(ctx context.Context, accountType string) (*
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询账户日终余额
@@ -134,7 +134,7 @@ func (c *ClientV3) V3MerchantDayBalance(ctx context.Context, accountType, date s
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 特约商户银行来账查询API
@@ -162,7 +162,7 @@ func (c *ClientV3) V3EcommerceIncomeRecord(ctx context.Context, bm pay.BodyMap)
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 商户/服务商银行来账查询API
@@ -190,5 +190,5 @@ func (c *ClientV3) V3MerchantIncomeRecord(ctx context.Context, bm pay.BodyMap) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
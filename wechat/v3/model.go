@@ -1044,6 +1044,7 @@ type SignInfo struct {
 	HeaderNonce     string `json:"Wechatpay-Nonce"`
 	HeaderSignature string `json:"Wechatpay-Signature"`
 	HeaderSerial    string `json:"Wechatpay-Serial"`
+	HeaderRequestId string `json:"Request-ID"` // 微信返回的请求 ID，用于联系微信排查
 	SignBody        string `json:"sign_body"`
 }
 
@@ -48,7 +48,7 @@ func (c *ClientV3) V3TransactionApp(ctx context.Context, bm pay.BodyMap) (wxRsp
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // JSAPI/小程序下单API
@@ -78,7 +78,7 @@ func (c *ClientV3) V3TransactionJsapi(ctx context.Context, bm pay.BodyMap) (wxRs
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // Native下单API
@@ -107,7 +107,7 @@ func (c *ClientV3) V3TransactionNative(ctx context.Context, bm pay.BodyMap) (wxR
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // H5下单API
@@ -136,7 +136,7 @@ func (c *ClientV3) V3TransactionH5(ctx context.Context, bm pay.BodyMap) (wxRsp *
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询订单API
@@ -172,7 +172,7 @@ func (c *ClientV3) V3TransactionQueryOrder(ctx context.Context, orderNoType Orde
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 关闭订单API
@@ -198,5 +198,5 @@ func (c *ClientV3) V3TransactionCloseOrder(ctx context.Context, tradeNo string)
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -49,7 +49,7 @@ func (c *ClientV3) V3CombineTransactionApp(ctx context.Context, bm pay.BodyMap)
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 合单JSAPI/小程序下单API
@@ -83,7 +83,7 @@ func (c *ClientV3) V3CombineTransactionJsapi(ctx context.Context, bm pay.BodyMap
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 合单Native下单API
@@ -114,7 +114,7 @@ func (c *ClientV3) V3CombineTransactionNative(ctx context.Context, bm pay.BodyMa
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 合单H5下单API
@@ -143,7 +143,7 @@ func (c *ClientV3) V3CombineTransactionH5(ctx context.Context, bm pay.BodyMap) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 合单查询订单API
@@ -173,7 +173,7 @@ func (c *ClientV3) V3CombineQueryOrder(ctx context.Context, traderNo string) (wx
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 合单关闭订单API
@@ -199,5 +199,5 @@ func (c *ClientV3) V3CombineCloseOrder(ctx context.Context, tradeNo string, bm p
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
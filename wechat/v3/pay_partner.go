@@ -49,7 +49,7 @@ func (c *ClientV3) V3PartnerTransactionApp(ctx context.Context, bm pay.BodyMap)
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // （服务商、电商模式）JSAPI/小程序下单API
@@ -81,7 +81,7 @@ func (c *ClientV3) V3PartnerTransactionJsapi(ctx context.Context, bm pay.BodyMap
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // （服务商、电商模式）Native下单API
@@ -111,7 +111,7 @@ func (c *ClientV3) V3PartnerTransactionNative(ctx context.Context, bm pay.BodyMa
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // （服务商模式）H5下单API
@@ -140,7 +140,7 @@ func (c *ClientV3) V3PartnerTransactionH5(ctx context.Context, bm pay.BodyMap) (
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // （服务商、电商模式）查询订单API
@@ -180,7 +180,7 @@ func (c *ClientV3) V3PartnerQueryOrder(ctx context.Context, orderNoType OrderNoT
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // （服务商、电商模式）关单API
@@ -208,5 +208,5 @@ func (c *ClientV3) V3PartnerCloseOrder(ctx context.Context, tradeNo string, bm p
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -45,7 +45,7 @@ func (c *ClientV3) V3ProfitShareOrder(ctx context.Context, bm pay.BodyMap) (*Pro
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询分账结果API
@@ -74,7 +74,7 @@ func (c *ClientV3) V3ProfitShareOrderQuery(ctx context.Context, orderNo string,
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 请求分账回退API
@@ -102,7 +102,7 @@ func (c *ClientV3) V3ProfitShareReturn(ctx context.Context, bm pay.BodyMap) (*Pr
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询分账回退结果API
@@ -131,7 +131,7 @@ func (c *ClientV3) V3ProfitShareReturnResult(ctx context.Context, returnNo strin
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 解冻剩余资金API
@@ -159,7 +159,7 @@ func (c *ClientV3) V3ProfitShareOrderUnfreeze(ctx context.Context, bm pay.BodyMa
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询剩余待分金额API
@@ -188,7 +188,7 @@ func (c *ClientV3) V3ProfitShareUnsplitAmount(ctx context.Context, transId strin
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询最大分账比例API
@@ -216,7 +216,7 @@ func (c *ClientV3) V3ProfitShareMerchantConfigs(ctx context.Context, subMchId st
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 新增分账接收方API
@@ -244,7 +244,7 @@ func (c *ClientV3) V3ProfitShareAddReceiver(ctx context.Context, bm pay.BodyMap)
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 删除分账接收方API
@@ -272,7 +272,7 @@ func (c *ClientV3) V3ProfitShareDeleteReceiver(ctx context.Context, bm pay.BodyM
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 申请分账账单
@@ -301,5 +301,5 @@ func (c *ClientV3) V3ProfitShareBills(ctx context.Context, bm pay.BodyMap) (*Pro
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
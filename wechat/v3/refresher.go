@@ -0,0 +1,250 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rwscode/payutil/pkg/xlog"
+)
+
+// CertRefresher 微信平台证书刷新器，按间隔（随机抖动）定时刷新客户端的平台证书
+//
+//	Start() 启动后台刷新并开启客户端自动验签，Stop() 或 client.Close() 停止并等待后台协程退出
+//	同步验签遇到未知的 Wechatpay-Serial 时调用 Refresh() 立即刷新，并发调用只请求一次
+//	字段需在 Start() 前设置
+type CertRefresher struct {
+	Interval      time.Duration   // 刷新间隔，默认 12 小时
+	Jitter        float64         // 间隔随机抖动比例（0~1），默认 0.1，即 ±10%，避免多个客户端同时刷新
+	RetryInterval time.Duration   // 刷新失败后的重试间隔，默认 1 分钟
+	MinInterval   time.Duration   // Refresh() 距上次成功刷新小于该间隔时不再请求，默认 1 分钟
+	Timeout       time.Duration   // 单次刷新超时，默认 1 分钟
+	OnError       func(err error) // 刷新失败回调，用于告警、指标统计，勿在回调中调用 Stop()
+
+	c        *ClientV3
+	mu       sync.Mutex
+	ctx      context.Context // 后台刷新的 ctx，Stop() 时取消，未启动时为空
+	cancel   context.CancelFunc
+	done     chan struct{}
+	kick     chan struct{}
+	inflight *refreshCall
+	stats    CertRefreshStats
+}
+
+// CertRefreshStats 平台证书刷新统计
+type CertRefreshStats struct {
+	Refreshes   int64     // 成功次数
+	Failures    int64     // 失败次数
+	LastRefresh time.Time // 最近一次成功时间
+	LastError   error     // 最近一次失败原因，成功后清空
+}
+
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewCertRefresher 初始化客户端的平台证书刷新器
+func NewCertRefresher(c *ClientV3) *CertRefresher {
+	return &CertRefresher{
+		Interval:      12 * time.Hour,
+		Jitter:        0.1,
+		RetryInterval: time.Minute,
+		MinInterval:   time.Minute,
+		Timeout:       time.Minute,
+		c:             c,
+		kick:          make(chan struct{}, 1),
+	}
+}
+
+// Start 启动后台刷新并开启客户端自动验签，已启动时忽略
+//
+//	客户端已有其他运行中的刷新器时，停止原刷新器
+func (r *CertRefresher) Start() {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.ctx, r.cancel, r.done = ctx, cancel, make(chan struct{})
+	go r.run(ctx, r.done)
+	r.mu.Unlock()
+
+	r.c.rwMu.Lock()
+	r.c.autoSign = true
+	r.c.rwMu.Unlock()
+	if old := r.c.swapRefresher(r); old != nil && old != r {
+		old.Stop()
+	}
+}
+
+// Stop 停止后台刷新并等待后台协程退出，可重复调用
+func (r *CertRefresher) Stop() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.ctx, r.cancel, r.done = nil, nil, nil
+	r.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+	// 等待进行中的刷新退出
+	r.mu.Lock()
+	call := r.inflight
+	r.mu.Unlock()
+	if call != nil {
+		<-call.done
+	}
+	r.c.clearRefresher(r)
+}
+
+// Refresh 立即刷新平台证书，并从当前时间重新计算下次定时刷新
+//
+//	并发调用只请求一次，距上次成功刷新小于 MinInterval 时直接返回
+//	ctx 取消时本次调用返回 ctx.Err()，进行中的刷新继续执行，不影响其他调用方
+func (r *CertRefresher) Refresh(ctx context.Context) error {
+	r.mu.Lock()
+	recent := !r.stats.LastRefresh.IsZero() && time.Since(r.stats.LastRefresh) < r.MinInterval
+	r.mu.Unlock()
+	if recent {
+		return nil
+	}
+	err := r.refresh(ctx)
+	select {
+	case r.kick <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// Stats 返回刷新统计
+func (r *CertRefresher) Stats() CertRefreshStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+func (r *CertRefresher) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	timer := time.NewTimer(r.next(nil))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.kick:
+			// Refresh() 已刷新，重新计时
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(r.next(r.Stats().LastError))
+			continue
+		case <-timer.C:
+		}
+		err := r.refresh(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		timer.Reset(r.next(err))
+	}
+}
+
+// refresh 执行一次刷新，并发调用等待同一次结果
+//
+//	刷新在刷新器自身的 ctx 上执行，ctx 仅控制调用方的等待，某个调用方取消不影响其他等待者
+func (r *CertRefresher) refresh(ctx context.Context) error {
+	r.mu.Lock()
+	call := r.inflight
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		r.inflight = call
+		fetchCtx := r.ctx
+		if fetchCtx == nil {
+			fetchCtx = context.Background()
+		}
+		go r.doRefresh(fetchCtx, call)
+	}
+	r.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *CertRefresher) doRefresh(ctx context.Context, call *refreshCall) {
+	call.err = r.fetch(ctx)
+	// 停止刷新器时不计为失败
+	failed := call.err != nil && ctx.Err() == nil
+
+	r.mu.Lock()
+	r.inflight = nil
+	if failed {
+		r.stats.Failures++
+		r.stats.LastError = call.err
+	} else if call.err == nil {
+		r.stats.Refreshes++
+		r.stats.LastRefresh = time.Now()
+		r.stats.LastError = nil
+	}
+	r.mu.Unlock()
+	close(call.done)
+
+	if failed {
+		r.c.logf(xlog.ErrorLevel, "CertRefresher refresh wechat platform cert, err:%+v", call.err)
+		if r.OnError != nil {
+			r.OnError(call.err)
+		}
+	}
+}
+
+func (r *CertRefresher) fetch(ctx context.Context) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			buf := make([]byte, 64<<10)
+			buf = buf[:runtime.Stack(buf, false)]
+			err = fmt.Errorf("refresh wechat platform cert panic: %v\n%s", p, buf)
+		}
+	}()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	return r.c.refreshCerts(ctx, false)
+}
+
+// next 下次刷新的等待时间，上次失败时使用 RetryInterval
+func (r *CertRefresher) next(lastErr error) time.Duration {
+	d := r.Interval
+	if lastErr != nil && r.RetryInterval > 0 && r.RetryInterval < d {
+		d = r.RetryInterval
+	}
+	if r.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * r.Jitter * float64(d))
+	}
+	if d <= 0 {
+		d = time.Second
+	}
+	return d
+}
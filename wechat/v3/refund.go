@@ -24,7 +24,15 @@ import (
 //	Code = 0 is success
 //	商户文档：https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_1_9.shtml
 //	服务商文档：https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter4_1_9.shtml
+//	设置 client.SetIdempotency() 后按 out_refund_no 幂等，见 idempotency 包
 func (c *ClientV3) V3Refund(ctx context.Context, bm pay.BodyMap) (wxRsp *RefundRsp, err error) {
+	if c.idempotency != nil && bm.GetString("out_refund_no") != pay.NULL {
+		return c.idempotentRefund(ctx, bm)
+	}
+	return c.v3Refund(ctx, bm)
+}
+
+func (c *ClientV3) v3Refund(ctx context.Context, bm pay.BodyMap) (wxRsp *RefundRsp, err error) {
 	authorization, err := c.authorization(MethodPost, v3DomesticRefund, bm)
 	if err != nil {
 		return nil, err
@@ -44,7 +52,7 @@ func (c *ClientV3) V3Refund(ctx context.Context, bm pay.BodyMap) (wxRsp *RefundR
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询单笔退款API
@@ -77,7 +85,7 @@ func (c *ClientV3) V3RefundQuery(ctx context.Context, outRefundNo string, bm pay
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 申请退款API
@@ -104,7 +112,7 @@ func (c *ClientV3) V3EcommerceRefund(ctx context.Context, bm pay.BodyMap) (wxRsp
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 通过微信支付退款单号查询退款API
@@ -132,7 +140,7 @@ func (c *ClientV3) V3EcommerceRefundQueryById(ctx context.Context, refundId stri
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 通过商户退款单号查询退款API
@@ -160,7 +168,7 @@ func (c *ClientV3) V3EcommerceRefundQueryByNo(ctx context.Context, outRefundNo s
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 垫付退款回补API
@@ -188,7 +196,7 @@ func (c *ClientV3) V3EcommerceRefundAdvance(ctx context.Context, refundId string
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询垫付回补结果API
@@ -216,5 +224,5 @@ func (c *ClientV3) V3EcommerceRefundAdvanceResult(ctx context.Context, refundId
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -0,0 +1,61 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"context"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// Credential.Certs 中微信平台证书序列号的 key
+const CertPlatformSerialNo = "platform_serial_no"
+
+// NewClientFromCredential 根据凭证初始化微信V3客户端
+//
+//	MerchantId 为 mchid，SerialNo 为商户API证书序列号，ApiKey 为 APIv3Key，PrivateKey 为商户API私钥
+//	PublicKey 不为空时设置为微信平台证书（Certs[CertPlatformSerialNo] 为其序列号），否则调用 client.AutoVerifySign(false) 获取平台证书，
+//	不启动平台证书自动刷新，可通过注册表刷新重建客户端
+//	opts 在获取平台证书前调用，用于设置 Doer、中间件、日志、重试策略等
+func NewClientFromCredential(ctx context.Context, c *pay.Credential, opts ...func(c *ClientV3)) (client *ClientV3, err error) {
+	if client, err = NewClientV3(c.MerchantId, c.SerialNo, c.ApiKey, c.PrivateKey); err != nil {
+		return nil, err
+	}
+	client.SetBaseURL(c.BaseURL)
+	for _, opt := range opts {
+		opt(client)
+	}
+	if c.PublicKey != "" {
+		client.SetPlatformCert([]byte(c.PublicKey), c.Certs[CertPlatformSerialNo])
+		return client, nil
+	}
+	if err = client.AutoVerifySign(ctx, false); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Register 向注册表注册微信V3客户端构建方法，opts 见 NewClientFromCredential()
+func Register(r *pay.Registry, opts ...func(c *ClientV3)) {
+	r.Register(pay.ProviderWechat, func(ctx context.Context, c *pay.Credential) (interface{}, error) {
+		return NewClientFromCredential(ctx, c, opts...)
+	})
+}
+
+// FromRegistry 从注册表获取 mchid 对应的微信V3客户端，需先调用 wechat.Register()
+func FromRegistry(ctx context.Context, r *pay.Registry, mchid string) (*ClientV3, error) {
+	client, err := r.Client(ctx, pay.ProviderWechat, mchid)
+	if err != nil {
+		return nil, err
+	}
+	return client.(*ClientV3), nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"fmt"
+
+	pay "github.com/rwscode/payutil"
+)
+
+// TransactionRequest Native/App下单请求参数，mchid 为空时由 ClientV3 自动填充
+//
+//	bm, err := req.BodyMap()
+//	wxRsp, err := client.V3TransactionNative(ctx, bm)
+type TransactionRequest struct {
+	Appid         string                 `json:"appid" validate:"required,max=32"`              // 应用ID
+	Mchid         string                 `json:"mchid,omitempty" validate:"max=32"`             // 直连商户号
+	Description   string                 `json:"description" validate:"required,max=127"`       // 商品描述
+	OutTradeNo    string                 `json:"out_trade_no" validate:"required,min=6,max=32"` // 商户订单号
+	TimeExpire    string                 `json:"time_expire,omitempty" validate:"max=64"`       // 交易结束时间，rfc3339格式
+	Attach        string                 `json:"attach,omitempty" validate:"max=128"`           // 附加数据
+	NotifyUrl     string                 `json:"notify_url" validate:"required,max=256"`        // 通知地址
+	GoodsTag      string                 `json:"goods_tag,omitempty" validate:"max=32"`         // 订单优惠标记
+	SupportFapiao bool                   `json:"support_fapiao,omitempty"`                      // 电子发票入口开放标识
+	Amount        *TransactionAmount     `json:"amount" validate:"required"`                    // 订单金额
+	Payer         *TransactionPayer      `json:"payer,omitempty"`                               // 支付者，JSAPI下单必传
+	SceneInfo     *TransactionSceneInfo  `json:"scene_info,omitempty"`                          // 场景信息，H5下单必传
+	SettleInfo    *TransactionSettleInfo `json:"settle_info,omitempty"`                         // 结算信息
+}
+
+type TransactionAmount struct {
+	Total    int64  `json:"total" validate:"required,gt=0"`          // 订单总金额，单位为分
+	Currency string `json:"currency,omitempty" validate:"oneof=CNY"` // CNY：人民币，境内商户号仅支持人民币
+}
+
+type TransactionPayer struct {
+	Openid string `json:"openid" validate:"required,max=128"` // 用户在直连商户appid下的唯一标识
+}
+
+type TransactionSceneInfo struct {
+	PayerClientIp string                `json:"payer_client_ip" validate:"required,max=45"` // 用户终端IP
+	DeviceId      string                `json:"device_id,omitempty" validate:"max=32"`      // 商户端设备号
+	StoreInfo     *TransactionStoreInfo `json:"store_info,omitempty"`                       // 商户门店信息
+	H5Info        *TransactionH5Info    `json:"h5_info,omitempty"`                          // H5场景信息，H5下单必传
+}
+
+type TransactionStoreInfo struct {
+	Id       string `json:"id" validate:"required,max=32"`         // 门店编号
+	Name     string `json:"name,omitempty" validate:"max=256"`     // 门店名称
+	AreaCode string `json:"area_code,omitempty" validate:"max=32"` // 地区编码
+	Address  string `json:"address,omitempty" validate:"max=512"`  // 详细地址
+}
+
+type TransactionH5Info struct {
+	Type        string `json:"type" validate:"required,oneof=iOS Android Wap"` // 场景类型
+	AppName     string `json:"app_name,omitempty" validate:"max=64"`           // 应用名称
+	AppUrl      string `json:"app_url,omitempty" validate:"max=128"`           // 网站URL
+	BundleId    string `json:"bundle_id,omitempty" validate:"max=128"`         // iOS平台BundleID
+	PackageName string `json:"package_name,omitempty" validate:"max=128"`      // Android平台PackageName
+}
+
+type TransactionSettleInfo struct {
+	ProfitSharing bool `json:"profit_sharing,omitempty"` // 是否指定分账
+}
+
+func (r *TransactionRequest) Validate() error {
+	return pay.ValidateStruct(r)
+}
+
+// BodyMap 校验后转为 V3TransactionNative、V3TransactionApp 的请求参数
+func (r *TransactionRequest) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
+
+// TransactionJsapiRequest JSAPI/小程序下单请求参数，payer.openid 必传
+type TransactionJsapiRequest struct {
+	TransactionRequest
+}
+
+func (r *TransactionJsapiRequest) Validate() error {
+	if err := r.TransactionRequest.Validate(); err != nil {
+		return err
+	}
+	if r.Payer == nil {
+		return fmt.Errorf("[%w], payer.openid", pay.MissParamErr)
+	}
+	return nil
+}
+
+// BodyMap 校验后转为 V3TransactionJsapi 的请求参数
+func (r *TransactionJsapiRequest) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
+
+// TransactionH5Request H5下单请求参数，scene_info.payer_client_ip 与 scene_info.h5_info 必传
+type TransactionH5Request struct {
+	TransactionRequest
+}
+
+func (r *TransactionH5Request) Validate() error {
+	if err := r.TransactionRequest.Validate(); err != nil {
+		return err
+	}
+	if r.SceneInfo == nil {
+		return fmt.Errorf("[%w], scene_info.payer_client_ip", pay.MissParamErr)
+	}
+	if r.SceneInfo.H5Info == nil {
+		return fmt.Errorf("[%w], scene_info.h5_info.type", pay.MissParamErr)
+	}
+	return nil
+}
+
+// BodyMap 校验后转为 V3TransactionH5 的请求参数
+func (r *TransactionH5Request) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
+
+// RefundRequest 申请退款请求参数，transaction_id 与 out_trade_no 二选一
+type RefundRequest struct {
+	TransactionId string               `json:"transaction_id,omitempty" validate:"max=32"`         // 微信支付订单号
+	OutTradeNo    string               `json:"out_trade_no,omitempty" validate:"min=6,max=32"`     // 商户订单号
+	OutRefundNo   string               `json:"out_refund_no" validate:"required,max=64"`           // 商户退款单号
+	Reason        string               `json:"reason,omitempty" validate:"max=80"`                 // 退款原因
+	NotifyUrl     string               `json:"notify_url,omitempty" validate:"max=256"`            // 退款结果回调url
+	FundsAccount  string               `json:"funds_account,omitempty" validate:"oneof=AVAILABLE"` // 退款资金来源
+	Amount        *RefundRequestAmount `json:"amount" validate:"required"`                         // 金额信息
+}
+
+type RefundRequestAmount struct {
+	Refund   int64  `json:"refund" validate:"required,gt=0"`        // 退款金额，单位为分，不能超过原订单支付金额
+	Total    int64  `json:"total" validate:"required,gt=0"`         // 原订单金额，单位为分
+	Currency string `json:"currency" validate:"required,oneof=CNY"` // 退款币种，境内商户号仅支持人民币
+}
+
+func (r *RefundRequest) Validate() error {
+	if r.TransactionId == "" && r.OutTradeNo == "" {
+		return fmt.Errorf("[%w], transaction_id and out_trade_no are not allowed to be null at the same time", pay.MissParamErr)
+	}
+	if err := pay.ValidateStruct(r); err != nil {
+		return err
+	}
+	if r.Amount.Refund > r.Amount.Total {
+		return fmt.Errorf("[%w]: amount.refund(%d) exceeds amount.total(%d)", pay.InvalidParamErr, r.Amount.Refund, r.Amount.Total)
+	}
+	return nil
+}
+
+// BodyMap 校验后转为 V3Refund 的请求参数
+func (r *RefundRequest) BodyMap() (pay.BodyMap, error) {
+	return pay.ToBodyMap(r)
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 payutil Author. All Rights Reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//      http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wechat
+
+import (
+	"errors"
+	"testing"
+
+	pay "github.com/rwscode/payutil"
+)
+
+func TestTransactionJsapiRequest(t *testing.T) {
+	req := &TransactionJsapiRequest{TransactionRequest{
+		Appid:       "wxdace645e0bc2cXXX",
+		Description: "测试Jsapi支付商品",
+		OutTradeNo:  "20230101120000001",
+		NotifyUrl:   "https://www.example.com",
+		Amount:      &TransactionAmount{Total: 1, Currency: "CNY"},
+	}}
+	if err := req.Validate(); !errors.Is(err, pay.MissParamErr) {
+		t.Fatalf("missing payer: %v", err)
+	}
+	req.Payer = &TransactionPayer{Openid: "asdas"}
+	bm, err := req.BodyMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.GetString("out_trade_no") != req.OutTradeNo || bm.JsonBody() == "" {
+		t.Fatalf("bm = %s", bm.JsonBody())
+	}
+	var back TransactionRequest
+	if err = bm.Unmarshal(&back); err != nil || back.Amount.Total != 1 || back.Payer.Openid != "asdas" {
+		t.Fatalf("round trip: %+v, %v", back, err)
+	}
+
+	req.Description = string(make([]rune, 128))
+	if err = req.Validate(); !errors.Is(err, pay.InvalidParamErr) {
+		t.Fatalf("description too long: %v", err)
+	}
+}
+
+func TestTransactionH5Request(t *testing.T) {
+	req := &TransactionH5Request{TransactionRequest{
+		Appid:       "wxdace645e0bc2cXXX",
+		Description: "测试H5支付商品",
+		OutTradeNo:  "20230101120000001",
+		NotifyUrl:   "https://www.example.com",
+		Amount:      &TransactionAmount{Total: 1},
+		SceneInfo:   &TransactionSceneInfo{PayerClientIp: "127.0.0.1"},
+	}}
+	if err := req.Validate(); !errors.Is(err, pay.MissParamErr) {
+		t.Fatalf("missing h5_info: %v", err)
+	}
+	req.SceneInfo.H5Info = &TransactionH5Info{Type: "Web"}
+	if err := req.Validate(); !errors.Is(err, pay.InvalidParamErr) {
+		t.Fatalf("invalid h5_info.type: %v", err)
+	}
+	req.SceneInfo.H5Info.Type = "Wap"
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefundRequest(t *testing.T) {
+	req := &RefundRequest{
+		OutTradeNo:  "20230101120000001",
+		OutRefundNo: "R20230101120000001",
+		Amount:      &RefundRequestAmount{Refund: 2, Total: 1, Currency: "CNY"},
+	}
+	if err := req.Validate(); !errors.Is(err, pay.InvalidParamErr) {
+		t.Fatalf("refund exceeds total: %v", err)
+	}
+	req.Amount.Refund = 1
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
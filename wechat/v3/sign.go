@@ -12,6 +12,7 @@
 package wechat
 
 import (
+	"context"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
@@ -23,8 +24,8 @@ import (
 	"time"
 
 	pay "github.com/rwscode/payutil"
+	"github.com/rwscode/payutil/pkg/redact"
 	"github.com/rwscode/payutil/pkg/util"
-	"github.com/rwscode/payutil/pkg/xlog"
 	"github.com/rwscode/payutil/pkg/xpem"
 )
 
@@ -143,7 +144,7 @@ func (c *ClientV3) authorization(method, path string, bm pay.BodyMap) (string, e
 	ts := util.Int642String(timestamp)
 	_str := method + "\n" + path + "\n" + ts + "\n" + nonceStr + "\n" + jb + "\n"
 	if c.DebugSwitch == pay.DebugOn {
-		xlog.Debugf("Wechat_V3_SignString:\n%s", _str)
+		c.debugf("Wechat_V3_SignString:\n%s", redact.String(_str))
 	}
 	sign, err := c.rsaSign(_str)
 	if err != nil {
@@ -166,8 +167,11 @@ func (c *ClientV3) rsaSign(str string) (string, error) {
 }
 
 // 自动同步请求验签
-func (c *ClientV3) verifySyncSign(si *SignInfo) (err error) {
-	if !c.autoSign {
+func (c *ClientV3) verifySyncSign(ctx context.Context, si *SignInfo) (err error) {
+	c.rwMu.RLock()
+	autoSign := c.autoSign
+	c.rwMu.RUnlock()
+	if !autoSign {
 		return nil
 	}
 	if si == nil {
@@ -177,7 +181,12 @@ func (c *ClientV3) verifySyncSign(si *SignInfo) (err error) {
 	wxPublicKey, exist := c.SnCertMap[si.HeaderSerial]
 	c.rwMu.RUnlock()
 	if !exist {
-		err = c.AutoVerifySign(false)
+		// 应答使用了未知的平台证书，立即刷新
+		if r := c.CertRefresher(); r != nil {
+			err = r.Refresh(ctx)
+		} else {
+			err = c.refreshCerts(ctx, true)
+		}
 		if err != nil {
 			return fmt.Errorf("[get all public key err]: %v", err)
 		}
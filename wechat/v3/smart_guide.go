@@ -44,7 +44,7 @@ func (c *ClientV3) V3SmartGuideReg(ctx context.Context, bm pay.BodyMap) (wxRsp *
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 服务人员分配API
@@ -70,7 +70,7 @@ func (c *ClientV3) V3SmartGuideAssign(ctx context.Context, guideId, tradeNo stri
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 服务人员查询API
@@ -102,7 +102,7 @@ func (c *ClientV3) V3SmartGuideQuery(ctx context.Context, bm pay.BodyMap) (wxRsp
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 服务人员信息更新API
@@ -127,5 +127,5 @@ func (c *ClientV3) V3SmartGuideUpdate(ctx context.Context, guideId string, bm pa
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
@@ -24,7 +24,15 @@ import (
 //	注意：入参加密字段数据加密：client.V3EncryptText()
 //	Code = 0 is success
 //	商户文档：https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter4_3_1.shtml
+//	设置 client.SetIdempotency() 后按 out_batch_no 幂等，见 idempotency 包
 func (c *ClientV3) V3Transfer(ctx context.Context, bm pay.BodyMap) (*TransferRsp, error) {
+	if c.idempotency != nil && bm.GetString("out_batch_no") != pay.NULL {
+		return c.idempotentTransfer(ctx, bm)
+	}
+	return c.v3Transfer(ctx, bm)
+}
+
+func (c *ClientV3) v3Transfer(ctx context.Context, bm pay.BodyMap) (*TransferRsp, error) {
 	authorization, err := c.authorization(MethodPost, v3Transfer, bm)
 	if err != nil {
 		return nil, err
@@ -44,7 +52,7 @@ func (c *ClientV3) V3Transfer(ctx context.Context, bm pay.BodyMap) (*TransferRsp
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 发起批量转账API（服务商）
@@ -72,7 +80,7 @@ func (c *ClientV3) V3PartnerTransfer(ctx context.Context, bm pay.BodyMap) (*Tran
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 微信批次单号查询批次单API
@@ -102,7 +110,7 @@ func (c *ClientV3) V3TransferQuery(ctx context.Context, batchId string, bm pay.B
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 微信批次单号查询批次单API（服务商）
@@ -132,7 +140,7 @@ func (c *ClientV3) V3PartnerTransferQuery(ctx context.Context, batchId string, b
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 微信明细单号查询明细单API
@@ -160,7 +168,7 @@ func (c *ClientV3) V3TransferDetail(ctx context.Context, batchId, detailId strin
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 微信明细单号查询明细单API（服务商）
@@ -188,7 +196,7 @@ func (c *ClientV3) V3PartnerTransferDetail(ctx context.Context, batchId, detailI
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // Deprecated
@@ -224,7 +232,7 @@ func (c *ClientV3) V3TransferMerchantQuery(ctx context.Context, outBatchNo strin
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 商家批次单号查询批次单API（服务商）
@@ -254,7 +262,7 @@ func (c *ClientV3) V3PartnerTransferMerchantQuery(ctx context.Context, outBatchN
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 商家明细单号查询明细单API
@@ -282,7 +290,7 @@ func (c *ClientV3) V3TransferMerchantDetail(ctx context.Context, outBatchNo, out
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 商家明细单号查询明细单API（服务商）
@@ -310,7 +318,7 @@ func (c *ClientV3) V3PartnerTransferMerchantDetail(ctx context.Context, outBatch
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // Deprecated
@@ -347,7 +355,7 @@ func (c *ClientV3) V3TransferReceipt(ctx context.Context, outBatchNo string) (*T
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询转账电子回单API
@@ -376,7 +384,7 @@ func (c *ClientV3) V3TransferReceiptQuery(ctx context.Context, outBatchNo string
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 转账明细电子回单受理API
@@ -404,7 +412,7 @@ func (c *ClientV3) V3TransferDetailReceipt(ctx context.Context, bm pay.BodyMap)
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询转账明细电子回单受理结果API
@@ -433,5 +441,5 @@ func (c *ClientV3) V3TransferDetailReceiptQuery(ctx context.Context, bm pay.Body
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
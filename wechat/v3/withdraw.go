@@ -49,7 +49,7 @@ func (c *ClientV3) V3Withdraw(ctx context.Context, bm pay.BodyMap) (*WithdrawRsp
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 查询特约商户提现状态、二级商户查询预约提现状态
@@ -85,7 +85,7 @@ func (c *ClientV3) V3WithdrawStatus(ctx context.Context, withdrawId, outRequestN
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 电商平台预约提现
@@ -115,7 +115,7 @@ func (c *ClientV3) V3EcommerceWithdraw(ctx context.Context, bm pay.BodyMap) (*Ec
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 电商平台查询预约提现状态
@@ -151,7 +151,7 @@ func (c *ClientV3) V3EcommerceWithdrawStatus(ctx context.Context, withdrawId, ou
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }
 
 // 按日下载提现异常文件
@@ -188,5 +188,5 @@ func (c *ClientV3) V3WithdrawDownloadErrBill(ctx context.Context, bm pay.BodyMap
 		wxRsp.Error = string(bs)
 		return wxRsp, nil
 	}
-	return wxRsp, c.verifySyncSign(si)
+	return wxRsp, c.verifySyncSign(ctx, si)
 }